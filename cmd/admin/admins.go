@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+)
+
+// admins lists the system administrators, who can read the audit trail, and
+// grants or revokes the role.
+//
+//	admin admins list [-config .]
+//	admin admins grant [-config .] -email <email>
+//	admin admins revoke [-config .] -email <email>
+func admins(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected list, grant or revoke")
+	}
+
+	switch args[0] {
+	case "list":
+		return listAdmins(args[1:])
+
+	case "grant":
+		return setAdmin(args[1:], true)
+
+	case "revoke":
+		return setAdmin(args[1:], false)
+
+	default:
+		return fmt.Errorf("unknown admins command %q", args[0])
+	}
+}
+
+func listAdmins(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	flags.Parse(args)
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	users, err := store.ListAdmins(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tID")
+	for _, user := range users {
+		fmt.Fprintf(w, "%s\t%s\n", user.Email, user.ID)
+	}
+	return w.Flush()
+}
+
+// setAdmin grants or revokes the role of the account, it applies to the
+// requests that follow
+func setAdmin(args []string, isAdmin bool) error {
+	name := "revoke"
+	if isAdmin {
+		name = "grant"
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	email := flags.String("email", "", "account to change")
+	flags.Parse(args)
+
+	if *email == "" {
+		return fmt.Errorf("expected -email")
+	}
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	user, err := store.SetUserAdmin(context.Background(), db.SetUserAdminParams{
+		Email:   *email,
+		IsAdmin: isAdmin,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return fmt.Errorf("no account with email %q", *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.IsAdmin {
+		fmt.Printf("%s is now an admin\n", user.Email)
+	} else {
+		fmt.Printf("%s is no longer an admin\n", user.Email)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/audit"
+	"github.com/Luckny/space-it/pkg/config"
+)
+
+// auditLog generates checkpoint keys, verifies the audit chain and lists
+// or verifies the archives of old months.
+//
+//	admin audit generate-key
+//	admin audit verify [-config .] [-public-key <key>]
+//	admin audit archives [-config .]
+//	admin audit verify-archive [-config .] <file>
+func auditLog(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected generate-key, verify, archives or verify-archive")
+	}
+
+	switch args[0] {
+	case "generate-key":
+		key, err := audit.GenerateCheckpointKey()
+		if err != nil {
+			return err
+		}
+
+		private, err := audit.ParseCheckpointKey(key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("AUDIT_CHECKPOINT_KEY:", key)
+		fmt.Println("public key:          ", encodePublicKey(private))
+		return nil
+
+	case "verify":
+		return verifyAuditChain(args[1:])
+
+	case "archives":
+		return listAuditArchives(args[1:])
+
+	case "verify-archive":
+		return verifyAuditArchive(args[1:])
+
+	default:
+		return fmt.Errorf("unknown audit command %q", args[0])
+	}
+}
+
+// verifyAuditChain walks the audit chain and reports the first entry that
+// was changed or removed. Checkpoint signatures are checked with the public
+// key given, or with the one of the configured checkpoint key.
+func verifyAuditChain(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	publicKeyFlag := flags.String("public-key", "", "base64 public key of the checkpoint key")
+	flags.Parse(args)
+
+	var publicKey ed25519.PublicKey
+	if *publicKeyFlag != "" {
+		key, err := audit.ParseCheckpointPublicKey(*publicKeyFlag)
+		if err != nil {
+			return err
+		}
+		publicKey = key
+	} else if key := config.Load(*configPath).AuditCheckpointKey; key != "" {
+		private, err := audit.ParseCheckpointKey(key)
+		if err != nil {
+			return err
+		}
+		publicKey = private.Public().(ed25519.PublicKey)
+	}
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	report, err := audit.Verify(context.Background(), store, publicKey)
+	if err != nil {
+		return err
+	}
+
+	if publicKey == nil {
+		fmt.Println("warning: no checkpoint key, checkpoint signatures not checked")
+	}
+	fmt.Printf("%d entries and %d checkpoints verified\n", report.Entries, report.Checkpoints)
+	if report.Archived > 0 {
+		fmt.Printf("%d archived entries skipped, verify their archives with verify-archive\n", report.Archived)
+	}
+
+	if report.Break != nil {
+		return fmt.Errorf("audit chain broken at entry %d: %s", report.Break.Seq, report.Break.Reason)
+	}
+
+	fmt.Println("audit chain intact")
+	return nil
+}
+
+// listAuditArchives lists the months of audit logs no longer in the
+// database, and where they were archived
+func listAuditArchives(args []string) error {
+	flags := flag.NewFlagSet("archives", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	flags.Parse(args)
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	archives, err := store.ListAuditArchives(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MONTH\tENTRIES\tCHAIN\tFILE")
+	for _, archive := range archives {
+		chain := "-"
+		if archive.Chained > 0 {
+			chain = fmt.Sprintf("%d-%d", archive.FirstSeq, archive.LastSeq)
+		}
+		path := archive.Path
+		if path == "" {
+			path = "(dropped)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", archive.Month.Time.Format("2006-01"), archive.Entries, chain, path)
+	}
+	return w.Flush()
+}
+
+// verifyAuditArchive checks an archive file against its record and the
+// entries it holds against the audit chain. The archive is found by the
+// name of the file, which may have been moved since.
+func verifyAuditArchive(args []string) error {
+	flags := flag.NewFlagSet("verify-archive", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected the archive file")
+	}
+	path := flags.Arg(0)
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	archives, err := store.ListAuditArchives(context.Background())
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(archives, func(archive db.AuditArchive) bool {
+		return archive.Path != "" && filepath.Base(archive.Path) == filepath.Base(path)
+	})
+	if index < 0 {
+		return fmt.Errorf("no archive recorded for %s", filepath.Base(path))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report, err := audit.VerifyArchive(context.Background(), store, archives[index], file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d chained entries verified\n", report.Entries)
+	if report.Break != nil {
+		if report.Break.Seq == 0 {
+			return fmt.Errorf("archive broken: %s", report.Break.Reason)
+		}
+		return fmt.Errorf("archive broken at entry %d: %s", report.Break.Seq, report.Break.Reason)
+	}
+
+	fmt.Println("archive intact")
+	return nil
+}
+
+func encodePublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
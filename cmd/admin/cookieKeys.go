@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Luckny/space-it/pkg/token"
+)
+
+const cookieKeysEnv = "COOKIE_KEYS"
+
+// cookieKeys generates new cookie key pairs and promotes them to signing keys.
+//
+//	admin cookie-keys generate
+//	admin cookie-keys promote [-env app.env] [-key <pair>] [-retain 3]
+func cookieKeys(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected generate or promote")
+	}
+
+	switch args[0] {
+	case "generate":
+		pair, err := token.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+		fmt.Println(pair.String())
+		return nil
+
+	case "promote":
+		return promoteCookieKey(args[1:])
+
+	default:
+		return fmt.Errorf("unknown cookie-keys command %q", args[0])
+	}
+}
+
+// promoteCookieKey makes a key pair the signing key in the env file. The
+// previous keys are kept for verification so existing sessions stay valid
+// until they expire; restart the servers to pick up the change.
+func promoteCookieKey(args []string) error {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	envFile := flags.String("env", "app.env", "env file holding "+cookieKeysEnv)
+	key := flags.String("key", "", "key pair to promote, a new one is generated if empty")
+	retain := flags.Int("retain", 3, "number of key pairs to keep, 0 keeps all")
+	flags.Parse(args)
+
+	if *key == "" {
+		pair, err := token.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+		*key = pair.String()
+	}
+
+	if _, err := token.ParseKeyPair(*key); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(*envFile)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	keys, index := findEnvValue(lines, cookieKeysEnv)
+
+	var current []string
+	if keys != "" {
+		current = strings.Split(keys, ",")
+	}
+
+	// refuse to write a file the server would not start with
+	if _, err := token.ParseKeyPairs(current); err != nil {
+		return err
+	}
+
+	line := cookieKeysEnv + "=" + strings.Join(token.PromoteKey(current, *key, *retain), ",")
+	if index < 0 {
+		// keep the trailing newline last
+		if n := len(lines); n > 0 && lines[n-1] == "" {
+			lines = append(lines[:n-1], line, "")
+		} else {
+			lines = append(lines, line)
+		}
+	} else {
+		lines[index] = line
+	}
+
+	info, err := os.Stat(*envFile)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(*envFile, []byte(strings.Join(lines, "\n")), info.Mode())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("promoted cookie key, restart the api servers to start signing with it")
+	return nil
+}
+
+// findEnvValue returns the value of name in env file lines and its line index, -1 if not set
+func findEnvValue(lines []string, name string) (string, int) {
+	for i, line := range lines {
+		k, v, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == name {
+			return strings.Trim(strings.TrimSpace(v), `"`), i
+		}
+	}
+	return "", -1
+}
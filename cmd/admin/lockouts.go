@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockouts lists the accounts and client IPs locked out after failed
+// logins and unlocks them.
+//
+//	admin lockouts list [-config .]
+//	admin lockouts unlock [-config .] [-email <email>] [-ip <client ip>]
+func lockouts(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected list or unlock")
+	}
+
+	switch args[0] {
+	case "list":
+		return listLockouts(args[1:])
+
+	case "unlock":
+		return unlockLogin(args[1:])
+
+	default:
+		return fmt.Errorf("unknown lockouts command %q", args[0])
+	}
+}
+
+// listLockouts prints the active lockouts, an empty email is a client IP
+// locked out across accounts
+func listLockouts(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	flags.Parse(args)
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	active, err := store.ListActiveLoginLockouts(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tCLIENT IP\tFAILURES\tLOCKED UNTIL")
+	for _, lockout := range active {
+		email := lockout.Email
+		if email == "" {
+			email = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", email, lockout.ClientIp, lockout.Failures, lockout.LockedUntil.Time.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// unlockLogin clears the failed logins of an account, of a client IP or of
+// the account from the client IP when both are given
+func unlockLogin(args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ExitOnError)
+	configPath := flags.String("config", ".", "directory holding app.env")
+	email := flags.String("email", "", "account to unlock")
+	clientIP := flags.String("ip", "", "client IP to unlock")
+	flags.Parse(args)
+
+	if *email == "" && *clientIP == "" {
+		return fmt.Errorf("expected -email or -ip")
+	}
+
+	store, closeStore, err := openStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	unlocked, err := store.UnlockLoginTx(context.Background(), db.UnlockLoginTxParams{
+		Email:    pgtype.Text{String: *email, Valid: *email != ""},
+		ClientIp: pgtype.Text{String: *clientIP, Valid: *clientIP != ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("cleared failed logins, %d lockout(s) ended\n", unlocked)
+	return nil
+}
+
+// openStore connects to the database of the api server configured in path
+func openStore(path string) (db.Store, func(), error) {
+	config := config.Load(path)
+
+	connPool, err := pgxpool.New(context.Background(), config.DBSource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := connPool.Ping(context.Background()); err != nil {
+		connPool.Close()
+		return nil, nil, err
+	}
+
+	return db.NewStore(connPool), connPool.Close, nil
+}
@@ -0,0 +1,68 @@
+// Command admin groups maintenance tasks that operators run next to the api server.
+//
+//	go run ./cmd/admin <command> [flags]
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"admins": {
+		usage: "list, grant or revoke system administrators",
+		run:   admins,
+	},
+	"audit": {
+		usage: "generate audit checkpoint keys, verify the audit chain or its archives",
+		run:   auditLog,
+	},
+	"cookie-keys": {
+		usage: "generate or promote session cookie keys",
+		run:   cookieKeys,
+	},
+	"lockouts": {
+		usage: "list or end login lockouts",
+		run:   lockouts,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: admin <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+)
+
+// what happens to the spaces of a deleted account
+const (
+	// spaces go to their member with the most access, spaces without other
+	// members are deleted
+	spacePolicyTransfer = "transfer"
+	spacePolicyDelete   = "delete"
+)
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Spaces   string `json:"spaces"   binding:"required,oneof=transfer delete"`
+}
+
+type deleteAccountResponse struct {
+	TransferredSpaces []db.Space `json:"transferred_spaces"`
+	DeletedSpaces     []db.Space `json:"deleted_spaces"`
+}
+
+// deleteAccount deletes the account of the user, the password is required.
+// The user's spaces are transferred to another member or deleted, as chosen,
+// and the user is signed out everywhere. The request logs of the user are
+// kept, the account they reference is anonymized.
+func (server *Server) deleteAccount(ctx *gin.Context) {
+	var req deleteAccountRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	dbUser, err := server.store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a stolen session must not be enough to delete the account, nor to
+	// guess the password
+	if !server.loginThrottle.CheckPassword(ctx, dbUser, req.Password, fmt.Errorf("password is incorrect")) {
+		return
+	}
+
+	result, err := server.store.DeleteUserTx(ctx, db.DeleteUserTxParams{
+		UserID:         user.ID,
+		TransferSpaces: req.Spaces == spacePolicyTransfer,
+		RequestID:      httpx.GetRequestIDFromContext(ctx),
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, deleteAccountResponse{
+		TransferredSpaces: result.TransferredSpaces,
+		DeletedSpaces:     result.DeletedSpaces,
+	})
+}
+
+// exportAccount responds with a ZIP archive of the data kept about the user,
+// one JSON file per kind of record
+func (server *Server) exportAccount(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	dbUser, err := server.store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	spaces, err := server.store.ListSpacesByOwner(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	memberships, err := server.store.ListPermissionsByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	messages, err := server.store.ListMessagesByAuthor(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	requests, err := server.store.ListRequestLogsByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses, err := server.store.ListResponseLogsByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, file := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", dto.NewProfile(dbUser)},
+		{"spaces.json", spaces},
+		{"memberships.json", memberships},
+		{"messages.json", messages},
+		{"audit/requests.json", requests},
+		{"audit/responses.json", responses},
+	} {
+		if err := writeJSONFile(zw, file.name, file.value); err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("space-it-export-%s.zip", time.Now().UTC().Format("20060102"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// writeJSONFile adds the indented JSON encoding of value to the archive
+func writeJSONFile(zw *zip.Writer, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDeleteAccountAPI(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+	member, _ := mockdb.RandomUser(t)
+	transferred := mockdb.RandomSpace(t, member.ID)
+	deleted := mockdb.RandomSpace(t, user.ID)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "transfer spaces",
+			body: gin.H{"password": password, "spaces": spacePolicyTransfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Eq(db.DeleteUserTxParams{
+						UserID:         user.ID,
+						TransferSpaces: true,
+					})).
+					Times(1).
+					Return(db.DeleteUserTxResult{
+						TransferredSpaces: []db.Space{transferred},
+						DeletedSpaces:     []db.Space{deleted},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res deleteAccountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Len(t, res.TransferredSpaces, 1)
+				require.Equal(t, member.ID, res.TransferredSpaces[0].Owner)
+				require.Len(t, res.DeletedSpaces, 1)
+				require.Equal(t, deleted.ID, res.DeletedSpaces[0].ID)
+			},
+		},
+
+		{
+			name: "delete spaces",
+			body: gin.H{"password": password, "spaces": spacePolicyDelete},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Eq(db.DeleteUserTxParams{
+						UserID:         user.ID,
+						TransferSpaces: false,
+					})).
+					Times(1).
+					Return(db.DeleteUserTxResult{
+						TransferredSpaces: []db.Space{},
+						DeletedSpaces:     []db.Space{deleted},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "wrong password",
+			body: gin.H{"password": "wrong-password", "spaces": spacePolicyDelete},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				// the failure counts for the account and for the client IP
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						require.Contains(t, []string{user.Email, ""}, arg.Email)
+						return db.LoginThrottle{Email: arg.Email, ClientIp: arg.ClientIp, Failures: 1}, nil
+					})
+				store.EXPECT().BlockLogin(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		{
+			name: "password attempts blocked",
+			body: gin.H{"password": password, "spaces": spacePolicyDelete},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListLoginThrottlesParams) ([]db.LoginThrottle, error) {
+						require.Equal(t, user.Email, arg.Email)
+						return []db.LoginThrottle{{
+							Email:        user.Email,
+							ClientIp:     arg.ClientIp,
+							Failures:     5,
+							BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(time.Minute), Valid: true},
+						}}, nil
+					})
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+			},
+		},
+
+		{
+			name: "no space policy",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "unknown space policy",
+			body: gin.H{"password": password, "spaces": "keep"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"password": password, "spaces": spacePolicyTransfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.DeleteUserTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.DELETE("/users/me", server.deleteAccount)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodDelete, "/users/me", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestExportAccountAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	user.DisplayName = "Ada"
+	user.Avatar = "https://example.com/ada.png"
+	user.Timezone = "Europe/London"
+	user.Locale = "en-GB"
+	space := mockdb.RandomSpace(t, user.ID)
+	membership := db.Permission{SpaceID: space.ID, UserID: user.ID, ReadPermission: true}
+	message := db.Message{SpaceID: space.ID, Author: user.ID}
+	request := db.RequestLog{Method: http.MethodGet, Path: "/api/v1/test", UserID: user.ID}
+
+	buildStubs := func(store *mockdb.MockStore, requestsErr error) {
+		store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+		store.EXPECT().ListSpacesByOwner(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return([]db.Space{space}, nil)
+		store.EXPECT().
+			ListPermissionsByUser(gomock.Any(), gomock.Eq(user.ID)).
+			Times(1).
+			Return([]db.Permission{membership}, nil)
+		store.EXPECT().
+			ListMessagesByAuthor(gomock.Any(), gomock.Eq(user.ID)).
+			Times(1).
+			Return([]db.Message{message}, nil)
+		store.EXPECT().
+			ListRequestLogsByUser(gomock.Any(), gomock.Eq(user.ID)).
+			Times(1).
+			Return([]db.RequestLog{request}, requestsErr)
+		if requestsErr == nil {
+			store.EXPECT().
+				ListResponseLogsByUser(gomock.Any(), gomock.Eq(user.ID)).
+				Times(1).
+				Return([]db.ResponseLog{{ID: request.ID, Status: http.StatusOK}}, nil)
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				buildStubs(store, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Header().Get("Content-Disposition"), "attachment")
+
+				body := recorder.Body.Bytes()
+				archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+				require.NoError(t, err)
+
+				files := map[string][]byte{}
+				for _, file := range archive.File {
+					r, err := file.Open()
+					require.NoError(t, err)
+					files[file.Name], err = io.ReadAll(r)
+					require.NoError(t, err)
+					r.Close()
+				}
+				require.Len(t, files, 6)
+
+				var profile dto.Profile
+				require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+				require.Equal(t, user.ID, profile.ID)
+				require.Equal(t, user.Email, profile.Email)
+				require.Equal(t, user.DisplayName, profile.DisplayName)
+				require.Equal(t, user.Avatar, profile.Avatar)
+				require.Equal(t, user.Timezone, profile.Timezone)
+				require.Equal(t, user.Locale, profile.Locale)
+				require.NotContains(t, string(files["profile.json"]), user.Password)
+
+				var spaces []db.Space
+				require.NoError(t, json.Unmarshal(files["spaces.json"], &spaces))
+				require.Len(t, spaces, 1)
+				require.Equal(t, space.ID, spaces[0].ID)
+
+				var memberships []db.Permission
+				require.NoError(t, json.Unmarshal(files["memberships.json"], &memberships))
+				require.Equal(t, []db.Permission{membership}, memberships)
+
+				var messages []db.Message
+				require.NoError(t, json.Unmarshal(files["messages.json"], &messages))
+				require.Len(t, messages, 1)
+
+				require.Contains(t, string(files["audit/requests.json"]), request.Path)
+				require.Contains(t, string(files["audit/responses.json"]), `"status": 200`)
+			},
+		},
+
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				buildStubs(store, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.GET("/users/me/export", server.exportAccount)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me/export", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
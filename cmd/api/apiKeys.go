@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	res := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Time,
+	}
+	if key.LastUsedAt.Valid {
+		res.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.ExpiresAt.Valid {
+		res.ExpiresAt = &key.ExpiresAt.Time
+	}
+	return res
+}
+
+type createAPIKeyRequest struct {
+	Name      string              `json:"name"       binding:"required,max=64"`
+	Scopes    []middlewares.Scope `json:"scopes"     binding:"required,min=1,dive,scope"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	// only returned once, when the key is created
+	Key string `json:"key"`
+}
+
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("expires_at must be in the future"))
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	arg := db.CreateAPIKeyParams{
+		UserID:  user.ID,
+		Name:    req.Name,
+		Prefix:  key.DisplayPrefix,
+		KeyHash: key.Hash,
+		Scopes:  scopes,
+	}
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = pgtype.Timestamp{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	created, err := server.store.CreateAPIKey(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusCreated, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(created),
+		Key:            key.Key,
+	})
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	keys, err := server.store.ListAPIKeysByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		res[i] = newAPIKeyResponse(key)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	keyID, err := uuid.Parse(ctx.Param("apiKeyID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid api key id"))
+		return
+	}
+
+	arg := db.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: user.ID,
+	}
+
+	revoked, err := server.store.RevokeAPIKey(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a key of another user is reported as not found too
+	if revoked == 0 {
+		httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("api key not found"))
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, created *db.CreateAPIKeyParams)
+		checkResponse func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams)
+	}{
+		{
+			name: "should create key",
+			body: gin.H{
+				"name":   "ci",
+				"scopes": []string{"spaces:read", "messages:write"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						*created = arg
+						return db.ApiKey{
+							ID:      uuid.New(),
+							UserID:  arg.UserID,
+							Name:    arg.Name,
+							Prefix:  arg.Prefix,
+							KeyHash: arg.KeyHash,
+							Scopes:  arg.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var res createAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+
+				// only the hash of the returned key is stored
+				require.Equal(t, user.ID, created.UserID)
+				require.Equal(t, apikey.Hash(res.Key), created.KeyHash)
+				require.NotContains(t, recorder.Body.String(), created.KeyHash)
+				require.Equal(t, []string{"spaces:read", "messages:write"}, res.Scopes)
+				require.Equal(t, res.Key[:len(res.Prefix)], res.Prefix)
+				require.Nil(t, res.ExpiresAt)
+			},
+		},
+
+		{
+			name: "with expiry",
+			body: gin.H{
+				"name":       "ci",
+				"scopes":     []string{"spaces:read"},
+				"expires_at": time.Now().Add(24 * time.Hour),
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						*created = arg
+						return db.ApiKey{ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.True(t, created.ExpiresAt.Valid)
+			},
+		},
+
+		{
+			name: "expiry in the past",
+			body: gin.H{
+				"name":       "ci",
+				"scopes":     []string{"spaces:read"},
+				"expires_at": time.Now().Add(-time.Hour),
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "unknown scope",
+			body: gin.H{
+				"name":   "ci",
+				"scopes": []string{"users:admin"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "no scope",
+			body: gin.H{
+				"name":   "ci",
+				"scopes": []string{},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{
+				"name":   "ci",
+				"scopes": []string{"spaces:read"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateAPIKeyParams) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateAPIKeyParams) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+
+			var created db.CreateAPIKeyParams
+			tc.buildStubs(store, &created)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/users/me/api-keys", server.createAPIKey)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/api-keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, created)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	key := db.ApiKey{
+		ID:      uuid.New(),
+		UserID:  user.ID,
+		Name:    "ci",
+		Prefix:  "sit_abcdefgh",
+		KeyHash: apikey.Hash("sit_abcdefgh"),
+		Scopes:  []string{"spaces:read"},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAPIKeysByUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return([]db.ApiKey{key}, nil)
+
+	server := NewServer(store, testConfig())
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		httpx.SetUserInContext(ctx, user)
+		ctx.Next()
+	})
+	router.GET("/users/me/api-keys", server.listAPIKeys)
+
+	request, err := http.NewRequest(http.MethodGet, "/users/me/api-keys", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), key.KeyHash)
+
+	var res []apiKeyResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, key.ID, res[0].ID)
+	require.Equal(t, key.Prefix, res[0].Prefix)
+	require.Nil(t, res[0].LastUsedAt)
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	keyID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		apiKeyID      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "should revoke key",
+			apiKeyID: keyID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeAPIKeyParams{ID: keyID, UserID: user.ID}
+
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:     "not found",
+			apiKeyID: keyID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name:     "invalid api key id",
+			apiKeyID: "invalid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.DELETE("/users/me/api-keys/:apiKeyID", server.revokeAPIKey)
+
+			url := fmt.Sprintf("/users/me/api-keys/%s", tc.apiKeyID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// TestAPIKeyRoutes checks which routes accept an API key through the whole router
+func TestAPIKeyRoutes(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		KeyHash:    key.Hash,
+		Scopes:     []string{"spaces:write"},
+		LastUsedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetActiveAPIKeyByHash(gomock.Any(), gomock.Eq(key.Hash)).AnyTimes().Return(apiKey, nil)
+	store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).AnyTimes().Return(user, nil)
+	store.EXPECT().
+		CreateSpaceTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(mockdb.RandomSpaceTxResult(t, user.ID), nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().ListActiveSessionsByUser(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+
+	server := NewServer(store, testConfig())
+	disableRateLimits(server)
+
+	spaceBody, err := json.Marshal(gin.H{"name": "space-from-script"})
+	require.NoError(t, err)
+	keyBody, err := json.Marshal(gin.H{"name": "escalate", "scopes": []string{"spaces:admin"}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		code   int
+	}{
+		{name: "create space with scope", method: http.MethodPost, path: "/spaces", body: spaceBody, code: http.StatusCreated},
+		{name: "login", method: http.MethodPost, path: "/users/login", code: http.StatusForbidden},
+		{name: "list sessions", method: http.MethodGet, path: "/users/me/sessions", code: http.StatusForbidden},
+		{name: "create api key", method: http.MethodPost, path: "/users/me/api-keys", body: keyBody, code: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, makeUrl(tc.path), bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+key.Key)
+
+			recorder := httptest.NewRecorder()
+			server.Router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// audit entries listed in a page when no limit is given, and read at once
+// when exporting
+const (
+	defaultAuditPageSize = 100
+	auditExportPageSize  = 1000
+)
+
+// formats the audit entries are listed in
+const (
+	auditFormatJSON   = "json"
+	auditFormatCSV    = "csv"
+	auditFormatNDJSON = "ndjson"
+)
+
+// filters are combined, a path ending with * matches the paths starting with
+// it. Times are RFC 3339, since is inclusive and until exclusive.
+type listAuditLogsRequest struct {
+	UserID    string    `form:"user_id"    binding:"omitempty,uuid"`
+	Path      string    `form:"path"       binding:"max=101"`
+	Method    string    `form:"method"     binding:"max=10"`
+	MinStatus int32     `form:"min_status" binding:"omitempty,min=100,max=599"`
+	MaxStatus int32     `form:"max_status" binding:"omitempty,min=100,max=599"`
+	Since     time.Time `form:"since"`
+	Until     time.Time `form:"until"`
+	// next_cursor of the previous page
+	Cursor string `form:"cursor"`
+	Limit  int32  `form:"limit"  binding:"omitempty,min=1,max=1000"`
+	Format string `form:"format" binding:"omitempty,oneof=json csv ndjson"`
+}
+
+// auditLogResponse is an audit entry, the user and space are null when the
+// request had none
+type auditLogResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Query        string     `json:"query"`
+	UserID       *uuid.UUID `json:"user_id"`
+	AuthMethod   string     `json:"auth_method"`
+	SpaceID      *uuid.UUID `json:"space_id"`
+	RequestID    string     `json:"request_id"`
+	ClientIP     string     `json:"client_ip"`
+	UserAgent    string     `json:"user_agent"`
+	RequestSize  int64      `json:"request_size"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	Status       int32      `json:"status"`
+	ResponseSize int64      `json:"response_size"`
+	LatencyUs    int64      `json:"latency_us"`
+	Outcome      string     `json:"outcome"`
+	RespondedAt  time.Time  `json:"responded_at"`
+}
+
+type listAuditLogsResponse struct {
+	Entries []auditLogResponse `json:"entries"`
+	// empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// listAuditLogs responds with the audit entries matching the filters, newest
+// first. JSON responses are paginated, CSV and NDJSON exports hold every
+// entry from the cursor on.
+func (server *Server) listAuditLogs(ctx *gin.Context) {
+	var req listAuditLogsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.MinStatus != 0 && req.MaxStatus != 0 && req.MinStatus > req.MaxStatus {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("min_status is greater than max_status"))
+		return
+	}
+
+	arg, err := newListAuditLogsParams(req)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	switch req.Format {
+	case auditFormatCSV, auditFormatNDJSON:
+		server.exportAuditLogs(ctx, req.Format, arg)
+		return
+	}
+
+	rows, err := server.store.ListAuditLogs(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := listAuditLogsResponse{Entries: make([]auditLogResponse, 0, len(rows))}
+	for _, row := range rows {
+		res.Entries = append(res.Entries, newAuditLogResponse(row))
+	}
+	if len(rows) == int(arg.PageSize) {
+		last := rows[len(rows)-1]
+		res.NextCursor = encodeCursor(last.RequestedAt.Time, last.ID)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+// exportAuditLogs writes the entries page after page as they are read. An
+// error once the response has started can only cut it short.
+func (server *Server) exportAuditLogs(ctx *gin.Context, format string, arg db.ListAuditLogsParams) {
+	arg.PageSize = auditExportPageSize
+
+	contentType := "text/csv; charset=utf-8"
+	if format == auditFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+
+	filename := fmt.Sprintf("space-it-audit-%s.%s", time.Now().UTC().Format("20060102"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "no-store")
+
+	csvWriter := csv.NewWriter(ctx.Writer)
+	encoder := json.NewEncoder(ctx.Writer)
+
+	started := false
+	for {
+		rows, err := server.store.ListAuditLogs(ctx, arg)
+		if err != nil {
+			if !started {
+				httpx.WriteError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+			slog.ErrorContext(ctx, "audit export: interrupted", "error", err)
+			ctx.Abort()
+			return
+		}
+
+		if !started {
+			ctx.Status(http.StatusOK)
+			if format == auditFormatCSV {
+				csvWriter.Write(auditCSVHeader)
+			}
+			started = true
+		}
+
+		for _, row := range rows {
+			entry := newAuditLogResponse(row)
+			if format == auditFormatCSV {
+				err = csvWriter.Write(entry.csvRecord())
+			} else {
+				err = encoder.Encode(entry)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "audit export: interrupted", "error", err)
+				return
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			slog.ErrorContext(ctx, "audit export: interrupted", "error", err)
+			return
+		}
+		ctx.Writer.Flush()
+
+		if len(rows) < int(arg.PageSize) {
+			return
+		}
+		last := rows[len(rows)-1]
+		arg.CursorAt, arg.CursorID = last.RequestedAt, last.ID
+	}
+}
+
+func newListAuditLogsParams(req listAuditLogsRequest) (db.ListAuditLogsParams, error) {
+	arg := db.ListAuditLogsParams{
+		PageSize: req.Limit,
+	}
+	if arg.PageSize == 0 {
+		arg.PageSize = defaultAuditPageSize
+	}
+
+	if req.UserID != "" {
+		arg.UserID = uuid.MustParse(req.UserID)
+	}
+
+	if req.Path != "" {
+		pattern := escapeLike(req.Path)
+		if prefix, ok := strings.CutSuffix(req.Path, "*"); ok {
+			pattern = escapeLike(prefix) + "%"
+		}
+		arg.Path = pgtype.Text{String: pattern, Valid: true}
+	}
+
+	if req.Method != "" {
+		arg.Method = pgtype.Text{String: strings.ToUpper(req.Method), Valid: true}
+	}
+
+	if req.MinStatus != 0 {
+		arg.MinStatus = pgtype.Int4{Int32: req.MinStatus, Valid: true}
+	}
+	if req.MaxStatus != 0 {
+		arg.MaxStatus = pgtype.Int4{Int32: req.MaxStatus, Valid: true}
+	}
+
+	if !req.Since.IsZero() {
+		arg.Since = pgtype.Timestamp{Time: req.Since.UTC(), Valid: true}
+	}
+	if !req.Until.IsZero() {
+		arg.Until = pgtype.Timestamp{Time: req.Until.UTC(), Valid: true}
+	}
+
+	if req.Cursor != "" {
+		at, id, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return arg, err
+		}
+		arg.CursorAt = pgtype.Timestamp{Time: at, Valid: true}
+		arg.CursorID = id
+	}
+
+	return arg, nil
+}
+
+// escapeLike escapes the characters LIKE patterns give a meaning to
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func newAuditLogResponse(row db.ListAuditLogsRow) auditLogResponse {
+	res := auditLogResponse{
+		ID:           row.ID,
+		Method:       row.Method,
+		Path:         row.Path,
+		Query:        row.Query,
+		AuthMethod:   row.AuthMethod,
+		RequestID:    row.RequestID,
+		ClientIP:     row.ClientIp,
+		UserAgent:    row.UserAgent,
+		RequestSize:  row.RequestSize,
+		RequestedAt:  row.RequestedAt.Time,
+		Status:       row.Status,
+		ResponseSize: row.ResponseSize,
+		LatencyUs:    row.LatencyUs,
+		Outcome:      row.Outcome,
+		RespondedAt:  row.RespondedAt.Time,
+	}
+	if row.UserID != uuid.Nil {
+		res.UserID = &row.UserID
+	}
+	if row.SpaceID != uuid.Nil {
+		res.SpaceID = &row.SpaceID
+	}
+
+	return res
+}
+
+// columns of CSV exports, named as the JSON fields
+var auditCSVHeader = []string{
+	"id",
+	"method",
+	"path",
+	"query",
+	"user_id",
+	"auth_method",
+	"space_id",
+	"request_id",
+	"client_ip",
+	"user_agent",
+	"request_size",
+	"requested_at",
+	"status",
+	"response_size",
+	"latency_us",
+	"outcome",
+	"responded_at",
+}
+
+// csvRecord returns the entry as a CSV record, a missing user or space is
+// empty. Cells are escaped with csvCell.
+func (e auditLogResponse) csvRecord() []string {
+	optionalID := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+
+	record := []string{
+		e.ID.String(),
+		e.Method,
+		e.Path,
+		e.Query,
+		optionalID(e.UserID),
+		e.AuthMethod,
+		optionalID(e.SpaceID),
+		e.RequestID,
+		e.ClientIP,
+		e.UserAgent,
+		strconv.FormatInt(e.RequestSize, 10),
+		e.RequestedAt.Format(time.RFC3339Nano),
+		strconv.Itoa(int(e.Status)),
+		strconv.FormatInt(e.ResponseSize, 10),
+		strconv.FormatInt(e.LatencyUs, 10),
+		e.Outcome,
+		e.RespondedAt.Format(time.RFC3339Nano),
+	}
+	for i, cell := range record {
+		record[i] = csvCell(cell)
+	}
+	return record
+}
+
+// csvCell prefixes the cells spreadsheets would read as a formula with a
+// quote. Paths, queries and user agents are sent by clients, an export must
+// not run what they hold when opened.
+func csvCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomAuditLog(userID uuid.UUID, at time.Time) db.ListAuditLogsRow {
+	return db.ListAuditLogsRow{
+		ID:          uuid.New(),
+		Method:      http.MethodGet,
+		Path:        "/api/v1/users/me",
+		UserID:      userID,
+		AuthMethod:  "session",
+		RequestID:   "request",
+		ClientIp:    "192.0.2.1",
+		UserAgent:   "test, with a comma",
+		RequestedAt: pgtype.Timestamp{Time: at, Valid: true},
+		Status:      http.StatusOK,
+		LatencyUs:   1200,
+		Outcome:     "success",
+		RespondedAt: pgtype.Timestamp{Time: at.Add(time.Millisecond), Valid: true},
+	}
+}
+
+func TestListAuditLogsAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	logs := []db.ListAuditLogsRow{
+		randomAuditLog(user.ID, now),
+		randomAuditLog(uuid.Nil, now.Add(-time.Second)),
+	}
+	cursor := encodeCursor(logs[1].RequestedAt.Time, logs[1].ID)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "first page",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{PageSize: defaultAuditPageSize})).
+					Times(1).
+					Return(logs, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res listAuditLogsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Len(t, res.Entries, 2)
+				require.Equal(t, logs[0].ID, res.Entries[0].ID)
+				require.Equal(t, user.ID, *res.Entries[0].UserID)
+				require.Nil(t, res.Entries[1].UserID)
+				require.Empty(t, res.NextCursor)
+			},
+		},
+
+		{
+			name:  "full page",
+			query: "?limit=2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{PageSize: 2})).
+					Times(1).
+					Return(logs, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res listAuditLogsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, cursor, res.NextCursor)
+			},
+		},
+
+		{
+			name:  "next page",
+			query: "?limit=2&cursor=" + cursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+						CursorAt: logs[1].RequestedAt,
+						CursorID: logs[1].ID,
+						PageSize: 2,
+					})).
+					Times(1).
+					Return([]db.ListAuditLogsRow{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"entries": [], "next_cursor": ""}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name: "filters",
+			query: "?user_id=" + user.ID.String() +
+				"&path=/api/v1/spaces/*&method=post&min_status=400&max_status=499" +
+				"&since=2024-01-01T00:00:00Z&until=2024-02-01T00:00:00%2B01:00",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+						UserID:    user.ID,
+						Path:      pgtype.Text{String: "/api/v1/spaces/%", Valid: true},
+						Method:    pgtype.Text{String: http.MethodPost, Valid: true},
+						MinStatus: pgtype.Int4{Int32: 400, Valid: true},
+						MaxStatus: pgtype.Int4{Int32: 499, Valid: true},
+						Since:     pgtype.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+						Until:     pgtype.Timestamp{Time: time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC), Valid: true},
+						PageSize:  defaultAuditPageSize,
+					})).
+					Times(1).
+					Return([]db.ListAuditLogsRow{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:  "path wildcards are escaped",
+			query: "?path=/api/v1/100%25_done",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+						Path:     pgtype.Text{String: `/api/v1/100\%\_done`, Valid: true},
+						PageSize: defaultAuditPageSize,
+					})).
+					Times(1).
+					Return([]db.ListAuditLogsRow{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:  "csv export",
+			query: "?format=csv&method=GET",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+						Method:   pgtype.Text{String: http.MethodGet, Valid: true},
+						PageSize: auditExportPageSize,
+					})).
+					Times(1).
+					Return(logs, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Header().Get("Content-Disposition"), "attachment")
+
+				records, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 3)
+				require.Equal(t, auditCSVHeader, records[0])
+				require.Equal(t, logs[0].ID.String(), records[1][0])
+				require.Equal(t, user.ID.String(), records[1][4])
+				require.Equal(t, "test, with a comma", records[1][9])
+				require.Empty(t, records[2][4])
+			},
+		},
+
+		{
+			name:  "csv export of formulas",
+			query: "?format=csv",
+			buildStubs: func(store *mockdb.MockStore) {
+				formulas := randomAuditLog(user.ID, now)
+				formulas.Query = "-2+3"
+				formulas.RequestID = "+cmd"
+				formulas.UserAgent = `=HYPERLINK("https://attacker.example.com")`
+				formulas.AuthMethod = "@SUM(A1)"
+
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListAuditLogsRow{formulas}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				records, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 2)
+				require.Equal(t, "/api/v1/users/me", records[1][2])
+				require.Equal(t, "'-2+3", records[1][3])
+				require.Equal(t, "'@SUM(A1)", records[1][5])
+				require.Equal(t, "'+cmd", records[1][7])
+				require.Equal(t, `'=HYPERLINK("https://attacker.example.com")`, records[1][9])
+			},
+		},
+
+		{
+			name:  "ndjson export",
+			query: "?format=ndjson",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(logs, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+
+				scanner := bufio.NewScanner(recorder.Body)
+				ids := []uuid.UUID{}
+				for scanner.Scan() {
+					var entry auditLogResponse
+					require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+					ids = append(ids, entry.ID)
+				}
+				require.Equal(t, []uuid.UUID{logs[0].ID, logs[1].ID}, ids)
+			},
+		},
+
+		{
+			name:  "invalid cursor",
+			query: "?cursor=not-a-cursor",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "invalid status range",
+			query: "?min_status=500&max_status=400",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "invalid time",
+			query: "?since=yesterday",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "invalid format",
+			query: "?format=xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "internal error",
+			query: "?format=csv",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.GET("/admin/audit", server.listAuditLogs)
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit"+tc.query, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errInvalidCursor is returned for cursors not given by a previous page
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor returns the cursor of the records listed after the one
+// created at the given time with the given id, newest first
+func encodeCursor(at time.Time, id uuid.UUID) string {
+	cursor := fmt.Sprintf("%d.%s", at.UnixMicro(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	at, id, ok := strings.Cut(string(data), ".")
+	if !ok {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	recordID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	return time.UnixMicro(micros).UTC(), recordID, nil
+}
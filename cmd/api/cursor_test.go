@@ -0,0 +1,27 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	id := uuid.New()
+	cursor := encodeCursor(createdAt, id)
+
+	at, decodedID, err := decodeCursor(cursor)
+	require.NoError(t, err)
+	require.Equal(t, createdAt, at)
+	require.Equal(t, id, decodedID)
+
+	for _, cursor := range []string{"", "!!", "bm8tZG90", "MTIzLm5vdC1hLXV1aWQ"} {
+		_, _, err := decodeCursor(cursor)
+		require.ErrorIs(t, err, errInvalidCursor, cursor)
+	}
+	require.False(t, strings.ContainsAny(cursor, "+/="))
+}
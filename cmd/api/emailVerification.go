@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// how long a verification link can be used
+const emailVerificationTokenDuration = 24 * time.Hour
+
+// at most verificationEmailLimit verification emails are sent to a user
+// within verificationEmailWindow
+const (
+	verificationEmailLimit  = 3
+	verificationEmailWindow = time.Hour
+)
+
+// what an unverified email can block, see config.EmailVerificationRequired
+const (
+	verifiedEmailForLogin  = config.EmailVerificationRequiredForLogin
+	verifiedEmailForSpaces = config.EmailVerificationRequiredForSpaces
+)
+
+var errVerificationEmailLimit = errors.New("too many verification emails sent")
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// verifyEmail marks the email of a user as verified with the token of a
+// verification link
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, hashEmailToken(req.Token))
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid or expired verification token"))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, dto.NewUser(user))
+}
+
+type resendVerificationEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resendVerificationEmail sends a new verification link. Users may not be
+// able to log in before verifying their email, so they are identified by
+// email only and the response is the same whether an email was sent or not.
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	var req resendVerificationEmailRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+		return
+	}
+
+	err = server.sendVerificationEmail(ctx, user)
+	if err != nil && err != errVerificationEmailLimit {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+}
+
+// sendVerificationEmail emails a verification link to the user. It returns
+// errVerificationEmailLimit when too many were sent recently. A failure to
+// deliver the email is only logged, a new one can be requested.
+func (server *Server) sendVerificationEmail(ctx context.Context, user db.User) error {
+	sent, err := server.store.CountEmailVerificationTokensSince(ctx, db.CountEmailVerificationTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: pgtype.Timestamp{Time: time.Now().UTC().Add(-verificationEmailWindow), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	if sent >= verificationEmailLimit {
+		return errVerificationEmailLimit
+	}
+
+	verificationToken, err := generateEmailToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreateEmailVerificationToken(ctx, db.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashEmailToken(verificationToken),
+		ExpiresAt: pgtype.Timestamp{
+			Time:  time.Now().UTC().Add(emailVerificationTokenDuration),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := server.mailer.Send(ctx, server.verificationMessage(user.Email, verificationToken)); err != nil {
+		slog.ErrorContext(ctx, "cannot send verification email", "error", err)
+	}
+
+	return nil
+}
+
+// verificationMessage returns the email sending verificationToken to the user
+func (server *Server) verificationMessage(email, verificationToken string) mailer.Message {
+	link := verificationToken
+	if server.Config.EmailVerificationURL != "" {
+		link = withQuery(server.Config.EmailVerificationURL, map[string]string{"token": verificationToken})
+	}
+
+	return mailer.Message{
+		To:      email,
+		Subject: "Verify your space-it email",
+		Body: fmt.Sprintf(
+			"Welcome to space-it!\r\n"+
+				"Use this within %d hours to verify your email:\r\n\r\n%s\r\n\r\n"+
+				"If you didn't create an account, you can ignore this email.",
+			int(emailVerificationTokenDuration.Hours()),
+			link,
+		),
+	}
+}
+
+// requireVerifiedEmail returns the middleware refusing users whose email is
+// not verified when the configuration requires it for action
+func (server *Server) requireVerifiedEmail(action string) gin.HandlerFunc {
+	if server.Config.EmailVerificationRequired != action {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return middlewares.RequireVerifiedEmail(server.store)
+}
+
+// validEmailVerificationRequired reports whether value is a known
+// config.EmailVerificationRequired value
+func validEmailVerificationRequired(value string) bool {
+	switch value {
+	case "", verifiedEmailForLogin, verifiedEmailForSpaces:
+		return true
+	default:
+		return false
+	}
+}
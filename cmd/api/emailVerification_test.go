@@ -0,0 +1,349 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	verified := user
+	verified.EmailVerifiedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+	verificationToken, err := generateEmailToken()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(hashEmailToken(verificationToken))).
+					Times(1).
+					Return(verified, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res dto.User
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, user.ID, res.ID)
+				require.True(t, res.EmailVerified)
+			},
+		},
+
+		{
+			name: "used or expired token",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "missing token",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.POST("/users/email/verify", server.verifyEmail)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/email/verify", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestResendVerificationEmailAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	verified := user
+	verified.EmailVerifiedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder, sent []mailer.Message)
+	}{
+		{
+			name: "unverified email",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(
+						_ context.Context,
+						arg db.CountEmailVerificationTokensSinceParams,
+					) (int64, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.WithinDuration(t, time.Now().Add(-verificationEmailWindow), arg.CreatedAt.Time, time.Minute)
+						return verificationEmailLimit - 1, nil
+					})
+				store.EXPECT().
+					CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(
+						_ context.Context,
+						arg db.CreateEmailVerificationTokenParams,
+					) (db.EmailVerificationToken, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.WithinDuration(t, time.Now().Add(emailVerificationTokenDuration), arg.ExpiresAt.Time, time.Minute)
+						return db.EmailVerificationToken{UserID: arg.UserID, TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Len(t, sent, 1)
+				require.Equal(t, user.Email, sent[0].To)
+				require.Regexp(t, regexp.MustCompile(`https://app\.example\.com/verify\?token=[\w-]+`), sent[0].Body)
+			},
+		},
+
+		{
+			name: "too many emails sent",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(verificationEmailLimit), nil)
+				store.EXPECT().CreateEmailVerificationToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+
+		{
+			name: "already verified",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(verified, nil)
+				store.EXPECT().CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+
+		{
+			name: "unknown email",
+			body: gin.H{"email": "unknown@email.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := testConfig()
+			config.EmailVerificationURL = "https://app.example.com/verify"
+			server := NewServer(store, config)
+			mail := mailer.NewMemoryMailer()
+			server.mailer = mail
+
+			router := gin.Default()
+			router.POST("/users/email/resend", server.resendVerificationEmail)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/email/resend", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, mail.Sent())
+		})
+	}
+}
+
+// TestEmailVerificationRequired goes through the whole router to check what
+// each configuration blocks for a user whose email is not verified
+func TestEmailVerificationRequired(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name     string
+		required string
+		method   string
+		path     string
+		code     int
+	}{
+		{
+			name:   "nothing required -> login",
+			method: http.MethodPost,
+			path:   "/users/login",
+			code:   http.StatusOK,
+		},
+		{
+			name:     "required for login -> login refused",
+			required: verifiedEmailForLogin,
+			method:   http.MethodPost,
+			path:     "/users/login",
+			code:     http.StatusForbidden,
+		},
+		{
+			name:     "required for login -> other requests refused",
+			required: verifiedEmailForLogin,
+			method:   http.MethodGet,
+			path:     "/users/me/sessions",
+			code:     http.StatusForbidden,
+		},
+		{
+			name:     "required for spaces -> login",
+			required: verifiedEmailForSpaces,
+			method:   http.MethodPost,
+			path:     "/users/login",
+			code:     http.StatusOK,
+		},
+		{
+			name:     "required for spaces -> space creation refused",
+			required: verifiedEmailForSpaces,
+			method:   http.MethodPost,
+			path:     "/spaces",
+			code:     http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).AnyTimes().Return(user, nil)
+			store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).AnyTimes().Return(user, nil)
+			store.EXPECT().
+				GetTOTPByUserID(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(db.UserTotp{}, db.ErrRecordNotFound)
+			store.EXPECT().
+				ListLoginThrottles(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return([]db.LoginThrottle{}, nil)
+			store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).AnyTimes().Return(db.Session{}, nil)
+			store.EXPECT().
+				ListActiveSessionsByUser(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return([]db.Session{}, nil)
+			store.EXPECT().CreateSpaceTx(gomock.Any(), gomock.Any()).Times(0)
+
+			config := testConfig()
+			config.EmailVerificationRequired = tc.required
+			server := NewServer(store, config)
+			disableRateLimits(server)
+
+			request, err := http.NewRequest(tc.method, makeUrl(tc.path), bytes.NewReader([]byte(`{"name":"space"}`)))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			request.SetBasicAuth(user.Email, password)
+
+			recorder := httptest.NewRecorder()
+			server.Router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+
+	// unknown values are configuration mistakes
+	require.Panics(t, func() {
+		config := testConfig()
+		config.EmailVerificationRequired = "everything"
+		NewServer(mockdb.NewMockStore(gomock.NewController(t)), config)
+	})
+}
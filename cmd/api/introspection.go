@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// Token types reported by the introspection endpoint
+const (
+	tokenTypeAccessToken = "access_token"
+	tokenTypeSession     = "session"
+)
+
+type tokenIntrospectionRequest struct {
+	Token string `form:"token" binding:"required"`
+	// every token is looked up, whatever the hint
+	TokenTypeHint string `form:"token_type_hint"`
+	// clients can also authenticate with Basic auth
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// tokenIntrospectionResponse is specified by RFC 7662. Only active is set for
+// inactive tokens. Sessions have no scope, they give full access to the user.
+type tokenIntrospectionResponse struct {
+	Active    bool      `json:"active"`
+	TokenType string    `json:"token_type,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Subject   string    `json:"sub,omitempty"`
+	ExpiresAt int64     `json:"exp,omitempty"`
+	IssuedAt  int64     `json:"iat,omitempty"`
+	User      *dto.User `json:"user,omitempty"`
+	// the spaces the token is restricted to, none when it is not restricted
+	Spaces []uuid.UUID `json:"spaces,omitempty"`
+}
+
+// introspectToken tells a service whether a token sent to it is active, and
+// for whom. Only confidential clients may call it, and anyone can register
+// one, so clients only learn about the access tokens issued to them. The
+// clients of OAUTH_INTROSPECTION_CLIENTS can introspect the tokens of every
+// token maker of the server, so services don't need the cookie keys.
+func (server *Server) introspectToken(ctx *gin.Context) {
+	var req tokenIntrospectionRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(oauth.ErrCodeInvalidRequest, "%v", err))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+
+	// anyone can register a public client, they can't keep a secret
+	if !client.SecretHash.Valid {
+		writeOAuthError(ctx, http.StatusUnauthorized, oauth.NewError(
+			oauth.ErrCodeInvalidClient, "public clients can't introspect tokens",
+		))
+		return
+	}
+
+	// sessions are never issued to a client
+	trusted := slices.Contains(server.Config.OAuthIntrospectionClients, client.ID.String())
+	makers := []token.Maker{server.oauthTokens}
+	if trusted {
+		makers = append(makers, server.tokenMaker)
+	}
+
+	for _, maker := range makers {
+		payload, err := maker.IntrospectToken(ctx, req.Token)
+		if err != nil {
+			if err == token.ErrInvalidToken {
+				continue
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		// the tokens of others are reported as inactive, like unknown ones
+		if !trusted && payload.Attributes[token.ClientIDAttribute] != client.ID.String() {
+			break
+		}
+
+		res, err := newTokenIntrospectionResponse(payload)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		httpx.WriteResponse(ctx, http.StatusOK, res)
+		return
+	}
+
+	// unknown, revoked and expired tokens are all reported the same way
+	httpx.WriteResponse(ctx, http.StatusOK, tokenIntrospectionResponse{Active: false})
+}
+
+func newTokenIntrospectionResponse(payload *token.Payload) (tokenIntrospectionResponse, error) {
+	res := tokenIntrospectionResponse{
+		Active:    true,
+		TokenType: tokenTypeSession,
+		Username:  payload.User.Email,
+		Subject:   payload.User.ID.String(),
+		ExpiresAt: payload.ExpiresAt.Unix(),
+		IssuedAt:  payload.IssuedAt.Unix(),
+		User:      &payload.User,
+	}
+
+	clientID, isAccessToken := payload.Attributes[token.ClientIDAttribute]
+	if !isAccessToken {
+		return res, nil
+	}
+
+	spaceIDs, err := payload.Spaces()
+	if err != nil {
+		return tokenIntrospectionResponse{}, err
+	}
+
+	res.TokenType = tokenTypeAccessToken
+	res.Scope = payload.Attributes[token.ScopeAttribute]
+	res.ClientID = clientID
+	res.Spaces = spaceIDs
+	return res, nil
+}
+
+type tokenRevocationRequest struct {
+	Token string `form:"token" binding:"required"`
+	// tokens are told apart by their prefix, whatever the hint
+	TokenTypeHint string `form:"token_type_hint"`
+	// clients can also authenticate with Basic auth
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// revokeToken revokes an access or refresh token issued to the calling
+// client, as specified by RFC 7009. Revoking a refresh token revokes every
+// token of its grant. Unknown tokens are ignored, a client can't tell them
+// apart from revoked ones.
+func (server *Server) revokeToken(ctx *gin.Context) {
+	var req tokenRevocationRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(oauth.ErrCodeInvalidRequest, "%v", err))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+
+	foreignToken := oauth.NewError(oauth.ErrCodeUnauthorizedClient, "token was issued to another client")
+
+	switch {
+	case oauth.IsAccessToken(req.Token):
+		payload, err := server.oauthTokens.IntrospectToken(ctx, req.Token)
+		if err != nil {
+			if err == token.ErrInvalidToken {
+				break
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if payload.Attributes[token.ClientIDAttribute] != client.ID.String() {
+			writeOAuthError(ctx, http.StatusBadRequest, foreignToken)
+			return
+		}
+
+		if err := server.oauthTokens.RevokeToken(ctx, req.Token); err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+	case oauth.IsRefreshToken(req.Token):
+		refreshToken, err := server.store.GetOAuthRefreshTokenByHash(ctx, oauth.Hash(req.Token))
+		if err != nil {
+			if err == db.ErrRecordNotFound {
+				break
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if refreshToken.ClientID != client.ID {
+			writeOAuthError(ctx, http.StatusBadRequest, foreignToken)
+			return
+		}
+
+		if err := server.store.RevokeOAuthGrant(ctx, refreshToken.GrantID); err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIntrospectTokenAPI(t *testing.T) {
+	owner, _ := mockdb.RandomUser(t)
+	user, _ := mockdb.RandomUser(t)
+	confidential, secret := randomOAuthClient(t, owner.ID, true)
+	untrusted, untrustedSecret := randomOAuthClient(t, owner.ID, true)
+	public, _ := randomOAuthClient(t, owner.ID, false)
+
+	config := testConfig()
+	config.OAuthIntrospectionClients = []string{confidential.ID.String()}
+
+	accessToken, err := oauth.GenerateToken(oauth.AccessTokenPrefix)
+	require.NoError(t, err)
+	storedToken := db.OauthAccessToken{
+		ID:        uuid.New(),
+		GrantID:   uuid.New(),
+		TokenHash: oauth.Hash(accessToken),
+		ClientID:  public.ID,
+		UserID:    user.ID,
+		Scopes:    []string{"spaces:read"},
+		SpaceIds:  []uuid.UUID{uuid.New()},
+		CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	ownToken, err := oauth.GenerateToken(oauth.AccessTokenPrefix)
+	require.NoError(t, err)
+	storedOwnToken := storedToken
+	storedOwnToken.TokenHash = oauth.Hash(ownToken)
+	storedOwnToken.ClientID = untrusted.ID
+
+	// session tokens are the base64 encoding of a sha256 sum
+	sum := sha256.Sum256([]byte(uuid.NewString()))
+	sessionToken := base64.StdEncoding.EncodeToString(sum[:])
+	sessionHash := sha256.Sum256([]byte(sessionToken))
+	session := mockdb.RandomSession(t, user.ID)
+	session.TokenHash = pgtype.Text{String: hex.EncodeToString(sessionHash[:]), Valid: true}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "access token",
+			form:      url.Values{"token": {accessToken}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(oauth.Hash(accessToken))).
+					Times(1).
+					Return(storedToken, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				res := decodeIntrospectionResponse(t, recorder.Body)
+				require.True(t, res.Active)
+				require.Equal(t, tokenTypeAccessToken, res.TokenType)
+				require.Equal(t, "spaces:read", res.Scope)
+				require.Equal(t, public.ID.String(), res.ClientID)
+				require.Equal(t, user.ID.String(), res.Subject)
+				require.Equal(t, user.Email, res.Username)
+				require.Equal(t, storedToken.ExpiresAt.Time.Unix(), res.ExpiresAt)
+				require.Equal(t, storedToken.SpaceIds, res.Spaces)
+				require.Equal(t, dto.NewUser(user).ID, res.User.ID)
+			},
+		},
+		{
+			name:      "session token",
+			form:      url.Values{"token": {sessionToken}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				store.EXPECT().GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					GetActiveSessionByTokenHash(gomock.Any(), gomock.Eq(session.TokenHash)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				res := decodeIntrospectionResponse(t, recorder.Body)
+				require.True(t, res.Active)
+				require.Equal(t, tokenTypeSession, res.TokenType)
+				require.Empty(t, res.Scope)
+				require.Empty(t, res.ClientID)
+				require.Empty(t, res.Spaces)
+				require.Equal(t, user.ID.String(), res.Subject)
+				require.Equal(t, session.ExpiresAt.Time.Unix(), res.ExpiresAt)
+			},
+		},
+		{
+			name:      "inactive token",
+			form:      url.Values{"token": {sessionToken}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				store.EXPECT().
+					GetActiveSessionByTokenHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active":false}`, recorder.Body.String())
+			},
+		},
+		{
+			name:      "untrusted client, own access token",
+			form:      url.Values{"token": {ownToken}},
+			basicAuth: []string{untrusted.ID.String(), untrustedSecret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(untrusted.ID)).Times(1).Return(untrusted, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(oauth.Hash(ownToken))).
+					Times(1).
+					Return(storedOwnToken, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				res := decodeIntrospectionResponse(t, recorder.Body)
+				require.True(t, res.Active)
+				require.Equal(t, untrusted.ID.String(), res.ClientID)
+			},
+		},
+		{
+			name:      "untrusted client, access token of another client",
+			form:      url.Values{"token": {accessToken}},
+			basicAuth: []string{untrusted.ID.String(), untrustedSecret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(untrusted.ID)).Times(1).Return(untrusted, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(oauth.Hash(accessToken))).
+					Times(1).
+					Return(storedToken, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active":false}`, recorder.Body.String())
+			},
+		},
+		{
+			name:      "untrusted client, session token",
+			form:      url.Values{"token": {sessionToken}},
+			basicAuth: []string{untrusted.ID.String(), untrustedSecret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(untrusted.ID)).Times(1).Return(untrusted, nil)
+				store.EXPECT().GetActiveSessionByTokenHash(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active":false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "public client",
+			form: url.Values{"token": {accessToken}, "client_id": {public.ID.String()}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(public.ID)).Times(1).Return(public, nil)
+				store.EXPECT().GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauth.ErrCodeInvalidClient)
+			},
+		},
+		{
+			name:      "wrong secret",
+			form:      url.Values{"token": {accessToken}},
+			basicAuth: []string{confidential.ID.String(), "scs_wrong"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				store.EXPECT().GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "missing token",
+			form:      url.Values{},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauth.ErrCodeInvalidRequest)
+			},
+		},
+		{
+			name:      "internal error",
+			form:      url.Values{"token": {accessToken}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAccessToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serveOAuthFormWithConfig(t, config, "/oauth/introspect", tc.form, tc.basicAuth, tc.buildStubs)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRevokeTokenAPI(t *testing.T) {
+	owner, _ := mockdb.RandomUser(t)
+	user, _ := mockdb.RandomUser(t)
+	client, secret := randomOAuthClient(t, owner.ID, true)
+	other, _ := randomOAuthClient(t, owner.ID, false)
+
+	accessToken, err := oauth.GenerateToken(oauth.AccessTokenPrefix)
+	require.NoError(t, err)
+	refreshToken, err := oauth.GenerateToken(oauth.RefreshTokenPrefix)
+	require.NoError(t, err)
+
+	storedAccessToken := func(clientID uuid.UUID) db.OauthAccessToken {
+		return db.OauthAccessToken{
+			ID:        uuid.New(),
+			GrantID:   uuid.New(),
+			TokenHash: oauth.Hash(accessToken),
+			ClientID:  clientID,
+			UserID:    user.ID,
+			Scopes:    []string{"spaces:read"},
+			SpaceIds:  []uuid.UUID{},
+			ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true},
+		}
+	}
+	storedRefreshToken := func(clientID uuid.UUID) db.OauthRefreshToken {
+		return db.OauthRefreshToken{
+			ID:        uuid.New(),
+			GrantID:   uuid.New(),
+			TokenHash: oauth.Hash(refreshToken),
+			ClientID:  clientID,
+			UserID:    user.ID,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "access token",
+			form:      url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}},
+			basicAuth: []string{client.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(oauth.Hash(accessToken))).
+					Times(1).
+					Return(storedAccessToken(client.ID), nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RevokeOAuthAccessToken(gomock.Any(), gomock.Eq(oauth.Hash(accessToken))).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "refresh token revokes the grant",
+			form:      url.Values{"token": {refreshToken}},
+			basicAuth: []string{client.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				refresh := storedRefreshToken(client.ID)
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetOAuthRefreshTokenByHash(gomock.Any(), gomock.Eq(oauth.Hash(refreshToken))).
+					Times(1).
+					Return(refresh, nil)
+				store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Eq(refresh.GrantID)).Times(1).Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "public client",
+			form: url.Values{"token": {refreshToken}, "client_id": {other.ID.String()}},
+			buildStubs: func(store *mockdb.MockStore) {
+				refresh := storedRefreshToken(other.ID)
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(other.ID)).Times(1).Return(other, nil)
+				store.EXPECT().GetOAuthRefreshTokenByHash(gomock.Any(), gomock.Any()).Times(1).Return(refresh, nil)
+				store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Eq(refresh.GrantID)).Times(1).Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "unknown token",
+			form:      url.Values{"token": {refreshToken}},
+			basicAuth: []string{client.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetOAuthRefreshTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthRefreshToken{}, db.ErrRecordNotFound)
+				store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "access token of another client",
+			form:      url.Values{"token": {accessToken}},
+			basicAuth: []string{client.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedAccessToken(other.ID), nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().RevokeOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauth.ErrCodeUnauthorizedClient)
+			},
+		},
+		{
+			name:      "refresh token of another client",
+			form:      url.Values{"token": {refreshToken}},
+			basicAuth: []string{client.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetOAuthRefreshTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedRefreshToken(other.ID), nil)
+				store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "unauthenticated client",
+			form: url.Values{"token": {accessToken}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RevokeOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serveOAuthForm(t, "/oauth/revoke", tc.form, tc.basicAuth, tc.buildStubs)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// serveOAuthForm posts a form encoded request to an endpoint of the
+// authorization server, with Basic auth when basicAuth is set
+func serveOAuthForm(
+	t *testing.T,
+	path string,
+	form url.Values,
+	basicAuth []string,
+	buildStubs func(store *mockdb.MockStore),
+) *httptest.ResponseRecorder {
+	return serveOAuthFormWithConfig(t, testConfig(), path, form, basicAuth, buildStubs)
+}
+
+func serveOAuthFormWithConfig(
+	t *testing.T,
+	config config.Config,
+	path string,
+	form url.Values,
+	basicAuth []string,
+	buildStubs func(store *mockdb.MockStore),
+) *httptest.ResponseRecorder {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	buildStubs(store)
+
+	server := NewServer(store, config)
+	disableRateLimits(server)
+
+	request, err := http.NewRequest(http.MethodPost, makeUrl(path), strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicAuth != nil {
+		request.SetBasicAuth(basicAuth[0], basicAuth[1])
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decodeIntrospectionResponse(t *testing.T, body io.Reader) tokenIntrospectionResponse {
+	var res tokenIntrospectionResponse
+	require.NoError(t, json.NewDecoder(body).Decode(&res))
+	return res
+}
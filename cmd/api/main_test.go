@@ -3,8 +3,12 @@ package api
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/Luckny/space-it/cmd/middlewares"
+	"github.com/Luckny/space-it/pkg/config"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
 )
 
 func TestMain(m *testing.M) {
@@ -12,3 +16,27 @@ func TestMain(m *testing.M) {
 
 	os.Exit(m.Run())
 }
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "https://localhost:3000"
+)
+
+// testConfig returns a config usable to create and verify session tokens
+// and with passkeys enabled
+func testConfig() config.Config {
+	return config.Config{
+		CookieSecret:    "a-32-byte-long-cookie-secret-key",
+		CookieAge:       time.Minute,
+		WebAuthnRPID:    testRPID,
+		WebAuthnOrigins: []string{testOrigin},
+	}
+}
+
+// disableRateLimits lets tests going through the whole router make as many
+// requests as they need
+func disableRateLimits(server *Server) {
+	for _, limiter := range server.Limiters {
+		limiter.(*middlewares.MemoryRateLimiter).SetLimit(rate.Inf)
+	}
+}
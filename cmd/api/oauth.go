@@ -0,0 +1,698 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt.Time,
+	}
+}
+
+type registerOAuthClientRequest struct {
+	Name         string              `json:"name"          binding:"required,max=64"`
+	RedirectURIs []string            `json:"redirect_uris" binding:"required,min=1,max=10,dive,max=255"`
+	Scopes       []middlewares.Scope `json:"scopes"        binding:"required,min=1,dive,scope"`
+	// confidential clients can keep a secret, only they can use the
+	// client_credentials grant
+	Confidential bool `json:"confidential"`
+}
+
+type registerOAuthClientResponse struct {
+	oauthClientResponse
+	// only returned once, to confidential clients
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func (server *Server) registerOAuthClient(ctx *gin.Context) {
+	var req registerOAuthClientRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, uri := range req.RedirectURIs {
+		if err := oauth.ValidRedirectURI(uri); err != nil {
+			httpx.WriteError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	arg := db.CreateOAuthClientParams{
+		OwnerID:      user.ID,
+		Name:         req.Name,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       make([]string, 0, len(req.Scopes)),
+	}
+	for _, scope := range req.Scopes {
+		arg.Scopes = append(arg.Scopes, string(scope))
+	}
+
+	var secret string
+	if req.Confidential {
+		secret, err = oauth.GenerateToken(oauth.ClientSecretPrefix)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		arg.SecretHash = pgtype.Text{String: oauth.Hash(secret), Valid: true}
+	}
+
+	client, err := server.store.CreateOAuthClient(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusCreated, registerOAuthClientResponse{
+		oauthClientResponse: newOAuthClientResponse(client),
+		ClientSecret:        secret,
+	})
+}
+
+func (server *Server) listOAuthClients(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	clients, err := server.store.ListOAuthClientsByOwner(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		res[i] = newOAuthClientResponse(client)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+// revokeOAuthClient unregisters a client, the tokens issued to it stop
+// working with it
+func (server *Server) revokeOAuthClient(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	clientID, err := uuid.Parse(ctx.Param("clientID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid client id"))
+		return
+	}
+
+	arg := db.RevokeOAuthClientParams{
+		ID:      clientID,
+		OwnerID: user.ID,
+	}
+
+	revoked, err := server.store.RevokeOAuthClient(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a client of another user is reported as not found too
+	if revoked == 0 {
+		httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("client not found"))
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
+
+// authorizationRequest is the request a client sends the user to. The consent
+// page gets it from the query string, then posts it back with the decision.
+type authorizationRequest struct {
+	ResponseType        string `form:"response_type"         json:"response_type"         binding:"required"`
+	ClientID            string `form:"client_id"             json:"client_id"             binding:"required"`
+	RedirectURI         string `form:"redirect_uri"          json:"redirect_uri"`
+	Scope               string `form:"scope"                 json:"scope"`
+	State               string `form:"state"                 json:"state"`
+	CodeChallenge       string `form:"code_challenge"        json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+type authorizationResponse struct {
+	ClientID    uuid.UUID `json:"client_id"`
+	ClientName  string    `json:"client_name"`
+	RedirectURI string    `json:"redirect_uri"`
+	Scopes      []string  `json:"scopes"`
+}
+
+// getAuthorization validates an authorization request and returns what the
+// user is asked to consent to
+func (server *Server) getAuthorization(ctx *gin.Context) {
+	var req authorizationRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	client, redirectURI, scopes, ok := server.validateAuthorizationRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, authorizationResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: redirectURI,
+		Scopes:      scopes,
+	})
+}
+
+type authorizeRequest struct {
+	authorizationRequest
+	Approved bool `json:"approved"`
+	// spaces the user restricts the access to, all of them when empty
+	SpaceIDs []uuid.UUID `json:"spaces" binding:"max=50"`
+}
+
+type authorizeResponse struct {
+	// where the user agent should be sent back to the client
+	RedirectTo string `json:"redirect_to"`
+}
+
+// authorize records the decision of the user. The client gets an
+// authorization code when access is approved.
+func (server *Server) authorize(ctx *gin.Context) {
+	var req authorizeRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	client, redirectURI, scopes, ok := server.validateAuthorizationRequest(ctx, req.authorizationRequest)
+	if !ok {
+		return
+	}
+
+	if !req.Approved {
+		httpx.WriteResponse(ctx, http.StatusOK, authorizeResponse{
+			RedirectTo: withQuery(redirectURI, map[string]string{
+				"error": oauth.ErrCodeAccessDenied,
+				"state": req.State,
+			}),
+		})
+		return
+	}
+
+	// the user can only delegate access to spaces they are a member of
+	for _, spaceID := range req.SpaceIDs {
+		_, err := server.store.GetPermissionsByUserAndSpaceID(ctx, db.GetPermissionsByUserAndSpaceIDParams{
+			UserID:  user.ID,
+			SpaceID: spaceID,
+		})
+		if err != nil {
+			if err == db.ErrRecordNotFound {
+				httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("no access to space %s", spaceID))
+				return
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	code, err := oauth.GenerateToken("")
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	spaceIDs := req.SpaceIDs
+	if spaceIDs == nil {
+		spaceIDs = []uuid.UUID{}
+	}
+
+	_, err = server.store.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:        oauth.Hash(code),
+		ClientID:        client.ID,
+		UserID:          user.ID,
+		RedirectUri:     redirectURI,
+		RedirectUriSent: req.RedirectURI != "",
+		Scopes:          scopes,
+		SpaceIds:        spaceIDs,
+		CodeChallenge:   req.CodeChallenge,
+		ExpiresAt: pgtype.Timestamp{
+			Time:  time.Now().UTC().Add(oauth.AuthorizationCodeDuration),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, authorizeResponse{
+		RedirectTo: withQuery(redirectURI, map[string]string{
+			"code":  code,
+			"state": req.State,
+		}),
+	})
+}
+
+// validateAuthorizationRequest checks the client, redirect uri, PKCE
+// challenge and scopes of an authorization request. It writes the error
+// response and returns false when the request is invalid.
+func (server *Server) validateAuthorizationRequest(
+	ctx *gin.Context,
+	req authorizationRequest,
+) (db.OauthClient, string, []string, bool) {
+	clientID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("unknown client"))
+		return db.OauthClient{}, "", nil, false
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("unknown client"))
+			return db.OauthClient{}, "", nil, false
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return db.OauthClient{}, "", nil, false
+	}
+
+	// the redirect uri can only be left out when a single one is registered
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectUris) == 1 {
+		redirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("redirect_uri is not registered"))
+		return db.OauthClient{}, "", nil, false
+	}
+
+	if req.ResponseType != oauth.ResponseTypeCode {
+		httpx.WriteError(ctx, http.StatusBadRequest, oauth.NewError(
+			oauth.ErrCodeUnsupportedResponseType, "response_type must be %s", oauth.ResponseTypeCode,
+		))
+		return db.OauthClient{}, "", nil, false
+	}
+
+	// PKCE is required from every client, confidential ones included
+	if err := oauth.ValidCodeChallenge(req.CodeChallenge, req.CodeChallengeMethod); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, oauth.NewError(oauth.ErrCodeInvalidRequest, "%v", err))
+		return db.OauthClient{}, "", nil, false
+	}
+
+	scopes, err := requestedScopes(req.Scope, client.Scopes)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return db.OauthClient{}, "", nil, false
+	}
+
+	return client, redirectURI, scopes, true
+}
+
+type tokenRequest struct {
+	GrantType    string `form:"grant_type"    binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	// clients can also authenticate with Basic auth
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// oauthGrant is an authorization given to a client, the tokens issued for it
+// share its id
+type oauthGrant struct {
+	ID       uuid.UUID
+	ClientID uuid.UUID
+	UserID   uuid.UUID
+	Scopes   []string
+	SpaceIDs []uuid.UUID
+}
+
+// issueOAuthToken is the token endpoint of the authorization server. Requests
+// are form encoded and errors are reported as specified by RFC 6749.
+func (server *Server) issueOAuthToken(ctx *gin.Context) {
+	var req tokenRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(oauth.ErrCodeInvalidRequest, "%v", err))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+
+	switch req.GrantType {
+	case oauth.GrantAuthorizationCode:
+		server.exchangeAuthorizationCode(ctx, client, req)
+	case oauth.GrantClientCredentials:
+		server.grantClientCredentials(ctx, client, req)
+	case oauth.GrantRefreshToken:
+		server.refreshOAuthToken(ctx, client, req)
+	default:
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(
+			oauth.ErrCodeUnsupportedGrantType, "unsupported grant_type %q", req.GrantType,
+		))
+	}
+}
+
+func (server *Server) exchangeAuthorizationCode(ctx *gin.Context, client db.OauthClient, req tokenRequest) {
+	// codes are deleted when used, so they can't be replayed
+	code, err := server.store.ConsumeOAuthAuthorizationCode(ctx, oauth.Hash(req.Code))
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(
+				oauth.ErrCodeInvalidGrant, "invalid or expired code",
+			))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the redirect uri must be repeated when it was sent to the authorization
+	// endpoint, and match the code's whenever it is
+	redirectURIChecked := code.RedirectUriSent || req.RedirectURI != ""
+	if code.ClientID != client.ID || redirectURIChecked && code.RedirectUri != req.RedirectURI {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(
+			oauth.ErrCodeInvalidGrant, "code was issued to another client or redirect_uri",
+		))
+		return
+	}
+
+	if err := oauth.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge); err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(oauth.ErrCodeInvalidGrant, "%v", err))
+		return
+	}
+
+	server.issueOAuthTokens(ctx, oauthGrant{
+		ID:       uuid.New(),
+		ClientID: client.ID,
+		UserID:   code.UserID,
+		Scopes:   code.Scopes,
+		SpaceIDs: code.SpaceIds,
+	}, code.Scopes, true)
+}
+
+// grantClientCredentials issues a token acting as the user who registered the
+// client, for tools that don't act on behalf of another user
+func (server *Server) grantClientCredentials(ctx *gin.Context, client db.OauthClient, req tokenRequest) {
+	if !client.SecretHash.Valid {
+		writeOAuthError(ctx, http.StatusBadRequest, oauth.NewError(
+			oauth.ErrCodeUnauthorizedClient, "public clients can't use the client_credentials grant",
+		))
+		return
+	}
+
+	scopes, err := requestedScopes(req.Scope, client.Scopes)
+	if err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// there is nobody to ask for consent again, so no refresh token
+	server.issueOAuthTokens(ctx, oauthGrant{
+		ID:       uuid.New(),
+		ClientID: client.ID,
+		UserID:   client.OwnerID,
+		Scopes:   scopes,
+		SpaceIDs: []uuid.UUID{},
+	}, scopes, false)
+}
+
+func (server *Server) refreshOAuthToken(ctx *gin.Context, client db.OauthClient, req tokenRequest) {
+	invalidGrant := oauth.NewError(oauth.ErrCodeInvalidGrant, "invalid or expired refresh token")
+
+	refreshToken, err := server.store.GetOAuthRefreshTokenByHash(ctx, oauth.Hash(req.RefreshToken))
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			writeOAuthError(ctx, http.StatusBadRequest, invalidGrant)
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if refreshToken.ClientID != client.ID {
+		writeOAuthError(ctx, http.StatusBadRequest, invalidGrant)
+		return
+	}
+
+	// a refresh token that was already used has probably been stolen, every
+	// token of the grant is revoked since we can't tell who is legitimate
+	if refreshToken.RevokedAt.Valid {
+		if err := server.store.RevokeOAuthGrant(ctx, refreshToken.GrantID); err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		slog.WarnContext(ctx, "oauth: refresh token reused, grant revoked", "grant_id", refreshToken.GrantID)
+		writeOAuthError(ctx, http.StatusBadRequest, invalidGrant)
+		return
+	}
+
+	// the access token can be narrowed down, not widened
+	scopes, err := requestedScopes(req.Scope, refreshToken.Scopes)
+	if err != nil {
+		writeOAuthError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	refreshToken, err = server.store.RotateOAuthRefreshToken(ctx, refreshToken.TokenHash)
+	if err != nil {
+		// expired, used concurrently or client revoked
+		if err == db.ErrRecordNotFound {
+			writeOAuthError(ctx, http.StatusBadRequest, invalidGrant)
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	server.issueOAuthTokens(ctx, oauthGrant{
+		ID:       refreshToken.GrantID,
+		ClientID: refreshToken.ClientID,
+		UserID:   refreshToken.UserID,
+		Scopes:   refreshToken.Scopes,
+		SpaceIDs: refreshToken.SpaceIds,
+	}, scopes, true)
+}
+
+// issueOAuthTokens issues an access token with scopes for grant and, when
+// withRefreshToken is set, a refresh token for the whole grant
+func (server *Server) issueOAuthTokens(
+	ctx *gin.Context,
+	grant oauthGrant,
+	scopes []string,
+	withRefreshToken bool,
+) {
+	user, err := server.store.GetUserByID(ctx, grant.UserID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	payload, err := token.NewOAuthPayload(dto.NewUser(user), grant.ClientID, grant.ID, scopes, grant.SpaceIDs)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	accessToken, err := server.oauthTokens.CreateToken(ctx, payload)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(oauth.AccessTokenDuration.Seconds()),
+		Scope:       oauth.FormatScope(scopes),
+	}
+
+	if withRefreshToken {
+		res.RefreshToken, err = oauth.GenerateToken(oauth.RefreshTokenPrefix)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		_, err = server.store.CreateOAuthRefreshToken(ctx, db.CreateOAuthRefreshTokenParams{
+			GrantID:   grant.ID,
+			TokenHash: oauth.Hash(res.RefreshToken),
+			ClientID:  grant.ClientID,
+			UserID:    grant.UserID,
+			Scopes:    grant.Scopes,
+			SpaceIds:  grant.SpaceIDs,
+			ExpiresAt: pgtype.Timestamp{
+				Time:  time.Now().UTC().Add(oauth.RefreshTokenDuration),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+// authenticateOAuthClient authenticates the client calling the token
+// endpoint with Basic auth or the client_id and client_secret parameters.
+// Public clients only send their id. It writes the error response and
+// returns false when the client can't be authenticated.
+func (server *Server) authenticateOAuthClient(ctx *gin.Context, formID, formSecret string) (db.OauthClient, bool) {
+	id, secret, hasBasicAuth := ctx.Request.BasicAuth()
+	if !hasBasicAuth {
+		id, secret = formID, formSecret
+	}
+
+	invalidClient := func() (db.OauthClient, bool) {
+		if hasBasicAuth {
+			ctx.Header("WWW-Authenticate", "Basic realm=\"oauth\"")
+		}
+		writeOAuthError(ctx, http.StatusUnauthorized, oauth.NewError(
+			oauth.ErrCodeInvalidClient, "client authentication failed",
+		))
+		return db.OauthClient{}, false
+	}
+
+	clientID, err := uuid.Parse(id)
+	if err != nil {
+		return invalidClient()
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			return invalidClient()
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return db.OauthClient{}, false
+	}
+
+	// public clients have no secret to check, PKCE binds their codes instead
+	if !client.SecretHash.Valid {
+		if secret != "" {
+			return invalidClient()
+		}
+		return client, true
+	}
+
+	computed := oauth.Hash(secret)
+	// constant time comparison to help prevent timing attacks
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(client.SecretHash.String)) != 1 {
+		return invalidClient()
+	}
+
+	return client, true
+}
+
+// requestedScopes parses the scope parameter of a request, which may only
+// ask for allowed scopes. All allowed scopes are granted when it is empty.
+func requestedScopes(scope string, allowed []string) ([]string, error) {
+	scopes, err := middlewares.ParseScopes(scope)
+	if err != nil {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidScope, "%v", err)
+	}
+
+	if len(scopes) == 0 {
+		return allowed, nil
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(allowed, s) {
+			return nil, oauth.NewError(oauth.ErrCodeInvalidScope, "scope %q is not allowed", s)
+		}
+	}
+
+	return scopes, nil
+}
+
+// writeOAuthError writes an error of the token endpoint
+func writeOAuthError(ctx *gin.Context, status int, err error) {
+	oauthErr, ok := err.(*oauth.Error)
+	if !ok {
+		oauthErr = oauth.NewError(oauth.ErrCodeInvalidRequest, "%v", err)
+	}
+
+	httpx.WriteResponse(ctx, status, oauthErr)
+}
+
+// withQuery adds params to the query of uri, empty values are left out
+func withQuery(uri string, params map[string]string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		// registered redirect uris are validated
+		panic(err)
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,1144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testRedirectURI = "https://tool.example.com/callback"
+
+// randomOAuthClient returns a client of owner, with its secret when confidential
+func randomOAuthClient(t *testing.T, ownerID uuid.UUID, confidential bool) (db.OauthClient, string) {
+	client := db.OauthClient{
+		ID:           uuid.New(),
+		OwnerID:      ownerID,
+		Name:         "deploy bot",
+		RedirectUris: []string{testRedirectURI},
+		Scopes:       []string{"spaces:read", "spaces:write", "messages:write"},
+	}
+
+	var secret string
+	if confidential {
+		var err error
+		secret, err = oauth.GenerateToken(oauth.ClientSecretPrefix)
+		require.NoError(t, err)
+		client.SecretHash = pgtype.Text{String: oauth.Hash(secret), Valid: true}
+	}
+
+	return client, secret
+}
+
+// pkcePair returns a code verifier and its S256 challenge
+func pkcePair(t *testing.T) (string, string) {
+	verifier, err := oauth.GenerateToken("")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestRegisterOAuthClientAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, created *db.CreateOAuthClientParams)
+		checkResponse func(recorder *httptest.ResponseRecorder, created db.CreateOAuthClientParams)
+	}{
+		{
+			name: "confidential client",
+			body: gin.H{
+				"name":          "deploy bot",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{"spaces:read"},
+				"confidential":  true,
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthClientParams) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						*created = arg
+						return db.OauthClient{
+							ID:           uuid.New(),
+							OwnerID:      arg.OwnerID,
+							Name:         arg.Name,
+							SecretHash:   arg.SecretHash,
+							RedirectUris: arg.RedirectUris,
+							Scopes:       arg.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthClientParams) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var res registerOAuthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.True(t, res.Confidential)
+				require.Equal(t, user.ID, created.OwnerID)
+				require.Equal(t, []string{"spaces:read"}, created.Scopes)
+
+				// only the hash of the secret is stored
+				require.True(t, created.SecretHash.Valid)
+				require.Equal(t, oauth.Hash(res.ClientSecret), created.SecretHash.String)
+				require.NotContains(t, recorder.Body.String(), created.SecretHash.String)
+			},
+		},
+
+		{
+			name: "public client",
+			body: gin.H{
+				"name":          "cli",
+				"redirect_uris": []string{"http://127.0.0.1:53682/"},
+				"scopes":        []string{"spaces:read"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthClientParams) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						*created = arg
+						return db.OauthClient{ID: uuid.New(), SecretHash: arg.SecretHash}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthClientParams) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.False(t, created.SecretHash.Valid)
+				require.NotContains(t, recorder.Body.String(), "client_secret")
+			},
+		},
+
+		{
+			name: "insecure redirect uri",
+			body: gin.H{
+				"name":          "deploy bot",
+				"redirect_uris": []string{"http://tool.example.com/callback"},
+				"scopes":        []string{"spaces:read"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthClientParams) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthClientParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "unknown scope",
+			body: gin.H{
+				"name":          "deploy bot",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{"users:admin"},
+			},
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthClientParams) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthClientParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+
+			var created db.CreateOAuthClientParams
+			tc.buildStubs(store, &created)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/oauth/clients", server.registerOAuthClient)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/clients", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, created)
+		})
+	}
+}
+
+func TestRevokeOAuthClientAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	clientID := uuid.New()
+
+	for _, revoked := range []int64{1, 0} {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().
+			RevokeOAuthClient(gomock.Any(), gomock.Eq(db.RevokeOAuthClientParams{ID: clientID, OwnerID: user.ID})).
+			Times(1).
+			Return(revoked, nil)
+
+		server := NewServer(store, testConfig())
+		router := gin.Default()
+		router.Use(func(ctx *gin.Context) {
+			httpx.SetUserInContext(ctx, user)
+			ctx.Next()
+		})
+		router.DELETE("/oauth/clients/:clientID", server.revokeOAuthClient)
+
+		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/oauth/clients/%s", clientID), nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if revoked == 1 {
+			require.Equal(t, http.StatusOK, recorder.Code)
+		} else {
+			// a client of another user is not found
+			require.Equal(t, http.StatusNotFound, recorder.Code)
+		}
+	}
+}
+
+func TestGetAuthorizationAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	client, _ := randomOAuthClient(t, user.ID, false)
+	_, challenge := pkcePair(t)
+
+	valid := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {"xyz"},
+	}
+
+	with := func(key, value string) url.Values {
+		query := url.Values{}
+		for k, v := range valid {
+			query[k] = v
+		}
+		query.Set(key, value)
+		return query
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "defaults to the registered redirect uri and client scopes",
+			query: valid,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res authorizationResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, client.Name, res.ClientName)
+				require.Equal(t, testRedirectURI, res.RedirectURI)
+				require.Equal(t, client.Scopes, res.Scopes)
+			},
+		},
+
+		{
+			name:  "narrower scope",
+			query: with("scope", "spaces:read"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"scopes":["spaces:read"]`)
+			},
+		},
+
+		{
+			name:  "scope not allowed for the client",
+			query: with("scope", "spaces:admin"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauth.ErrCodeInvalidScope)
+			},
+		},
+
+		{
+			name:  "unregistered redirect uri",
+			query: with("redirect_uri", "https://attacker.example.com/callback"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "plain code challenge",
+			query: with("code_challenge_method", "plain"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "unsupported response type",
+			query: with("response_type", "token"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauth.ErrCodeUnsupportedResponseType)
+			},
+		},
+
+		{
+			name:  "unknown client",
+			query: valid,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.GET("/oauth/authorize", server.getAuthorization)
+
+			request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAuthorizeAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	client, _ := randomOAuthClient(t, user.ID, false)
+	space := mockdb.RandomSpace(t, user.ID)
+	_, challenge := pkcePair(t)
+
+	body := func(approved bool, spaces ...uuid.UUID) gin.H {
+		return gin.H{
+			"response_type":         "code",
+			"client_id":             client.ID,
+			"redirect_uri":          testRedirectURI,
+			"scope":                 "spaces:read",
+			"state":                 "xyz",
+			"code_challenge":        challenge,
+			"code_challenge_method": "S256",
+			"approved":              approved,
+			"spaces":                spaces,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, created *db.CreateOAuthAuthorizationCodeParams)
+		checkResponse func(recorder *httptest.ResponseRecorder, created db.CreateOAuthAuthorizationCodeParams)
+	}{
+		{
+			name: "approved -> code",
+			body: body(true, space.ID),
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthAuthorizationCodeParams) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Eq(db.GetPermissionsByUserAndSpaceIDParams{
+						UserID:  user.ID,
+						SpaceID: space.ID,
+					})).
+					Times(1).
+					Return(mockdb.CreatePermission(t, user.ID, space.ID, true, false, false), nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+						*created = arg
+						return db.OauthAuthorizationCode{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res authorizeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(res.RedirectTo)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(res.RedirectTo, testRedirectURI+"?"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+
+				// only the hash of the code is stored
+				code := redirect.Query().Get("code")
+				require.Equal(t, oauth.Hash(code), created.CodeHash)
+				require.Equal(t, user.ID, created.UserID)
+				require.Equal(t, challenge, created.CodeChallenge)
+				require.Equal(t, []string{"spaces:read"}, created.Scopes)
+				require.Equal(t, []uuid.UUID{space.ID}, created.SpaceIds)
+				require.Equal(t, testRedirectURI, created.RedirectUri)
+				require.True(t, created.RedirectUriSent)
+			},
+		},
+
+		{
+			name: "approved without redirect uri -> code for the registered one",
+			body: func() gin.H {
+				b := body(true)
+				delete(b, "redirect_uri")
+				return b
+			}(),
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthAuthorizationCodeParams) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+						*created = arg
+						return db.OauthAuthorizationCode{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res authorizeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(res.RedirectTo, testRedirectURI+"?"))
+
+				require.Equal(t, testRedirectURI, created.RedirectUri)
+				require.False(t, created.RedirectUriSent)
+			},
+		},
+
+		{
+			name: "denied -> access_denied",
+			body: body(false),
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthAuthorizationCodeParams) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res authorizeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(res.RedirectTo)
+				require.NoError(t, err)
+				require.Equal(t, oauth.ErrCodeAccessDenied, redirect.Query().Get("error"))
+				require.Empty(t, redirect.Query().Get("code"))
+			},
+		},
+
+		{
+			name: "space of another user",
+			body: body(true, space.ID),
+			buildStubs: func(store *mockdb.MockStore, created *db.CreateOAuthAuthorizationCodeParams) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Permission{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, created db.CreateOAuthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+
+			var created db.CreateOAuthAuthorizationCodeParams
+			tc.buildStubs(store, &created)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/oauth/authorize", server.authorize)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, created)
+		})
+	}
+}
+
+func TestOAuthTokenAPI(t *testing.T) {
+	owner, _ := mockdb.RandomUser(t)
+	user, _ := mockdb.RandomUser(t)
+	confidential, secret := randomOAuthClient(t, owner.ID, true)
+	public, _ := randomOAuthClient(t, owner.ID, false)
+	verifier, challenge := pkcePair(t)
+
+	code := db.OauthAuthorizationCode{
+		ClientID:        public.ID,
+		UserID:          user.ID,
+		RedirectUri:     testRedirectURI,
+		RedirectUriSent: true,
+		Scopes:          []string{"spaces:read"},
+		SpaceIds:        []uuid.UUID{},
+		CodeChallenge:   challenge,
+	}
+
+	// a code the client asked for without a redirect uri
+	unsent := code
+	unsent.RedirectUriSent = false
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "authorization code",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {verifier},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(public.ID)).Times(1).Return(public, nil)
+				store.EXPECT().
+					ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Eq(oauth.Hash("the-code"))).
+					Times(1).
+					Return(code, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateOAuthAccessToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthAccessTokenParams) (db.OauthAccessToken, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, public.ID, arg.ClientID)
+						return db.OauthAccessToken{}, nil
+					})
+				store.EXPECT().
+					CreateOAuthRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthRefreshToken{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var res tokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.True(t, oauth.IsAccessToken(res.AccessToken))
+				require.True(t, strings.HasPrefix(res.RefreshToken, oauth.RefreshTokenPrefix))
+				require.Equal(t, "Bearer", res.TokenType)
+				require.Equal(t, "spaces:read", res.Scope)
+			},
+		},
+
+		{
+			name: "wrong code verifier",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {strings.Repeat("a", 43)},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_grant"`)
+			},
+		},
+
+		{
+			name: "redirect uri left out after it was sent",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"code_verifier": {verifier},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_grant"`)
+			},
+		},
+
+		{
+			name: "redirect uri left out at both endpoints",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"code_verifier": {verifier},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(unsent, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthAccessToken{}, nil)
+				store.EXPECT().CreateOAuthRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRefreshToken{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "other redirect uri after it was left out",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"redirect_uri":  {"https://attacker.example.com/callback"},
+				"code_verifier": {verifier},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(unsent, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_grant"`)
+			},
+		},
+
+		{
+			name: "code of another client",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {verifier},
+			},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+				store.EXPECT().ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_grant"`)
+			},
+		},
+
+		{
+			name: "used or expired code",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"the-code"},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {verifier},
+				"client_id":     {public.ID.String()},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().
+					ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_grant"`)
+			},
+		},
+
+		{
+			name:      "client credentials",
+			form:      url.Values{"grant_type": {"client_credentials"}, "scope": {"spaces:read"}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				// the client acts as its owner
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(owner.ID)).Times(1).Return(owner, nil)
+				store.EXPECT().
+					CreateOAuthAccessToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateOAuthAccessTokenParams) (db.OauthAccessToken, error) {
+						require.Equal(t, owner.ID, arg.UserID)
+						require.Equal(t, []string{"spaces:read"}, arg.Scopes)
+						return db.OauthAccessToken{}, nil
+					})
+				store.EXPECT().CreateOAuthRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+				// Basic auth is not mistaken for user credentials
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res tokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Empty(t, res.RefreshToken)
+			},
+		},
+
+		{
+			name:      "wrong client secret",
+			form:      url.Values{"grant_type": {"client_credentials"}},
+			basicAuth: []string{confidential.ID.String(), "scs_wrong"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"invalid_client"`)
+				require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+			},
+		},
+
+		{
+			name: "client credentials of a public client",
+			form: url.Values{"grant_type": {"client_credentials"}, "client_id": {public.ID.String()}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().CreateOAuthAccessToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"unauthorized_client"`)
+			},
+		},
+
+		{
+			name:      "unsupported grant type",
+			form:      url.Values{"grant_type": {"password"}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error":"unsupported_grant_type"`)
+			},
+		},
+
+		{
+			name:      "internal error",
+			form:      url.Values{"grant_type": {"client_credentials"}},
+			basicAuth: []string{confidential.ID.String(), secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			disableRateLimits(server)
+
+			request, err := http.NewRequest(
+				http.MethodPost,
+				makeUrl("/oauth/token"),
+				strings.NewReader(tc.form.Encode()),
+			)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth != nil {
+				request.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+			}
+
+			recorder := httptest.NewRecorder()
+			server.Router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// oauthTestStore keeps the authorization server state of TestOAuthFlow in memory
+type oauthTestStore struct {
+	codes         map[string]db.OauthAuthorizationCode
+	accessTokens  map[string]db.OauthAccessToken
+	refreshTokens map[string]db.OauthRefreshToken
+}
+
+func (s *oauthTestStore) stub(store *mockdb.MockStore) {
+	store.EXPECT().
+		CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+			code := db.OauthAuthorizationCode{
+				CodeHash:        arg.CodeHash,
+				ClientID:        arg.ClientID,
+				UserID:          arg.UserID,
+				RedirectUri:     arg.RedirectUri,
+				RedirectUriSent: arg.RedirectUriSent,
+				Scopes:          arg.Scopes,
+				SpaceIds:        arg.SpaceIds,
+				CodeChallenge:   arg.CodeChallenge,
+				ExpiresAt:       arg.ExpiresAt,
+			}
+			s.codes[arg.CodeHash] = code
+			return code, nil
+		})
+	store.EXPECT().
+		ConsumeOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, codeHash string) (db.OauthAuthorizationCode, error) {
+			code, ok := s.codes[codeHash]
+			if !ok {
+				return db.OauthAuthorizationCode{}, db.ErrRecordNotFound
+			}
+			delete(s.codes, codeHash)
+			return code, nil
+		})
+	store.EXPECT().
+		CreateOAuthAccessToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOAuthAccessTokenParams) (db.OauthAccessToken, error) {
+			accessToken := db.OauthAccessToken{
+				ID:        arg.ID,
+				GrantID:   arg.GrantID,
+				TokenHash: arg.TokenHash,
+				ClientID:  arg.ClientID,
+				UserID:    arg.UserID,
+				Scopes:    arg.Scopes,
+				SpaceIds:  arg.SpaceIds,
+				ExpiresAt: arg.ExpiresAt,
+			}
+			s.accessTokens[arg.TokenHash] = accessToken
+			return accessToken, nil
+		})
+	store.EXPECT().
+		GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, tokenHash string) (db.OauthAccessToken, error) {
+			accessToken, ok := s.accessTokens[tokenHash]
+			if !ok || accessToken.RevokedAt.Valid {
+				return db.OauthAccessToken{}, db.ErrRecordNotFound
+			}
+			return accessToken, nil
+		})
+	store.EXPECT().
+		CreateOAuthRefreshToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOAuthRefreshTokenParams) (db.OauthRefreshToken, error) {
+			refreshToken := db.OauthRefreshToken{
+				GrantID:   arg.GrantID,
+				TokenHash: arg.TokenHash,
+				ClientID:  arg.ClientID,
+				UserID:    arg.UserID,
+				Scopes:    arg.Scopes,
+				SpaceIds:  arg.SpaceIds,
+				ExpiresAt: arg.ExpiresAt,
+			}
+			s.refreshTokens[arg.TokenHash] = refreshToken
+			return refreshToken, nil
+		})
+	store.EXPECT().
+		GetOAuthRefreshTokenByHash(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, tokenHash string) (db.OauthRefreshToken, error) {
+			refreshToken, ok := s.refreshTokens[tokenHash]
+			if !ok {
+				return db.OauthRefreshToken{}, db.ErrRecordNotFound
+			}
+			return refreshToken, nil
+		})
+	store.EXPECT().
+		RotateOAuthRefreshToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, tokenHash string) (db.OauthRefreshToken, error) {
+			refreshToken, ok := s.refreshTokens[tokenHash]
+			if !ok || refreshToken.RevokedAt.Valid {
+				return db.OauthRefreshToken{}, db.ErrRecordNotFound
+			}
+			refreshToken.RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			s.refreshTokens[tokenHash] = refreshToken
+			return refreshToken, nil
+		})
+	store.EXPECT().
+		RevokeOAuthGrant(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, grantID uuid.UUID) error {
+			revokedAt := pgtype.Timestamp{Time: time.Now(), Valid: true}
+			for hash, accessToken := range s.accessTokens {
+				if accessToken.GrantID == grantID {
+					accessToken.RevokedAt = revokedAt
+					s.accessTokens[hash] = accessToken
+				}
+			}
+			for hash, refreshToken := range s.refreshTokens {
+				if refreshToken.GrantID == grantID {
+					refreshToken.RevokedAt = revokedAt
+					s.refreshTokens[hash] = refreshToken
+				}
+			}
+			return nil
+		})
+}
+
+// TestOAuthFlow goes through the whole router: a user authorizes a public
+// client for a single space, the client exchanges the code with PKCE, uses
+// and refreshes its token
+func TestOAuthFlow(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+	client, _ := randomOAuthClient(t, user.ID, false)
+	space := mockdb.RandomSpace(t, user.ID)
+	other := mockdb.RandomSpace(t, user.ID)
+	verifier, challenge := pkcePair(t)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	state := &oauthTestStore{
+		codes:         map[string]db.OauthAuthorizationCode{},
+		accessTokens:  map[string]db.OauthAccessToken{},
+		refreshTokens: map[string]db.OauthRefreshToken{},
+	}
+	state.stub(store)
+
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).AnyTimes().Return(user, nil)
+	store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).AnyTimes().Return(user, nil)
+	store.EXPECT().
+		GetTOTPByUserID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		ListLoginThrottles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.LoginThrottle{}, nil)
+	store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).AnyTimes().Return(client, nil)
+	store.EXPECT().
+		GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(mockdb.CreatePermission(t, user.ID, space.ID, true, true, true), nil)
+
+	server := NewServer(store, testConfig())
+	disableRateLimits(server)
+
+	send := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// the user approves the access, restricted to space
+	consent, err := json.Marshal(gin.H{
+		"response_type":         "code",
+		"client_id":             client.ID,
+		"redirect_uri":          testRedirectURI,
+		"scope":                 "messages:write",
+		"code_challenge":        challenge,
+		"code_challenge_method": "S256",
+		"approved":              true,
+		"spaces":                []uuid.UUID{space.ID},
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, makeUrl("/oauth/authorize"), bytes.NewReader(consent))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	request.SetBasicAuth(user.Email, password)
+
+	recorder := send(request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var authorized authorizeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &authorized))
+	redirect, err := url.Parse(authorized.RedirectTo)
+	require.NoError(t, err)
+
+	exchange := func(form url.Values) (*httptest.ResponseRecorder, tokenResponse) {
+		form.Set("client_id", client.ID.String())
+		request, err := http.NewRequest(http.MethodPost, makeUrl("/oauth/token"), strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		recorder := send(request)
+		var res tokenResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+		return recorder, res
+	}
+
+	recorder, tokens := exchange(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirect.Query().Get("code")},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// codes are single use
+	recorder, _ = exchange(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirect.Query().Get("code")},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	useToken := func(accessToken, method, path string) int {
+		request, err := http.NewRequest(method, makeUrl(path), nil)
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		return send(request).Code
+	}
+
+	// the token passes the access guard of its space only
+	require.Equal(t, http.StatusNotImplemented, useToken(tokens.AccessToken, http.MethodPost, "/spaces/"+space.ID.String()+"/messages"))
+	require.Equal(t, http.StatusForbidden, useToken(tokens.AccessToken, http.MethodPost, "/spaces/"+other.ID.String()+"/messages"))
+	// outside its scopes
+	require.Equal(t, http.StatusForbidden, useToken(tokens.AccessToken, http.MethodPost, "/spaces"))
+	// and can't manage the account
+	require.Equal(t, http.StatusForbidden, useToken(tokens.AccessToken, http.MethodGet, "/users/me/sessions"))
+	require.Equal(t, http.StatusForbidden, useToken(tokens.AccessToken, http.MethodGet, "/oauth/clients"))
+
+	recorder, refreshed := exchange(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	require.Equal(t, http.StatusNotImplemented, useToken(refreshed.AccessToken, http.MethodPost, "/spaces/"+space.ID.String()+"/messages"))
+
+	// reusing the rotated refresh token revokes the whole grant
+	recorder, _ = exchange(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, http.StatusUnauthorized, useToken(refreshed.AccessToken, http.MethodPost, "/spaces/"+space.ID.String()+"/messages"))
+
+	recorder, _ = exchange(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshed.RefreshToken},
+	})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
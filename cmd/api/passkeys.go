@@ -0,0 +1,266 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errChallengeNotFound = fmt.Errorf("passkey challenge not found or expired")
+
+type passkeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func newPasskeyResponse(passkey db.Passkey) passkeyResponse {
+	res := passkeyResponse{
+		ID:        passkey.ID,
+		Name:      passkey.Name,
+		CreatedAt: passkey.CreatedAt.Time,
+	}
+	if passkey.LastUsedAt.Valid {
+		res.LastUsedAt = &passkey.LastUsedAt.Time
+	}
+	return res
+}
+
+type beginPasskeyRegistrationResponse struct {
+	ChallengeID uuid.UUID                `json:"challenge_id"`
+	PublicKey   webauthn.CreationOptions `json:"public_key"`
+}
+
+// beginPasskeyRegistration returns the options to pass to navigator.credentials.create
+func (server *Server) beginPasskeyRegistration(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	passkeys, err := server.store.ListPasskeysByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	arg := db.CreateRegistrationChallengeParams{
+		UserID:    user.ID,
+		Challenge: challenge,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(webauthn.Timeout), Valid: true},
+	}
+
+	created, err := server.store.CreateRegistrationChallenge(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// an authenticator can only hold one credential per user
+	registered := make([][]byte, len(passkeys))
+	for i, passkey := range passkeys {
+		registered[i] = passkey.CredentialID
+	}
+
+	userEntity := webauthn.UserEntity{
+		ID:          user.ID[:],
+		Name:        user.Email,
+		DisplayName: user.Email,
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, beginPasskeyRegistrationResponse{
+		ChallengeID: created.ID,
+		PublicKey:   server.relyingParty.CreationOptions(challenge, userEntity, registered),
+	})
+}
+
+type finishPasskeyRegistrationRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" binding:"required"`
+	Name        string    `json:"name"         binding:"required,max=64"`
+	Credential  struct {
+		Response struct {
+			ClientDataJSON    webauthn.Base64URL `json:"clientDataJSON"    binding:"required"`
+			AttestationObject webauthn.Base64URL `json:"attestationObject" binding:"required"`
+		} `json:"response"`
+	} `json:"credential"`
+}
+
+// finishPasskeyRegistration verifies the response of navigator.credentials.create
+// and stores the new passkey
+func (server *Server) finishPasskeyRegistration(ctx *gin.Context) {
+	var req finishPasskeyRegistrationRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	challenge, err := server.store.ConsumeRegistrationChallenge(ctx, db.ConsumeRegistrationChallengeParams{
+		ID:     req.ChallengeID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusBadRequest, errChallengeNotFound)
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	credential, err := server.relyingParty.VerifyRegistration(
+		challenge.Challenge,
+		req.Credential.Response.ClientDataJSON,
+		req.Credential.Response.AttestationObject,
+	)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	arg := db.CreatePasskeyParams{
+		UserID:       user.ID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Name:         req.Name,
+	}
+
+	passkey, err := server.store.CreatePasskey(ctx, arg)
+	if err != nil {
+		handleCreatePasskeyError(ctx, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusCreated, newPasskeyResponse(passkey))
+}
+
+func (server *Server) listPasskeys(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	passkeys, err := server.store.ListPasskeysByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := make([]passkeyResponse, len(passkeys))
+	for i, passkey := range passkeys {
+		res[i] = newPasskeyResponse(passkey)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+func (server *Server) deletePasskey(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	passkeyID, err := uuid.Parse(ctx.Param("passkeyID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid passkey id"))
+		return
+	}
+
+	arg := db.DeletePasskeyParams{
+		ID:     passkeyID,
+		UserID: user.ID,
+	}
+
+	deleted, err := server.store.DeletePasskey(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a passkey of another user is reported as not found too
+	if deleted == 0 {
+		httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("passkey not found"))
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
+
+type beginPasskeyLoginResponse struct {
+	ChallengeID uuid.UUID               `json:"challenge_id"`
+	PublicKey   webauthn.RequestOptions `json:"public_key"`
+}
+
+// beginPasskeyLogin returns the options to pass to navigator.credentials.get.
+// The assertion is then sent to /users/login in a "WebAuthn" Authorization header.
+func (server *Server) beginPasskeyLogin(ctx *gin.Context) {
+	// challenges of abandoned logins are never consumed
+	if err := server.store.DeleteExpiredWebauthnChallenges(ctx); err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	arg := db.CreateLoginChallengeParams{
+		Challenge: challenge,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(webauthn.Timeout), Valid: true},
+	}
+
+	created, err := server.store.CreateLoginChallenge(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, beginPasskeyLoginResponse{
+		ChallengeID: created.ID,
+		PublicKey:   server.relyingParty.RequestOptions(challenge),
+	})
+}
+
+func handleCreatePasskeyError(ctx *gin.Context, err error) {
+	var pgErr *pgconn.PgError
+	// if not a pg error return generic error
+	if !errors.As(err, &pgErr) {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch pgErr.Code {
+	case db.ErrUniqueViolation.Code:
+		httpx.WriteError(ctx, http.StatusConflict, fmt.Errorf("passkey already registered"))
+
+	default:
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+	}
+}
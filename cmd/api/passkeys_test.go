@@ -0,0 +1,436 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/Luckny/space-it/pkg/webauthn/webauthntest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBeginPasskeyRegistrationAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	existing := mockdb.RandomPasskey(t, user.ID, webauthntest.NewAuthenticator(testRPID, testOrigin))
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should return creation options",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPasskeysByUser(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return([]db.Passkey{existing}, nil)
+
+				store.EXPECT().
+					CreateRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateRegistrationChallengeParams) (db.WebauthnChallenge, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Len(t, arg.Challenge, webauthn.ChallengeLength)
+						return db.WebauthnChallenge{ID: uuid.New(), UserID: arg.UserID, Challenge: arg.Challenge}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res beginPasskeyRegistrationResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+
+				require.NotEqual(t, uuid.Nil, res.ChallengeID)
+				require.Equal(t, testRPID, res.PublicKey.RelyingParty.ID)
+				require.Equal(t, user.ID[:], []byte(res.PublicKey.User.ID))
+				require.Equal(t, user.Email, res.PublicKey.User.Name)
+				require.Len(t, res.PublicKey.ExcludeCredentials, 1)
+				require.Equal(t, existing.CredentialID, []byte(res.PublicKey.ExcludeCredentials[0].ID))
+			},
+		},
+
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPasskeysByUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+
+				store.EXPECT().
+					CreateRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/users/me/passkeys/register", server.beginPasskeyRegistration)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/passkeys/register", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestFinishPasskeyRegistrationAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	// builds the request body from a registration of a new challenge
+	register := func(t *testing.T, authenticator *webauthntest.Authenticator) (gin.H, db.WebauthnChallenge) {
+		challenge, err := webauthn.NewChallenge()
+		require.NoError(t, err)
+
+		reg, err := authenticator.Register(challenge, user.ID[:])
+		require.NoError(t, err)
+
+		row := db.WebauthnChallenge{ID: uuid.New(), UserID: user.ID, Challenge: challenge}
+		body := gin.H{
+			"challenge_id": row.ID,
+			"name":         "laptop",
+			"credential": gin.H{
+				"id": webauthn.Base64URL(reg.CredentialID),
+				"response": gin.H{
+					"clientDataJSON":    webauthn.Base64URL(reg.ClientDataJSON),
+					"attestationObject": webauthn.Base64URL(reg.AttestationObject),
+				},
+			},
+		}
+		return body, row
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, store *mockdb.MockStore) gin.H
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should register passkey",
+			body: func(t *testing.T, store *mockdb.MockStore) gin.H {
+				body, challenge := register(t, webauthntest.NewAuthenticator(testRPID, testOrigin))
+
+				arg := db.ConsumeRegistrationChallengeParams{ID: challenge.ID, UserID: user.ID}
+				store.EXPECT().
+					ConsumeRegistrationChallenge(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(challenge, nil)
+
+				store.EXPECT().
+					CreatePasskey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreatePasskeyParams) (db.Passkey, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, "laptop", arg.Name)
+						require.NotEmpty(t, arg.CredentialID)
+
+						_, err := webauthn.ParsePublicKey(arg.PublicKey)
+						require.NoError(t, err)
+
+						return db.Passkey{ID: uuid.New(), UserID: arg.UserID, Name: arg.Name}, nil
+					})
+
+				return body
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var res passkeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, "laptop", res.Name)
+				require.Nil(t, res.LastUsedAt)
+			},
+		},
+
+		{
+			name: "other origin",
+			body: func(t *testing.T, store *mockdb.MockStore) gin.H {
+				body, challenge := register(t, webauthntest.NewAuthenticator(testRPID, "https://evil.example"))
+
+				store.EXPECT().
+					ConsumeRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+
+				store.EXPECT().
+					CreatePasskey(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				return body
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+
+		{
+			name: "challenge not found",
+			body: func(t *testing.T, store *mockdb.MockStore) gin.H {
+				body, _ := register(t, webauthntest.NewAuthenticator(testRPID, testOrigin))
+
+				store.EXPECT().
+					ConsumeRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnChallenge{}, db.ErrRecordNotFound)
+
+				return body
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "already registered",
+			body: func(t *testing.T, store *mockdb.MockStore) gin.H {
+				body, challenge := register(t, webauthntest.NewAuthenticator(testRPID, testOrigin))
+
+				store.EXPECT().
+					ConsumeRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+
+				store.EXPECT().
+					CreatePasskey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Passkey{}, db.ErrUniqueViolation)
+
+				return body
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+
+		{
+			name: "missing name",
+			body: func(t *testing.T, store *mockdb.MockStore) gin.H {
+				body, _ := register(t, webauthntest.NewAuthenticator(testRPID, testOrigin))
+				delete(body, "name")
+
+				store.EXPECT().
+					ConsumeRegistrationChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				return body
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			body := tc.body(t, store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/users/me/passkeys", server.finishPasskeyRegistration)
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/passkeys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDeletePasskeyAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	passkeyID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		passkeyID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "should delete passkey",
+			passkeyID: passkeyID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DeletePasskeyParams{ID: passkeyID, UserID: user.ID}
+
+				store.EXPECT().
+					DeletePasskey(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:      "not found",
+			passkeyID: passkeyID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeletePasskey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name:      "invalid passkey id",
+			passkeyID: "invalid-passkey-id",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeletePasskey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.DELETE("/users/me/passkeys/:passkeyID", server.deletePasskey)
+
+			url := fmt.Sprintf("/users/me/passkeys/%s", tc.passkeyID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// TestPasskeyLogin logs in through the whole router with a software authenticator
+func TestPasskeyLogin(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	passkey := mockdb.RandomPasskey(t, user.ID, authenticator)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	var challenge db.WebauthnChallenge
+	store.EXPECT().DeleteExpiredWebauthnChallenges(gomock.Any()).Times(1).Return(nil)
+	store.EXPECT().
+		CreateLoginChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateLoginChallengeParams) (db.WebauthnChallenge, error) {
+			challenge = db.WebauthnChallenge{ID: uuid.New(), Challenge: arg.Challenge}
+			return challenge, nil
+		})
+	store.EXPECT().
+		ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, id uuid.UUID) (db.WebauthnChallenge, error) {
+			require.Equal(t, challenge.ID, id)
+			return challenge, nil
+		})
+	store.EXPECT().GetPasskeyByCredentialID(gomock.Any(), gomock.Any()).Times(1).Return(passkey, nil)
+	store.EXPECT().
+		UsePasskey(gomock.Any(), gomock.Eq(db.UsePasskeyParams{ID: passkey.ID, SignCount: 1})).
+		Times(1).
+		Return(int64(1), nil)
+	store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+	store.EXPECT().CreateSession(gomock.Any(), mockdb.EqCreateSessionParams(user.ID)).Times(1)
+
+	server := NewServer(store, testConfig())
+	disableRateLimits(server)
+
+	// get a challenge
+	request, err := http.NewRequest(http.MethodPost, makeUrl("/users/login/passkey"), nil)
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var begin beginPasskeyLoginResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &begin)
+	require.NoError(t, err)
+	require.Equal(t, testRPID, begin.PublicKey.RelyingPartyID)
+
+	// sign it and log in
+	signed, err := authenticator.Login(begin.PublicKey.Challenge)
+	require.NoError(t, err)
+
+	assertion := middlewares.PasskeyAssertion{
+		ChallengeID:  begin.ChallengeID,
+		CredentialID: signed.CredentialID,
+	}
+	assertion.Response.ClientDataJSON = signed.ClientDataJSON
+	assertion.Response.AuthenticatorData = signed.AuthenticatorData
+	assertion.Response.Signature = signed.Signature
+	assertion.Response.UserHandle = signed.UserHandle
+
+	header, err := assertion.Encode()
+	require.NoError(t, err)
+
+	request, err = http.NewRequest(http.MethodPost, makeUrl("/users/login"), nil)
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", header)
+
+	recorder = httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"token"`)
+}
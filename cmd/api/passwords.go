@@ -0,0 +1,241 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/Luckny/space-it/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// how long a password reset link can be used
+const passwordResetTokenDuration = time.Hour
+
+// at most passwordResetEmailLimit reset emails are sent to a user within
+// passwordResetEmailWindow
+const (
+	passwordResetEmailLimit  = 3
+	passwordResetEmailWindow = time.Hour
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password"     binding:"required"`
+}
+
+// changePassword replaces the password of the user, the current password is
+// required. Every other session of the user is signed out.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	if !server.checkPasswordPolicy(ctx, "new_password", req.NewPassword, user.Email) {
+		return
+	}
+
+	dbUser, err := server.store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a stolen session must not be enough to take the account over, nor to
+	// guess the password
+	if !server.loginThrottle.CheckPassword(ctx, dbUser, req.CurrentPassword, fmt.Errorf("current password is incorrect")) {
+		return
+	}
+
+	passwordHash, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	revoked, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		UserID:    user.ID,
+		SessionID: currentSessionID(ctx),
+		Password:  passwordHash,
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+// checkPasswordPolicy writes the reasons a new password is refused for field
+// and returns false. User inputs are words of the account, like its email.
+func (server *Server) checkPasswordPolicy(ctx *gin.Context, field, password string, userInputs ...string) bool {
+	problems := server.passwordPolicy.Check(password, userInputs...)
+	if len(problems) == 0 {
+		return true
+	}
+
+	httpx.WriteFieldErrors(ctx, httpx.FieldErrors{field: problems})
+	return false
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a password reset link to the user. The response is
+// the same whether the email is registered or not.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// past the limit, no email is sent but the response stays the same, it
+	// would tell the email is registered
+	sent, err := server.store.CountPasswordResetTokensSince(ctx, db.CountPasswordResetTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: pgtype.Timestamp{Time: time.Now().UTC().Add(-passwordResetEmailWindow), Valid: true},
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if sent >= passwordResetEmailLimit {
+		httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+		return
+	}
+
+	resetToken, err := generateEmailToken()
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashEmailToken(resetToken),
+		ExpiresAt: pgtype.Timestamp{
+			Time:  time.Now().UTC().Add(passwordResetTokenDuration),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a failure is not reported, it would tell the email is registered
+	if err := server.mailer.Send(ctx, server.passwordResetMessage(user.Email, resetToken)); err != nil {
+		slog.ErrorContext(ctx, "cannot send password reset email", "error", err)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"    binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// resetPassword sets a new password with the token of a reset link. Every
+// session of the user is signed out.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// the user is only known from the token, the password is checked without
+	// their email
+	if !server.checkPasswordPolicy(ctx, "password", req.Password) {
+		return
+	}
+
+	passwordHash, err := util.HashPassword(req.Password)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash: hashEmailToken(req.Token),
+		Password:  passwordHash,
+	})
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid or expired reset token"))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
+
+// passwordResetMessage returns the email sending resetToken to the user
+func (server *Server) passwordResetMessage(email, resetToken string) mailer.Message {
+	link := resetToken
+	if server.Config.PasswordResetURL != "" {
+		link = withQuery(server.Config.PasswordResetURL, map[string]string{"token": resetToken})
+	}
+
+	return mailer.Message{
+		To:      email,
+		Subject: "Reset your space-it password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your space-it account.\r\n"+
+				"Use this within %d minutes to choose a new one:\r\n\r\n%s\r\n\r\n"+
+				"If it wasn't you, you can ignore this email.",
+			int(passwordResetTokenDuration.Minutes()),
+			link,
+		),
+	}
+}
+
+// generateEmailToken returns a random token to send in a link by email, only
+// its hash is stored
+func generateEmailToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashEmailToken returns the value stored for a token sent by email.
+// Tokens are random enough that a fast hash is sufficient.
+func hashEmailToken(emailToken string) string {
+	sum := sha256.Sum256([]byte(emailToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/Luckny/space-it/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+	current := mockdb.RandomSession(t, user.ID)
+	newPassword := util.RandomPassword()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		withToken     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "keeps current session",
+			body:      gin.H{"current_password": password, "new_password": newPassword},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ChangePasswordTxParams) (int64, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, current.ID, arg.SessionID)
+						require.NoError(t, util.CheckPassword(newPassword, arg.Password))
+						return 2, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"revoked": 2}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name:      "basic auth revokes all sessions",
+			body:      gin.H{"current_password": password, "new_password": newPassword},
+			withToken: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ChangePasswordTxParams) (int64, error) {
+						require.Equal(t, uuid.Nil, arg.SessionID)
+						return 3, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:      "wrong current password",
+			body:      gin.H{"current_password": "wrong-password", "new_password": newPassword},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				// the failure counts for the account and for the client IP
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						require.Contains(t, []string{user.Email, ""}, arg.Email)
+						return db.LoginThrottle{Email: arg.Email, ClientIp: arg.ClientIp, Failures: 1}, nil
+					})
+				store.EXPECT().
+					BlockLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BlockLoginParams) error {
+						require.Equal(t, user.Email, arg.Email)
+						return nil
+					})
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		{
+			name:      "current password attempts blocked",
+			body:      gin.H{"current_password": password, "new_password": newPassword},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListLoginThrottlesParams) ([]db.LoginThrottle, error) {
+						require.Equal(t, user.Email, arg.Email)
+						return []db.LoginThrottle{{
+							Email:        user.Email,
+							ClientIp:     arg.ClientIp,
+							Failures:     5,
+							BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(time.Minute), Valid: true},
+						}}, nil
+					})
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+			},
+		},
+
+		{
+			name:      "new password too short",
+			body:      gin.H{"current_password": password, "new_password": "short"},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"new_password":["must be at least 8 characters"]`)
+			},
+		},
+
+		{
+			name:      "common new password",
+			body:      gin.H{"current_password": password, "new_password": "password123"},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"new_password":["is too easy to guess`)
+			},
+		},
+
+		{
+			name:      "internal error",
+			body:      gin.H{"current_password": password, "new_password": newPassword},
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				if tc.withToken {
+					ctx.Set("token", &token.Payload{
+						ID:        current.ID,
+						User:      dto.NewUser(user),
+						ExpiresAt: time.Now().Add(time.Minute),
+					})
+				}
+				ctx.Next()
+			})
+			router.PUT("/users/me/password", server.changePassword)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/users/me/password", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder, sent []mailer.Message)
+	}{
+		{
+			name: "registered email",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountPasswordResetTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CountPasswordResetTokensSinceParams) (int64, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.WithinDuration(t, time.Now().Add(-passwordResetEmailWindow), arg.CreatedAt.Time, time.Minute)
+						return passwordResetEmailLimit - 1, nil
+					})
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(
+						_ context.Context,
+						arg db.CreatePasswordResetTokenParams,
+					) (db.PasswordResetToken, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Len(t, arg.TokenHash, 64)
+						require.WithinDuration(t, time.Now().Add(passwordResetTokenDuration), arg.ExpiresAt.Time, time.Minute)
+						return db.PasswordResetToken{UserID: arg.UserID, TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Len(t, sent, 1)
+				require.Equal(t, user.Email, sent[0].To)
+
+				// the link carries the token, only its hash is stored
+				match := regexp.MustCompile(`https://app\.example\.com/reset\?token=([\w-]+)`).FindStringSubmatch(sent[0].Body)
+				require.Len(t, match, 2)
+			},
+		},
+
+		{
+			name: "too many reset emails",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountPasswordResetTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(passwordResetEmailLimit), nil)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				// the same response as for an unknown email
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+
+		{
+			name: "unknown email",
+			body: gin.H{"email": "unknown@email.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+
+		{
+			name: "invalid email",
+			body: gin.H{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CountPasswordResetTokensSince(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := testConfig()
+			config.PasswordResetURL = "https://app.example.com/reset"
+			server := NewServer(store, config)
+
+			mail := mailer.NewMemoryMailer()
+			server.mailer = mail
+
+			router := gin.Default()
+			router.POST("/users/password/forgot", server.forgotPassword)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, mail.Sent())
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	resetToken, err := generateEmailToken()
+	require.NoError(t, err)
+	newPassword := util.RandomPassword()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": resetToken, "password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.PasswordResetToken, error) {
+						require.Equal(t, hashEmailToken(resetToken), arg.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, arg.Password))
+						return db.PasswordResetToken{TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "used or expired token",
+			body: gin.H{"token": resetToken, "password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "password too short",
+			body: gin.H{"token": resetToken, "password": "short"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"password":["must be at least 8 characters"]`)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"token": resetToken, "password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.POST("/users/password/reset", server.resetPassword)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// getCurrentUser responds with the account and profile of the user
+func (server *Server) getCurrentUser(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	// the user in the context may come from a session created before the
+	// last profile update
+	dbUser, err := server.store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, dto.NewProfile(dbUser))
+}
+
+// fields are only changed when given, an empty avatar removes it
+type updateCurrentUserRequest struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=64"`
+	Avatar      *string `json:"avatar"       binding:"omitempty,max=255,http_url|len=0"`
+	Timezone    *string `json:"timezone"     binding:"omitempty,timezone"`
+	Locale      *string `json:"locale"       binding:"omitempty,max=35,bcp47_language_tag"`
+}
+
+// updateCurrentUser changes the profile of the user
+func (server *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	dbUser, err := server.store.UpdateUserProfile(ctx, db.UpdateUserProfileParams{
+		ID:          user.ID,
+		DisplayName: optionalText(req.DisplayName),
+		Avatar:      optionalText(req.Avatar),
+		Timezone:    optionalText(req.Timezone),
+		Locale:      optionalText(req.Locale),
+	})
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, dto.NewProfile(dbUser))
+}
+
+// getUserProfile responds with the public profile of a user. Users only see
+// the profiles of the users they share a space with, other users are not
+// found.
+func (server *Server) getUserProfile(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	userID, err := uuid.Parse(ctx.Param("userID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid user id"))
+		return
+	}
+
+	if userID != user.ID {
+		shared, err := server.store.UsersShareSpace(ctx, db.UsersShareSpaceParams{
+			UserID:      user.ID,
+			OtherUserID: userID,
+		})
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if !shared {
+			httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("user not found"))
+			return
+		}
+	}
+
+	dbUser, err := server.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("user not found"))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, dto.NewPublicProfile(dbUser))
+}
+
+// optionalText is a nullable query argument, NULL when value is nil
+func optionalText(value *string) pgtype.Text {
+	if value == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *value, Valid: true}
+}
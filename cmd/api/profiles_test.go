@@ -0,0 +1,362 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomProfileUser(t *testing.T) db.User {
+	user, _ := mockdb.RandomUser(t)
+	user.DisplayName = "Ada"
+	user.Avatar = "https://cdn.example.com/ada.png"
+	user.Timezone = "Europe/London"
+	user.Locale = "en-GB"
+	return user
+}
+
+func TestGetCurrentUserAPI(t *testing.T) {
+	user := randomProfileUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var profile dto.Profile
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+				require.Equal(t, user.ID, profile.ID)
+				require.Equal(t, user.Email, profile.Email)
+				require.Equal(t, user.DisplayName, profile.DisplayName)
+				require.Equal(t, user.Avatar, profile.Avatar)
+				require.Equal(t, user.Timezone, profile.Timezone)
+				require.Equal(t, user.Locale, profile.Locale)
+				require.NotContains(t, recorder.Body.String(), user.Password)
+			},
+		},
+
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.GET("/users/me", server.getCurrentUser)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUpdateCurrentUserAPI(t *testing.T) {
+	user := randomProfileUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "only given fields",
+			body: gin.H{"display_name": "Ada Lovelace", "timezone": "America/Toronto"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserProfile(gomock.Any(), gomock.Eq(db.UpdateUserProfileParams{
+						ID:          user.ID,
+						DisplayName: pgtype.Text{String: "Ada Lovelace", Valid: true},
+						Timezone:    pgtype.Text{String: "America/Toronto", Valid: true},
+					})).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserProfileParams) (db.User, error) {
+						updated := user
+						updated.DisplayName = arg.DisplayName.String
+						updated.Timezone = arg.Timezone.String
+						return updated, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var profile dto.Profile
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+				require.Equal(t, "Ada Lovelace", profile.DisplayName)
+				require.Equal(t, "America/Toronto", profile.Timezone)
+				require.Equal(t, user.Locale, profile.Locale)
+			},
+		},
+
+		{
+			name: "remove avatar",
+			body: gin.H{"avatar": "", "locale": "fr-CA"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserProfile(gomock.Any(), gomock.Eq(db.UpdateUserProfileParams{
+						ID:     user.ID,
+						Avatar: pgtype.Text{String: "", Valid: true},
+						Locale: pgtype.Text{String: "fr-CA", Valid: true},
+					})).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "invalid timezone",
+			body: gin.H{"timezone": "Mars/Olympus_Mons"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "empty timezone",
+			body: gin.H{"timezone": ""},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "invalid locale",
+			body: gin.H{"locale": "not a locale"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "avatar not a URL",
+			body: gin.H{"avatar": "javascript:alert(1)"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "display name too long",
+			body: gin.H{"display_name": string(bytes.Repeat([]byte("a"), 65))},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"display_name": "Ada"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserProfile(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.PATCH("/users/me", server.updateCurrentUser)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetUserProfileAPI(t *testing.T) {
+	user := randomProfileUser(t)
+	other := randomProfileUser(t)
+
+	testCases := []struct {
+		name          string
+		userID        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "shares a space",
+			userID: other.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UsersShareSpace(gomock.Any(), gomock.Eq(db.UsersShareSpaceParams{
+						UserID:      user.ID,
+						OtherUserID: other.ID,
+					})).
+					Times(1).
+					Return(true, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(other.ID)).Times(1).Return(other, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var profile dto.PublicProfile
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+				require.Equal(t, dto.NewPublicProfile(other), profile)
+				require.NotContains(t, recorder.Body.String(), other.Email)
+			},
+		},
+
+		{
+			name:   "own profile",
+			userID: user.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UsersShareSpace(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "no shared space",
+			userID: other.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UsersShareSpace(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name:   "unknown user",
+			userID: uuid.NewString(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UsersShareSpace(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name:   "invalid id",
+			userID: "me2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UsersShareSpace(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:   "internal error",
+			userID: other.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UsersShareSpace(gomock.Any(), gomock.Any()).Times(1).Return(false, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.GET("/users/:userID", server.getUserProfile)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/"+tc.userID, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// route groups with their own rate limit
+const (
+	// every route
+	rateGroupAPI = "api"
+	// routes accepting credentials or sending emails
+	rateGroupAuth = "auth"
+	// every route, per client IP before requests are authenticated
+	rateGroupClientIP = "ip"
+)
+
+// where rate limits are kept
+const (
+	rateLimitsInMemory   = config.RateLimitBackendMemory
+	rateLimitsInPostgres = config.RateLimitBackendPostgres
+)
+
+// rate limits used when not configured, in requests per second
+const (
+	defaultRateLimitAPI       = 5
+	defaultRateLimitAPIBurst  = 20
+	defaultRateLimitAuth      = 0.5
+	defaultRateLimitAuthBurst = 5
+	// users behind the same NAT share it
+	defaultRateLimitClientIP      = 20
+	defaultRateLimitClientIPBurst = 100
+)
+
+// newRateLimiters returns the rate limiter of each route group, kept by the
+// configured backend
+func newRateLimiters(store db.Store, config config.Config) (map[string]middlewares.RateLimiter, error) {
+	limits := []struct {
+		group string
+		limit float64
+		burst int
+		// used when not configured
+		defaultLimit float64
+		defaultBurst int
+	}{
+		{rateGroupAPI, config.RateLimitAPI, config.RateLimitAPIBurst, defaultRateLimitAPI, defaultRateLimitAPIBurst},
+		{rateGroupAuth, config.RateLimitAuth, config.RateLimitAuthBurst, defaultRateLimitAuth, defaultRateLimitAuthBurst},
+		{
+			rateGroupClientIP,
+			config.RateLimitClientIP,
+			config.RateLimitClientIPBurst,
+			defaultRateLimitClientIP,
+			defaultRateLimitClientIPBurst,
+		},
+	}
+
+	limiters := make(map[string]middlewares.RateLimiter, len(limits))
+	for _, l := range limits {
+		if l.limit <= 0 {
+			l.limit = l.defaultLimit
+		}
+		if l.burst <= 0 {
+			l.burst = l.defaultBurst
+		}
+
+		switch config.RateLimitBackend {
+		case "", rateLimitsInMemory:
+			limiters[l.group] = middlewares.NewMemoryRateLimiter(rate.Limit(l.limit), l.burst, config.RateLimitMaxKeys)
+		case rateLimitsInPostgres:
+			limiters[l.group] = middlewares.NewStoreRateLimiter(store, l.group, rate.Limit(l.limit), l.burst)
+		default:
+			return nil, fmt.Errorf("invalid RATE_LIMIT_BACKEND %q", config.RateLimitBackend)
+		}
+	}
+
+	return limiters, nil
+}
+
+// rateGuard throttles the requests of the route group
+func (server *Server) rateGuard(group string) gin.HandlerFunc {
+	return middlewares.RateGuard(server.Limiters[group], !server.Config.RateLimitFailClosed)
+}
+
+// clientIPGuard throttles client IPs before their requests are authenticated
+func (server *Server) clientIPGuard() gin.HandlerFunc {
+	return middlewares.ClientIPRateGuard(server.Limiters[rateGroupClientIP], !server.Config.RateLimitFailClosed)
+}
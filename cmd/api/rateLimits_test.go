@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Luckny/space-it/cmd/middlewares"
+	mockdb "github.com/Luckny/space-it/db/mock"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewRateLimiters(t *testing.T) {
+	// takes requests of a client until it is throttled
+	burst := func(limits map[string]int, config config.Config) {
+		limiters, err := newRateLimiters(nil, config)
+		require.NoError(t, err)
+		require.Len(t, limiters, len(limits))
+
+		for group, limit := range limits {
+			for i := 0; i < limit; i++ {
+				res, err := limiters[group].Take(context.Background(), "ip:127.0.0.1")
+				require.NoError(t, err)
+				require.True(t, res.Allowed, group)
+			}
+			res, err := limiters[group].Take(context.Background(), "ip:127.0.0.1")
+			require.NoError(t, err)
+			require.False(t, res.Allowed, group)
+		}
+	}
+
+	burst(map[string]int{
+		rateGroupAPI:      defaultRateLimitAPIBurst,
+		rateGroupAuth:     defaultRateLimitAuthBurst,
+		rateGroupClientIP: defaultRateLimitClientIPBurst,
+	}, config.Config{})
+
+	burst(map[string]int{
+		rateGroupAPI:      50,
+		rateGroupAuth:     2,
+		rateGroupClientIP: 80,
+	}, config.Config{
+		RateLimitAPI:           10,
+		RateLimitAPIBurst:      50,
+		RateLimitAuth:          0.1,
+		RateLimitAuthBurst:     2,
+		RateLimitClientIP:      20,
+		RateLimitClientIPBurst: 80,
+		RateLimitBackend:       rateLimitsInMemory,
+	})
+}
+
+func TestNewRateLimitersBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	limiters, err := newRateLimiters(store, config.Config{RateLimitBackend: rateLimitsInPostgres})
+	require.NoError(t, err)
+	for _, limiter := range limiters {
+		require.IsType(t, &middlewares.StoreRateLimiter{}, limiter)
+	}
+
+	_, err = newRateLimiters(store, config.Config{RateLimitBackend: "redis"})
+	require.Error(t, err)
+}
@@ -1,74 +1,241 @@
 package api
 
 import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Luckny/space-it/cmd/middlewares"
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/audit"
 	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/Luckny/space-it/pkg/passwordpolicy"
 	"github.com/Luckny/space-it/pkg/token"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
-	"golang.org/x/time/rate"
 )
 
+// how long a shutdown waits when no timeout is configured
+const defaultShutdownTimeout = 30 * time.Second
+
 type Server struct {
-	store      db.Store
-	Router     *gin.Engine
-	Limiter    *rate.Limiter
+	store  db.Store
+	Router *gin.Engine
+	// rate limiter of each route group
+	Limiters   map[string]middlewares.RateLimiter
 	tokenMaker token.Maker
-	Config     config.Config
+	// access tokens of the OAuth2 authorization server
+	oauthTokens token.Maker
+	activity    *token.ActivityTracker
+	// writes the audit trail in the background
+	audit *audit.Pipeline
+	// signs checkpoints of the audit chain, nil when it isn't checkpointed
+	checkpointKey ed25519.PrivateKey
+	mailer        mailer.Mailer
+	// checks the passwords users choose
+	passwordPolicy *passwordpolicy.Policy
+	// slows down password guessing, on Basic auth and where handlers check
+	// the password again
+	loginThrottle *middlewares.LoginThrottle
+	Config        config.Config
+
+	// nil when passkeys are not configured
+	relyingParty *webauthn.RelyingParty
 }
 
 func NewServer(store db.Store, config config.Config) *Server {
 	server := &Server{
-		store:      store,
-		Limiter:    rate.NewLimiter(rate.Limit(2), 2),
-		tokenMaker: token.NewCookieStore(),
-		Config:     config,
+		store:       store,
+		tokenMaker:  token.NewCookieStore(config, store),
+		oauthTokens: token.NewOAuthStore(store),
+		activity:    token.NewActivityTracker(store),
+		audit: audit.New(store, audit.Options{
+			BufferSize:    config.AuditBufferSize,
+			BatchSize:     config.AuditBatchSize,
+			FlushInterval: config.AuditFlushInterval,
+			DropWhenFull:  config.AuditDropWhenFull,
+		}),
+		loginThrottle: middlewares.NewLoginThrottle(
+			store,
+			config.LoginMaxFailures,
+			config.LoginMaxFailuresPerIP,
+			config.LoginLockout,
+		),
+		Config: config,
+	}
+
+	mail, err := mailer.NewFileMailer(config.MailFrom, config.MailerFile)
+	if err != nil {
+		panic(err)
+	}
+	server.mailer = mail
+
+	for _, link := range []string{config.PasswordResetURL, config.EmailVerificationURL} {
+		if _, err := url.Parse(link); err != nil {
+			panic(err)
+		}
+	}
+
+	util.SetArgon2Params(util.Argon2Params{
+		Memory:  config.PasswordArgon2Memory,
+		Time:    config.PasswordArgon2Time,
+		Threads: config.PasswordArgon2Threads,
+	})
+
+	var breached *passwordpolicy.BreachedList
+	if config.BreachedPasswordsFile != "" {
+		breached, err = passwordpolicy.LoadBreachedList(config.BreachedPasswordsFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	server.passwordPolicy, err = passwordpolicy.New(config.PasswordMinStrength, breached)
+	if err != nil {
+		panic(err)
+	}
+
+	server.Limiters, err = newRateLimiters(store, config)
+	if err != nil {
+		panic(err)
+	}
+
+	if config.AuditCheckpointKey != "" {
+		server.checkpointKey, err = audit.ParseCheckpointKey(config.AuditCheckpointKey)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if !validEmailVerificationRequired(config.EmailVerificationRequired) {
+		panic(fmt.Sprintf("invalid EMAIL_VERIFICATION_REQUIRED %q", config.EmailVerificationRequired))
+	}
+
+	if config.WebAuthnRPID != "" {
+		relyingParty, err := webauthn.NewRelyingParty(
+			config.WebAuthnRPID,
+			config.WebAuthnRPName,
+			config.WebAuthnOrigins,
+		)
+		if err != nil {
+			panic(err)
+		}
+		server.relyingParty = relyingParty
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("accesslvl", middlewares.ValidAccessLvl)
+		v.RegisterValidation("scope", middlewares.ValidScope)
 	}
 
-	router := gin.Default()
+	router := gin.New()
 
-	router.Use(middlewares.EnsureJSONContentType())
-	router.Use(middlewares.RateGuard(server.Limiter))
+	// handlers pass the gin context on to the store and the logs, the values
+	// of the request context, like its request ID, must be reachable from it
+	router.ContextWithFallback = true
+
+	// client IPs key login throttles and rate limits, they must not be
+	// spoofable
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		panic(err)
+	}
+
+	// every request gets an ID before anything is logged for it, and is
+	// logged even when its handler panics
+	requestID, err := middlewares.RequestID(config.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	router.Use(requestID, middlewares.RequestLogger(), middlewares.Recovery())
 
 	// CORS preflight requests should
 	// be handled before API requests authentication because credentials are never
 	// sent on a preflight request, so it would always fail otherwise
 	router.Use(middlewares.CorsFilter())
 
-	// at least one of the following two middleware should succeed
-	// for user to be authenticated
-	router.Use(middlewares.Authenticate(store))
-	router.Use(middlewares.VerifyToken(server.tokenMaker))
+	// client IPs are throttled before any credentials are checked, so a flood
+	// of bad ones doesn't cost a password hash and lookups per request
+	router.Use(server.clientIPGuard())
+
+	// the OAuth2 token, introspection and revocation endpoints take form
+	// encoded bodies and authenticate clients, not users, with Basic auth.
+	// They are registered before the middlewares that would refuse their
+	// requests, and rate limited per client IP.
+	authGuard := server.rateGuard(rateGroupAuth)
+	router.POST(makeUrl("/oauth/token"), authGuard, middlewares.AuditLogger(server.audit), server.issueOAuthToken)
+	router.POST(makeUrl("/oauth/introspect"), authGuard, middlewares.AuditLogger(server.audit), server.introspectToken)
+	router.POST(makeUrl("/oauth/revoke"), authGuard, middlewares.AuditLogger(server.audit), server.revokeToken)
+
+	router.Use(middlewares.EnsureJSONContentType())
+
+	// at least one of the following middlewares should succeed
+	// for user to be authenticated. API keys and OAuth2 access tokens
+	// authenticate as their user, within their scopes
+	router.Use(middlewares.Authenticate(store, server.relyingParty, server.loginThrottle))
+	router.Use(middlewares.VerifyToken(server.tokenMaker, server.activity))
+	router.Use(middlewares.AuthenticateAPIKey(store))
+	router.Use(middlewares.AuthenticateOAuthToken(server.oauthTokens))
+
+	// requests are counted against whoever they are authenticated as, throttled
+	// requests are not logged
+	router.Use(server.rateGuard(rateGroupAPI))
 
 	// log all requests
-	router.Use(middlewares.AuditLogger(store))
+	router.Use(middlewares.AuditLogger(server.audit))
+
+	// routes authenticating users or sending them emails are also held to
+	// the stricter auth rate limit
+	router.POST(makeUrl("/users"), authGuard, server.registerUser)
 
-	router.POST(makeUrl("/users"), server.registerUser)
+	// users with two-factor authentication log in with their password first,
+	// then verify the second factor with the token they were given
+	router.POST(
+		makeUrl("/users/login"),
+		authGuard,
+		middlewares.RejectDelegatedAccess(),
+		middlewares.RequireFirstFactor(),
+		server.requireVerifiedEmail(verifiedEmailForLogin),
+		server.loginUser,
+	)
+	router.POST(
+		makeUrl("/users/login/2fa"),
+		authGuard,
+		middlewares.RequireSecondFactorPending(),
+		server.verifySecondFactor,
+	)
+	if server.relyingParty != nil {
+		router.POST(makeUrl("/users/login/passkey"), authGuard, server.beginPasskeyLogin)
+	}
+	router.POST(makeUrl("/users/password/forgot"), authGuard, server.forgotPassword)
+	router.POST(makeUrl("/users/password/reset"), authGuard, server.resetPassword)
+	router.POST(makeUrl("/users/email/verify"), authGuard, server.verifyEmail)
+	router.POST(makeUrl("/users/email/resend"), authGuard, server.resendVerificationEmail)
 
 	// require that all following requests require authentication
 	router.Use(middlewares.RequireAuthentication())
+	router.Use(server.requireVerifiedEmail(verifiedEmailForLogin))
 
-	router.POST(makeUrl("/users/login"), server.loginUser)
-	router.DELETE(makeUrl("/users/logout"), server.logoutUser)
-	router.POST(makeUrl("/spaces"), server.createSpace)
-
-	router.GET(makeUrl("/test"), func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Hello World",
-		})
-	})
+	// routes usable with an API key or an OAuth2 access token declare the
+	// scope they require
+	router.POST(
+		makeUrl("/spaces"),
+		middlewares.RequireScope(middlewares.ScopeSpacesWrite),
+		server.requireVerifiedEmail(verifiedEmailForSpaces),
+		server.createSpace,
+	)
 
 	router.POST(
 		makeUrl("/spaces/:spaceID/messages"),
-		middlewares.RequireAccessLvl(middlewares.WriteAccess, store),
+		middlewares.RequireAccessLvl(middlewares.WriteAccess, middlewares.ScopeMessagesWrite, store),
 		func(c *gin.Context) {
 			c.JSON(http.StatusNotImplemented, "not implemented")
 		},
@@ -76,17 +243,105 @@ func NewServer(store db.Store, config config.Config) *Server {
 
 	router.POST(
 		makeUrl("/spaces/:spaceID/members"),
-		middlewares.RequireAccessLvl(middlewares.AdminAccess, store),
+		middlewares.RequireAccessLvl(middlewares.AdminAccess, middlewares.ScopeSpacesAdmin, store),
 		server.addMemberToSpace,
 	)
 
+	router.GET(
+		makeUrl("/spaces/:spaceID/activity"),
+		middlewares.RequireAccessLvl(middlewares.AdminAccess, middlewares.ScopeSpacesAdmin, store),
+		server.listSpaceActivity,
+	)
+
+	// the account and its credentials can't be managed, nor access delegated,
+	// with an API key or an OAuth2 access token
+	router.Use(middlewares.RejectDelegatedAccess())
+
+	router.DELETE(makeUrl("/users/logout"), server.logoutUser)
+	router.GET(makeUrl("/users/me"), server.getCurrentUser)
+	router.PATCH(makeUrl("/users/me"), server.updateCurrentUser)
+	router.DELETE(makeUrl("/users/me"), server.deleteAccount)
+	router.GET(makeUrl("/users/me/export"), server.exportAccount)
+	router.GET(makeUrl("/users/:userID"), server.getUserProfile)
+	router.GET(makeUrl("/users/me/sessions"), server.listSessions)
+	router.DELETE(makeUrl("/users/me/sessions"), server.revokeOtherSessions)
+	router.DELETE(makeUrl("/users/me/sessions/:sessionID"), server.revokeSession)
+	router.PUT(makeUrl("/users/me/password"), server.changePassword)
+	router.POST(makeUrl("/users/me/2fa"), server.enrollTwoFactor)
+	router.POST(makeUrl("/users/me/2fa/confirm"), server.confirmTwoFactor)
+	router.DELETE(makeUrl("/users/me/2fa"), server.disableTwoFactor)
+	if server.relyingParty != nil {
+		router.POST(makeUrl("/users/me/passkeys/register"), server.beginPasskeyRegistration)
+		router.POST(makeUrl("/users/me/passkeys"), server.finishPasskeyRegistration)
+		router.GET(makeUrl("/users/me/passkeys"), server.listPasskeys)
+		router.DELETE(makeUrl("/users/me/passkeys/:passkeyID"), server.deletePasskey)
+	}
+	router.POST(makeUrl("/users/me/api-keys"), server.createAPIKey)
+	router.GET(makeUrl("/users/me/api-keys"), server.listAPIKeys)
+	router.DELETE(makeUrl("/users/me/api-keys/:apiKeyID"), server.revokeAPIKey)
+	router.POST(makeUrl("/oauth/clients"), server.registerOAuthClient)
+	router.GET(makeUrl("/oauth/clients"), server.listOAuthClients)
+	router.DELETE(makeUrl("/oauth/clients/:clientID"), server.revokeOAuthClient)
+	router.GET(makeUrl("/oauth/authorize"), server.getAuthorization)
+	router.POST(makeUrl("/oauth/authorize"), server.authorize)
+
+	// system administrators only
+	router.GET(makeUrl("/admin/audit"), middlewares.RequireAdmin(store), server.listAuditLogs)
+
+	router.GET(makeUrl("/test"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Hello World",
+		})
+	})
+
 	server.Router = router
 	return server
 }
 
-// Run's the api server
+// Run's the api server until it is interrupted or terminated, then stops it
+// gracefully: requests in progress are completed and their audit entries
+// written
 func (server *Server) Run(addr string) error {
-	return server.Router.RunTLS(addr, "cert.pem", "key.pem")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go server.activity.Run(ctx, server.Config.SessionFlushInterval)
+	go server.audit.Run()
+	go audit.RunRetention(ctx, server.store, audit.RetentionPolicy{
+		Months:     server.Config.AuditRetentionMonths,
+		ArchiveDir: server.Config.AuditArchiveDir,
+	}, server.Config.AuditRetentionInterval)
+	if server.checkpointKey != nil {
+		go audit.RunCheckpoints(ctx, server.store, server.checkpointKey, server.Config.AuditCheckpointInterval)
+	}
+	if server.Config.RateLimitBackend == rateLimitsInPostgres {
+		go middlewares.PruneRateLimits(ctx, server.store, 0)
+	}
+
+	srv := &http.Server{Addr: addr, Handler: server.Router}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServeTLS("cert.pem", "key.pem")
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := server.Config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return server.audit.Close(shutdownCtx)
 }
 
 func makeUrl(path string) string {
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/mailer"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// TestResponsesDoNotLeakPasswords goes through the whole router, so every
+// middleware and handler that can put a user in a response is covered.
+func TestResponsesDoNotLeakPasswords(t *testing.T) {
+	user, unHashedPassword := mockdb.RandomUser(t)
+	session := mockdb.RandomSession(t, user.ID)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().RegisterUser(gomock.Any(), gomock.Any()).AnyTimes().Return(user, nil)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).AnyTimes().Return(user, nil)
+	store.EXPECT().
+		CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(int64(0), nil)
+	store.EXPECT().
+		CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.EmailVerificationToken{}, nil)
+	store.EXPECT().
+		GetTOTPByUserID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		ListLoginThrottles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.LoginThrottle{}, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).AnyTimes().Return(session, nil)
+	store.EXPECT().
+		ListActiveSessionsByUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.Session{session}, nil)
+
+	server := NewServer(store, testConfig())
+	disableRateLimits(server)
+	server.mailer = mailer.NewMemoryMailer()
+
+	registerBody, err := json.Marshal(registerUserRequest{
+		Email:    user.Email,
+		Password: unHashedPassword,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		auth   bool
+	}{
+		{name: "register", method: http.MethodPost, path: "/users", body: registerBody},
+		{name: "login", method: http.MethodPost, path: "/users/login", auth: true},
+		{name: "list sessions", method: http.MethodGet, path: "/users/me/sessions", auth: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, makeUrl(tc.path), bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			if tc.auth {
+				request.SetBasicAuth(user.Email, unHashedPassword)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.Router.ServeHTTP(recorder, request)
+
+			require.Less(t, recorder.Code, http.StatusBadRequest)
+			require.NotContains(t, recorder.Body.String(), user.Password)
+			requireNoPasswordField(t, recorder.Body.Bytes())
+		})
+	}
+}
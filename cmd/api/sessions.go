@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func (server *Server) listSessions(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	sessions, err := server.store.ListActiveSessionsByUser(ctx, user.ID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	currentID := currentSessionID(ctx)
+	res := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		lastSeen := session.LastSeenAt.Time
+		// activity not yet flushed to the database
+		if pending, ok := server.activity.LastSeen(session.ID); ok && pending.After(lastSeen) {
+			lastSeen = pending
+		}
+
+		res = append(res, sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			ClientIP:   session.ClientIp,
+			CreatedAt:  session.CreatedAt.Time,
+			LastSeenAt: lastSeen,
+			ExpiresAt:  session.ExpiresAt.Time,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+func (server *Server) revokeSession(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	sessionID, err := uuid.Parse(ctx.Param("sessionID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, fmt.Errorf("invalid session id"))
+		return
+	}
+
+	arg := db.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: user.ID,
+	}
+
+	revoked, err := server.store.RevokeSession(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// a session of another user is reported as not found too
+	if revoked == 0 {
+		httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("session not found"))
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
+
+// revokeOtherSessions revokes every session of the user except the one making the request
+func (server *Server) revokeOtherSessions(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	arg := db.RevokeOtherSessionsParams{
+		UserID: user.ID,
+		ID:     currentSessionID(ctx),
+	}
+
+	revoked, err := server.store.RevokeOtherSessions(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+// currentSessionID returns the id of the session used to authenticate the request.
+// Requests authenticated with Basic auth have no session, uuid.Nil is returned.
+func currentSessionID(ctx *gin.Context) uuid.UUID {
+	payload, ok := httpx.GetTokenFromContext(ctx)
+	if !ok {
+		return uuid.Nil
+	}
+	return payload.ID
+}
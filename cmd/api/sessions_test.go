@@ -0,0 +1,297 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListSessionsAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	current := mockdb.RandomSession(t, user.ID)
+	other := mockdb.RandomSession(t, user.ID)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should list sessions",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListActiveSessionsByUser(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return([]db.Session{current, other}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				gotSessions := requireBodyMatchSessions(t, recorder.Body)
+				require.Len(t, gotSessions, 2)
+				require.Equal(t, current.ID, gotSessions[0].ID)
+				require.True(t, gotSessions[0].Current)
+				require.Equal(t, other.ID, gotSessions[1].ID)
+				require.False(t, gotSessions[1].Current)
+				require.Equal(t, other.UserAgent, gotSessions[1].UserAgent)
+				require.Equal(t, other.ClientIp, gotSessions[1].ClientIP)
+			},
+		},
+
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListActiveSessionsByUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Set("token", &token.Payload{ID: current.ID, User: dto.NewUser(user)})
+				ctx.Next()
+			})
+			router.GET("/users/me/sessions", server.listSessions)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRevokeSessionAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	session := mockdb.RandomSession(t, user.ID)
+
+	testCases := []struct {
+		name          string
+		sessionID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "should revoke session",
+			sessionID: session.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeSessionParams{
+					ID:     session.ID,
+					UserID: user.ID,
+				}
+
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:      "not found",
+			sessionID: uuid.NewString(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name:      "invalid session id",
+			sessionID: "invalid-session-id",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:      "internal error",
+			sessionID: session.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.DELETE("/users/me/sessions/:sessionID", server.revokeSession)
+
+			url := fmt.Sprintf("/users/me/sessions/%s", tc.sessionID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRevokeOtherSessionsAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	current := mockdb.RandomSession(t, user.ID)
+
+	testCases := []struct {
+		name          string
+		withToken     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "keeps current session",
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeOtherSessionsParams{
+					UserID: user.ID,
+					ID:     current.ID,
+				}
+
+				store.EXPECT().
+					RevokeOtherSessions(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(3), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"revoked": 3}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name:      "basic auth revokes all sessions",
+			withToken: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeOtherSessionsParams{
+					UserID: user.ID,
+					ID:     uuid.Nil,
+				}
+
+				store.EXPECT().
+					RevokeOtherSessions(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:      "internal error",
+			withToken: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeOtherSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				if tc.withToken {
+					ctx.Set("token", &token.Payload{
+						ID:        current.ID,
+						User:      dto.NewUser(user),
+						ExpiresAt: time.Now().Add(time.Minute),
+					})
+				}
+				ctx.Next()
+			})
+			router.DELETE("/users/me/sessions", server.revokeOtherSessions)
+
+			request, err := http.NewRequest(http.MethodDelete, "/users/me/sessions", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// requireBodyMatchSessions decodes a list of sessions from the body
+func requireBodyMatchSessions(t *testing.T, body *bytes.Buffer) []sessionResponse {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotSessions []sessionResponse
+	err = json.Unmarshal(data, &gotSessions)
+	require.NoError(t, err)
+
+	return gotSessions
+}
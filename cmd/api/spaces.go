@@ -1,17 +1,19 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Luckny/space-it/cmd/middlewares"
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/httpx"
-	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type createSpaceRequest struct {
@@ -28,13 +30,13 @@ func (server *Server) createSpace(ctx *gin.Context) {
 	user, err := httpx.GetUserFromContext(ctx)
 	if err != nil {
 		// user should be authenticated by the auth middlewares
-		util.ErrorLog.Panic(err)
-		return
+		panic(err)
 	}
 
 	arg := db.CreateSpaceTxParams{
-		Name:  req.Name,
-		Owner: user.ID,
+		Name:      req.Name,
+		Owner:     user.ID,
+		RequestID: httpx.GetRequestIDFromContext(ctx),
 	}
 
 	// creates space and gives it owner permissions
@@ -67,18 +69,145 @@ func (server *Server) addMemberToSpace(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreatePermissionParams{
-		UserID:           req.UserID,
-		SpaceID:          spaceID,
-		WritePermission:  req.Permissions[middlewares.WriteAccess],
-		ReadPermission:   req.Permissions[middlewares.ViewAccess],
-		DeletePermission: req.Permissions[middlewares.DeleteAccess],
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	arg := db.AddSpaceMemberTxParams{
+		Permission: db.CreatePermissionParams{
+			UserID:           req.UserID,
+			SpaceID:          spaceID,
+			WritePermission:  req.Permissions[middlewares.WriteAccess],
+			ReadPermission:   req.Permissions[middlewares.ViewAccess],
+			DeletePermission: req.Permissions[middlewares.DeleteAccess],
+		},
+		ActorID:   user.ID,
+		RequestID: httpx.GetRequestIDFromContext(ctx),
+	}
+	permission, err := server.store.AddSpaceMemberTx(ctx, arg)
+	if err != nil {
+		handleAddMemberError(ctx, err)
+		return
 	}
-	permission, err := server.store.CreatePermission(ctx, arg)
 
 	httpx.WriteResponse(ctx, http.StatusCreated, permission)
 }
 
+func handleAddMemberError(ctx *gin.Context, err error) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch pgErr.Code {
+	case db.ErrUniqueViolation.Code:
+		httpx.WriteError(ctx, http.StatusConflict, fmt.Errorf("user is already a member of the space"))
+
+	// the space exists, it was checked by the access guard
+	case db.ErrForeignKeyConstraint.Code:
+		httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("user not found"))
+
+	default:
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// space events listed in a page when no limit is given
+const defaultActivityPageSize = 50
+
+type listSpaceActivityRequest struct {
+	// next_cursor of the previous page
+	Cursor string `form:"cursor"`
+	Limit  int32  `form:"limit"  binding:"omitempty,min=1,max=100"`
+}
+
+// spaceEventResponse is an event of the activity of a space, the subject is
+// null for events about no member in particular
+type spaceEventResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	ActorID   uuid.UUID       `json:"actor_id"`
+	SubjectID *uuid.UUID      `json:"subject_id"`
+	Details   json.RawMessage `json:"details"`
+	RequestID string          `json:"request_id"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type listSpaceActivityResponse struct {
+	Events []spaceEventResponse `json:"events"`
+	// empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// listSpaceActivity responds with the events of the space, newest first
+func (server *Server) listSpaceActivity(ctx *gin.Context) {
+	var req listSpaceActivityRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	spaceID, err := uuid.Parse(ctx.Param("spaceID"))
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	arg := db.ListSpaceEventsParams{
+		SpaceID:  spaceID,
+		PageSize: req.Limit,
+	}
+	if arg.PageSize == 0 {
+		arg.PageSize = defaultActivityPageSize
+	}
+
+	if req.Cursor != "" {
+		at, id, err := decodeCursor(req.Cursor)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		arg.CursorAt = pgtype.Timestamp{Time: at, Valid: true}
+		arg.CursorID = id
+	}
+
+	events, err := server.store.ListSpaceEvents(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := listSpaceActivityResponse{Events: make([]spaceEventResponse, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, newSpaceEventResponse(event))
+	}
+	if len(events) == int(arg.PageSize) {
+		last := events[len(events)-1]
+		res.NextCursor = encodeCursor(last.CreatedAt.Time, last.ID)
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, res)
+}
+
+func newSpaceEventResponse(event db.SpaceEvent) spaceEventResponse {
+	res := spaceEventResponse{
+		ID:        event.ID,
+		Type:      event.Type,
+		ActorID:   event.ActorID,
+		Details:   event.Details,
+		RequestID: event.RequestID,
+		CreatedAt: event.CreatedAt.Time,
+	}
+	if event.SubjectID != uuid.Nil {
+		res.SubjectID = &event.SubjectID
+	}
+
+	return res
+}
+
 func handleCreateSpaceError(ctx *gin.Context, err error) {
 	var pgErr *pgconn.PgError
 	// if not a pg error return generic error
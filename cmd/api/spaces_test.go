@@ -7,11 +7,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	mockdb "github.com/Luckny/space-it/db/mock"
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/httpx"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -95,7 +99,7 @@ func TestCreateSpaceAPI(t *testing.T) {
 			router := gin.Default()
 			// middleware to add user in context if test is authenticated
 			router.Use(func(ctx *gin.Context) {
-				ctx.Set("user", &user)
+				httpx.SetUserInContext(ctx, user)
 				ctx.Next()
 			})
 
@@ -130,3 +134,288 @@ func requireBodyMatchSpace(t *testing.T, body *bytes.Buffer, space db.Space) {
 	require.Equal(t, space.Name, gotSpace.Name)
 	require.Equal(t, space.Owner, gotSpace.Owner)
 }
+
+func TestAddMemberToSpaceAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	member, _ := mockdb.RandomUser(t)
+	space := mockdb.RandomSpace(t, user.ID)
+	permission := mockdb.CreatePermission(t, member.ID, space.ID, true, true, false)
+
+	body := gin.H{
+		"user_id":     member.ID,
+		"permissions": gin.H{"read": true, "write": true},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "member added",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AddSpaceMemberTx(gomock.Any(), gomock.Eq(db.AddSpaceMemberTxParams{
+						Permission: db.CreatePermissionParams{
+							UserID:          member.ID,
+							SpaceID:         space.ID,
+							ReadPermission:  true,
+							WritePermission: true,
+						},
+						ActorID: user.ID,
+					})).
+					Times(1).
+					Return(permission, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var got db.Permission
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, member.ID, got.UserID)
+				require.Equal(t, space.ID, got.SpaceID)
+			},
+		},
+
+		{
+			name: "already a member",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AddSpaceMemberTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Permission{}, db.ErrUniqueViolation)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+
+		{
+			name: "unknown user",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AddSpaceMemberTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Permission{}, db.ErrForeignKeyConstraint)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name: "bad request",
+			body: gin.H{"permissions": gin.H{"read": true}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AddSpaceMemberTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AddSpaceMemberTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Permission{}, db.ErrConnectionFailure)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/spaces/:spaceID/members", server.addMemberToSpace)
+
+			jsonBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/spaces/" + space.ID.String() + "/members"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jsonBody))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListSpaceActivityAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	member, _ := mockdb.RandomUser(t)
+	space := mockdb.RandomSpace(t, user.ID)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	events := []db.SpaceEvent{
+		{
+			ID:        uuid.New(),
+			SpaceID:   space.ID,
+			Type:      db.SpaceEventMemberAdded,
+			ActorID:   user.ID,
+			SubjectID: member.ID,
+			Details:   []byte(`{"read": true, "write": false, "delete": false}`),
+			RequestID: "request",
+			CreatedAt: pgtype.Timestamp{Time: now, Valid: true},
+		},
+		{
+			ID:        uuid.New(),
+			SpaceID:   space.ID,
+			Type:      db.SpaceEventCreated,
+			ActorID:   user.ID,
+			Details:   []byte(`{"name": "space"}`),
+			CreatedAt: pgtype.Timestamp{Time: now.Add(-time.Second), Valid: true},
+		},
+	}
+	cursor := encodeCursor(events[1].CreatedAt.Time, events[1].ID)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "first page",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpaceEvents(gomock.Any(), gomock.Eq(db.ListSpaceEventsParams{
+						SpaceID:  space.ID,
+						PageSize: defaultActivityPageSize,
+					})).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res listSpaceActivityResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Len(t, res.Events, 2)
+				require.Equal(t, db.SpaceEventMemberAdded, res.Events[0].Type)
+				require.Equal(t, member.ID, *res.Events[0].SubjectID)
+				require.JSONEq(t, string(events[0].Details), string(res.Events[0].Details))
+				require.Nil(t, res.Events[1].SubjectID)
+				require.Empty(t, res.NextCursor)
+			},
+		},
+
+		{
+			name:  "full page",
+			query: "?limit=2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpaceEvents(gomock.Any(), gomock.Eq(db.ListSpaceEventsParams{
+						SpaceID:  space.ID,
+						PageSize: 2,
+					})).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res listSpaceActivityResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, cursor, res.NextCursor)
+			},
+		},
+
+		{
+			name:  "next page",
+			query: "?limit=2&cursor=" + cursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpaceEvents(gomock.Any(), gomock.Eq(db.ListSpaceEventsParams{
+						SpaceID:  space.ID,
+						CursorAt: events[1].CreatedAt,
+						CursorID: events[1].ID,
+						PageSize: 2,
+					})).
+					Times(1).
+					Return([]db.SpaceEvent{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"events": [], "next_cursor": ""}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name:  "invalid cursor",
+			query: "?cursor=not-a-cursor",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListSpaceEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "invalid limit",
+			query: "?limit=1000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListSpaceEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:  "internal error",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpaceEvents(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, db.ErrConnectionFailure)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.GET("/spaces/:spaceID/activity", server.listSpaceActivity)
+
+			url := "/spaces/" + space.ID.String() + "/activity" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/otp"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// how long a user has to provide the second factor after the password
+const secondFactorTokenDuration = 5 * time.Minute
+
+// failed second factor attempts of a user, within the failure window, before
+// the second factor is refused for the lockout. Without it, a 6 digit code
+// could be guessed within the lifetime of a few pending tokens.
+const (
+	maxSecondFactorFailures   = 5
+	secondFactorFailureWindow = 15 * time.Minute
+	secondFactorLockout       = 15 * time.Minute
+)
+
+const defaultTOTPIssuer = "space-it"
+
+var (
+	errInvalidSecondFactor = fmt.Errorf("invalid two-factor code")
+	errSecondFactorLocked  = fmt.Errorf("too many failed two-factor attempts, try again later")
+)
+
+type enrollTwoFactorResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// enrollTwoFactor generates a new TOTP secret, two-factor authentication is
+// only enabled once a code generated from it is confirmed
+func (server *Server) enrollTwoFactor(ctx *gin.Context) {
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	arg := db.UpsertTOTPParams{
+		UserID: user.ID,
+		Secret: secret,
+	}
+
+	// a confirmed secret is never replaced
+	_, err = server.store.UpsertTOTP(ctx, arg)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusConflict, fmt.Errorf("two-factor authentication already enabled"))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	issuer := server.Config.TOTPIssuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+
+	httpx.WriteResponse(ctx, http.StatusCreated, enrollTwoFactorResponse{
+		Secret: secret,
+		URI:    otp.URI(issuer, user.Email, secret),
+	})
+}
+
+type confirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTwoFactorResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTwoFactor enables two-factor authentication and returns the recovery codes
+func (server *Server) confirmTwoFactor(ctx *gin.Context) {
+	var req confirmTwoFactorRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	totp, err := server.store.GetTOTPByUserID(ctx, user.ID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("two-factor enrollment not started"))
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if totp.ConfirmedAt.Valid {
+		httpx.WriteError(ctx, http.StatusConflict, fmt.Errorf("two-factor authentication already enabled"))
+		return
+	}
+
+	step, ok := otp.Validate(totp.Secret, req.Code, time.Now())
+	if !ok {
+		httpx.WriteError(ctx, http.StatusUnprocessableEntity, errInvalidSecondFactor)
+		return
+	}
+
+	codes, err := otp.GenerateRecoveryCodes(otp.RecoveryCodeCount)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = otp.HashRecoveryCode(code)
+	}
+
+	arg := db.EnableTwoFactorTxParams{
+		UserID:             user.ID,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	}
+
+	_, err = server.store.EnableTwoFactorTx(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, confirmTwoFactorResponse{RecoveryCodes: codes})
+}
+
+type secondFactorRequest struct {
+	Code         string `json:"code"          binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// disableTwoFactor turns two-factor authentication off, a current code or
+// a recovery code is required
+func (server *Server) disableTwoFactor(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	if err := server.checkSecondFactor(ctx, user.ID, req); err != nil {
+		writeSecondFactorError(ctx, err)
+		return
+	}
+
+	if err := server.store.DisableTwoFactorTx(ctx, user.ID); err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusOK, nil)
+}
+
+type secondFactorRequiredResponse struct {
+	Token                string `json:"token"`
+	SecondFactorRequired bool   `json:"second_factor_required"`
+}
+
+// requestSecondFactor issues a short-lived token only accepted to verify the second factor
+func (server *Server) requestSecondFactor(ctx *gin.Context, user *dto.User) {
+	payload, err := token.NewPayload(*user, secondFactorTokenDuration)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	payload.Attributes[token.SecondFactorAttribute] = token.SecondFactorPending
+
+	tokenId, err := server.tokenMaker.CreateToken(ctx, payload)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusAccepted, secondFactorRequiredResponse{
+		Token:                tokenId,
+		SecondFactorRequired: true,
+	})
+}
+
+// verifySecondFactor completes a login started with the password and
+// replaces the partial token with a session token
+func (server *Server) verifySecondFactor(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := httpx.GetPendingUserFromContext(ctx)
+	if err != nil {
+		// user should be authenticated by the auth middlewares
+		panic(err)
+	}
+
+	if err := server.checkSecondFactor(ctx, user.ID, req); err != nil {
+		// the pending token is done with, the password has to be given again
+		if err == errSecondFactorLocked {
+			if err := server.tokenMaker.RevokeToken(ctx, ctx.GetHeader("X-CSRF-Token")); err != nil {
+				httpx.WriteError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		writeSecondFactorError(ctx, err)
+		return
+	}
+
+	server.createSession(ctx, user)
+}
+
+// checkSecondFactor verifies a TOTP code or consumes a recovery code.
+// Each code is accepted only once. Failures are counted against the user,
+// codes are refused without being checked while the user is locked out.
+func (server *Server) checkSecondFactor(ctx *gin.Context, userID uuid.UUID, req secondFactorRequest) error {
+	throttle, err := server.store.GetSecondFactorThrottle(ctx, userID)
+	throttled := err == nil
+	if err != nil && err != db.ErrRecordNotFound {
+		return err
+	}
+	if throttled && time.Now().UTC().Before(throttle.LockedUntil.Time) {
+		return errSecondFactorLocked
+	}
+
+	err = server.checkSecondFactorCode(ctx, userID, req)
+	if err == errInvalidSecondFactor {
+		return server.recordSecondFactorFailure(ctx, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if throttled {
+		return server.store.DeleteSecondFactorThrottle(ctx, userID)
+	}
+	return nil
+}
+
+// recordSecondFactorFailure counts a failed attempt of the user and locks
+// them out once they failed too many times. It returns the error to report.
+func (server *Server) recordSecondFactorFailure(ctx *gin.Context, userID uuid.UUID) error {
+	now := time.Now().UTC()
+	throttle, err := server.store.RecordSecondFactorFailure(ctx, db.RecordSecondFactorFailureParams{
+		UserID:      userID,
+		WindowStart: pgtype.Timestamp{Time: now.Add(-secondFactorFailureWindow), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	if throttle.Failures < maxSecondFactorFailures {
+		return errInvalidSecondFactor
+	}
+
+	err = server.store.LockSecondFactor(ctx, db.LockSecondFactorParams{
+		UserID:      userID,
+		LockedUntil: pgtype.Timestamp{Time: now.Add(secondFactorLockout), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return errSecondFactorLocked
+}
+
+// checkSecondFactorCode verifies the code of the request
+func (server *Server) checkSecondFactorCode(ctx *gin.Context, userID uuid.UUID, req secondFactorRequest) error {
+	if req.Code == "" {
+		used, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: otp.HashRecoveryCode(req.RecoveryCode),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errInvalidSecondFactor
+		}
+		return nil
+	}
+
+	totp, err := server.store.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			return errInvalidSecondFactor
+		}
+		return err
+	}
+
+	if !totp.ConfirmedAt.Valid {
+		return errInvalidSecondFactor
+	}
+
+	step, ok := otp.Validate(totp.Secret, req.Code, time.Now())
+	if !ok {
+		return errInvalidSecondFactor
+	}
+
+	// refuses a code that was already used, even within its validity window
+	used, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errInvalidSecondFactor
+	}
+
+	return nil
+}
+
+func writeSecondFactorError(ctx *gin.Context, err error) {
+	switch err {
+	case errInvalidSecondFactor:
+		httpx.WriteError(ctx, http.StatusUnauthorized, err)
+		return
+	case errSecondFactorLocked:
+		ctx.Header("Retry-After", strconv.Itoa(int(secondFactorLockout.Seconds())))
+		httpx.WriteError(ctx, http.StatusTooManyRequests, err)
+		return
+	}
+	httpx.WriteError(ctx, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,641 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/otp"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEnrollTwoFactorAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should enroll",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpsertTOTPParams) (db.UserTotp, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.NotEmpty(t, arg.Secret)
+						return db.UserTotp{UserID: arg.UserID, Secret: arg.Secret}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var res enrollTwoFactorResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.NotEmpty(t, res.Secret)
+				require.Contains(t, res.URI, "otpauth://totp/")
+				require.Contains(t, res.URI, res.Secret)
+			},
+		},
+
+		{
+			name: "already enabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/users/me/2fa", server.enrollTwoFactor)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/2fa", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmTwoFactorAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	secret, err := otp.GenerateSecret()
+	require.NoError(t, err)
+
+	step := otp.Step(time.Now())
+	pending := db.UserTotp{UserID: user.ID, Secret: secret}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should confirm",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(pending, nil)
+
+				store.EXPECT().
+					EnableTwoFactorTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.EnableTwoFactorTxParams) (db.UserTotp, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Len(t, arg.RecoveryCodeHashes, otp.RecoveryCodeCount)
+						return pending, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res confirmTwoFactorResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Len(t, res.RecoveryCodes, otp.RecoveryCodeCount)
+			},
+		},
+
+		{
+			name: "invalid code",
+			body: gin.H{"code": totpCode(t, secret, step+10)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(pending, nil)
+
+				store.EXPECT().
+					EnableTwoFactorTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+
+		{
+			name: "not enrolled",
+			body: gin.H{"code": "123456"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+
+		{
+			name: "already confirmed",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				confirmed := pending
+				confirmed.ConfirmedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(confirmed, nil)
+
+				store.EXPECT().
+					EnableTwoFactorTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+
+		{
+			name: "malformed code",
+			body: gin.H{"code": "12ab"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.POST("/users/me/2fa/confirm", server.confirmTwoFactor)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/2fa/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestVerifySecondFactorAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	secret, err := otp.GenerateSecret()
+	require.NoError(t, err)
+
+	step := otp.Step(time.Now())
+	enabled := db.UserTotp{
+		UserID:      user.ID,
+		Secret:      secret,
+		ConfirmedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+	recoveryCode := "abcde-fghij"
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "valid code",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(enabled, nil)
+
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(db.UseTOTPStepParams{UserID: user.ID, LastUsedStep: step})).
+					Times(1).
+					Return(int64(1), nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), mockdb.EqCreateSessionParams(user.ID)).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"token"`)
+			},
+		},
+
+		{
+			name: "replayed code",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+				expectSecondFactorFailure(store, user.ID, 1)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(enabled, nil)
+
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name: "invalid code",
+			body: gin.H{"code": totpCode(t, secret, step+10)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+				expectSecondFactorFailure(store, user.ID, 1)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(enabled, nil)
+
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name: "valid recovery code",
+			body: gin.H{"recovery_code": recoveryCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+
+				arg := db.UseRecoveryCodeParams{
+					UserID:   user.ID,
+					CodeHash: otp.HashRecoveryCode(recoveryCode),
+				}
+
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), mockdb.EqCreateSessionParams(user.ID)).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "used recovery code",
+			body: gin.H{"recovery_code": recoveryCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+				expectSecondFactorFailure(store, user.ID, 1)
+
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name: "valid code clears failures",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSecondFactorThrottle(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.SecondFactorThrottle{UserID: user.ID, Failures: 3}, nil)
+
+				store.EXPECT().GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(enabled, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().DeleteSecondFactorThrottle(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "too many failures lock out",
+			body: gin.H{"code": totpCode(t, secret, step+10)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+				expectSecondFactorFailure(store, user.ID, maxSecondFactorFailures)
+
+				store.EXPECT().GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(enabled, nil)
+				store.EXPECT().
+					LockSecondFactor(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LockSecondFactorParams) error {
+						require.Equal(t, user.ID, arg.UserID)
+						require.WithinDuration(t, time.Now().Add(secondFactorLockout), arg.LockedUntil.Time, time.Minute)
+						return nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+
+		{
+			name: "locked out",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSecondFactorThrottle(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.SecondFactorThrottle{
+						UserID:      user.ID,
+						LockedUntil: pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true},
+					}, nil)
+
+				// the code is not even checked
+				store.EXPECT().GetTOTPByUserID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordSecondFactorFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+
+		{
+			name: "missing code",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"code": totpCode(t, secret, step)},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+				ctx.Set("token", &token.Payload{
+					User:       dto.NewUser(user),
+					Attributes: map[string]string{token.SecondFactorAttribute: token.SecondFactorPending},
+				})
+				ctx.Next()
+			})
+			router.POST("/users/login/2fa", server.verifySecondFactor)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/2fa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDisableTwoFactorAPI(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	recoveryCode := "abcde-fghij"
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "should disable",
+			body: gin.H{"recovery_code": recoveryCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+
+				store.EXPECT().
+					DisableTwoFactorTx(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "invalid recovery code",
+			body: gin.H{"recovery_code": recoveryCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+				expectSecondFactorFailure(store, user.ID, 1)
+
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+
+				store.EXPECT().
+					DisableTwoFactorTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name: "internal error",
+			body: gin.H{"recovery_code": recoveryCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoSecondFactorThrottle(store, user.ID)
+
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+
+				store.EXPECT().
+					DisableTwoFactorTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewServer(store, testConfig())
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Next()
+			})
+			router.DELETE("/users/me/2fa", server.disableTwoFactor)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodDelete, "/users/me/2fa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// totpCode generates the code of the given time step
+func totpCode(t *testing.T, secret string, step int64) string {
+	code, err := otp.Code(secret, step)
+	require.NoError(t, err)
+	return code
+}
+
+// expectNoSecondFactorThrottle expects the user to have no failed second
+// factor attempts
+func expectNoSecondFactorThrottle(store *mockdb.MockStore, userID uuid.UUID) {
+	store.EXPECT().
+		GetSecondFactorThrottle(gomock.Any(), gomock.Eq(userID)).
+		Times(1).
+		Return(db.SecondFactorThrottle{}, db.ErrRecordNotFound)
+}
+
+// expectSecondFactorFailure expects a failed second factor attempt of the
+// user to be recorded, as the failures-th one
+func expectSecondFactorFailure(store *mockdb.MockStore, userID uuid.UUID, failures int32) {
+	store.EXPECT().
+		RecordSecondFactorFailure(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordSecondFactorFailureParams) (db.SecondFactorThrottle, error) {
+			if arg.UserID != userID {
+				return db.SecondFactorThrottle{}, fmt.Errorf("unexpected user %s", arg.UserID)
+			}
+			return db.SecondFactorThrottle{UserID: userID, Failures: failures}, nil
+		})
+}
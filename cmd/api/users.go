@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
 	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/mailer"
 	"github.com/Luckny/space-it/pkg/token"
 	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
@@ -14,8 +18,8 @@ import (
 )
 
 type registerUserRequest struct {
-	Email    string `json:"email"    binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Email    string `json:"email"    binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required"`
 }
 
 func (server *Server) registerUser(ctx *gin.Context) {
@@ -25,6 +29,10 @@ func (server *Server) registerUser(ctx *gin.Context) {
 		return
 	}
 
+	if !server.checkPasswordPolicy(ctx, "password", req.Password, req.Email) {
+		return
+	}
+
 	// hash the password
 	passwordHash, err := util.HashPassword(req.Password)
 	if err != nil {
@@ -39,21 +47,73 @@ func (server *Server) registerUser(ctx *gin.Context) {
 
 	user, err := server.store.RegisterUser(ctx, arg)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if server.Config.RegistrationConcealsAccounts &&
+			errors.As(err, &pgErr) && pgErr.Code == db.UniqueViolation {
+			server.sendAccountExistsEmail(ctx, req.Email)
+			httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+			return
+		}
 		handleRegisterUserError(ctx, err)
 		return
 	}
 
-	httpx.WriteResponse(ctx, http.StatusCreated, user)
+	// the account is created even if the email can't be sent, the user can
+	// ask for another one
+	if err := server.sendVerificationEmail(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "cannot send verification email", "error", err)
+	}
+
+	// the response must not differ from the one for a taken email
+	if server.Config.RegistrationConcealsAccounts {
+		httpx.WriteResponse(ctx, http.StatusAccepted, nil)
+		return
+	}
+
+	httpx.WriteResponse(ctx, http.StatusCreated, dto.NewUser(user))
+}
+
+// sendAccountExistsEmail tells the owner of an email that someone tried to
+// register with it, instead of telling whoever registered. A failure is only
+// logged.
+func (server *Server) sendAccountExistsEmail(ctx context.Context, email string) {
+	body := "Someone tried to create a space-it account with your email, " +
+		"but you already have one.\r\n" +
+		"If it was you, log in or reset your password"
+	if server.Config.PasswordResetURL != "" {
+		body += ":\r\n\r\n" + server.Config.PasswordResetURL
+	}
+	body += "\r\n\r\nIf it wasn't you, you can ignore this email."
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Your space-it account",
+		Body:    body,
+	}
+
+	if err := server.mailer.Send(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "cannot send account exists email", "error", err)
+	}
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	user, err := httpx.GetUserFromContext(ctx)
 	if err != nil {
+		// the password is verified but two-factor authentication is enabled
+		if pendingUser, err := httpx.GetPendingUserFromContext(ctx); err == nil {
+			server.requestSecondFactor(ctx, pendingUser)
+			return
+		}
+
 		// user should be authenticated by the auth middlewares
-		util.ErrorLog.Panic(err)
-		return
+		panic(err)
 	}
 
+	server.createSession(ctx, user)
+}
+
+// createSession issues a session token for a fully authenticated user
+func (server *Server) createSession(ctx *gin.Context, user *dto.User) {
 	payload, err := token.NewPayload(*user, server.Config.CookieAge)
 	if err != nil {
 		httpx.WriteError(ctx, http.StatusInternalServerError, err)
@@ -2,15 +2,20 @@ package api
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	mockdb "github.com/Luckny/space-it/db/mock"
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/mailer"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -23,7 +28,7 @@ func TestRegisterUserAPI(t *testing.T) {
 		name          string
 		body          registerUserRequest
 		buildStubs    func(store *mockdb.MockStore)
-		checkResponse func(recorder *httptest.ResponseRecorder)
+		checkResponse func(recorder *httptest.ResponseRecorder, sent []mailer.Message)
 	}{
 		{
 			name: "should register user",
@@ -42,11 +47,47 @@ func TestRegisterUserAPI(t *testing.T) {
 					RegisterUser(gomock.Any(), mockdb.EqRegisterUserParams(arg)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EmailVerificationToken{}, nil)
 			},
 
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
 				requireBodyMatchUser(t, recorder.Body, user)
+
+				// a verification link is sent
+				require.Len(t, sent, 1)
+				require.Equal(t, user.Email, sent[0].To)
+			},
+		},
+
+		{
+			name: "verification email fails -> still registered",
+			body: registerUserRequest{
+				Email:    user.Email,
+				Password: unHashedPassword,
+			},
+
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RegisterUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Empty(t, sent)
 			},
 		},
 
@@ -54,7 +95,7 @@ func TestRegisterUserAPI(t *testing.T) {
 			name: "no email -> bad request",
 			body: registerUserRequest{
 				Email:    "", // no email
-				Password: user.Password,
+				Password: unHashedPassword,
 			},
 
 			buildStubs: func(store *mockdb.MockStore) {
@@ -64,7 +105,7 @@ func TestRegisterUserAPI(t *testing.T) {
 					Return(user, nil)
 			},
 
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 				data, err := io.ReadAll(recorder.Body)
 				require.NoError(t, err)
@@ -80,7 +121,7 @@ func TestRegisterUserAPI(t *testing.T) {
 			name: "bad email -> bad request",
 			body: registerUserRequest{
 				Email:    "thisisabadmail", // bad email
-				Password: user.Password,
+				Password: unHashedPassword,
 			},
 
 			buildStubs: func(store *mockdb.MockStore) {
@@ -90,8 +131,49 @@ func TestRegisterUserAPI(t *testing.T) {
 					Return(user, nil)
 			},
 
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name: "weak password -> field errors",
+			body: registerUserRequest{
+				Email:    user.Email,
+				Password: user.Email,
+			},
+
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RegisterUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var res struct {
+					Error  string              `json:"error"`
+					Fields map[string][]string `json:"fields"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, "invalid request", res.Error)
+				require.Equal(t, []string{"is too easy to guess, it contains your email"}, res.Fields["password"])
+			},
+		},
+
+		{
+			name: "password too long -> field errors",
+			body: registerUserRequest{
+				Email:    user.Email,
+				Password: strings.Repeat(unHashedPassword, 8),
+			},
+
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RegisterUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"password":["must be at most 72 bytes"]`)
 			},
 		},
 
@@ -99,7 +181,7 @@ func TestRegisterUserAPI(t *testing.T) {
 			name: "internal server error",
 			body: registerUserRequest{
 				Email:    user.Email,
-				Password: user.Password,
+				Password: unHashedPassword,
 			},
 
 			buildStubs: func(store *mockdb.MockStore) {
@@ -109,7 +191,7 @@ func TestRegisterUserAPI(t *testing.T) {
 					Return(db.User{}, db.ErrUniqueViolation)
 			},
 
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
+			checkResponse: func(recorder *httptest.ResponseRecorder, sent []mailer.Message) {
 				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
@@ -125,6 +207,8 @@ func TestRegisterUserAPI(t *testing.T) {
 
 			// api server with mock store
 			server := NewServer(store, config.Config{})
+			mail := mailer.NewMemoryMailer()
+			server.mailer = mail
 			router := gin.Default()
 			router.POST("/users", server.registerUser)
 
@@ -140,31 +224,147 @@ func TestRegisterUserAPI(t *testing.T) {
 			recorder := httptest.NewRecorder()
 			router.ServeHTTP(recorder, request)
 			// check response
-			tc.checkResponse(recorder)
+			tc.checkResponse(recorder, mail.Sent())
 		})
 	}
 
 }
 
+func TestRegisterUserConcealsAccountsAPI(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkSent  func(sent []mailer.Message)
+	}{
+		{
+			name: "new email -> verification email",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RegisterUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					CountEmailVerificationTokensSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EmailVerificationToken{}, nil)
+			},
+			checkSent: func(sent []mailer.Message) {
+				require.Len(t, sent, 1)
+				require.Equal(t, "Verify your space-it email", sent[0].Subject)
+			},
+		},
+
+		{
+			name: "taken email -> owner notified",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RegisterUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrUniqueViolation)
+				store.EXPECT().CreateEmailVerificationToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkSent: func(sent []mailer.Message) {
+				require.Len(t, sent, 1)
+				require.Equal(t, user.Email, sent[0].To)
+				require.Contains(t, sent[0].Body, "you already have one")
+				require.Contains(t, sent[0].Body, "https://app.example.com/reset")
+			},
+		},
+	}
+
+	// every response is compared with the first one
+	var first *httptest.ResponseRecorder
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := testConfig()
+			config.RegistrationConcealsAccounts = true
+			config.PasswordResetURL = "https://app.example.com/reset"
+			server := NewServer(store, config)
+			mail := mailer.NewMemoryMailer()
+			server.mailer = mail
+
+			router := gin.Default()
+			router.POST("/users", server.registerUser)
+
+			jsonBody, err := json.Marshal(registerUserRequest{Email: user.Email, Password: password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(jsonBody))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusAccepted, recorder.Code)
+			require.NotContains(t, recorder.Body.String(), user.ID.String())
+			if first == nil {
+				first = recorder
+			} else {
+				require.Equal(t, first.Body.String(), recorder.Body.String())
+				require.Equal(t, first.Header(), recorder.Header())
+			}
+
+			tc.checkSent(mail.Sent())
+		})
+	}
+}
+
 func TestLoginUserAPI(t *testing.T) {
 	user, _ := mockdb.RandomUser(t)
 
 	testCases := []struct {
 		name          string
 		setContext    bool
+		pending       bool
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name:       "should login user",
 			setContext: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateSession(gomock.Any(), mockdb.EqCreateSessionParams(user.ID)).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
 
+		{
+			name:       "two-factor enabled -> second factor required",
+			setContext: true,
+			pending:    true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateSession(gomock.Any(), mockdb.EqCreateSessionParams(user.ID)).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"second_factor_required":true`)
+			},
+		},
+
 		{
 			name:       "error -> user not in context",
 			setContext: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 		},
 	}
 
@@ -174,15 +374,19 @@ func TestLoginUserAPI(t *testing.T) {
 			// init gomock
 			ctrl := gomock.NewController(t)
 			store := mockdb.NewMockStore(ctrl)
-			// tc.buildStubs(store)
+			tc.buildStubs(store)
 
 			// api server with mock store
-			server := NewServer(store, config.Config{})
+			server := NewServer(store, testConfig())
 			router := gin.Default()
 
 			if tc.setContext {
 				router.Use(func(c *gin.Context) {
-					c.Set("user", &user)
+					if tc.pending {
+						httpx.SetPendingUserInContext(c, dto.NewUser(user))
+						return
+					}
+					httpx.SetUserInContext(c, user)
 				})
 			}
 
@@ -214,11 +418,37 @@ func TestLoginUserAPI(t *testing.T) {
 func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
+	requireNoPasswordField(t, data)
 
-	var gotUser db.User
+	var gotUser dto.User
 	err = json.Unmarshal(data, &gotUser)
-	gotUser.ID = user.ID
 	require.NoError(t, err)
+	require.Equal(t, user.ID, gotUser.ID)
 	require.Equal(t, user.Email, gotUser.Email)
-	require.Equal(t, user.Password, gotUser.Password)
+}
+
+// requireNoPasswordField fails if any object in a json body has a password key
+func requireNoPasswordField(t *testing.T, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	var body interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, field := range v {
+				require.NotContains(t, strings.ToLower(key), "password", "response leaks %q", key)
+				walk(field)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(body)
 }
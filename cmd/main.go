@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"flag"
+	"log/slog"
+	"os"
 
 	"github.com/Luckny/space-it/cmd/api"
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/config"
-	"github.com/Luckny/space-it/util"
+	"github.com/Luckny/space-it/pkg/logx"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "go.uber.org/mock/gomock"
 )
@@ -18,13 +20,27 @@ func main() {
 	addr := flag.String("addr", config.ServerAddr, "server address")
 	flag.Parse()
 
-	connPool, err := pgxpool.New(context.Background(), config.DBSource)
+	level, err := logx.ParseLevel(config.LogLevel)
 	if err != nil {
-		util.ErrorLog.Fatal("error creating database connection", err)
+		fatal("invalid LOG_LEVEL", err)
+	}
+	logger := logx.New(os.Stdout, level)
+	slog.SetDefault(logger)
+
+	poolConfig, err := pgxpool.ParseConfig(config.DBSource)
+	if err != nil {
+		fatal("invalid database connection string", err)
+	}
+	// queries are logged with the request they are run for
+	poolConfig.ConnConfig.Tracer = logx.QueryTracer(logger)
+
+	connPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		fatal("error creating database connection", err)
 	}
 
 	if err = connPool.Ping(context.Background()); err != nil {
-		util.ErrorLog.Fatal("cannot connec to database", err)
+		fatal("cannot connect to database", err)
 	}
 
 	store := db.NewStore(connPool)
@@ -32,6 +48,11 @@ func main() {
 
 	err = server.Run(*addr)
 	if err != nil {
-		util.ErrorLog.Fatal("cannot start the server", err)
+		fatal("cannot start the server", err)
 	}
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -6,7 +6,6 @@ import (
 
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/httpx"
-	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -21,12 +20,21 @@ const (
 	AdminAccess            = "admin"
 )
 
-func RequireAccessLvl(accessLvl AccessLvl, store db.Store) gin.HandlerFunc {
+// RequireAccessLvl requires the user to have accessLvl on the space. Requests
+// authenticated with an API key or an OAuth2 access token also need scope,
+// and access tokens may be restricted to some spaces, so they can never do
+// more than their user.
+func RequireAccessLvl(accessLvl AccessLvl, scope Scope, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		user, err := httpx.GetUserFromContext(ctx)
 		if err != nil {
 			// user should be authenticated by the auth middlewares
-			util.ErrorLog.Panic(err)
+			panic(err)
+		}
+
+		if !hasScope(ctx, scope) {
+			httpx.WriteError(ctx, http.StatusForbidden, fmt.Errorf("denied: %s scope required", scope))
+			ctx.Abort()
 			return
 		}
 
@@ -37,6 +45,12 @@ func RequireAccessLvl(accessLvl AccessLvl, store db.Store) gin.HandlerFunc {
 			return
 		}
 
+		if !hasSpace(ctx, spaceID) {
+			httpx.WriteError(ctx, http.StatusForbidden, fmt.Errorf("denied: token not valid for this space"))
+			ctx.Abort()
+			return
+		}
+
 		arg := db.GetPermissionsByUserAndSpaceIDParams{
 			UserID:  user.ID,
 			SpaceID: spaceID,
@@ -9,7 +9,11 @@ import (
 
 	mockdb "github.com/Luckny/space-it/db/mock"
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/token"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -236,7 +240,7 @@ func TestRequireAccessLvl(t *testing.T) {
 			// api server with mock store
 			router := gin.Default()
 			router.Use(func(c *gin.Context) {
-				c.Set("user", &user)
+				httpx.SetUserInContext(c, user)
 				c.Next()
 			})
 
@@ -244,7 +248,7 @@ func TestRequireAccessLvl(t *testing.T) {
 
 			router.GET(
 				"/spaces/:spaceID/something",
-				RequireAccessLvl(ViewAccess, store),
+				RequireAccessLvl(ViewAccess, ScopeSpacesRead, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, nil)
 				},
@@ -252,7 +256,7 @@ func TestRequireAccessLvl(t *testing.T) {
 
 			router.POST(
 				"/spaces/:spaceID/something",
-				RequireAccessLvl(WriteAccess, store),
+				RequireAccessLvl(WriteAccess, ScopeSpacesWrite, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, nil)
 				},
@@ -260,7 +264,7 @@ func TestRequireAccessLvl(t *testing.T) {
 
 			router.DELETE(
 				"/spaces/:spaceID/something",
-				RequireAccessLvl(DeleteAccess, store),
+				RequireAccessLvl(DeleteAccess, ScopeSpacesDelete, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, nil)
 				},
@@ -268,7 +272,7 @@ func TestRequireAccessLvl(t *testing.T) {
 
 			router.PUT(
 				"/spaces/:spaceID/something",
-				RequireAccessLvl(AdminAccess, store),
+				RequireAccessLvl(AdminAccess, ScopeSpacesAdmin, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, nil)
 				},
@@ -287,3 +291,185 @@ func TestRequireAccessLvl(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAccessLvlWithAPIKey(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	space := mockdb.RandomSpace(t, user.ID)
+
+	allPerms := mockdb.CreatePermission(t, user.ID, space.ID, true, true, true)
+	readPerms := mockdb.CreatePermission(t, user.ID, space.ID, true, false, false)
+
+	testCases := []struct {
+		name          string
+		scopes        []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "scope and permission",
+			scopes: []string{string(ScopeSpacesRead), string(ScopeMessagesWrite)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(allPerms, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "permission without scope",
+			scopes: []string{string(ScopeSpacesRead)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+
+		{
+			name:   "scope without permission",
+			scopes: []string{string(ScopeMessagesWrite)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(readPerms, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			router := gin.Default()
+			router.Use(func(c *gin.Context) {
+				httpx.SetUserInContext(c, user)
+				httpx.SetAPIKeyInContext(c, db.ApiKey{UserID: user.ID, Scopes: tc.scopes})
+				c.Next()
+			})
+
+			router.POST(
+				"/spaces/:spaceID/messages",
+				RequireAccessLvl(WriteAccess, ScopeMessagesWrite, store),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, nil)
+				},
+			)
+
+			url := fmt.Sprintf("/spaces/%s/messages", space.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRequireAccessLvlWithOAuthToken(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	space := mockdb.RandomSpace(t, user.ID)
+	other := mockdb.RandomSpace(t, user.ID)
+
+	allPerms := mockdb.CreatePermission(t, user.ID, space.ID, true, true, true)
+
+	testCases := []struct {
+		name          string
+		spaces        []uuid.UUID
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "not restricted",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(allPerms, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "restricted to the space",
+			spaces: []uuid.UUID{other.ID, space.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(allPerms, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "restricted to another space",
+			spaces: []uuid.UUID{other.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPermissionsByUserAndSpaceID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			payload, err := token.NewOAuthPayload(
+				dto.NewUser(user),
+				uuid.New(),
+				uuid.New(),
+				[]string{string(ScopeMessagesWrite)},
+				tc.spaces,
+			)
+			require.NoError(t, err)
+
+			router := gin.Default()
+			router.Use(func(c *gin.Context) {
+				httpx.SetUserInContext(c, user)
+				httpx.SetOAuthTokenInContext(c, payload)
+				c.Next()
+			})
+
+			router.POST(
+				"/spaces/:spaceID/messages",
+				RequireAccessLvl(WriteAccess, ScopeMessagesWrite, store),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, nil)
+				},
+			)
+
+			url := fmt.Sprintf("/spaces/%s/messages", space.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin requires the user to be a system administrator. The role is
+// read from the database, the user in the context may come from a session
+// created before it was revoked.
+func RequireAdmin(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := httpx.GetUserFromContext(ctx)
+		if err != nil {
+			// user should be authenticated by the auth middlewares
+			panic(err)
+		}
+
+		dbUser, err := store.GetUserByID(ctx, user.ID)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		if !dbUser.IsAdmin {
+			httpx.WriteError(ctx, http.StatusForbidden, fmt.Errorf("denied: admin role required"))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+)
+
+// last used timestamps are only written when older than this
+const apiKeyTouchInterval = time.Minute
+
+// AuthenticateAPIKey authenticates requests with a personal API key sent as a
+// bearer token. The request is then made on behalf of the key's user, within
+// the key's scopes.
+func AuthenticateAPIKey(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authHeader := ctx.GetHeader("Authorization")
+		key, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok || !apikey.IsAPIKey(key) {
+			ctx.Next()
+			return
+		}
+
+		apiKey, err := store.GetActiveAPIKeyByHash(ctx, apikey.Hash(key))
+		if err != nil {
+			// unknown, revoked or expired key
+			if err == db.ErrRecordNotFound {
+				ctx.Next()
+				return
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		user, err := store.GetUserByID(ctx, apiKey.UserID)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		if !apiKey.LastUsedAt.Valid || time.Since(apiKey.LastUsedAt.Time) > apiKeyTouchInterval {
+			if err := store.TouchAPIKey(ctx, apiKey.ID); err != nil {
+				// not worth failing the request
+				slog.ErrorContext(ctx, "api key: cannot touch", "api_key_id", apiKey.ID, "error", err)
+			}
+		}
+
+		httpx.SetUserInContext(ctx, user)
+		httpx.SetAPIKeyInContext(ctx, apiKey)
+		httpx.SetAuthMethodInContext(ctx, httpx.AuthMethodAPIKey)
+		ctx.Next()
+	}
+}
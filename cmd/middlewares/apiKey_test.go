@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:      uuid.New(),
+		UserID:  user.ID,
+		KeyHash: key.Hash,
+		Scopes:  []string{string(ScopeSpacesRead)},
+	}
+
+	testCases := []struct {
+		name          string
+		header        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "valid key -> authenticated",
+			header: "Bearer " + key.Key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveAPIKeyByHash(gomock.Any(), gomock.Eq(key.Hash)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res struct {
+					User   dto.User `json:"user"`
+					Scopes []string `json:"scopes"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, user.ID, res.User.ID)
+				require.Equal(t, apiKey.Scopes, res.Scopes)
+			},
+		},
+
+		{
+			name:   "recently used -> last used not written",
+			header: "Bearer " + key.Key,
+			buildStubs: func(store *mockdb.MockStore) {
+				recent := apiKey
+				recent.LastUsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					GetActiveAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(recent, nil)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "unknown, revoked or expired key -> not authenticated",
+			header: "Bearer " + key.Key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"user": null, "scopes": null}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name:   "other bearer token -> ignored",
+			header: "Bearer some-oauth-token",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "internal error",
+			header: "Bearer " + key.Key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			router := gin.Default()
+			router.Use(AuthenticateAPIKey(store))
+			router.GET("/auth", func(c *gin.Context) {
+				user, _ := httpx.GetUserFromContext(c)
+				res := gin.H{"user": user, "scopes": nil}
+				if key, ok := httpx.GetAPIKeyFromContext(c); ok {
+					res["scopes"] = key.Scopes
+				}
+				c.JSON(http.StatusOK, res)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+			request.Header.Set("Authorization", tc.header)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -1,59 +1,98 @@
 package middlewares
 
 import (
-	"net/http"
+	"io"
+	"strings"
+	"time"
 
-	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/audit"
 	"github.com/Luckny/space-it/pkg/httpx"
-	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// longest values request_log stores, longer ones are truncated so they don't
+// fail the whole batch they are written with
+const (
+	maxAuditMethodLength    = 10
+	maxAuditPathLength      = 100
+	maxAuditQueryLength     = 255
+	maxAuditUserAgentLength = 255
+	maxAuditClientIPLength  = 45
+	maxAuditRequestIDLength = 64
 )
 
 // AuditLogger is middleware that logs incoming HTTP requests and their corresponding responses.
-func AuditLogger(store db.Store) gin.HandlerFunc {
+// Entries are written by the pipeline in the background, requests don't wait
+// for them.
+//
+// The entry is correlated with the logs of the request by the ID the RequestID
+// middleware gave it. The id of the entry is used when it has none.
+func AuditLogger(pipeline *audit.Pipeline) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		reqLog, err := logRequest(ctx, store)
-		if err != nil {
-			httpx.WriteError(ctx, http.StatusInternalServerError, err)
-			ctx.Abort()
-			return
+		entry := audit.Entry{
+			ID:          uuid.New(),
+			Method:      truncate(ctx.Request.Method, maxAuditMethodLength),
+			Path:        truncate(ctx.Request.URL.Path, maxAuditPathLength),
+			Query:       truncate(audit.RedactQuery(ctx.Request.URL.RawQuery), maxAuditQueryLength),
+			AuthMethod:  httpx.GetAuthMethodFromContext(ctx),
+			ClientIP:    truncate(ctx.ClientIP(), maxAuditClientIPLength),
+			UserAgent:   truncate(ctx.Request.UserAgent(), maxAuditUserAgentLength),
+			RequestedAt: time.Now(),
 		}
 
-		// Wrap the response writer
-		w := httpx.NewResponseWriter(ctx.Writer)
-		ctx.Writer = w
+		entry.RequestID = truncate(httpx.GetRequestIDFromContext(ctx), maxAuditRequestIDLength)
+		if entry.RequestID == "" {
+			entry.RequestID = entry.ID.String()
+			httpx.SetRequestIDInContext(ctx, entry.RequestID)
+		}
 
-		ctx.Next()
+		// the request is logged as authenticated by the user set by the
+		// authentication middlewares
+		if user, err := httpx.GetUserFromContext(ctx); err == nil {
+			entry.UserID = user.ID
+		}
+
+		if spaceID, err := uuid.Parse(ctx.Param("spaceID")); err == nil {
+			entry.SpaceID = spaceID
+		}
 
-		// log the response
-		arg := db.CreateResponseLogParams{ID: reqLog.ID, Status: int32(w.Status())}
-		_, err = store.CreateResponseLog(ctx, arg)
-		if err != nil {
-			util.ErrorLog.Println("error creating response log", err)
-			ctx.Abort()
-			return
+		// bodies of unknown length are counted as they are read
+		body := &countingReader{ReadCloser: ctx.Request.Body}
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = body
 		}
 
+		ctx.Next()
+
+		entry.RequestSize = max(ctx.Request.ContentLength, body.n)
+		entry.Status = ctx.Writer.Status()
+		entry.ResponseSize = int64(max(ctx.Writer.Size(), 0))
+		entry.RespondedAt = time.Now()
+		pipeline.Record(entry)
 	}
 }
 
-// logRequest logs an incoming HTTP request to the database
-func logRequest(ctx *gin.Context, store db.Store) (db.RequestLog, error) {
-	u, _ := ctx.Get("user")
-	if u != nil {
-		// Handle authenticated request logging
-		arg := db.CreateAuthenticatedRequestLogParams{
-			Path:   ctx.Request.URL.Path,
-			Method: ctx.Request.Method,
-			UserID: u.(*db.User).ID,
-		}
-		return store.CreateAuthenticatedRequestLog(ctx, arg)
-	} else {
-		// Handle unauthenticated request logging
-		arg := db.CreateUnauthenticatedRequestLogParams{
-			Path:   ctx.Request.URL.Path,
-			Method: ctx.Request.Method,
-		}
-		return store.CreateUnauthenticatedRequestLog(ctx, arg)
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// truncate returns the first n bytes of s at most, without the bytes of a cut
+// character. Invalid UTF-8 and NUL bytes are dropped whatever the length,
+// Postgres refuses them and they would fail the whole batch.
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+	if len(s) <= n {
+		return s
 	}
+
+	return strings.ToValidUTF8(s[:n], "")
 }
@@ -1,70 +1,63 @@
 package middlewares
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
-	"time"
 
 	mockdb "github.com/Luckny/space-it/db/mock"
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/audit"
+	"github.com/Luckny/space-it/pkg/httpx"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
 func TestAuditLogger(t *testing.T) {
-	reqLog := db.RequestLog{
-		ID:        uuid.New(),
-		Method:    "GET",
-		Path:      "/somepath",
-		CreatedAt: pgtype.Timestamp{Time: time.Now()},
-	}
-
-	resLog := db.ResponseLog{
-		ID:        reqLog.ID,
-		Status:    http.StatusOK,
-		CreatedAt: pgtype.Timestamp{Time: time.Now()},
-	}
-
 	user, unHashedPassword := mockdb.RandomUser(t)
+	spaceID := uuid.New()
 
 	testCases := []struct {
-		name          string
-		path          string
-		setHeader     bool
-		username      string
-		password      string
-		buildStubs    func(store *mockdb.MockStore)
+		name string
+		// the route, requested unless url is set
+		path string
+		url  string
+		// the User-Agent header, audit-test unless set
+		userAgent  string
+		setHeader  bool
+		username   string
+		password   string
+		buildStubs func(store *mockdb.MockStore)
+		// the entries written, nil when writing them fails
+		checkLogs     func(arg *db.CreateAuditLogsTxParams)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "unauthenticated request -> ok",
-			path: reqLog.Path,
+			path: "/somepath",
 			buildStubs: func(store *mockdb.MockStore) {
-
-				reqLogArg := db.CreateUnauthenticatedRequestLogParams{
-					Path:   reqLog.Path,
-					Method: reqLog.Method,
-				}
-
-				resLogArg := db.CreateResponseLogParams{
-					ID:     resLog.ID,
-					Status: resLog.Status,
-				}
-				store.EXPECT().
-					CreateUnauthenticatedRequestLog(gomock.Any(), mockdb.EqUnauthenticatedLogParam(reqLogArg)).
-					Times(1).
-					Return(reqLog, nil)
-				store.EXPECT().
-					CreateResponseLog(gomock.Any(), mockdb.EqResponseLogParam(resLogArg)).
-					Times(1).
-					Return(resLog, nil)
 			},
-
+			checkLogs: func(arg *db.CreateAuditLogsTxParams) {
+				require.Equal(t, []string{http.MethodGet}, arg.Requests.Methods)
+				require.Equal(t, []string{"/somepath"}, arg.Requests.Paths)
+				require.Equal(t, []uuid.UUID{uuid.Nil}, arg.Requests.UserIds)
+				require.Equal(t, arg.Requests.Ids, arg.Responses.Ids)
+				require.Equal(t, []int32{http.StatusOK}, arg.Responses.Statuses)
+				require.False(t, arg.Responses.CreatedAt[0].Time.Before(arg.Requests.CreatedAt[0].Time))
+				require.Equal(t, []string{""}, arg.Requests.AuthMethods)
+				require.Equal(t, []uuid.UUID{uuid.Nil}, arg.Requests.SpaceIds)
+				require.Equal(t, []string{"192.0.2.1"}, arg.Requests.ClientIps)
+				require.Equal(t, []string{"audit-test"}, arg.Requests.UserAgents)
+				require.Equal(t, []string{arg.Requests.Ids[0].String()}, arg.Requests.RequestIds)
+				require.Equal(t, []int64{0}, arg.Requests.Sizes)
+				require.Equal(t, []int64{4}, arg.Responses.Sizes)
+				require.Equal(t, []string{audit.OutcomeSuccess}, arg.Responses.Outcomes)
+			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
 			},
@@ -72,84 +65,84 @@ func TestAuditLogger(t *testing.T) {
 
 		{
 			name:      "authenticated request -> ok",
-			path:      reqLog.Path,
+			path:      "/somepath",
 			setHeader: true,
 			username:  user.Email,
 			password:  unHashedPassword,
 			buildStubs: func(store *mockdb.MockStore) {
-
-				reqLogArg := db.CreateAuthenticatedRequestLogParams{
-					Path:   reqLog.Path,
-					Method: reqLog.Method,
-					UserID: user.ID,
-				}
-
-				resLogArg := db.CreateResponseLogParams{
-					ID:     resLog.ID,
-					Status: resLog.Status,
-				}
-
 				store.EXPECT().
 					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
 					Times(1).
 					Return(user, nil)
 
 				store.EXPECT().
-					CreateAuthenticatedRequestLog(gomock.Any(), mockdb.EqAuthenticatedLogParam(reqLogArg)).
-					Times(1).
-					Return(reqLog, nil)
-				store.EXPECT().
-					CreateResponseLog(gomock.Any(), mockdb.EqResponseLogParam(resLogArg)).
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
 					Times(1).
-					Return(resLog, nil)
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkLogs: func(arg *db.CreateAuditLogsTxParams) {
+				require.Equal(t, []uuid.UUID{user.ID}, arg.Requests.UserIds)
+				require.Equal(t, []string{httpx.AuthMethodPassword}, arg.Requests.AuthMethods)
+				require.Equal(t, []int32{http.StatusOK}, arg.Responses.Statuses)
 			},
-
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
 			},
 		},
 
 		{
-			name: "request log internal error",
-			path: reqLog.Path,
+			name: "long path is truncated",
+			path: "/" + strings.Repeat("é", 60),
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					CreateUnauthenticatedRequestLog(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return(db.RequestLog{}, sql.ErrConnDone)
-				store.EXPECT().
-					CreateResponseLog(gomock.Any(), gomock.Any()).
-					Times(0)
 			},
-
+			checkLogs: func(arg *db.CreateAuditLogsTxParams) {
+				// 49 characters of 2 bytes after the slash
+				require.Len(t, arg.Requests.Paths[0], 99)
+				require.Equal(t, "/"+strings.Repeat("é", 49), arg.Requests.Paths[0])
+			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, recorder.Code, http.StatusInternalServerError)
+				require.Equal(t, recorder.Code, http.StatusOK)
 			},
 		},
 
 		{
-			name: "response log internal error",
-			path: reqLog.Path,
+			name:      "invalid UTF-8 and NUL bytes are dropped",
+			path:      "/users/:id",
+			url:       "/users/%00",
+			userAgent: "agent\xff\x00",
 			buildStubs: func(store *mockdb.MockStore) {
-				reqLogArg := db.CreateUnauthenticatedRequestLogParams{
-					Path:   reqLog.Path,
-					Method: reqLog.Method,
-				}
+			},
+			checkLogs: func(arg *db.CreateAuditLogsTxParams) {
+				require.Equal(t, []string{"/users/"}, arg.Requests.Paths)
+				require.Equal(t, []string{"agent"}, arg.Requests.UserAgents)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+			},
+		},
 
-				resLogArg := db.CreateResponseLogParams{
-					ID:     resLog.ID,
-					Status: resLog.Status,
-				}
+		{
+			name: "space request with secret query",
+			path: "/spaces/:spaceID",
+			url:  "/spaces/" + spaceID.String() + "?code=abc&state=xyz",
+			buildStubs: func(store *mockdb.MockStore) {
+			},
+			checkLogs: func(arg *db.CreateAuditLogsTxParams) {
+				require.Equal(t, []string{"/spaces/" + spaceID.String()}, arg.Requests.Paths)
+				require.Equal(t, []string{"code=REDACTED&state=xyz"}, arg.Requests.Queries)
+				require.Equal(t, []uuid.UUID{spaceID}, arg.Requests.SpaceIds)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+			},
+		},
 
-				store.EXPECT().
-					CreateUnauthenticatedRequestLog(gomock.Any(), mockdb.EqUnauthenticatedLogParam(reqLogArg)).
-					Times(1).
-					Return(reqLog, nil)
-				store.EXPECT().
-					CreateResponseLog(gomock.Any(), mockdb.EqResponseLogParam(resLogArg)).
-					Times(1).
-					Return(db.ResponseLog{}, sql.ErrNoRows)
+		{
+			name: "audit log internal error",
+			path: "/somepath",
+			buildStubs: func(store *mockdb.MockStore) {
 			},
+			// the request doesn't wait for the entry to be written
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
 			},
@@ -163,18 +156,44 @@ func TestAuditLogger(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
+			var logs *db.CreateAuditLogsTxParams
+			store.EXPECT().
+				CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+					if tc.checkLogs == nil {
+						return sql.ErrConnDone
+					}
+					logs = &arg
+					return nil
+				})
+
+			pipeline := audit.New(store, audit.Options{})
+			go pipeline.Run()
+
 			router := gin.Default()
 
-			router.Use(Authenticate(store))
-			router.Use(AuditLogger(store))
+			router.Use(Authenticate(store, nil, nil))
+			router.Use(AuditLogger(pipeline))
 
 			router.GET(tc.path, func(ctx *gin.Context) {
 				ctx.JSON(http.StatusOK, nil)
 			})
 
+			url := tc.url
+			if url == "" {
+				url = tc.path
+			}
+
 			recorder := httptest.NewRecorder()
-			request, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+			userAgent := tc.userAgent
+			if userAgent == "" {
+				userAgent = "audit-test"
+			}
+			request.Header.Set("User-Agent", userAgent)
 
 			if tc.setHeader {
 				request.SetBasicAuth(tc.username, tc.password)
@@ -183,6 +202,12 @@ func TestAuditLogger(t *testing.T) {
 			router.ServeHTTP(recorder, request)
 			tc.checkResponse(recorder)
 
+			// the entry is written on close at the latest
+			require.NoError(t, pipeline.Close(context.Background()))
+			if tc.checkLogs != nil {
+				require.NotNil(t, logs)
+				tc.checkLogs(logs)
+			}
 		})
 	}
 }
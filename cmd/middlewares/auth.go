@@ -2,20 +2,32 @@ package middlewares
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
 	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/logx"
+	"github.com/Luckny/space-it/pkg/webauthn"
 	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate(store db.Store) gin.HandlerFunc {
+// Authenticate authenticates requests with Basic auth or, when relyingParty
+// is not nil, with a passkey assertion. Password attempts are throttled
+// unless throttle is nil.
+func Authenticate(store db.Store, relyingParty *webauthn.RelyingParty, throttle *LoginThrottle) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		ctx.Set("user", nil) // empty user context
 
+		if relyingParty != nil && strings.HasPrefix(authHeader, PasskeyScheme+" ") {
+			authenticatePasskey(ctx, store, relyingParty, authHeader)
+			return
+		}
+
 		// request is not authenticated
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Basic") {
 			ctx.Next()
@@ -29,27 +41,93 @@ func Authenticate(store db.Store) gin.HandlerFunc {
 			return
 		}
 
+		// blocked attempts are refused before the password is checked, they
+		// don't tell whether it is right
+		attempt := newLoginAttempt(ctx, email)
+		wait, err := throttle.wait(ctx, attempt)
+		if err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		if wait > 0 {
+			writeLoginBlocked(ctx, wait)
+			ctx.Abort()
+			return
+		}
+
+		// an unknown email is handled like a wrong password, with a password
+		// check as long, so that responses don't tell which emails exist
 		user, err := store.GetUserByEmail(ctx, email)
+		if err != nil && err != db.ErrRecordNotFound {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		if err == db.ErrRecordNotFound {
+			err = util.CheckDummyPassword(password)
+		} else {
+			err = util.CheckPassword(password, user.Password)
+		}
+
+		// set user email in context if no error
 		if err != nil {
-			if err == db.ErrRecordNotFound {
-				httpx.WriteError(ctx, http.StatusNotFound, fmt.Errorf("user not found"))
+			if err := throttle.fail(ctx, attempt); err != nil {
+				httpx.WriteError(ctx, http.StatusInternalServerError, err)
 				ctx.Abort()
 				return
 			}
+			ctx.Next()
+			return
+		}
+
+		throttle.succeed(ctx, attempt)
+
+		if util.NeedsRehash(user.Password) {
+			rehashPassword(ctx, store, user, password)
+		}
+
+		// with two-factor authentication the password is only the first factor
+		totp, err := store.GetTOTPByUserID(ctx, user.ID)
+		if err != nil && err != db.ErrRecordNotFound {
 			httpx.WriteError(ctx, http.StatusInternalServerError, err)
 			ctx.Abort()
 			return
 		}
 
-		// set user email in context if no error
-		if err := util.CheckPassword(password, user.Password); err == nil {
-			ctx.Set("user", &user)
+		if err == nil && totp.ConfirmedAt.Valid {
+			httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+		} else {
+			httpx.SetUserInContext(ctx, user)
 		}
+		httpx.SetAuthMethodInContext(ctx, httpx.AuthMethodPassword)
 
 		ctx.Next()
 	}
 }
 
+// rehashPassword replaces an outdated hash of the password of the user with
+// one of the current scheme, the password is only known when the user
+// authenticates. A failure is only logged, it is tried again next time.
+func rehashPassword(ctx *gin.Context, store db.Store, user db.User, password string) {
+	passwordHash, err := util.HashPassword(password)
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot rehash password", logx.KeyUserID, user.ID, "error", err)
+		return
+	}
+
+	_, err = store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		ID:          user.ID,
+		NewPassword: passwordHash,
+		OldPassword: user.Password,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot rehash password", logx.KeyUserID, user.ID, "error", err)
+	}
+}
+
 func RequireAuthentication() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		_, err := httpx.GetUserFromContext(ctx)
@@ -63,3 +141,42 @@ func RequireAuthentication() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// RequireFirstFactor lets a request through when it is authenticated, or when
+// the password of a user with two-factor authentication has been verified
+// with Basic auth. Tokens waiting for a second factor are refused.
+func RequireFirstFactor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, err := httpx.GetUserFromContext(ctx); err == nil {
+			ctx.Next()
+			return
+		}
+
+		_, err := httpx.GetPendingUserFromContext(ctx)
+		_, hasToken := httpx.GetTokenFromContext(ctx)
+		if err != nil || hasToken {
+			ctx.Header("WWW-Authenticate", "Basic realm=\"/\", charset\"UTF-8\"")
+			httpx.WriteError(ctx, http.StatusUnauthorized, fmt.Errorf("please authenticate"))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSecondFactorPending only lets through requests made with a token
+// issued after the first factor, to verify the second one.
+func RequireSecondFactorPending() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_, err := httpx.GetPendingUserFromContext(ctx)
+		_, hasToken := httpx.GetTokenFromContext(ctx)
+		if err != nil || !hasToken {
+			httpx.WriteError(ctx, http.StatusUnauthorized, fmt.Errorf("second factor not requested"))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
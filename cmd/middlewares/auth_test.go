@@ -1,23 +1,37 @@
 package middlewares
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	mockdb "github.com/Luckny/space-it/db/mock"
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/Luckny/space-it/util"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestAuthenticate(t *testing.T) {
 	user, unHashedPassword := mockdb.RandomUser(t)
 
+	// users registered before argon2id have bcrypt hashes
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(unHashedPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+	legacyUser := user
+	legacyUser.Password = string(bcryptHash)
+
 	testCases := []struct {
 		name          string
 		setHeader     bool
@@ -54,7 +68,7 @@ func TestAuthenticate(t *testing.T) {
 				data, err := io.ReadAll(recorder.Body)
 				require.NoError(t, err)
 
-				var responseBodyUser db.User
+				var responseBodyUser dto.User
 				err = json.Unmarshal(data, &responseBodyUser)
 				require.NoError(t, err)
 
@@ -73,24 +87,134 @@ func TestAuthenticate(t *testing.T) {
 					Times(1).
 					Return(user, nil)
 
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
 				data, err := io.ReadAll(recorder.Body)
 				require.NoError(t, err)
 
-				var responseBodyUser db.User
+				var responseBodyUser dto.User
 				err = json.Unmarshal(data, &responseBodyUser)
 				require.NoError(t, err)
 
 				require.Equal(t, responseBodyUser.Email, user.Email)
 				require.Equal(t, responseBodyUser.ID, user.ID)
-				require.Equal(t, responseBodyUser.Password, user.Password)
+				// only the public user is stored in the context
+				require.NotContains(t, string(data), "password")
+			},
+		},
+
+		{
+			name:      "outdated hash -> rehashed",
+			setHeader: true,
+			username:  user.Email,
+			password:  unHashedPassword,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(legacyUser, nil)
+
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RehashUserPasswordParams) (int64, error) {
+						require.Equal(t, user.ID, arg.ID)
+						require.Equal(t, legacyUser.Password, arg.OldPassword)
+						require.NoError(t, util.CheckPassword(unHashedPassword, arg.NewPassword))
+						require.False(t, util.NeedsRehash(arg.NewPassword))
+						return 1, nil
+					})
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Contains(t, recorder.Body.String(), user.Email)
 			},
 		},
 
 		{
-			name:      "not found",
+			name:      "rehash fails -> still authenticated",
+			setHeader: true,
+			username:  user.Email,
+			password:  unHashedPassword,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(legacyUser, nil)
+
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Contains(t, recorder.Body.String(), user.Email)
+			},
+		},
+
+		{
+			name:      "outdated hash, wrong password -> not rehashed",
+			setHeader: true,
+			username:  user.Email,
+			password:  "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(legacyUser, nil)
+
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:      "two-factor enabled -> not authenticated",
+			setHeader: true,
+			username:  user.Email,
+			password:  unHashedPassword,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{
+						UserID:      user.ID,
+						ConfirmedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// the password alone does not authenticate the user
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:      "not found -> not authenticated",
 			setHeader: true,
 			username:  user.Email,
 			password:  unHashedPassword,
@@ -101,7 +225,27 @@ func TestAuthenticate(t *testing.T) {
 					Return(db.User{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, recorder.Code, http.StatusNotFound)
+				// same as a wrong password
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:      "wrong password -> not authenticated",
+			setHeader: true,
+			username:  user.Email,
+			password:  "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetTOTPByUserID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusOK)
+				require.Equal(t, "null", recorder.Body.String())
 			},
 		},
 
@@ -130,7 +274,7 @@ func TestAuthenticate(t *testing.T) {
 
 			// server := NewServer(store)
 			router := gin.Default()
-			router.Use(Authenticate(store))
+			router.Use(Authenticate(store, nil, nil))
 
 			router.GET(
 				"/getpath",
@@ -185,7 +329,7 @@ func TestRequireAuth(t *testing.T) {
 			router := gin.Default()
 			if tc.authenticate {
 				router.Use(func(c *gin.Context) {
-					c.Set("user", &user)
+					httpx.SetUserInContext(c, user)
 					c.Next()
 				})
 			}
@@ -208,3 +352,146 @@ func TestRequireAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireFirstFactor(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	partialToken := &token.Payload{
+		User:       dto.NewUser(user),
+		Attributes: map[string]string{token.SecondFactorAttribute: token.SecondFactorPending},
+	}
+
+	testCases := []struct {
+		name          string
+		setContext    func(ctx *gin.Context)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "authenticated -> ok",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "password verified -> ok",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "partial token -> unauthorized",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+				ctx.Set("token", partialToken)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name:       "unauthenticated -> unauthorized",
+			setContext: func(ctx *gin.Context) {},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				tc.setContext(ctx)
+				ctx.Next()
+			})
+			router.Use(RequireFirstFactor())
+
+			router.POST("/login", func(c *gin.Context) {
+				c.JSON(http.StatusOK, nil)
+			})
+
+			request, err := http.NewRequest(http.MethodPost, "/login", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRequireSecondFactorPending(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	partialToken := &token.Payload{
+		User:       dto.NewUser(user),
+		Attributes: map[string]string{token.SecondFactorAttribute: token.SecondFactorPending},
+	}
+
+	testCases := []struct {
+		name          string
+		setContext    func(ctx *gin.Context)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "partial token -> ok",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+				ctx.Set("token", partialToken)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name: "basic auth password -> unauthorized",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetPendingUserInContext(ctx, dto.NewUser(user))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+
+		{
+			name: "full session -> unauthorized",
+			setContext: func(ctx *gin.Context) {
+				httpx.SetUserInContext(ctx, user)
+				ctx.Set("token", &token.Payload{User: dto.NewUser(user)})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				tc.setContext(ctx)
+				ctx.Next()
+			})
+			router.Use(RequireSecondFactorPending())
+
+			router.POST("/login/2fa", func(c *gin.Context) {
+				c.JSON(http.StatusOK, nil)
+			})
+
+			request, err := http.NewRequest(http.MethodPost, "/login/2fa", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	verified := user
+	verified.EmailVerifiedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name         string
+		authenticate func(c *gin.Context)
+		buildStubs   func(store *mockdb.MockStore)
+		code         int
+	}{
+		{
+			name:         "verified",
+			authenticate: func(c *gin.Context) { httpx.SetUserInContext(c, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(verified, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:         "not verified",
+			authenticate: func(c *gin.Context) { httpx.SetUserInContext(c, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			// a session saved before the verification says the email is not verified
+			name: "verified after the session was created",
+			authenticate: func(c *gin.Context) {
+				c.Set("user", &dto.User{ID: user.ID, Email: user.Email, EmailVerified: false})
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(verified, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:         "second factor pending",
+			authenticate: func(c *gin.Context) { httpx.SetPendingUserInContext(c, dto.NewUser(user)) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name:         "unauthenticated",
+			authenticate: func(c *gin.Context) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:         "internal error",
+			authenticate: func(c *gin.Context) { httpx.SetUserInContext(c, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			router := gin.Default()
+			router.Use(func(c *gin.Context) {
+				tc.authenticate(c)
+				c.Next()
+			})
+			router.POST("/spaces", RequireVerifiedEmail(store), func(c *gin.Context) {
+				c.JSON(http.StatusOK, nil)
+			})
+
+			request, err := http.NewRequest(http.MethodPost, "/spaces", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// settings of NewLoginThrottle used when zero
+const (
+	defaultLoginMaxFailures      = 5
+	defaultLoginMaxFailuresPerIP = 100
+	defaultLoginLockout          = 15 * time.Minute
+)
+
+const (
+	// wait after the first failed attempt on an account
+	loginBackoff = time.Second
+	// longest wait, failures are forgotten after as long without new ones
+	maxLoginLockout = 24 * time.Hour
+)
+
+// the throttles of a client IP across accounts use an empty email
+const anyAccount = ""
+
+// LoginThrottle slows down password guessing on Basic auth.
+//
+// Failures are counted per account and client IP. Each one doubles the wait
+// before the next attempt and, after maxFailures, the account is locked out
+// for that client IP. An attacker can't lock a user out from the user's own
+// IP this way. Failures of a client IP across accounts are also counted, to
+// slow down credential stuffing, and lock the IP out after maxFailuresPerIP.
+// Lockouts double with each further failure.
+type LoginThrottle struct {
+	store            db.Store
+	maxFailures      int32
+	maxFailuresPerIP int32
+	lockout          time.Duration
+}
+
+// NewLoginThrottle returns a login throttle, the defaults are used for zero
+// settings
+func NewLoginThrottle(store db.Store, maxFailures, maxFailuresPerIP int, lockout time.Duration) *LoginThrottle {
+	if maxFailures <= 0 {
+		maxFailures = defaultLoginMaxFailures
+	}
+	if maxFailuresPerIP <= 0 {
+		maxFailuresPerIP = defaultLoginMaxFailuresPerIP
+	}
+	if lockout <= 0 {
+		lockout = defaultLoginLockout
+	}
+
+	return &LoginThrottle{
+		store:            store,
+		maxFailures:      int32(maxFailures),
+		maxFailuresPerIP: int32(maxFailuresPerIP),
+		lockout:          lockout,
+	}
+}
+
+// loginAttempt is a Basic auth attempt of a client IP on an account
+type loginAttempt struct {
+	email    string
+	clientIP string
+	// the account has failures from the client IP
+	failed bool
+}
+
+func newLoginAttempt(ctx *gin.Context, email string) *loginAttempt {
+	// longer emails can't be registered, they share the key of their prefix
+	if len(email) > 255 {
+		email = email[:255]
+	}
+
+	return &loginAttempt{email: email, clientIP: ctx.ClientIP()}
+}
+
+// CheckPassword checks a password of the user outside of Basic auth, like
+// the current one when it is changed. Attempts are throttled with those of
+// Basic auth on the account from the client IP. It writes the response and
+// returns false when the attempt is blocked or the password is wrong, with
+// incorrect as the error.
+func (throttle *LoginThrottle) CheckPassword(ctx *gin.Context, user db.User, password string, incorrect error) bool {
+	attempt := newLoginAttempt(ctx, user.Email)
+	wait, err := throttle.wait(ctx, attempt)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if wait > 0 {
+		writeLoginBlocked(ctx, wait)
+		return false
+	}
+
+	if err := util.CheckPassword(password, user.Password); err != nil {
+		if err := throttle.fail(ctx, attempt); err != nil {
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			return false
+		}
+		httpx.WriteError(ctx, http.StatusForbidden, incorrect)
+		return false
+	}
+
+	throttle.succeed(ctx, attempt)
+	return true
+}
+
+// writeLoginBlocked refuses an attempt blocked for wait
+func writeLoginBlocked(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	httpx.WriteError(ctx, http.StatusTooManyRequests, fmt.Errorf("too many failed login attempts, try again later"))
+}
+
+// wait returns how long the client must wait before trying a password on
+// the account, 0 when it can try now
+func (throttle *LoginThrottle) wait(ctx *gin.Context, attempt *loginAttempt) (time.Duration, error) {
+	if throttle == nil {
+		return 0, nil
+	}
+
+	throttles, err := throttle.store.ListLoginThrottles(ctx, db.ListLoginThrottlesParams{
+		ClientIp: attempt.clientIP,
+		Email:    attempt.email,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, t := range throttles {
+		if t.Email == attempt.email {
+			attempt.failed = true
+		}
+		wait = max(wait, time.Until(t.BlockedUntil.Time))
+	}
+
+	return wait, nil
+}
+
+// fail records a failed attempt and blocks the next ones for as long as
+// needed, lockouts are recorded for auditing
+func (throttle *LoginThrottle) fail(ctx *gin.Context, attempt *loginAttempt) error {
+	if throttle == nil {
+		return nil
+	}
+
+	keys := []struct {
+		email       string
+		maxFailures int32
+		backoff     bool
+	}{
+		{email: attempt.email, maxFailures: throttle.maxFailures, backoff: true},
+		{email: anyAccount, maxFailures: throttle.maxFailuresPerIP},
+	}
+
+	now := time.Now().UTC()
+	for _, key := range keys {
+		t, err := throttle.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Email:       key.email,
+			ClientIp:    attempt.clientIP,
+			WindowStart: pgtype.Timestamp{Time: now.Add(-maxLoginLockout), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		wait, locked := throttle.delay(t.Failures, key.maxFailures, key.backoff)
+		if wait == 0 {
+			continue
+		}
+
+		blockedUntil := pgtype.Timestamp{Time: now.Add(wait), Valid: true}
+		err = throttle.store.BlockLogin(ctx, db.BlockLoginParams{
+			Email:        key.email,
+			ClientIp:     attempt.clientIP,
+			BlockedUntil: blockedUntil,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !locked {
+			continue
+		}
+
+		_, err = throttle.store.CreateLoginLockout(ctx, db.CreateLoginLockoutParams{
+			Email:       key.email,
+			ClientIp:    attempt.clientIP,
+			Failures:    t.Failures,
+			LockedUntil: blockedUntil,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// succeed forgets the failures of the account from the client IP. Those of
+// the client IP across accounts are kept, logging into an account of their
+// own must not let attackers try more passwords.
+func (throttle *LoginThrottle) succeed(ctx *gin.Context, attempt *loginAttempt) {
+	if throttle == nil || !attempt.failed {
+		return
+	}
+
+	err := throttle.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Email:    attempt.email,
+		ClientIp: attempt.clientIP,
+	})
+	if err != nil {
+		// not worth failing the request
+		slog.ErrorContext(ctx, "login throttle: cannot reset", "client_ip", attempt.clientIP, "error", err)
+	}
+}
+
+// delay returns how long attempts are blocked after failures and whether it
+// is a lockout. Without backoff nothing is blocked before the lockout, with
+// it the wait never exceeds the first lockout.
+func (throttle *LoginThrottle) delay(failures, maxFailures int32, backoff bool) (time.Duration, bool) {
+	if failures >= maxFailures {
+		return doubled(throttle.lockout, failures-maxFailures), true
+	}
+
+	if backoff && failures > 0 {
+		return min(doubled(loginBackoff, failures-1), throttle.lockout), false
+	}
+
+	return 0, false
+}
+
+// doubled returns d doubled n times, at most maxLoginLockout
+func doubled(d time.Duration, n int32) time.Duration {
+	for ; n > 0 && d < maxLoginLockout; n-- {
+		d *= 2
+	}
+
+	return min(d, maxLoginLockout)
+}
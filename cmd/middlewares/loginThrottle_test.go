@@ -0,0 +1,285 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testClientIP = "203.0.113.7"
+
+func TestAuthenticateLoginThrottle(t *testing.T) {
+	user, password := mockdb.RandomUser(t)
+
+	// failures returns the throttle of a key after a new failure
+	failures := func(n int32) func(context.Context, db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+		return func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+			require.Equal(t, testClientIP, arg.ClientIp)
+			require.WithinDuration(t, time.Now().Add(-maxLoginLockout), arg.WindowStart.Time, time.Minute)
+			return db.LoginThrottle{Email: arg.Email, ClientIp: arg.ClientIp, Failures: n}, nil
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		password      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "blocked -> password not checked",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Eq(db.ListLoginThrottlesParams{
+						ClientIp: testClientIP,
+						Email:    user.Email,
+					})).
+					Times(1).
+					Return([]db.LoginThrottle{
+						{
+							Email:        user.Email,
+							ClientIp:     testClientIP,
+							Failures:     3,
+							BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(4 * time.Second), Valid: true},
+						},
+						{
+							Email:        anyAccount,
+							ClientIp:     testClientIP,
+							Failures:     3,
+							BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(-time.Minute), Valid: true},
+						},
+					}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "4", recorder.Header().Get("Retry-After"))
+			},
+		},
+
+		{
+			name:     "client IP blocked across accounts",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.LoginThrottle{{
+						Email:        anyAccount,
+						ClientIp:     testClientIP,
+						Failures:     100,
+						BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(15 * time.Minute), Valid: true},
+					}}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "900", recorder.Header().Get("Retry-After"))
+			},
+		},
+
+		{
+			name:     "wrong password -> backoff",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(failures(3))
+				store.EXPECT().
+					BlockLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BlockLoginParams) error {
+						require.Equal(t, user.Email, arg.Email)
+						require.WithinDuration(t, time.Now().Add(4*time.Second), arg.BlockedUntil.Time, time.Second)
+						return nil
+					})
+				store.EXPECT().CreateLoginLockout(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:     "too many wrong passwords -> lockout",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(failures(defaultLoginMaxFailures))
+				store.EXPECT().BlockLogin(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().
+					CreateLoginLockout(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateLoginLockoutParams) (db.LoginLockout, error) {
+						require.Equal(t, user.Email, arg.Email)
+						require.Equal(t, testClientIP, arg.ClientIp)
+						require.Equal(t, int32(defaultLoginMaxFailures), arg.Failures)
+						require.WithinDuration(t, time.Now().Add(defaultLoginLockout), arg.LockedUntil.Time, time.Second)
+						return db.LoginLockout{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:     "unknown email -> failure recorded",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(failures(1))
+				store.EXPECT().BlockLogin(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:     "right password -> failures forgotten",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.LoginThrottle{{
+						Email:        user.Email,
+						ClientIp:     testClientIP,
+						Failures:     2,
+						BlockedUntil: pgtype.Timestamp{Time: time.Now().Add(-time.Second), Valid: true},
+					}}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Email:    user.Email,
+						ClientIp: testClientIP,
+					})).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), user.Email)
+			},
+		},
+
+		{
+			name:     "right password without failures",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Any()).Times(1).Return([]db.LoginThrottle{}, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					GetTOTPByUserID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), user.Email)
+			},
+		},
+
+		{
+			name:     "internal error",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginThrottles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			router := gin.Default()
+			router.Use(Authenticate(store, nil, NewLoginThrottle(store, 0, 0, 0)))
+			router.GET("/getpath", func(ctx *gin.Context) {
+				user, _ := ctx.Get("user")
+				ctx.JSON(http.StatusOK, user)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/getpath", nil)
+			require.NoError(t, err)
+			request.RemoteAddr = testClientIP + ":54321"
+			request.SetBasicAuth(user.Email, tc.password)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	throttle := NewLoginThrottle(nil, 5, 100, 15*time.Minute)
+
+	testCases := []struct {
+		failures    int32
+		maxFailures int32
+		backoff     bool
+		wait        time.Duration
+		locked      bool
+	}{
+		{failures: 1, maxFailures: 5, backoff: true, wait: time.Second},
+		{failures: 2, maxFailures: 5, backoff: true, wait: 2 * time.Second},
+		{failures: 4, maxFailures: 5, backoff: true, wait: 8 * time.Second},
+		{failures: 5, maxFailures: 5, backoff: true, wait: 15 * time.Minute, locked: true},
+		{failures: 7, maxFailures: 5, backoff: true, wait: time.Hour, locked: true},
+		{failures: 50, maxFailures: 5, backoff: true, wait: maxLoginLockout, locked: true},
+		{failures: 99, maxFailures: 100, backoff: false, wait: 0},
+		{failures: 100, maxFailures: 100, backoff: false, wait: 15 * time.Minute, locked: true},
+		// the backoff never exceeds the first lockout
+		{failures: 19, maxFailures: 20, backoff: true, wait: 15 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		wait, locked := throttle.delay(tc.failures, tc.maxFailures, tc.backoff)
+		require.Equal(t, tc.wait, wait, "%d failures out of %d", tc.failures, tc.maxFailures)
+		require.Equal(t, tc.locked, locked, "%d failures out of %d", tc.failures, tc.maxFailures)
+	}
+}
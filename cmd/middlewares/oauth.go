@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthenticateOAuthToken authenticates requests with an OAuth2 access token
+// sent as a bearer token. The request is then made on behalf of the token's
+// user, within its scopes and spaces.
+func AuthenticateOAuthToken(maker token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authHeader := ctx.GetHeader("Authorization")
+		accessToken, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok || !oauth.IsAccessToken(accessToken) {
+			ctx.Next()
+			return
+		}
+
+		payload, err := maker.VerifyToken(ctx, accessToken)
+		if err != nil {
+			// unknown, revoked or expired token
+			if err == token.ErrInvalidToken {
+				ctx.Next()
+				return
+			}
+			httpx.WriteError(ctx, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("user", &payload.User)
+		httpx.SetOAuthTokenInContext(ctx, payload)
+		httpx.SetAuthMethodInContext(ctx, httpx.AuthMethodOAuth)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthenticateOAuthToken(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	accessToken, err := oauth.GenerateToken(oauth.AccessTokenPrefix)
+	require.NoError(t, err)
+
+	stored := db.OauthAccessToken{
+		ID:        uuid.New(),
+		GrantID:   uuid.New(),
+		TokenHash: oauth.Hash(accessToken),
+		ClientID:  uuid.New(),
+		UserID:    user.ID,
+		Scopes:    []string{string(ScopeSpacesRead)},
+		SpaceIds:  []uuid.UUID{},
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		header        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "valid token -> authenticated",
+			header: "Bearer " + accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(stored.TokenHash)).
+					Times(1).
+					Return(stored, nil)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res struct {
+					User   dto.User `json:"user"`
+					Scopes []string `json:"scopes"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, user.ID, res.User.ID)
+				require.Equal(t, stored.Scopes, res.Scopes)
+			},
+		},
+
+		{
+			name:   "unknown, revoked or expired token -> not authenticated",
+			header: "Bearer " + accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAccessToken{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"user": null, "scopes": null}`, recorder.Body.String())
+			},
+		},
+
+		{
+			name:   "API key -> ignored",
+			header: "Bearer " + apikey.Prefix + "key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+
+		{
+			name:   "internal error",
+			header: "Bearer " + accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAccessToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			router := gin.Default()
+			router.Use(AuthenticateOAuthToken(token.NewOAuthStore(store)))
+			router.GET("/auth", func(c *gin.Context) {
+				user, _ := httpx.GetUserFromContext(c)
+				res := gin.H{"user": user, "scopes": nil}
+				if payload, ok := httpx.GetOAuthTokenFromContext(c); ok {
+					res["scopes"] = payload.Scopes()
+				}
+				c.JSON(http.StatusOK, res)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+			request.Header.Set("Authorization", tc.header)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
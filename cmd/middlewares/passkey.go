@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PasskeyScheme is the Authorization header scheme of passkey logins
+const PasskeyScheme = "WebAuthn"
+
+// PasskeyAssertion is the response of navigator.credentials.get sent, JSON
+// and base64url encoded, in a "WebAuthn" Authorization header
+type PasskeyAssertion struct {
+	// challenge returned when the login was started
+	ChallengeID  uuid.UUID          `json:"challenge_id"`
+	CredentialID webauthn.Base64URL `json:"id"`
+	Response     struct {
+		ClientDataJSON    webauthn.Base64URL `json:"clientDataJSON"`
+		AuthenticatorData webauthn.Base64URL `json:"authenticatorData"`
+		Signature         webauthn.Base64URL `json:"signature"`
+		UserHandle        webauthn.Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Encode returns the Authorization header value of the assertion
+func (a PasskeyAssertion) Encode() (string, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return PasskeyScheme + " " + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func parsePasskeyHeader(header string) (PasskeyAssertion, error) {
+	var assertion PasskeyAssertion
+
+	encoded := strings.TrimSpace(strings.TrimPrefix(header, PasskeyScheme))
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return assertion, fmt.Errorf("invalid passkey assertion")
+	}
+
+	if err := json.Unmarshal(data, &assertion); err != nil {
+		return assertion, fmt.Errorf("invalid passkey assertion")
+	}
+
+	return assertion, nil
+}
+
+// authenticatePasskey verifies a passkey assertion. Like a wrong password, an
+// assertion that can't be verified leaves the request unauthenticated.
+func authenticatePasskey(ctx *gin.Context, store db.Store, relyingParty *webauthn.RelyingParty, header string) {
+	assertion, err := parsePasskeyHeader(header)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusBadRequest, err)
+		ctx.Abort()
+		return
+	}
+
+	// each challenge can only be used once
+	challenge, err := store.ConsumeLoginChallenge(ctx, assertion.ChallengeID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			ctx.Next()
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		ctx.Abort()
+		return
+	}
+
+	passkey, err := store.GetPasskeyByCredentialID(ctx, assertion.CredentialID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			ctx.Next()
+			return
+		}
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		ctx.Abort()
+		return
+	}
+
+	// the user handle is the user id the credential was created for
+	userHandle := assertion.Response.UserHandle
+	if len(userHandle) > 0 && !bytes.Equal(userHandle, passkey.UserID[:]) {
+		ctx.Next()
+		return
+	}
+
+	signCount, err := relyingParty.VerifyAssertion(
+		challenge.Challenge,
+		passkey.PublicKey,
+		assertion.Response.ClientDataJSON,
+		assertion.Response.AuthenticatorData,
+		assertion.Response.Signature,
+	)
+	if err != nil {
+		ctx.Next()
+		return
+	}
+
+	arg := db.UsePasskeyParams{
+		ID:        passkey.ID,
+		SignCount: int64(signCount),
+	}
+
+	used, err := store.UsePasskey(ctx, arg)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		ctx.Abort()
+		return
+	}
+
+	// the counter went backwards, the credential may have been cloned
+	if used == 0 {
+		slog.WarnContext(ctx, "passkey: signature counter did not increase", "passkey_id", passkey.ID)
+		ctx.Next()
+		return
+	}
+
+	user, err := store.GetUserByID(ctx, passkey.UserID)
+	if err != nil {
+		httpx.WriteError(ctx, http.StatusInternalServerError, err)
+		ctx.Abort()
+		return
+	}
+
+	// the authenticator verified the user, a passkey is not followed by a second factor
+	httpx.SetUserInContext(ctx, user)
+	httpx.SetAuthMethodInContext(ctx, httpx.AuthMethodPasskey)
+	ctx.Next()
+}
@@ -0,0 +1,286 @@
+package middlewares
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/Luckny/space-it/pkg/webauthn/webauthntest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthenticatePasskey(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	relyingParty, err := webauthn.NewRelyingParty("localhost", "", []string{"https://localhost:3000"})
+	require.NoError(t, err)
+
+	authenticator := webauthntest.NewAuthenticator("localhost", "https://localhost:3000")
+	passkey := mockdb.RandomPasskey(t, user.ID, authenticator)
+
+	// signs a new challenge, the assertion is returned with its challenge row
+	login := func(t *testing.T) (PasskeyAssertion, db.WebauthnChallenge) {
+		challenge, err := webauthn.NewChallenge()
+		require.NoError(t, err)
+
+		signed, err := authenticator.Login(challenge)
+		require.NoError(t, err)
+
+		assertion := PasskeyAssertion{
+			ChallengeID:  uuid.New(),
+			CredentialID: signed.CredentialID,
+		}
+		assertion.Response.ClientDataJSON = signed.ClientDataJSON
+		assertion.Response.AuthenticatorData = signed.AuthenticatorData
+		assertion.Response.Signature = signed.Signature
+		assertion.Response.UserHandle = signed.UserHandle
+
+		return assertion, db.WebauthnChallenge{ID: assertion.ChallengeID, Challenge: challenge}
+	}
+
+	testCases := []struct {
+		name          string
+		relyingParty  *webauthn.RelyingParty
+		header        func(t *testing.T, store *mockdb.MockStore) string
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:         "valid assertion -> authenticated",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, challenge := login(t)
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					GetPasskeyByCredentialID(gomock.Any(), gomock.Eq(passkey.CredentialID)).
+					Times(1).
+					Return(passkey, nil)
+				store.EXPECT().
+					UsePasskey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(user, nil)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotUser dto.User
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotUser)
+				require.NoError(t, err)
+				require.Equal(t, user.ID, gotUser.ID)
+			},
+		},
+
+		{
+			name:         "unknown or used challenge -> not authenticated",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, _ := login(t)
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnChallenge{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetPasskeyByCredentialID(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:         "invalid signature -> not authenticated",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, challenge := login(t)
+				assertion.Response.Signature[len(assertion.Response.Signature)-1] ^= 0xff
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					GetPasskeyByCredentialID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(passkey, nil)
+				store.EXPECT().
+					UsePasskey(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:         "credential of another user -> not authenticated",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, challenge := login(t)
+				assertion.Response.UserHandle = []byte("another user")
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					GetPasskeyByCredentialID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(passkey, nil)
+				store.EXPECT().
+					UsePasskey(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:         "sign counter not increased -> not authenticated",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, challenge := login(t)
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					GetPasskeyByCredentialID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(passkey, nil)
+				store.EXPECT().
+					UsePasskey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+
+		{
+			name:         "malformed assertion -> bad request",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+				return PasskeyScheme + " not-base64!"
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+
+		{
+			name:         "internal error",
+			relyingParty: relyingParty,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, _ := login(t)
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnChallenge{}, sql.ErrConnDone)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+
+		{
+			name:         "passkeys disabled -> ignored",
+			relyingParty: nil,
+			header: func(t *testing.T, store *mockdb.MockStore) string {
+				assertion, _ := login(t)
+
+				store.EXPECT().
+					ConsumeLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				header, err := assertion.Encode()
+				require.NoError(t, err)
+				return header
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "null", recorder.Body.String())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			header := tc.header(t, store)
+
+			router := gin.Default()
+			router.Use(Authenticate(store, tc.relyingParty, nil))
+
+			router.GET("/auth", func(c *gin.Context) {
+				user, _ := httpx.GetUserFromContext(c)
+				c.JSON(http.StatusOK, user)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+			request.Header.Set("Authorization", header)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -1,18 +1,198 @@
 package middlewares
 
 import (
+	"container/list"
+	"context"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/Luckny/space-it/pkg/httpx"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-func RateGuard(limiter *rate.Limiter) gin.HandlerFunc {
+// keys a MemoryRateLimiter keeps when none is given
+const defaultRateLimitMaxKeys = 10000
+
+// RateLimit is the outcome of taking a request from a rate limit
+type RateLimit struct {
+	Allowed bool
+	// requests a client can make at once
+	Limit int
+	// requests left before the client is throttled
+	Remaining int
+	// until the client can make Limit requests again
+	Reset time.Duration
+	// until the client can make its next request, 0 when allowed
+	RetryAfter time.Duration
+}
+
+// RateLimiter counts the requests of each key, e.g. of each user or client
+// IP, against a limit
+type RateLimiter interface {
+	// Take takes a request of key from its bucket
+	Take(ctx context.Context, key string) (RateLimit, error)
+}
+
+// MemoryRateLimiter is a token bucket per key, in memory. Each server
+// instance has its own limits.
+//
+// Buckets of the keys that have not been used for the longest time are
+// dropped once maxKeys are kept. A dropped key gets a full bucket again,
+// which only lets an idle client make its burst earlier than it would have.
+type MemoryRateLimiter struct {
+	limit   rate.Limit
+	burst   int
+	maxKeys int
+
+	mu sync.Mutex
+	// most recently used first
+	lru     *list.List
+	buckets map[string]*list.Element
+}
+
+type rateBucket struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+// NewMemoryRateLimiter returns a rate limiter allowing limit requests per
+// second and bursts of burst requests for each key. The default is used when
+// maxKeys is zero.
+func NewMemoryRateLimiter(limit rate.Limit, burst, maxKeys int) *MemoryRateLimiter {
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitMaxKeys
+	}
+
+	return &MemoryRateLimiter{
+		limit:   limit,
+		burst:   burst,
+		maxKeys: maxKeys,
+		lru:     list.New(),
+		buckets: make(map[string]*list.Element),
+	}
+}
+
+func (l *MemoryRateLimiter) Take(ctx context.Context, key string) (RateLimit, error) {
+	return l.take(key, time.Now()), nil
+}
+
+func (l *MemoryRateLimiter) take(key string, now time.Time) RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter := l.bucket(key)
+	res := RateLimit{
+		Allowed: limiter.AllowN(now, 1),
+		Limit:   l.burst,
+	}
+
+	tokens := limiter.TokensAt(now)
+	res.Remaining = max(int(math.Floor(tokens)), 0)
+	res.Reset = l.durationFromTokens(float64(l.burst) - tokens)
+	if !res.Allowed {
+		res.RetryAfter = l.durationFromTokens(1 - tokens)
+	}
+
+	return res
+}
+
+// SetLimit changes the rate of all keys
+func (l *MemoryRateLimiter) SetLimit(limit rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	for _, e := range l.buckets {
+		e.Value.(*rateBucket).limiter.SetLimit(limit)
+	}
+}
+
+// Len returns the number of keys kept
+func (l *MemoryRateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
+
+// bucket returns the limiter of key, marked as the most recently used.
+// l.mu must be held.
+func (l *MemoryRateLimiter) bucket(key string) *rate.Limiter {
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		return e.Value.(*rateBucket).limiter
+	}
+
+	for l.lru.Len() >= l.maxKeys {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*rateBucket).key)
+	}
+
+	b := &rateBucket{key: key, limiter: rate.NewLimiter(l.limit, l.burst)}
+	l.buckets[key] = l.lru.PushFront(b)
+	return b.limiter
+}
+
+// durationFromTokens returns how long the bucket takes to refill tokens
+func (l *MemoryRateLimiter) durationFromTokens(tokens float64) time.Duration {
+	if tokens <= 0 || l.limit == rate.Inf || l.limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(tokens / float64(l.limit) * float64(time.Second))
+}
+
+// RateGuard throttles clients that make requests faster than the limiter
+// allows. Requests are counted per API key, user or client IP, the first the
+// request is authenticated with, so it must run after the authentication
+// middlewares to tell users apart.
+//
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers tell
+// clients where they stand, and Retry-After when to try again once throttled.
+// A later guard on the same request overrides the headers of an earlier one.
+//
+// When the limiter fails, e.g. its database is unavailable, requests are let
+// through if failOpen and refused with 503 Service Unavailable otherwise.
+func RateGuard(limiter RateLimiter, failOpen bool) gin.HandlerFunc {
+	return rateGuard(limiter, failOpen, rateLimitKey)
+}
+
+// ClientIPRateGuard throttles client IPs, whoever the request authenticates
+// as. It runs before the authentication middlewares, so floods of requests
+// with bad credentials are throttled before their passwords are hashed.
+// It behaves as RateGuard otherwise.
+func ClientIPRateGuard(limiter RateLimiter, failOpen bool) gin.HandlerFunc {
+	return rateGuard(limiter, failOpen, clientIPRateLimitKey)
+}
+
+func rateGuard(limiter RateLimiter, failOpen bool, key func(ctx *gin.Context) string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		res, err := limiter.Take(ctx, key(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "rate limit: limiter failed", "error", err)
+			if failOpen {
+				ctx.Next()
+				return
+			}
 
-		if !limiter.Allow() {
+			httpx.WriteError(ctx, http.StatusServiceUnavailable, fmt.Errorf("rate limiter unavailable"))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.Header("RateLimit-Reset", seconds(res.Reset))
+
+		if !res.Allowed {
+			ctx.Header("Retry-After", seconds(res.RetryAfter))
 			httpx.WriteError(ctx, http.StatusTooManyRequests, fmt.Errorf("too many requests"))
 			ctx.Abort()
 			return
@@ -21,3 +201,25 @@ func RateGuard(limiter *rate.Limiter) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// rateLimitKey returns who the request is counted against
+func rateLimitKey(ctx *gin.Context) string {
+	if key, ok := httpx.GetAPIKeyFromContext(ctx); ok {
+		return "key:" + key.ID.String()
+	}
+
+	if user, err := httpx.GetUserFromContext(ctx); err == nil {
+		return "user:" + user.ID.String()
+	}
+
+	return clientIPRateLimitKey(ctx)
+}
+
+func clientIPRateLimitKey(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// seconds formats d as a number of seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
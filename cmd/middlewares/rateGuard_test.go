@@ -1,19 +1,25 @@
 package middlewares
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/httpx"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 )
 
 func TestRateLimiter(t *testing.T) {
 	router := gin.Default()
-	limiter := rate.NewLimiter(rate.Limit(2), 2)
-	router.Use(RateGuard(limiter))
+	limiter := NewMemoryRateLimiter(rate.Limit(2), 2, 0)
+	router.Use(RateGuard(limiter, true))
 
 	router.GET(
 		"/getpath",
@@ -25,7 +31,7 @@ func TestRateLimiter(t *testing.T) {
 	errs := make(chan error)
 	responseCode := make(chan int)
 
-	n := int(limiter.Limit()) + 1 // number of allowed request + 1
+	n := 2 + 1 // number of allowed request + 1
 
 	// n concurrent calls the an enpoint
 	for i := 0; i < n; i++ {
@@ -63,3 +69,172 @@ func TestRateLimiter(t *testing.T) {
 	require.ElementsMatch(t, codes, expectedCodes)
 
 }
+
+func TestRateGuardKeys(t *testing.T) {
+	users := []db.User{{ID: uuid.New()}, {ID: uuid.New()}}
+	key := db.ApiKey{ID: uuid.New(), UserID: users[0].ID}
+
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		if i := ctx.GetHeader("X-Test-User"); i != "" {
+			httpx.SetUserInContext(ctx, users[i[0]-'0'])
+		}
+		if ctx.GetHeader("X-Test-Key") != "" {
+			httpx.SetAPIKeyInContext(ctx, key)
+		}
+		ctx.Next()
+	})
+	router.Use(RateGuard(NewMemoryRateLimiter(rate.Every(time.Hour), 1, 0), true))
+	router.GET("/getpath", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	get := func(ip string, headers map[string]string) int {
+		request, err := http.NewRequest(http.MethodGet, "/getpath", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = ip + ":1234"
+		for k, v := range headers {
+			request.Header.Set(k, v)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// anonymous clients are told apart by IP
+	require.Equal(t, http.StatusOK, get("10.0.0.1", nil))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.1", nil))
+	require.Equal(t, http.StatusOK, get("10.0.0.2", nil))
+
+	// users behind the same IP have their own limit
+	require.Equal(t, http.StatusOK, get("10.0.0.1", map[string]string{"X-Test-User": "0"}))
+	require.Equal(t, http.StatusOK, get("10.0.0.1", map[string]string{"X-Test-User": "1"}))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.2", map[string]string{"X-Test-User": "1"}))
+
+	// and so do their API keys
+	require.Equal(t, http.StatusOK, get("10.0.0.1", map[string]string{"X-Test-User": "0", "X-Test-Key": "1"}))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.3", map[string]string{"X-Test-User": "0", "X-Test-Key": "1"}))
+}
+
+func TestClientIPRateGuard(t *testing.T) {
+	user := db.User{ID: uuid.New()}
+
+	// stands for the authentication middlewares, throttled requests must not
+	// reach them
+	authenticated := 0
+	router := gin.Default()
+	router.Use(ClientIPRateGuard(NewMemoryRateLimiter(rate.Every(time.Hour), 1, 0), true))
+	router.Use(func(ctx *gin.Context) {
+		authenticated++
+		httpx.SetUserInContext(ctx, user)
+		ctx.Next()
+	})
+	router.GET("/getpath", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	get := func(ip string) int {
+		request, err := http.NewRequest(http.MethodGet, "/getpath", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = ip + ":1234"
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("10.0.0.1"))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.1"))
+	require.Equal(t, http.StatusOK, get("10.0.0.2"))
+	require.Equal(t, 2, authenticated)
+}
+
+func TestRateGuardHeaders(t *testing.T) {
+	router := gin.Default()
+	router.Use(RateGuard(NewMemoryRateLimiter(rate.Every(10*time.Second), 2, 0), true))
+	router.GET("/getpath", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, "/getpath", nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get()
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "10", recorder.Header().Get("RateLimit-Reset"))
+	require.Empty(t, recorder.Header().Get("Retry-After"))
+
+	recorder = get()
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "20", recorder.Header().Get("RateLimit-Reset"))
+
+	recorder = get()
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "10", recorder.Header().Get("Retry-After"))
+}
+
+func TestRateLimiterEvictsIdleKeys(t *testing.T) {
+	limiter := NewMemoryRateLimiter(rate.Every(time.Hour), 1, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		require.True(t, limiter.take(fmt.Sprint(i), now).Allowed)
+	}
+	require.Equal(t, 3, limiter.Len())
+
+	// 0 becomes the most recently used, 1 the least
+	require.False(t, limiter.take("0", now).Allowed)
+
+	require.True(t, limiter.take("3", now).Allowed)
+	require.Equal(t, 3, limiter.Len())
+
+	// the evicted key starts over, the others are still throttled
+	require.True(t, limiter.take("1", now).Allowed)
+	require.False(t, limiter.take("0", now).Allowed)
+	require.False(t, limiter.take("3", now).Allowed)
+}
+
+// brokenRateLimiter is a rate limiter whose backend is unavailable
+type brokenRateLimiter struct{}
+
+func (brokenRateLimiter) Take(ctx context.Context, key string) (RateLimit, error) {
+	return RateLimit{}, fmt.Errorf("connection refused")
+}
+
+func TestRateGuardFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		failOpen bool
+		code     int
+	}{
+		{name: "fail open", failOpen: true, code: http.StatusOK},
+		{name: "fail closed", failOpen: false, code: http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.Default()
+			router.Use(RateGuard(brokenRateLimiter{}, tc.failOpen))
+			router.GET("/getpath", func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/getpath", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+			require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+		})
+	}
+}
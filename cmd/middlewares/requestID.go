@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/logx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// longest request ID accepted from a proxy, the length audit entries store
+const maxRequestIDLength = 64
+
+// RequestID is middleware giving each request an ID, sent back in the
+// X-Request-ID header. It correlates the audit entry of the request, the
+// events it causes and its logs.
+//
+// The X-Request-ID header of a request is only kept when the request comes
+// from one of the trusted proxies, addresses or CIDRs as in TRUSTED_PROXIES,
+// so clients can't make their requests pass for others. A new ID is
+// generated otherwise.
+//
+// The records logged for the request carry its space ID too, when the route
+// has one.
+func RequestID(trustedProxies []string) (gin.HandlerFunc, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader("X-Request-ID")
+		if !validRequestID(id) || !isTrusted(trusted, ctx.RemoteIP()) {
+			id = uuid.NewString()
+		}
+
+		httpx.SetRequestIDInContext(ctx, id)
+		ctx.Header("X-Request-ID", id)
+
+		if spaceID, err := uuid.Parse(ctx.Param("spaceID")); err == nil {
+			logx.Enrich(ctx.Request.Context(), slog.String(logx.KeySpaceID, spaceID.String()))
+		}
+
+		ctx.Next()
+	}, nil
+}
+
+// validRequestID reports whether id is short and only made of characters
+// that are safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func parsePrefixes(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func isTrusted(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/logx"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		checkID    func(t *testing.T, id string)
+	}{
+		{
+			name: "No header -> generated",
+			checkID: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "Trusted proxy -> kept",
+			remoteAddr: "10.1.2.3:1234",
+			header:     "lb-1:abc_123.4",
+			checkID: func(t *testing.T, id string) {
+				require.Equal(t, "lb-1:abc_123.4", id)
+			},
+		},
+		{
+			name:       "Untrusted client -> generated",
+			remoteAddr: "192.0.2.1:1234",
+			header:     "spoofed",
+			checkID: func(t *testing.T, id string) {
+				require.NotEqual(t, "spoofed", id)
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "Unsafe characters -> generated",
+			remoteAddr: "10.1.2.3:1234",
+			header:     "id\nforged log line",
+			checkID: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "Too long -> generated",
+			remoteAddr: "10.1.2.3:1234",
+			header:     strings.Repeat("a", maxRequestIDLength+1),
+			checkID: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requestID, err := RequestID([]string{"10.0.0.0/8", "127.0.0.1"})
+			require.NoError(t, err)
+
+			var inContext, inRequest, inStore string
+			router := gin.New()
+			router.ContextWithFallback = true
+			router.Use(requestID)
+			router.GET("/test", func(ctx *gin.Context) {
+				inContext = httpx.GetRequestIDFromContext(ctx)
+				inRequest = logx.RequestID(ctx.Request.Context())
+				// the store is given the gin context
+				inStore = logx.RequestID(ctx)
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tc.remoteAddr != "" {
+				request.RemoteAddr = tc.remoteAddr
+			}
+			if tc.header != "" {
+				request.Header.Set("X-Request-ID", tc.header)
+			}
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			id := recorder.Header().Get("X-Request-ID")
+			tc.checkID(t, id)
+			require.Equal(t, id, inContext)
+			require.Equal(t, id, inRequest)
+			require.Equal(t, id, inStore)
+		})
+	}
+}
+
+func TestRequestIDInvalidProxies(t *testing.T) {
+	_, err := RequestID([]string{"not an address"})
+	require.Error(t, err)
+
+	_, err = RequestID([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
+
+func TestRequestLogEnrichment(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	spaceID := uuid.New()
+
+	var buf bytes.Buffer
+	logger := logx.New(&buf, slog.LevelInfo)
+
+	requestID, err := RequestID(nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(requestID)
+	router.GET("/spaces/:spaceID", func(ctx *gin.Context) {
+		httpx.SetUserInContext(ctx, user)
+		logger.InfoContext(ctx, "handled")
+		ctx.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/spaces/"+spaceID.String(), nil)
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "handled", record["msg"])
+	require.Equal(t, recorder.Header().Get("X-Request-ID"), record[logx.KeyRequestID])
+	require.Equal(t, user.ID.String(), record[logx.KeyUserID])
+	require.Equal(t, spaceID.String(), record[logx.KeySpaceID])
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestLogger(), Recovery())
+	router.GET("/test", func(ctx *gin.Context) {
+		panic("handler failed")
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/test", nil)
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
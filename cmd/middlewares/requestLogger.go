@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger is middleware logging each request once it is responded to,
+// at error level when it failed on the server. Query strings are not logged,
+// they may hold tokens, the audit trail records them redacted.
+func RequestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		level := slog.LevelInfo
+		if ctx.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", ctx.Writer.Status()),
+			slog.Int("size", max(ctx.Writer.Size(), 0)),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if errs := ctx.Errors.String(); errs != "" {
+			attrs = append(attrs, slog.String("errors", errs))
+		}
+
+		slog.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Recovery is middleware responding 500 to the requests whose handlers
+// panic, the panic is logged with its stack
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, err any) {
+		slog.ErrorContext(ctx, "panic",
+			slog.String("error", fmt.Sprint(err)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
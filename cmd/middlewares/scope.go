@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// Scope limits what a request authenticated with an API key or an OAuth2
+// access token can do
+type Scope string
+
+const (
+	ScopeSpacesRead    Scope = "spaces:read"
+	ScopeSpacesWrite   Scope = "spaces:write"
+	ScopeSpacesDelete  Scope = "spaces:delete"
+	ScopeSpacesAdmin   Scope = "spaces:admin"
+	ScopeMessagesRead  Scope = "messages:read"
+	ScopeMessagesWrite Scope = "messages:write"
+)
+
+var scopes = []Scope{
+	ScopeSpacesRead,
+	ScopeSpacesWrite,
+	ScopeSpacesDelete,
+	ScopeSpacesAdmin,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+}
+
+// ParseScopes parses a space separated OAuth2 scope parameter
+func ParseScopes(scope string) ([]string, error) {
+	parsed := oauth.ParseScope(scope)
+	for _, s := range parsed {
+		if !slices.Contains(scopes, Scope(s)) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+	}
+
+	return parsed, nil
+}
+
+// RequireScope refuses requests authenticated with an API key or an OAuth2
+// access token without scope. Other requests are not restricted.
+func RequireScope(scope Scope) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !hasScope(ctx, scope) {
+			httpx.WriteError(ctx, http.StatusForbidden, fmt.Errorf("denied: %s scope required", scope))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RejectDelegatedAccess refuses requests authenticated with an API key or an
+// OAuth2 access token, for routes that manage the account itself
+func RejectDelegatedAccess() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_, hasAPIKey := httpx.GetAPIKeyFromContext(ctx)
+		_, hasOAuthToken := httpx.GetOAuthTokenFromContext(ctx)
+		if hasAPIKey || hasOAuthToken {
+			httpx.WriteError(
+				ctx,
+				http.StatusForbidden,
+				fmt.Errorf("denied: not available with an API key or an OAuth token"),
+			)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// hasScope reports whether the request is allowed by scope, requests not
+// authenticated with an API key or an OAuth2 access token have every scope
+func hasScope(ctx *gin.Context, scope Scope) bool {
+	if key, ok := httpx.GetAPIKeyFromContext(ctx); ok {
+		return slices.Contains(key.Scopes, string(scope))
+	}
+
+	if token, ok := httpx.GetOAuthTokenFromContext(ctx); ok {
+		return slices.Contains(token.Scopes(), string(scope))
+	}
+
+	return true
+}
+
+// hasSpace reports whether the request may access spaceID, only OAuth2
+// access tokens can be restricted to some spaces
+func hasSpace(ctx *gin.Context, spaceID uuid.UUID) bool {
+	token, ok := httpx.GetOAuthTokenFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	spaceIDs, err := token.Spaces()
+	if err != nil {
+		return false
+	}
+
+	return len(spaceIDs) == 0 || slices.Contains(spaceIDs, spaceID)
+}
+
+// input validator
+var ValidScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(Scope); ok {
+		return slices.Contains(scopes, scope)
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/httpx"
+	"github.com/Luckny/space-it/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireScope(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+
+	oauthToken := func(scopes ...string) *token.Payload {
+		payload, err := token.NewOAuthPayload(dto.NewUser(user), uuid.New(), uuid.New(), scopes, nil)
+		require.NoError(t, err)
+		return payload
+	}
+
+	testCases := []struct {
+		name       string
+		apiKey     *db.ApiKey
+		oauthToken *token.Payload
+		code       int
+	}{
+		{
+			name:   "key with scope",
+			apiKey: &db.ApiKey{Scopes: []string{string(ScopeSpacesWrite)}},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "key without scope",
+			apiKey: &db.ApiKey{Scopes: []string{string(ScopeSpacesRead)}},
+			code:   http.StatusForbidden,
+		},
+		{
+			name:       "oauth token with scope",
+			oauthToken: oauthToken(string(ScopeSpacesRead), string(ScopeSpacesWrite)),
+			code:       http.StatusOK,
+		},
+		{
+			name:       "oauth token without scope",
+			oauthToken: oauthToken(string(ScopeSpacesRead)),
+			code:       http.StatusForbidden,
+		},
+		{
+			name: "no key",
+			code: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.Default()
+			router.Use(func(c *gin.Context) {
+				httpx.SetUserInContext(c, user)
+				if tc.apiKey != nil {
+					httpx.SetAPIKeyInContext(c, *tc.apiKey)
+				}
+				if tc.oauthToken != nil {
+					httpx.SetOAuthTokenInContext(c, tc.oauthToken)
+				}
+				c.Next()
+			})
+			router.POST("/spaces", RequireScope(ScopeSpacesWrite), func(c *gin.Context) {
+				c.JSON(http.StatusOK, nil)
+			})
+
+			request, err := http.NewRequest(http.MethodPost, "/spaces", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
+
+func TestRejectDelegatedAccess(t *testing.T) {
+	user, _ := mockdb.RandomUser(t)
+	payload, err := token.NewOAuthPayload(
+		dto.NewUser(user),
+		uuid.New(),
+		uuid.New(),
+		[]string{string(ScopeSpacesAdmin)},
+		nil,
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		delegate func(c *gin.Context)
+		code     int
+	}{
+		{
+			name: "api key",
+			delegate: func(c *gin.Context) {
+				httpx.SetAPIKeyInContext(c, db.ApiKey{Scopes: []string{string(ScopeSpacesAdmin)}})
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "oauth token",
+			delegate: func(c *gin.Context) {
+				httpx.SetOAuthTokenInContext(c, payload)
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name:     "user credentials",
+			delegate: func(c *gin.Context) {},
+			code:     http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.Default()
+			router.Use(func(c *gin.Context) {
+				httpx.SetUserInContext(c, user)
+				tc.delegate(c)
+				c.Next()
+			})
+			router.GET("/users/me/sessions", RejectDelegatedAccess(), func(c *gin.Context) {
+				c.JSON(http.StatusOK, nil)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	parsed, err := ParseScopes("spaces:read messages:write")
+	require.NoError(t, err)
+	require.Equal(t, []string{"spaces:read", "messages:write"}, parsed)
+
+	_, err = ParseScopes("spaces:read users:admin")
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"golang.org/x/time/rate"
+)
+
+// how often PruneRateLimits deletes idle buckets when no interval is given
+const defaultRateLimitPruneInterval = time.Minute
+
+// StoreRateLimiter keeps the buckets in the database, so the limits hold
+// across server instances. Buckets are updated with the generic cell rate
+// algorithm in a single statement, concurrent requests can't both take the
+// last request of a bucket.
+//
+// Times come from the database clock, the clocks of the instances don't
+// need to agree.
+type StoreRateLimiter struct {
+	store db.Store
+	// tells apart the limiters sharing the table, e.g. the route group
+	bucket string
+	burst  int
+	// time between two requests at the limit rate
+	interval time.Duration
+}
+
+// NewStoreRateLimiter returns a rate limiter allowing limit requests per
+// second and bursts of burst requests for each key of bucket
+func NewStoreRateLimiter(store db.Store, bucket string, limit rate.Limit, burst int) *StoreRateLimiter {
+	return &StoreRateLimiter{
+		store:    store,
+		bucket:   bucket,
+		burst:    burst,
+		interval: time.Duration(float64(time.Second) / float64(limit)),
+	}
+}
+
+func (l *StoreRateLimiter) Take(ctx context.Context, key string) (RateLimit, error) {
+	row, err := l.store.TakeRateLimit(ctx, db.TakeRateLimitParams{
+		Bucket:           l.bucket,
+		Key:              key,
+		EmissionInterval: l.interval.Seconds(),
+		Tolerance:        l.tolerance().Seconds(),
+	})
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	// the bucket is full again once the tat is reached
+	ahead := time.Duration(row.Ahead * float64(time.Second))
+	res := RateLimit{
+		Allowed:   row.Allowed,
+		Limit:     l.burst,
+		Remaining: max(int(math.Floor(float64(l.tolerance()-ahead)/float64(l.interval))), 0),
+		Reset:     ahead,
+	}
+	if !res.Allowed {
+		res.RetryAfter = max(ahead+l.interval-l.tolerance(), 0)
+	}
+
+	return res, nil
+}
+
+// tolerance is how far ahead of now the tat can be after a request
+func (l *StoreRateLimiter) tolerance() time.Duration {
+	return time.Duration(l.burst) * l.interval
+}
+
+// PruneRateLimits deletes the idle buckets of all store rate limiters every
+// interval until the context is done. They are full, a new request creates
+// them again as they were.
+func PruneRateLimits(ctx context.Context, store db.Store, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultRateLimitPruneInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.DeleteIdleRateLimits(ctx); err != nil {
+				slog.ErrorContext(ctx, "rate limits: deleting idle limits", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/time/rate"
+)
+
+func TestStoreRateLimiter(t *testing.T) {
+	// 1 request every 10s, bursts of 3
+	params := db.TakeRateLimitParams{
+		Bucket:           "auth",
+		Key:              "ip:198.51.100.1",
+		EmissionInterval: 10,
+		Tolerance:        30,
+	}
+
+	testCases := []struct {
+		name     string
+		row      db.TakeRateLimitRow
+		err      error
+		expected RateLimit
+	}{
+		{
+			name: "first request",
+			row:  db.TakeRateLimitRow{Allowed: true, Ahead: 10},
+			expected: RateLimit{
+				Allowed:   true,
+				Limit:     3,
+				Remaining: 2,
+				Reset:     10 * time.Second,
+			},
+		},
+		{
+			name: "last request",
+			row:  db.TakeRateLimitRow{Allowed: true, Ahead: 29.5},
+			expected: RateLimit{
+				Allowed:   true,
+				Limit:     3,
+				Remaining: 0,
+				Reset:     29500 * time.Millisecond,
+			},
+		},
+		{
+			name: "throttled",
+			row:  db.TakeRateLimitRow{Allowed: false, Ahead: 25},
+			expected: RateLimit{
+				Allowed:    false,
+				Limit:      3,
+				Remaining:  0,
+				Reset:      25 * time.Second,
+				RetryAfter: 5 * time.Second,
+			},
+		},
+		{
+			name: "store error",
+			err:  sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().TakeRateLimit(gomock.Any(), gomock.Eq(params)).Times(1).Return(tc.row, tc.err)
+
+			limiter := NewStoreRateLimiter(store, params.Bucket, rate.Every(10*time.Second), 3)
+			res, err := limiter.Take(context.Background(), params.Key)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res)
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package middlewares
 
 import (
+	"time"
+
+	"github.com/Luckny/space-it/pkg/httpx"
 	"github.com/Luckny/space-it/pkg/token"
 	"github.com/gin-gonic/gin"
 )
 
 // Verify token
-func VerifyToken(maker token.Maker) gin.HandlerFunc {
+func VerifyToken(maker token.Maker, activity *token.ActivityTracker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// get token from header
 		tokenID := ctx.GetHeader("X-CSRF-Token")
@@ -24,7 +27,19 @@ func VerifyToken(maker token.Maker) gin.HandlerFunc {
 		}
 
 		// Token is valid
+		ctx.Set("token", token)
+		httpx.SetAuthMethodInContext(ctx, httpx.AuthMethodSession)
+		if token.SecondFactorPending() {
+			// only accepted to verify the second factor
+			httpx.SetPendingUserInContext(ctx, token.User)
+			ctx.Next()
+			return
+		}
+
 		ctx.Set("user", &token.User)
+
+		// last seen is buffered and written in batches
+		activity.Touch(token.ID, time.Now())
 		ctx.Next()
 
 	}
@@ -0,0 +1,18 @@
+CREATE TABLE "sessions" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL,
+  "user_agent" varchar(255) NOT NULL DEFAULT '',
+  "client_ip" varchar(45) NOT NULL DEFAULT '',
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "last_seen_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON sessions TO space_it_api;
+
+CREATE INDEX ON "sessions" ("user_id");
+
+CREATE INDEX ON "sessions" ("user_id", "revoked_at");
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,25 @@
+CREATE TABLE "user_totp" (
+  "user_id" uuid PRIMARY KEY,
+  "secret" varchar(64) NOT NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "confirmed_at" timestamp DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON user_totp TO space_it_api;
+
+CREATE TABLE "recovery_codes" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid NOT NULL,
+  "code_hash" varchar(64) NOT NULL,
+  "used_at" timestamp DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON recovery_codes TO space_it_api;
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("user_id", "code_hash");
+
+ALTER TABLE "user_totp" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,31 @@
+CREATE TABLE "passkeys" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid NOT NULL,
+  "credential_id" bytea UNIQUE NOT NULL,
+  "public_key" bytea NOT NULL,
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "name" varchar(64) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "last_used_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON passkeys TO space_it_api;
+
+CREATE INDEX ON "passkeys" ("user_id");
+
+ALTER TABLE "passkeys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+-- user_id is only set for registrations, login challenges are not bound to a user
+CREATE TABLE "webauthn_challenges" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid DEFAULT NULL,
+  "challenge" bytea NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL
+);
+
+GRANT SELECT, INSERT, DELETE ON webauthn_challenges TO space_it_api;
+
+CREATE INDEX ON "webauthn_challenges" ("expires_at");
+
+ALTER TABLE "webauthn_challenges" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
@@ -0,0 +1,18 @@
+CREATE TABLE "api_keys" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid NOT NULL,
+  "name" varchar(64) NOT NULL,
+  "prefix" varchar(16) NOT NULL,
+  "key_hash" varchar(64) UNIQUE NOT NULL,
+  "scopes" varchar(32)[] NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "last_used_at" timestamp DEFAULT NULL,
+  "expires_at" timestamp DEFAULT NULL,
+  "revoked_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON api_keys TO space_it_api;
+
+CREATE INDEX ON "api_keys" ("user_id");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
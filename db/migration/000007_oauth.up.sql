@@ -0,0 +1,84 @@
+-- secret_hash is NULL for public clients, which can only use the
+-- authorization code grant with PKCE
+CREATE TABLE "oauth_clients" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "owner_id" uuid NOT NULL,
+  "name" varchar(64) NOT NULL,
+  "secret_hash" varchar(64) DEFAULT NULL,
+  "redirect_uris" varchar(255)[] NOT NULL,
+  "scopes" varchar(32)[] NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "revoked_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON oauth_clients TO space_it_api;
+
+CREATE INDEX ON "oauth_clients" ("owner_id");
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("owner_id") REFERENCES "users" ("id");
+
+CREATE TABLE "oauth_authorization_codes" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "code_hash" varchar(64) UNIQUE NOT NULL,
+  "client_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "redirect_uri" varchar(255) NOT NULL,
+  "scopes" varchar(32)[] NOT NULL,
+  "space_ids" uuid[] NOT NULL,
+  "code_challenge" varchar(64) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL
+);
+
+GRANT SELECT, INSERT, DELETE ON oauth_authorization_codes TO space_it_api;
+
+CREATE INDEX ON "oauth_authorization_codes" ("expires_at");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+-- tokens issued from the same authorization share a grant id, so the whole
+-- grant can be revoked when a rotated refresh token is reused.
+-- An empty space_ids means the token is not restricted to some spaces.
+CREATE TABLE "oauth_access_tokens" (
+  "id" uuid PRIMARY KEY,
+  "grant_id" uuid NOT NULL,
+  "token_hash" varchar(64) UNIQUE NOT NULL,
+  "client_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "scopes" varchar(32)[] NOT NULL,
+  "space_ids" uuid[] NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON oauth_access_tokens TO space_it_api;
+
+CREATE INDEX ON "oauth_access_tokens" ("grant_id");
+
+ALTER TABLE "oauth_access_tokens" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_access_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+CREATE TABLE "oauth_refresh_tokens" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "grant_id" uuid NOT NULL,
+  "token_hash" varchar(64) UNIQUE NOT NULL,
+  "client_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "scopes" varchar(32)[] NOT NULL,
+  "space_ids" uuid[] NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON oauth_refresh_tokens TO space_it_api;
+
+CREATE INDEX ON "oauth_refresh_tokens" ("grant_id");
+
+ALTER TABLE "oauth_refresh_tokens" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_refresh_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
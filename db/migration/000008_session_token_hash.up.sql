@@ -0,0 +1,4 @@
+-- hash of the token given with the session cookie, so other services can
+-- introspect session tokens without the cookie. It is NULL for sessions
+-- waiting for a second factor, which can't be introspected.
+ALTER TABLE "sessions" ADD COLUMN "token_hash" varchar(64) UNIQUE DEFAULT NULL;
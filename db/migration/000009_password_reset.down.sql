@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "password_reset_tokens";
+
+REVOKE UPDATE ON users FROM space_it_api;
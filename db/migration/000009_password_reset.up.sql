@@ -0,0 +1,17 @@
+-- passwords can be changed and reset
+GRANT UPDATE ON users TO space_it_api;
+
+CREATE TABLE "password_reset_tokens" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid NOT NULL,
+  "token_hash" varchar(64) UNIQUE NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON password_reset_tokens TO space_it_api;
+
+CREATE INDEX ON "password_reset_tokens" ("user_id");
+
+ALTER TABLE "password_reset_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,17 @@
+-- accounts created before verification existed have an unverified email too
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamp DEFAULT NULL;
+
+CREATE TABLE "email_verification_tokens" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "user_id" uuid NOT NULL,
+  "token_hash" varchar(64) UNIQUE NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp DEFAULT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE ON email_verification_tokens TO space_it_api;
+
+CREATE INDEX ON "email_verification_tokens" ("user_id", "created_at");
+
+ALTER TABLE "email_verification_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
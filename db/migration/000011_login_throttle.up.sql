@@ -0,0 +1,31 @@
+-- failed password attempts per account and client IP. The email is empty
+-- for the attempts of a client IP across accounts.
+CREATE TABLE "login_throttles" (
+  "email" varchar(255) NOT NULL,
+  "client_ip" varchar(45) NOT NULL,
+  "failures" int NOT NULL DEFAULT 0,
+  "blocked_until" timestamp NOT NULL DEFAULT (now()),
+  "updated_at" timestamp NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("email", "client_ip")
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON login_throttles TO space_it_api;
+
+CREATE INDEX ON "login_throttles" ("client_ip");
+
+-- audit of the lockouts and of their end by an admin
+CREATE TABLE "login_lockouts" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "email" varchar(255) NOT NULL,
+  "client_ip" varchar(45) NOT NULL,
+  "failures" int NOT NULL,
+  "locked_until" timestamp NOT NULL,
+  "unlocked_at" timestamp DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT, UPDATE ON login_lockouts TO space_it_api;
+
+CREATE INDEX ON "login_lockouts" ("email");
+
+CREATE INDEX ON "login_lockouts" ("client_ip");
@@ -0,0 +1,4 @@
+REVOKE UPDATE, DELETE ON permissions FROM space_it_api;
+REVOKE DELETE ON messages FROM space_it_api;
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deleted_at";
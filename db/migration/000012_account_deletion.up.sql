@@ -0,0 +1,8 @@
+-- deleted users are anonymized instead of removed, so the request logs
+-- referencing them stay intact
+ALTER TABLE "users" ADD COLUMN "deleted_at" timestamp DEFAULT NULL;
+
+-- memberships are removed, and spaces deleted with their messages, when
+-- their user deletes their account
+GRANT UPDATE, DELETE ON permissions TO space_it_api;
+GRANT DELETE ON messages TO space_it_api;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "permissions_space_id_idx";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "locale";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "timezone";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "avatar";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "display_name";
+
+-- fails if a longer email was registered since
+ALTER TABLE "users" ALTER COLUMN "email" TYPE varchar(30);
@@ -0,0 +1,11 @@
+-- real addresses can be up to 254 characters
+ALTER TABLE "users" ALTER COLUMN "email" TYPE varchar(255);
+
+-- the avatar is the URL of an image chosen by the user, empty when none
+ALTER TABLE "users" ADD COLUMN "display_name" varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE "users" ADD COLUMN "avatar" varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE "users" ADD COLUMN "timezone" varchar(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE "users" ADD COLUMN "locale" varchar(35) NOT NULL DEFAULT 'en';
+
+-- users sharing a space are looked up by space
+CREATE INDEX ON "permissions" ("space_id");
@@ -0,0 +1,13 @@
+-- rate limits shared by the server instances, one row per route group and
+-- client. tat is the theoretical arrival time of the generic cell rate
+-- algorithm, a row with a past tat is a full bucket and can be dropped.
+CREATE TABLE "rate_limits" (
+  "bucket" varchar(16) NOT NULL,
+  "key" varchar(64) NOT NULL,
+  "tat" timestamp NOT NULL,
+  PRIMARY KEY ("bucket", "key")
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON rate_limits TO space_it_api;
+
+CREATE INDEX ON "rate_limits" ("tat");
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS "request_log_request_id_idx";
+DROP INDEX IF EXISTS "request_log_space_id_idx";
+
+ALTER TABLE "response_log" DROP COLUMN IF EXISTS "outcome";
+ALTER TABLE "response_log" DROP COLUMN IF EXISTS "latency_us";
+ALTER TABLE "response_log" DROP COLUMN IF EXISTS "size";
+
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "request_id";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "space_id";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "auth_method";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "size";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "query";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "user_agent";
+ALTER TABLE "request_log" DROP COLUMN IF EXISTS "client_ip";
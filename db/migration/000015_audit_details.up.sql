@@ -0,0 +1,18 @@
+-- who made the request and how, secrets in the query are redacted. The
+-- request id correlates the row with the logs of the request.
+ALTER TABLE "request_log" ADD COLUMN "client_ip" varchar(45) NOT NULL DEFAULT '';
+ALTER TABLE "request_log" ADD COLUMN "user_agent" varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE "request_log" ADD COLUMN "query" varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE "request_log" ADD COLUMN "size" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "request_log" ADD COLUMN "auth_method" varchar(16) NOT NULL DEFAULT '';
+ALTER TABLE "request_log" ADD COLUMN "space_id" uuid DEFAULT NULL;
+ALTER TABLE "request_log" ADD COLUMN "request_id" varchar(64) NOT NULL DEFAULT '';
+
+-- latency is measured from the request to the end of the response
+ALTER TABLE "response_log" ADD COLUMN "size" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "response_log" ADD COLUMN "latency_us" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "response_log" ADD COLUMN "outcome" varchar(16) NOT NULL DEFAULT '';
+
+-- the space id has no foreign key, logs outlive deleted spaces
+CREATE INDEX ON "request_log" ("space_id");
+CREATE INDEX ON "request_log" ("request_id");
@@ -0,0 +1,21 @@
+-- every audit entry written from now on is chained to the one before it by
+-- seq, its hash covering its request, its response and the hash of the
+-- previous entry. Entries written before have no link and aren't verified.
+CREATE TABLE "audit_chain" (
+  "seq" bigint PRIMARY KEY,
+  "id" uuid UNIQUE NOT NULL,
+  "hash" bytea NOT NULL
+);
+
+GRANT SELECT, INSERT ON audit_chain TO space_it_api;
+
+-- signed hashes of the chain, entries changed before a checkpoint or
+-- removed after it no longer match it
+CREATE TABLE "audit_checkpoints" (
+  "seq" bigint PRIMARY KEY,
+  "hash" bytea NOT NULL,
+  "signature" bytea NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT ON audit_checkpoints TO space_it_api;
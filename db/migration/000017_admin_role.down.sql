@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "response_log_id_idx";
+DROP INDEX IF EXISTS "request_log_user_id_created_at_idx";
+DROP INDEX IF EXISTS "request_log_created_at_id_idx";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_admin";
@@ -0,0 +1,9 @@
+-- system administrators read the audit trail, the role is granted and
+-- revoked with "admin admins grant|revoke"
+ALTER TABLE "users" ADD COLUMN "is_admin" boolean NOT NULL DEFAULT false;
+
+-- audit entries are listed newest first, a page after another, and their
+-- responses looked up by request
+CREATE INDEX ON "request_log" ("created_at", "id");
+CREATE INDEX ON "request_log" ("user_id", "created_at");
+CREATE INDEX ON "response_log" ("id");
@@ -0,0 +1,18 @@
+-- what happened in a space and who did it, for its admins. The subject is
+-- the member an event is about, if any. Events outlive their space and are
+-- correlated with the audit entry of their request by request id.
+CREATE TABLE "space_events" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "space_id" uuid NOT NULL,
+  "type" varchar(32) NOT NULL,
+  "actor_id" uuid NOT NULL,
+  "subject_id" uuid DEFAULT NULL,
+  "details" jsonb NOT NULL DEFAULT '{}',
+  "request_id" varchar(64) NOT NULL DEFAULT '',
+  -- events of a transaction are told apart by the time they happened at
+  "created_at" timestamp NOT NULL DEFAULT (clock_timestamp())
+);
+
+GRANT SELECT, INSERT ON space_events TO space_it_api;
+
+CREATE INDEX ON "space_events" ("space_id", "created_at", "id");
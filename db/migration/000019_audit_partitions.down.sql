@@ -0,0 +1,55 @@
+-- the logs still in the database go back to unpartitioned tables, archived
+-- months stay in their files
+CREATE TABLE "request_log_unpartitioned" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "method" varchar(10) NOT NULL,
+  "path" varchar(100) NOT NULL,
+  "user_id" uuid DEFAULT NULL REFERENCES "users" ("id"),
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "client_ip" varchar(45) NOT NULL DEFAULT '',
+  "user_agent" varchar(255) NOT NULL DEFAULT '',
+  "query" varchar(255) NOT NULL DEFAULT '',
+  "size" bigint NOT NULL DEFAULT 0,
+  "auth_method" varchar(16) NOT NULL DEFAULT '',
+  "space_id" uuid DEFAULT NULL,
+  "request_id" varchar(64) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE "response_log_unpartitioned" (
+  "id" uuid NOT NULL REFERENCES "request_log_unpartitioned" ("id"),
+  "status" int NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "size" bigint NOT NULL DEFAULT 0,
+  "latency_us" bigint NOT NULL DEFAULT 0,
+  "outcome" varchar(16) NOT NULL DEFAULT ''
+);
+
+INSERT INTO request_log_unpartitioned SELECT
+  id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id
+FROM request_log;
+
+INSERT INTO response_log_unpartitioned SELECT
+  id, status, created_at, size, latency_us, outcome
+FROM response_log;
+
+DROP FUNCTION IF EXISTS drop_audit_log_partitions(timestamp);
+DROP FUNCTION IF EXISTS create_audit_log_partitions(timestamp);
+DROP TABLE IF EXISTS "audit_archives";
+DROP TABLE "response_log";
+DROP TABLE "request_log";
+
+ALTER TABLE "request_log_unpartitioned" RENAME TO "request_log";
+ALTER TABLE "response_log_unpartitioned" RENAME TO "response_log";
+
+GRANT SELECT, INSERT ON request_log TO space_it_api;
+GRANT SELECT, INSERT ON response_log TO space_it_api;
+
+CREATE INDEX ON "request_log" ("path");
+CREATE INDEX ON "request_log" ("method");
+CREATE INDEX ON "request_log" ("path", "method");
+CREATE INDEX ON "request_log" ("space_id");
+CREATE INDEX ON "request_log" ("request_id");
+CREATE INDEX ON "request_log" ("created_at", "id");
+CREATE INDEX ON "request_log" ("user_id", "created_at");
+CREATE INDEX ON "response_log" ("id");
+CREATE INDEX ON "response_log" ("status");
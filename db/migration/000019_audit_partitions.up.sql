@@ -0,0 +1,133 @@
+-- audit logs are partitioned by month so that old months are archived and
+-- dropped at once. The primary key of a partitioned table holds its
+-- partition key, and responses no longer reference their request: both are
+-- written in the same transaction.
+ALTER TABLE "request_log" RENAME TO "request_log_unpartitioned";
+ALTER TABLE "response_log" RENAME TO "response_log_unpartitioned";
+
+CREATE TABLE "request_log" (
+  "id" uuid NOT NULL DEFAULT (gen_random_uuid()),
+  "method" varchar(10) NOT NULL,
+  "path" varchar(100) NOT NULL,
+  "user_id" uuid DEFAULT NULL REFERENCES "users" ("id"),
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "client_ip" varchar(45) NOT NULL DEFAULT '',
+  "user_agent" varchar(255) NOT NULL DEFAULT '',
+  "query" varchar(255) NOT NULL DEFAULT '',
+  "size" bigint NOT NULL DEFAULT 0,
+  "auth_method" varchar(16) NOT NULL DEFAULT '',
+  "space_id" uuid DEFAULT NULL,
+  "request_id" varchar(64) NOT NULL DEFAULT '',
+  PRIMARY KEY ("id", "created_at")
+) PARTITION BY RANGE ("created_at");
+
+CREATE TABLE "response_log" (
+  "id" uuid NOT NULL,
+  "status" int NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "size" bigint NOT NULL DEFAULT 0,
+  "latency_us" bigint NOT NULL DEFAULT 0,
+  "outcome" varchar(16) NOT NULL DEFAULT ''
+) PARTITION BY RANGE ("created_at");
+
+GRANT SELECT, INSERT ON request_log TO space_it_api;
+GRANT SELECT, INSERT ON response_log TO space_it_api;
+
+CREATE INDEX ON "request_log" ("path");
+CREATE INDEX ON "request_log" ("path", "method");
+CREATE INDEX ON "request_log" ("method");
+CREATE INDEX ON "request_log" ("space_id");
+CREATE INDEX ON "request_log" ("request_id");
+CREATE INDEX ON "request_log" ("created_at", "id");
+CREATE INDEX ON "request_log" ("user_id", "created_at");
+CREATE INDEX ON "response_log" ("id");
+CREATE INDEX ON "response_log" ("status");
+
+-- entries of months without a partition yet, kept empty by creating the
+-- partitions of the coming months ahead
+CREATE TABLE "request_log_default" PARTITION OF "request_log" DEFAULT;
+CREATE TABLE "response_log_default" PARTITION OF "response_log" DEFAULT;
+
+-- months of audit logs no longer in the database, archived to the file at
+-- path, or dropped when it is empty. Their entries are left out of the
+-- chain between first_seq and last_seq, the hashes of the chain are kept.
+CREATE TABLE "audit_archives" (
+  "month" timestamp PRIMARY KEY,
+  "path" text NOT NULL DEFAULT '',
+  -- SHA-256 of the file
+  "checksum" bytea DEFAULT NULL,
+  "entries" bigint NOT NULL,
+  "chained" bigint NOT NULL,
+  "first_seq" bigint NOT NULL,
+  "last_seq" bigint NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT ON audit_archives TO space_it_api;
+
+-- the API can't create nor drop tables, it manages the partitions with
+-- these functions, run as their owner. A month is dropped once archived and
+-- the current month never is. Logs are timestamped in UTC.
+CREATE FUNCTION create_audit_log_partitions(month timestamp) RETURNS void
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public AS $$
+DECLARE
+  from_at timestamp := date_trunc('month', month);
+  suffix text := to_char(from_at, '"y"YYYY"m"MM');
+BEGIN
+  EXECUTE format(
+    'CREATE TABLE IF NOT EXISTS %I PARTITION OF request_log FOR VALUES FROM (%L) TO (%L)',
+    'request_log_' || suffix, from_at, from_at + interval '1 month'
+  );
+  EXECUTE format(
+    'CREATE TABLE IF NOT EXISTS %I PARTITION OF response_log FOR VALUES FROM (%L) TO (%L)',
+    'response_log_' || suffix, from_at, from_at + interval '1 month'
+  );
+END;
+$$;
+
+CREATE FUNCTION drop_audit_log_partitions(month timestamp) RETURNS void
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public AS $$
+DECLARE
+  from_at timestamp := date_trunc('month', month);
+  suffix text := to_char(from_at, '"y"YYYY"m"MM');
+BEGIN
+  IF from_at >= date_trunc('month', now() AT TIME ZONE 'UTC') THEN
+    RAISE EXCEPTION 'audit logs of the current month can''t be dropped';
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM audit_archives WHERE audit_archives.month = from_at) THEN
+    RAISE EXCEPTION 'audit logs of % are not archived', from_at;
+  END IF;
+
+  EXECUTE format('DROP TABLE IF EXISTS %I', 'request_log_' || suffix);
+  EXECUTE format('DROP TABLE IF EXISTS %I', 'response_log_' || suffix);
+END;
+$$;
+
+REVOKE ALL ON FUNCTION create_audit_log_partitions(timestamp) FROM PUBLIC;
+REVOKE ALL ON FUNCTION drop_audit_log_partitions(timestamp) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION create_audit_log_partitions(timestamp) TO space_it_api;
+GRANT EXECUTE ON FUNCTION drop_audit_log_partitions(timestamp) TO space_it_api;
+
+-- the months logged so far and the coming one get their partitions before
+-- the logs are copied
+SELECT create_audit_log_partitions(month)
+FROM generate_series(
+  date_trunc('month', LEAST(
+    (SELECT min(created_at) FROM request_log_unpartitioned),
+    (SELECT min(created_at) FROM response_log_unpartitioned),
+    now() AT TIME ZONE 'UTC'
+  )),
+  date_trunc('month', now() AT TIME ZONE 'UTC') + interval '1 month',
+  interval '1 month'
+) AS month;
+
+INSERT INTO request_log SELECT
+  id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id
+FROM request_log_unpartitioned;
+
+INSERT INTO response_log SELECT
+  id, status, created_at, size, latency_us, outcome
+FROM response_log_unpartitioned;
+
+DROP TABLE "response_log_unpartitioned";
+DROP TABLE "request_log_unpartitioned";
@@ -0,0 +1,13 @@
+-- failed second factor attempts per user, across their pending logins. The
+-- second factor is refused until locked_until once too many fail, logging in
+-- again with the password doesn't give more attempts.
+CREATE TABLE "second_factor_throttles" (
+  "user_id" uuid PRIMARY KEY,
+  "failures" int NOT NULL DEFAULT 0,
+  "locked_until" timestamp NOT NULL DEFAULT (now()),
+  "updated_at" timestamp NOT NULL DEFAULT (now())
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON second_factor_throttles TO space_it_api;
+
+ALTER TABLE "second_factor_throttles" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
@@ -0,0 +1,35 @@
+-- responses go back to being partitioned by their own time, in the
+-- partitions of the months requests are partitioned by
+CREATE TABLE "response_log_copy" AS
+SELECT id, status, created_at, size, latency_us, outcome
+FROM response_log;
+
+DROP TABLE "response_log";
+
+CREATE TABLE "response_log" (
+  "id" uuid NOT NULL,
+  "status" int NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "size" bigint NOT NULL DEFAULT 0,
+  "latency_us" bigint NOT NULL DEFAULT 0,
+  "outcome" varchar(16) NOT NULL DEFAULT ''
+) PARTITION BY RANGE ("created_at");
+
+GRANT SELECT, INSERT ON response_log TO space_it_api;
+
+CREATE INDEX ON "response_log" ("id");
+CREATE INDEX ON "response_log" ("status");
+
+CREATE TABLE "response_log_default" PARTITION OF "response_log" DEFAULT;
+
+SELECT create_audit_log_partitions(to_timestamp(substring(c.relname FROM '\d{4}m\d{2}$'), 'YYYY"m"MM')::timestamp)
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'request_log'::regclass
+AND c.relname ~ '_y\d{4}m\d{2}$';
+
+INSERT INTO response_log SELECT
+  id, status, created_at, size, latency_us, outcome
+FROM response_log_copy;
+
+DROP TABLE "response_log_copy";
@@ -0,0 +1,42 @@
+-- responses are partitioned by the time of their request rather than their
+-- own, so that a request and its response always share the month archived
+-- and dropped at once, even when it is responded to after the month is over.
+-- The partition key of a table can't change: responses are copied aside and
+-- written back to the new table, those whose request was dropped already
+-- are left out.
+CREATE TABLE "response_log_copy" AS
+SELECT s.id, r.created_at AS requested_at, s.status, s.created_at, s.size, s.latency_us, s.outcome
+FROM response_log s
+JOIN request_log r ON r.id = s.id;
+
+DROP TABLE "response_log";
+
+CREATE TABLE "response_log" (
+  "id" uuid NOT NULL,
+  "requested_at" timestamp NOT NULL DEFAULT (now()),
+  "status" int NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "size" bigint NOT NULL DEFAULT 0,
+  "latency_us" bigint NOT NULL DEFAULT 0,
+  "outcome" varchar(16) NOT NULL DEFAULT ''
+) PARTITION BY RANGE ("requested_at");
+
+GRANT SELECT, INSERT ON response_log TO space_it_api;
+
+CREATE INDEX ON "response_log" ("id");
+CREATE INDEX ON "response_log" ("status");
+
+CREATE TABLE "response_log_default" PARTITION OF "response_log" DEFAULT;
+
+-- the months requests are partitioned by get their responses partitions back
+SELECT create_audit_log_partitions(to_timestamp(substring(c.relname FROM '\d{4}m\d{2}$'), 'YYYY"m"MM')::timestamp)
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'request_log'::regclass
+AND c.relname ~ '_y\d{4}m\d{2}$';
+
+INSERT INTO response_log SELECT
+  id, requested_at, status, created_at, size, latency_us, outcome
+FROM response_log_copy;
+
+DROP TABLE "response_log_copy";
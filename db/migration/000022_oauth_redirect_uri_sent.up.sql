@@ -0,0 +1,5 @@
+-- whether the client sent the redirect uri of the code to the authorization
+-- endpoint, or left it out for the one it registered. The token request
+-- must repeat it only when it was sent (RFC 6749, section 4.1.3). Codes
+-- issued before are short lived, they keep requiring it.
+ALTER TABLE "oauth_authorization_codes" ADD COLUMN "redirect_uri_sent" boolean NOT NULL DEFAULT true;
@@ -0,0 +1,4 @@
+-- whether the last request taken from the bucket was allowed. Requests
+-- update the row whether they are allowed or not, so that it is returned to
+-- concurrent first requests on a key too, and this tells them apart.
+ALTER TABLE "rate_limits" ADD COLUMN "allowed" boolean NOT NULL DEFAULT true;
@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS archive_audit_logs(timestamp, text, bytea);
+
+GRANT INSERT ON audit_archives TO space_it_api;
+GRANT EXECUTE ON FUNCTION drop_audit_log_partitions(timestamp) TO space_it_api;
@@ -0,0 +1,38 @@
+-- months are archived by a function counting their entries and the range of
+-- the chain they are linked in itself. The API could otherwise record the
+-- archive of any range of the chain and drop the month, verifying the chain
+-- would skip the range as archived.
+REVOKE INSERT ON audit_archives FROM space_it_api;
+REVOKE EXECUTE ON FUNCTION drop_audit_log_partitions(timestamp) FROM space_it_api;
+
+-- records the archive of the month, in the file at archive_path when given,
+-- then drops the month. It returns the number of entries archived.
+CREATE FUNCTION archive_audit_logs(archive_month timestamp, archive_path text, archive_checksum bytea) RETURNS bigint
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public AS $$
+DECLARE
+  from_at timestamp := date_trunc('month', archive_month);
+  archived bigint;
+BEGIN
+  INSERT INTO audit_archives (month, path, checksum, entries, chained, first_seq, last_seq)
+  SELECT
+    from_at,
+    archive_path,
+    archive_checksum,
+    count(*),
+    count(c.seq),
+    COALESCE(min(c.seq), 0),
+    COALESCE(max(c.seq), 0)
+  FROM request_log r
+  JOIN response_log s ON s.id = r.id AND s.requested_at = r.created_at
+  LEFT JOIN audit_chain c ON c.id = r.id
+  WHERE r.created_at >= from_at
+  AND r.created_at < from_at + interval '1 month'
+  RETURNING entries INTO archived;
+
+  PERFORM drop_audit_log_partitions(from_at);
+  RETURN archived;
+END;
+$$;
+
+REVOKE ALL ON FUNCTION archive_audit_logs(timestamp, text, bytea) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION archive_audit_logs(timestamp, text, bytea) TO space_it_api;
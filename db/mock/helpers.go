@@ -5,6 +5,8 @@ import (
 	"time"
 
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/Luckny/space-it/pkg/webauthn/webauthntest"
 	"github.com/Luckny/space-it/util"
 	uuid "github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -69,3 +71,44 @@ func RandomSpaceTxResult(t *testing.T, userId uuid.UUID) db.CreateSpaceTxResult
 		Permission: perm,
 	}
 }
+
+// RandomSession generates a random active db.Session object
+func RandomSession(t *testing.T, userID uuid.UUID) db.Session {
+	now := time.Now()
+
+	return db.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		UserAgent:  "Mozilla/5.0",
+		ClientIp:   "127.0.0.1",
+		CreatedAt:  pgtype.Timestamp{Time: now, Valid: true},
+		LastSeenAt: pgtype.Timestamp{Time: now, Valid: true},
+		ExpiresAt:  pgtype.Timestamp{Time: now.Add(time.Hour), Valid: true},
+	}
+}
+
+// RandomPasskey registers a credential of authenticator for the user and
+// returns the db.Passkey that would be stored
+func RandomPasskey(t *testing.T, userID uuid.UUID, authenticator *webauthntest.Authenticator) db.Passkey {
+	rp, err := webauthn.NewRelyingParty(authenticator.RelyingPartyID, "", []string{authenticator.Origin})
+	require.NoError(t, err)
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	reg, err := authenticator.Register(challenge, userID[:])
+	require.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(challenge, reg.ClientDataJSON, reg.AttestationObject)
+	require.NoError(t, err)
+
+	return db.Passkey{
+		ID:           uuid.New(),
+		UserID:       userID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Name:         "security key",
+		CreatedAt:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+}
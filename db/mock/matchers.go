@@ -6,6 +6,7 @@ import (
 
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/util"
+	"github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -36,107 +37,53 @@ func EqRegisterUserParams(arg db.RegisterUserParams) gomock.Matcher {
 	return eqRegisterUserParamsMatcher{arg}
 }
 
-// ----> Audit loggin matchers
-
-// Unauthenticated Request matchers
-
-type eqUnauthenticatedLogParam struct {
-	arg db.CreateUnauthenticatedRequestLogParams
-}
-
-func (e eqUnauthenticatedLogParam) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUnauthenticatedRequestLogParams)
-	if !ok {
-		return false
-	}
-
-	return reflect.DeepEqual(e.arg.Method, arg.Method) &&
-		reflect.DeepEqual(e.arg.Path, arg.Path)
-}
-
-func (e eqUnauthenticatedLogParam) String() string {
-	return fmt.Sprintf("matches arg %v", e.arg)
-}
-
-func EqUnauthenticatedLogParam(
-	arg db.CreateUnauthenticatedRequestLogParams,
-) gomock.Matcher {
-	return eqUnauthenticatedLogParam{arg}
-}
-
-// Authenticated request matcher
-type eqAuthenticatedLogParam struct {
-	arg db.CreateAuthenticatedRequestLogParams
-}
-
-func (e eqAuthenticatedLogParam) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateAuthenticatedRequestLogParams)
-	if !ok {
-		return false
-	}
-
-	return reflect.DeepEqual(e.arg.Method, arg.Method) &&
-		reflect.DeepEqual(e.arg.Path, arg.Path)
-}
-
-func (e eqAuthenticatedLogParam) String() string {
-	return fmt.Sprintf("matches arg %v", e.arg)
-}
-
-func EqAuthenticatedLogParam(
-	arg db.CreateAuthenticatedRequestLogParams,
-) gomock.Matcher {
-	return eqAuthenticatedLogParam{arg}
-}
-
-// Response Log matcher
+// Create space matcher
 
-type eqResponseLogParam struct {
-	arg db.CreateResponseLogParams
+type eqCreateSpaceTxParam struct {
+	arg db.CreateSpaceTxParams
 }
 
-func (e eqResponseLogParam) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateResponseLogParams)
+func (e eqCreateSpaceTxParam) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateSpaceTxParams)
 	if !ok {
 		return false
 	}
 
-	return reflect.DeepEqual(e.arg.Status, arg.Status) &&
-		reflect.DeepEqual(e.arg.ID, arg.ID)
+	return reflect.DeepEqual(e.arg.Name, arg.Name) &&
+		reflect.DeepEqual(e.arg.Owner, arg.Owner)
 }
 
-func (e eqResponseLogParam) String() string {
+func (e eqCreateSpaceTxParam) String() string {
 	return fmt.Sprintf("matches arg %v", e.arg)
 }
 
-func EqResponseLogParam(
-	arg db.CreateResponseLogParams,
+func EqCreateSpaceTxParam(
+	arg db.CreateSpaceTxParams,
 ) gomock.Matcher {
-	return eqResponseLogParam{arg}
+	return eqCreateSpaceTxParam{arg}
 }
 
-// Create space matcher
+// Create session matcher
 
-type eqCreateSpaceTxParam struct {
-	arg db.CreateSpaceTxParams
+type eqCreateSessionParams struct {
+	userID uuid.UUID
 }
 
-func (e eqCreateSpaceTxParam) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateSpaceTxParams)
+func (e eqCreateSessionParams) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateSessionParams)
 	if !ok {
 		return false
 	}
 
-	return reflect.DeepEqual(e.arg.Name, arg.Name) &&
-		reflect.DeepEqual(e.arg.Owner, arg.Owner)
+	return reflect.DeepEqual(e.userID, arg.UserID) &&
+		arg.ID != uuid.Nil &&
+		arg.ExpiresAt.Valid
 }
 
-func (e eqCreateSpaceTxParam) String() string {
-	return fmt.Sprintf("matches arg %v", e.arg)
+func (e eqCreateSessionParams) String() string {
+	return fmt.Sprintf("matches session of user %v", e.userID)
 }
 
-func EqCreateSpaceTxParam(
-	arg db.CreateSpaceTxParams,
-) gomock.Matcher {
-	return eqCreateSpaceTxParam{arg}
+func EqCreateSessionParams(userID uuid.UUID) gomock.Matcher {
+	return eqCreateSessionParams{userID}
 }
@@ -15,6 +15,7 @@ import (
 
 	db "github.com/Luckny/space-it/db/sqlc"
 	uuid "github.com/google/uuid"
+	pgtype "github.com/jackc/pgx/v5/pgtype"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -41,258 +42,1831 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AddSpaceMemberTx mocks base method.
+func (m *MockStore) AddSpaceMemberTx(arg0 context.Context, arg1 db.AddSpaceMemberTxParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSpaceMemberTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddSpaceMemberTx indicates an expected call of AddSpaceMemberTx.
+func (mr *MockStoreMockRecorder) AddSpaceMemberTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSpaceMemberTx", reflect.TypeOf((*MockStore)(nil).AddSpaceMemberTx), arg0, arg1)
+}
+
+// AnonymizeUser mocks base method.
+func (m *MockStore) AnonymizeUser(arg0 context.Context, arg1 uuid.UUID) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnonymizeUser indicates an expected call of AnonymizeUser.
+func (mr *MockStoreMockRecorder) AnonymizeUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeUser", reflect.TypeOf((*MockStore)(nil).AnonymizeUser), arg0, arg1)
+}
+
+// ArchiveAuditLogs mocks base method.
+func (m *MockStore) ArchiveAuditLogs(arg0 context.Context, arg1 db.ArchiveAuditLogsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveAuditLogs", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveAuditLogs indicates an expected call of ArchiveAuditLogs.
+func (mr *MockStoreMockRecorder) ArchiveAuditLogs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveAuditLogs", reflect.TypeOf((*MockStore)(nil).ArchiveAuditLogs), arg0, arg1)
+}
+
+// BlockLogin mocks base method.
+func (m *MockStore) BlockLogin(arg0 context.Context, arg1 db.BlockLoginParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockLogin", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockLogin indicates an expected call of BlockLogin.
+func (mr *MockStoreMockRecorder) BlockLogin(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockLogin", reflect.TypeOf((*MockStore)(nil).BlockLogin), arg0, arg1)
+}
+
+// ChangePasswordTx mocks base method.
+func (m *MockStore) ChangePasswordTx(arg0 context.Context, arg1 db.ChangePasswordTxParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePasswordTx", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePasswordTx indicates an expected call of ChangePasswordTx.
+func (mr *MockStoreMockRecorder) ChangePasswordTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePasswordTx", reflect.TypeOf((*MockStore)(nil).ChangePasswordTx), arg0, arg1)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockStore) ConfirmTOTP(arg0 context.Context, arg1 db.ConfirmTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", arg0, arg1)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockStoreMockRecorder) ConfirmTOTP(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockStore)(nil).ConfirmTOTP), arg0, arg1)
+}
+
+// ConsumeEmailVerificationToken mocks base method.
+func (m *MockStore) ConsumeEmailVerificationToken(arg0 context.Context, arg1 string) (db.EmailVerificationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeEmailVerificationToken", arg0, arg1)
+	ret0, _ := ret[0].(db.EmailVerificationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeEmailVerificationToken indicates an expected call of ConsumeEmailVerificationToken.
+func (mr *MockStoreMockRecorder) ConsumeEmailVerificationToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeEmailVerificationToken", reflect.TypeOf((*MockStore)(nil).ConsumeEmailVerificationToken), arg0, arg1)
+}
+
+// ConsumeLoginChallenge mocks base method.
+func (m *MockStore) ConsumeLoginChallenge(arg0 context.Context, arg1 uuid.UUID) (db.WebauthnChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeLoginChallenge", arg0, arg1)
+	ret0, _ := ret[0].(db.WebauthnChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeLoginChallenge indicates an expected call of ConsumeLoginChallenge.
+func (mr *MockStoreMockRecorder) ConsumeLoginChallenge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeLoginChallenge", reflect.TypeOf((*MockStore)(nil).ConsumeLoginChallenge), arg0, arg1)
+}
+
+// ConsumeOAuthAuthorizationCode mocks base method.
+func (m *MockStore) ConsumeOAuthAuthorizationCode(arg0 context.Context, arg1 string) (db.OauthAuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOAuthAuthorizationCode", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthAuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOAuthAuthorizationCode indicates an expected call of ConsumeOAuthAuthorizationCode.
+func (mr *MockStoreMockRecorder) ConsumeOAuthAuthorizationCode(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOAuthAuthorizationCode", reflect.TypeOf((*MockStore)(nil).ConsumeOAuthAuthorizationCode), arg0, arg1)
+}
+
+// ConsumePasswordResetToken mocks base method.
+func (m *MockStore) ConsumePasswordResetToken(arg0 context.Context, arg1 string) (db.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(db.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumePasswordResetToken indicates an expected call of ConsumePasswordResetToken.
+func (mr *MockStoreMockRecorder) ConsumePasswordResetToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasswordResetToken", reflect.TypeOf((*MockStore)(nil).ConsumePasswordResetToken), arg0, arg1)
+}
+
+// ConsumeRegistrationChallenge mocks base method.
+func (m *MockStore) ConsumeRegistrationChallenge(arg0 context.Context, arg1 db.ConsumeRegistrationChallengeParams) (db.WebauthnChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRegistrationChallenge", arg0, arg1)
+	ret0, _ := ret[0].(db.WebauthnChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeRegistrationChallenge indicates an expected call of ConsumeRegistrationChallenge.
+func (mr *MockStoreMockRecorder) ConsumeRegistrationChallenge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRegistrationChallenge", reflect.TypeOf((*MockStore)(nil).ConsumeRegistrationChallenge), arg0, arg1)
+}
+
+// CountEmailVerificationTokensSince mocks base method.
+func (m *MockStore) CountEmailVerificationTokensSince(arg0 context.Context, arg1 db.CountEmailVerificationTokensSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountEmailVerificationTokensSince", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountEmailVerificationTokensSince indicates an expected call of CountEmailVerificationTokensSince.
+func (mr *MockStoreMockRecorder) CountEmailVerificationTokensSince(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountEmailVerificationTokensSince", reflect.TypeOf((*MockStore)(nil).CountEmailVerificationTokensSince), arg0, arg1)
+}
+
+// CountPasswordResetTokensSince mocks base method.
+func (m *MockStore) CountPasswordResetTokensSince(arg0 context.Context, arg1 db.CountPasswordResetTokensSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPasswordResetTokensSince", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPasswordResetTokensSince indicates an expected call of CountPasswordResetTokensSince.
+func (mr *MockStoreMockRecorder) CountPasswordResetTokensSince(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPasswordResetTokensSince", reflect.TypeOf((*MockStore)(nil).CountPasswordResetTokensSince), arg0, arg1)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockStore) CreateAPIKey(arg0 context.Context, arg1 db.CreateAPIKeyParams) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockStoreMockRecorder) CreateAPIKey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockStore)(nil).CreateAPIKey), arg0, arg1)
+}
+
 // CreateAllPermission mocks base method.
 func (m *MockStore) CreateAllPermission(arg0 context.Context, arg1 db.CreateAllPermissionParams) (db.Permission, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAllPermission", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "CreateAllPermission", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAllPermission indicates an expected call of CreateAllPermission.
+func (mr *MockStoreMockRecorder) CreateAllPermission(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAllPermission", reflect.TypeOf((*MockStore)(nil).CreateAllPermission), arg0, arg1)
+}
+
+// CreateAuditChain mocks base method.
+func (m *MockStore) CreateAuditChain(arg0 context.Context, arg1 db.CreateAuditChainParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditChain", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditChain indicates an expected call of CreateAuditChain.
+func (mr *MockStoreMockRecorder) CreateAuditChain(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditChain", reflect.TypeOf((*MockStore)(nil).CreateAuditChain), arg0, arg1)
+}
+
+// CreateAuditCheckpoint mocks base method.
+func (m *MockStore) CreateAuditCheckpoint(arg0 context.Context, arg1 db.CreateAuditCheckpointParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditCheckpoint", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditCheckpoint indicates an expected call of CreateAuditCheckpoint.
+func (mr *MockStoreMockRecorder) CreateAuditCheckpoint(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditCheckpoint", reflect.TypeOf((*MockStore)(nil).CreateAuditCheckpoint), arg0, arg1)
+}
+
+// CreateAuditLogPartitions mocks base method.
+func (m *MockStore) CreateAuditLogPartitions(arg0 context.Context, arg1 pgtype.Timestamp) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditLogPartitions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditLogPartitions indicates an expected call of CreateAuditLogPartitions.
+func (mr *MockStoreMockRecorder) CreateAuditLogPartitions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditLogPartitions", reflect.TypeOf((*MockStore)(nil).CreateAuditLogPartitions), arg0, arg1)
+}
+
+// CreateAuditLogsTx mocks base method.
+func (m *MockStore) CreateAuditLogsTx(arg0 context.Context, arg1 db.CreateAuditLogsTxParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditLogsTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditLogsTx indicates an expected call of CreateAuditLogsTx.
+func (mr *MockStoreMockRecorder) CreateAuditLogsTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditLogsTx", reflect.TypeOf((*MockStore)(nil).CreateAuditLogsTx), arg0, arg1)
+}
+
+// CreateDeletePermission mocks base method.
+func (m *MockStore) CreateDeletePermission(arg0 context.Context, arg1 db.CreateDeletePermissionParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeletePermission", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDeletePermission indicates an expected call of CreateDeletePermission.
+func (mr *MockStoreMockRecorder) CreateDeletePermission(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeletePermission", reflect.TypeOf((*MockStore)(nil).CreateDeletePermission), arg0, arg1)
+}
+
+// CreateEmailVerificationToken mocks base method.
+func (m *MockStore) CreateEmailVerificationToken(arg0 context.Context, arg1 db.CreateEmailVerificationTokenParams) (db.EmailVerificationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailVerificationToken", arg0, arg1)
+	ret0, _ := ret[0].(db.EmailVerificationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEmailVerificationToken indicates an expected call of CreateEmailVerificationToken.
+func (mr *MockStoreMockRecorder) CreateEmailVerificationToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailVerificationToken", reflect.TypeOf((*MockStore)(nil).CreateEmailVerificationToken), arg0, arg1)
+}
+
+// CreateLoginChallenge mocks base method.
+func (m *MockStore) CreateLoginChallenge(arg0 context.Context, arg1 db.CreateLoginChallengeParams) (db.WebauthnChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginChallenge", arg0, arg1)
+	ret0, _ := ret[0].(db.WebauthnChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLoginChallenge indicates an expected call of CreateLoginChallenge.
+func (mr *MockStoreMockRecorder) CreateLoginChallenge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginChallenge", reflect.TypeOf((*MockStore)(nil).CreateLoginChallenge), arg0, arg1)
+}
+
+// CreateLoginLockout mocks base method.
+func (m *MockStore) CreateLoginLockout(arg0 context.Context, arg1 db.CreateLoginLockoutParams) (db.LoginLockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginLockout", arg0, arg1)
+	ret0, _ := ret[0].(db.LoginLockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLoginLockout indicates an expected call of CreateLoginLockout.
+func (mr *MockStoreMockRecorder) CreateLoginLockout(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginLockout", reflect.TypeOf((*MockStore)(nil).CreateLoginLockout), arg0, arg1)
+}
+
+// CreateOAuthAccessToken mocks base method.
+func (m *MockStore) CreateOAuthAccessToken(arg0 context.Context, arg1 db.CreateOAuthAccessTokenParams) (db.OauthAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthAccessToken", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthAccessToken indicates an expected call of CreateOAuthAccessToken.
+func (mr *MockStoreMockRecorder) CreateOAuthAccessToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthAccessToken", reflect.TypeOf((*MockStore)(nil).CreateOAuthAccessToken), arg0, arg1)
+}
+
+// CreateOAuthAuthorizationCode mocks base method.
+func (m *MockStore) CreateOAuthAuthorizationCode(arg0 context.Context, arg1 db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthAuthorizationCode", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthAuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthAuthorizationCode indicates an expected call of CreateOAuthAuthorizationCode.
+func (mr *MockStoreMockRecorder) CreateOAuthAuthorizationCode(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthAuthorizationCode", reflect.TypeOf((*MockStore)(nil).CreateOAuthAuthorizationCode), arg0, arg1)
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockStore) CreateOAuthClient(arg0 context.Context, arg1 db.CreateOAuthClientParams) (db.OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockStoreMockRecorder) CreateOAuthClient(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockStore)(nil).CreateOAuthClient), arg0, arg1)
+}
+
+// CreateOAuthRefreshToken mocks base method.
+func (m *MockStore) CreateOAuthRefreshToken(arg0 context.Context, arg1 db.CreateOAuthRefreshTokenParams) (db.OauthRefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthRefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthRefreshToken indicates an expected call of CreateOAuthRefreshToken.
+func (mr *MockStoreMockRecorder) CreateOAuthRefreshToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthRefreshToken", reflect.TypeOf((*MockStore)(nil).CreateOAuthRefreshToken), arg0, arg1)
+}
+
+// CreatePasskey mocks base method.
+func (m *MockStore) CreatePasskey(arg0 context.Context, arg1 db.CreatePasskeyParams) (db.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasskey", arg0, arg1)
+	ret0, _ := ret[0].(db.Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePasskey indicates an expected call of CreatePasskey.
+func (mr *MockStoreMockRecorder) CreatePasskey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasskey", reflect.TypeOf((*MockStore)(nil).CreatePasskey), arg0, arg1)
+}
+
+// CreatePasswordResetToken mocks base method.
+func (m *MockStore) CreatePasswordResetToken(arg0 context.Context, arg1 db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(db.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePasswordResetToken indicates an expected call of CreatePasswordResetToken.
+func (mr *MockStoreMockRecorder) CreatePasswordResetToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordResetToken", reflect.TypeOf((*MockStore)(nil).CreatePasswordResetToken), arg0, arg1)
+}
+
+// CreatePermission mocks base method.
+func (m *MockStore) CreatePermission(arg0 context.Context, arg1 db.CreatePermissionParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePermission", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePermission indicates an expected call of CreatePermission.
+func (mr *MockStoreMockRecorder) CreatePermission(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePermission", reflect.TypeOf((*MockStore)(nil).CreatePermission), arg0, arg1)
+}
+
+// CreateReadPermission mocks base method.
+func (m *MockStore) CreateReadPermission(arg0 context.Context, arg1 db.CreateReadPermissionParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReadPermission", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReadPermission indicates an expected call of CreateReadPermission.
+func (mr *MockStoreMockRecorder) CreateReadPermission(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReadPermission", reflect.TypeOf((*MockStore)(nil).CreateReadPermission), arg0, arg1)
+}
+
+// CreateRecoveryCodes mocks base method.
+func (m *MockStore) CreateRecoveryCodes(arg0 context.Context, arg1 db.CreateRecoveryCodesParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRecoveryCodes indicates an expected call of CreateRecoveryCodes.
+func (mr *MockStoreMockRecorder) CreateRecoveryCodes(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCodes", reflect.TypeOf((*MockStore)(nil).CreateRecoveryCodes), arg0, arg1)
+}
+
+// CreateRegistrationChallenge mocks base method.
+func (m *MockStore) CreateRegistrationChallenge(arg0 context.Context, arg1 db.CreateRegistrationChallengeParams) (db.WebauthnChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRegistrationChallenge", arg0, arg1)
+	ret0, _ := ret[0].(db.WebauthnChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRegistrationChallenge indicates an expected call of CreateRegistrationChallenge.
+func (mr *MockStoreMockRecorder) CreateRegistrationChallenge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRegistrationChallenge", reflect.TypeOf((*MockStore)(nil).CreateRegistrationChallenge), arg0, arg1)
+}
+
+// CreateRequestLogs mocks base method.
+func (m *MockStore) CreateRequestLogs(arg0 context.Context, arg1 db.CreateRequestLogsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRequestLogs", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRequestLogs indicates an expected call of CreateRequestLogs.
+func (mr *MockStoreMockRecorder) CreateRequestLogs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRequestLogs", reflect.TypeOf((*MockStore)(nil).CreateRequestLogs), arg0, arg1)
+}
+
+// CreateResponseLogs mocks base method.
+func (m *MockStore) CreateResponseLogs(arg0 context.Context, arg1 db.CreateResponseLogsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateResponseLogs", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateResponseLogs indicates an expected call of CreateResponseLogs.
+func (mr *MockStoreMockRecorder) CreateResponseLogs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResponseLogs", reflect.TypeOf((*MockStore)(nil).CreateResponseLogs), arg0, arg1)
+}
+
+// CreateSession mocks base method.
+func (m *MockStore) CreateSession(arg0 context.Context, arg1 db.CreateSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockStoreMockRecorder) CreateSession(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), arg0, arg1)
+}
+
+// CreateSpace mocks base method.
+func (m *MockStore) CreateSpace(arg0 context.Context, arg1 db.CreateSpaceParams) (db.Space, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSpace", arg0, arg1)
+	ret0, _ := ret[0].(db.Space)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSpace indicates an expected call of CreateSpace.
+func (mr *MockStoreMockRecorder) CreateSpace(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSpace", reflect.TypeOf((*MockStore)(nil).CreateSpace), arg0, arg1)
+}
+
+// CreateSpaceEvent mocks base method.
+func (m *MockStore) CreateSpaceEvent(arg0 context.Context, arg1 db.CreateSpaceEventParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSpaceEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSpaceEvent indicates an expected call of CreateSpaceEvent.
+func (mr *MockStoreMockRecorder) CreateSpaceEvent(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSpaceEvent", reflect.TypeOf((*MockStore)(nil).CreateSpaceEvent), arg0, arg1)
+}
+
+// CreateSpaceTx mocks base method.
+func (m *MockStore) CreateSpaceTx(arg0 context.Context, arg1 db.CreateSpaceTxParams) (db.CreateSpaceTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSpaceTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateSpaceTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSpaceTx indicates an expected call of CreateSpaceTx.
+func (mr *MockStoreMockRecorder) CreateSpaceTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSpaceTx", reflect.TypeOf((*MockStore)(nil).CreateSpaceTx), arg0, arg1)
+}
+
+// CreateWritePermission mocks base method.
+func (m *MockStore) CreateWritePermission(arg0 context.Context, arg1 db.CreateWritePermissionParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWritePermission", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWritePermission indicates an expected call of CreateWritePermission.
+func (mr *MockStoreMockRecorder) CreateWritePermission(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWritePermission", reflect.TypeOf((*MockStore)(nil).CreateWritePermission), arg0, arg1)
+}
+
+// DeleteExpiredOAuthAuthorizationCodes mocks base method.
+func (m *MockStore) DeleteExpiredOAuthAuthorizationCodes(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredOAuthAuthorizationCodes", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredOAuthAuthorizationCodes indicates an expected call of DeleteExpiredOAuthAuthorizationCodes.
+func (mr *MockStoreMockRecorder) DeleteExpiredOAuthAuthorizationCodes(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredOAuthAuthorizationCodes", reflect.TypeOf((*MockStore)(nil).DeleteExpiredOAuthAuthorizationCodes), arg0)
+}
+
+// DeleteExpiredWebauthnChallenges mocks base method.
+func (m *MockStore) DeleteExpiredWebauthnChallenges(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredWebauthnChallenges", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredWebauthnChallenges indicates an expected call of DeleteExpiredWebauthnChallenges.
+func (mr *MockStoreMockRecorder) DeleteExpiredWebauthnChallenges(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredWebauthnChallenges", reflect.TypeOf((*MockStore)(nil).DeleteExpiredWebauthnChallenges), arg0)
+}
+
+// DeleteIdleRateLimits mocks base method.
+func (m *MockStore) DeleteIdleRateLimits(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdleRateLimits", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIdleRateLimits indicates an expected call of DeleteIdleRateLimits.
+func (mr *MockStoreMockRecorder) DeleteIdleRateLimits(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdleRateLimits", reflect.TypeOf((*MockStore)(nil).DeleteIdleRateLimits), arg0)
+}
+
+// DeleteLoginThrottle mocks base method.
+func (m *MockStore) DeleteLoginThrottle(arg0 context.Context, arg1 db.DeleteLoginThrottleParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginThrottle", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLoginThrottle indicates an expected call of DeleteLoginThrottle.
+func (mr *MockStoreMockRecorder) DeleteLoginThrottle(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginThrottle", reflect.TypeOf((*MockStore)(nil).DeleteLoginThrottle), arg0, arg1)
+}
+
+// DeleteLoginThrottles mocks base method.
+func (m *MockStore) DeleteLoginThrottles(arg0 context.Context, arg1 db.DeleteLoginThrottlesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginThrottles", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLoginThrottles indicates an expected call of DeleteLoginThrottles.
+func (mr *MockStoreMockRecorder) DeleteLoginThrottles(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginThrottles", reflect.TypeOf((*MockStore)(nil).DeleteLoginThrottles), arg0, arg1)
+}
+
+// DeleteOAuthAuthorizationCodesByUser mocks base method.
+func (m *MockStore) DeleteOAuthAuthorizationCodesByUser(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuthAuthorizationCodesByUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuthAuthorizationCodesByUser indicates an expected call of DeleteOAuthAuthorizationCodesByUser.
+func (mr *MockStoreMockRecorder) DeleteOAuthAuthorizationCodesByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthAuthorizationCodesByUser", reflect.TypeOf((*MockStore)(nil).DeleteOAuthAuthorizationCodesByUser), arg0, arg1)
+}
+
+// DeletePasskey mocks base method.
+func (m *MockStore) DeletePasskey(arg0 context.Context, arg1 db.DeletePasskeyParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePasskey", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePasskey indicates an expected call of DeletePasskey.
+func (mr *MockStoreMockRecorder) DeletePasskey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePasskey", reflect.TypeOf((*MockStore)(nil).DeletePasskey), arg0, arg1)
+}
+
+// DeletePasskeysByUser mocks base method.
+func (m *MockStore) DeletePasskeysByUser(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePasskeysByUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePasskeysByUser indicates an expected call of DeletePasskeysByUser.
+func (mr *MockStoreMockRecorder) DeletePasskeysByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePasskeysByUser", reflect.TypeOf((*MockStore)(nil).DeletePasskeysByUser), arg0, arg1)
+}
+
+// DeletePermissionsByUser mocks base method.
+func (m *MockStore) DeletePermissionsByUser(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePermissionsByUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePermissionsByUser indicates an expected call of DeletePermissionsByUser.
+func (mr *MockStoreMockRecorder) DeletePermissionsByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePermissionsByUser", reflect.TypeOf((*MockStore)(nil).DeletePermissionsByUser), arg0, arg1)
+}
+
+// DeleteRecoveryCodes mocks base method.
+func (m *MockStore) DeleteRecoveryCodes(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCodes indicates an expected call of DeleteRecoveryCodes.
+func (mr *MockStoreMockRecorder) DeleteRecoveryCodes(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodes", reflect.TypeOf((*MockStore)(nil).DeleteRecoveryCodes), arg0, arg1)
+}
+
+// DeleteSecondFactorThrottle mocks base method.
+func (m *MockStore) DeleteSecondFactorThrottle(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSecondFactorThrottle", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSecondFactorThrottle indicates an expected call of DeleteSecondFactorThrottle.
+func (mr *MockStoreMockRecorder) DeleteSecondFactorThrottle(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecondFactorThrottle", reflect.TypeOf((*MockStore)(nil).DeleteSecondFactorThrottle), arg0, arg1)
+}
+
+// DeleteSpace mocks base method.
+func (m *MockStore) DeleteSpace(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSpace", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSpace indicates an expected call of DeleteSpace.
+func (mr *MockStoreMockRecorder) DeleteSpace(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSpace", reflect.TypeOf((*MockStore)(nil).DeleteSpace), arg0, arg1)
+}
+
+// DeleteSpaceMessages mocks base method.
+func (m *MockStore) DeleteSpaceMessages(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSpaceMessages", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSpaceMessages indicates an expected call of DeleteSpaceMessages.
+func (mr *MockStoreMockRecorder) DeleteSpaceMessages(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSpaceMessages", reflect.TypeOf((*MockStore)(nil).DeleteSpaceMessages), arg0, arg1)
+}
+
+// DeleteSpacePermissions mocks base method.
+func (m *MockStore) DeleteSpacePermissions(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSpacePermissions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSpacePermissions indicates an expected call of DeleteSpacePermissions.
+func (mr *MockStoreMockRecorder) DeleteSpacePermissions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSpacePermissions", reflect.TypeOf((*MockStore)(nil).DeleteSpacePermissions), arg0, arg1)
+}
+
+// DeleteTOTP mocks base method.
+func (m *MockStore) DeleteTOTP(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockStoreMockRecorder) DeleteTOTP(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockStore)(nil).DeleteTOTP), arg0, arg1)
+}
+
+// DeleteUserTx mocks base method.
+func (m *MockStore) DeleteUserTx(arg0 context.Context, arg1 db.DeleteUserTxParams) (db.DeleteUserTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTx", arg0, arg1)
+	ret0, _ := ret[0].(db.DeleteUserTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUserTx indicates an expected call of DeleteUserTx.
+func (mr *MockStoreMockRecorder) DeleteUserTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTx", reflect.TypeOf((*MockStore)(nil).DeleteUserTx), arg0, arg1)
+}
+
+// DisableTwoFactorTx mocks base method.
+func (m *MockStore) DisableTwoFactorTx(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactorTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactorTx indicates an expected call of DisableTwoFactorTx.
+func (mr *MockStoreMockRecorder) DisableTwoFactorTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactorTx", reflect.TypeOf((*MockStore)(nil).DisableTwoFactorTx), arg0, arg1)
+}
+
+// EnableTwoFactorTx mocks base method.
+func (m *MockStore) EnableTwoFactorTx(arg0 context.Context, arg1 db.EnableTwoFactorTxParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTwoFactorTx", arg0, arg1)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTwoFactorTx indicates an expected call of EnableTwoFactorTx.
+func (mr *MockStoreMockRecorder) EnableTwoFactorTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTwoFactorTx", reflect.TypeOf((*MockStore)(nil).EnableTwoFactorTx), arg0, arg1)
+}
+
+// GetActiveAPIKeyByHash mocks base method.
+func (m *MockStore) GetActiveAPIKeyByHash(arg0 context.Context, arg1 string) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveAPIKeyByHash", arg0, arg1)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveAPIKeyByHash indicates an expected call of GetActiveAPIKeyByHash.
+func (mr *MockStoreMockRecorder) GetActiveAPIKeyByHash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveAPIKeyByHash", reflect.TypeOf((*MockStore)(nil).GetActiveAPIKeyByHash), arg0, arg1)
+}
+
+// GetActiveOAuthAccessTokenByHash mocks base method.
+func (m *MockStore) GetActiveOAuthAccessTokenByHash(arg0 context.Context, arg1 string) (db.OauthAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveOAuthAccessTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveOAuthAccessTokenByHash indicates an expected call of GetActiveOAuthAccessTokenByHash.
+func (mr *MockStoreMockRecorder) GetActiveOAuthAccessTokenByHash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOAuthAccessTokenByHash", reflect.TypeOf((*MockStore)(nil).GetActiveOAuthAccessTokenByHash), arg0, arg1)
+}
+
+// GetActiveSessionByTokenHash mocks base method.
+func (m *MockStore) GetActiveSessionByTokenHash(arg0 context.Context, arg1 pgtype.Text) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveSessionByTokenHash", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveSessionByTokenHash indicates an expected call of GetActiveSessionByTokenHash.
+func (mr *MockStoreMockRecorder) GetActiveSessionByTokenHash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveSessionByTokenHash", reflect.TypeOf((*MockStore)(nil).GetActiveSessionByTokenHash), arg0, arg1)
+}
+
+// GetAuditChain mocks base method.
+func (m *MockStore) GetAuditChain(arg0 context.Context, arg1 int64) (db.AuditChain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditChain", arg0, arg1)
+	ret0, _ := ret[0].(db.AuditChain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditChain indicates an expected call of GetAuditChain.
+func (mr *MockStoreMockRecorder) GetAuditChain(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditChain", reflect.TypeOf((*MockStore)(nil).GetAuditChain), arg0, arg1)
+}
+
+// GetAuditChainHead mocks base method.
+func (m *MockStore) GetAuditChainHead(arg0 context.Context) (db.AuditChain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditChainHead", arg0)
+	ret0, _ := ret[0].(db.AuditChain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditChainHead indicates an expected call of GetAuditChainHead.
+func (mr *MockStoreMockRecorder) GetAuditChainHead(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditChainHead", reflect.TypeOf((*MockStore)(nil).GetAuditChainHead), arg0)
+}
+
+// GetAuditLogsSummary mocks base method.
+func (m *MockStore) GetAuditLogsSummary(arg0 context.Context, arg1 pgtype.Timestamp) (db.GetAuditLogsSummaryRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditLogsSummary", arg0, arg1)
+	ret0, _ := ret[0].(db.GetAuditLogsSummaryRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditLogsSummary indicates an expected call of GetAuditLogsSummary.
+func (mr *MockStoreMockRecorder) GetAuditLogsSummary(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLogsSummary", reflect.TypeOf((*MockStore)(nil).GetAuditLogsSummary), arg0, arg1)
+}
+
+// GetLatestAuditCheckpoint mocks base method.
+func (m *MockStore) GetLatestAuditCheckpoint(arg0 context.Context) (db.AuditCheckpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestAuditCheckpoint", arg0)
+	ret0, _ := ret[0].(db.AuditCheckpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestAuditCheckpoint indicates an expected call of GetLatestAuditCheckpoint.
+func (mr *MockStoreMockRecorder) GetLatestAuditCheckpoint(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestAuditCheckpoint", reflect.TypeOf((*MockStore)(nil).GetLatestAuditCheckpoint), arg0)
+}
+
+// GetOAuthClient mocks base method.
+func (m *MockStore) GetOAuthClient(arg0 context.Context, arg1 uuid.UUID) (db.OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClient", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClient indicates an expected call of GetOAuthClient.
+func (mr *MockStoreMockRecorder) GetOAuthClient(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClient", reflect.TypeOf((*MockStore)(nil).GetOAuthClient), arg0, arg1)
+}
+
+// GetOAuthRefreshTokenByHash mocks base method.
+func (m *MockStore) GetOAuthRefreshTokenByHash(arg0 context.Context, arg1 string) (db.OauthRefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthRefreshTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthRefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthRefreshTokenByHash indicates an expected call of GetOAuthRefreshTokenByHash.
+func (mr *MockStoreMockRecorder) GetOAuthRefreshTokenByHash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthRefreshTokenByHash", reflect.TypeOf((*MockStore)(nil).GetOAuthRefreshTokenByHash), arg0, arg1)
+}
+
+// GetPasskeyByCredentialID mocks base method.
+func (m *MockStore) GetPasskeyByCredentialID(arg0 context.Context, arg1 []byte) (db.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskeyByCredentialID", arg0, arg1)
+	ret0, _ := ret[0].(db.Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskeyByCredentialID indicates an expected call of GetPasskeyByCredentialID.
+func (mr *MockStoreMockRecorder) GetPasskeyByCredentialID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskeyByCredentialID", reflect.TypeOf((*MockStore)(nil).GetPasskeyByCredentialID), arg0, arg1)
+}
+
+// GetPermissionsByUserAndSpaceID mocks base method.
+func (m *MockStore) GetPermissionsByUserAndSpaceID(arg0 context.Context, arg1 db.GetPermissionsByUserAndSpaceIDParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissionsByUserAndSpaceID", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissionsByUserAndSpaceID indicates an expected call of GetPermissionsByUserAndSpaceID.
+func (mr *MockStoreMockRecorder) GetPermissionsByUserAndSpaceID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissionsByUserAndSpaceID", reflect.TypeOf((*MockStore)(nil).GetPermissionsByUserAndSpaceID), arg0, arg1)
+}
+
+// GetSecondFactorThrottle mocks base method.
+func (m *MockStore) GetSecondFactorThrottle(arg0 context.Context, arg1 uuid.UUID) (db.SecondFactorThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecondFactorThrottle", arg0, arg1)
+	ret0, _ := ret[0].(db.SecondFactorThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecondFactorThrottle indicates an expected call of GetSecondFactorThrottle.
+func (mr *MockStoreMockRecorder) GetSecondFactorThrottle(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecondFactorThrottle", reflect.TypeOf((*MockStore)(nil).GetSecondFactorThrottle), arg0, arg1)
+}
+
+// GetSessionByID mocks base method.
+func (m *MockStore) GetSessionByID(arg0 context.Context, arg1 uuid.UUID) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByID", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByID indicates an expected call of GetSessionByID.
+func (mr *MockStoreMockRecorder) GetSessionByID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByID", reflect.TypeOf((*MockStore)(nil).GetSessionByID), arg0, arg1)
+}
+
+// GetSpaceByID mocks base method.
+func (m *MockStore) GetSpaceByID(arg0 context.Context, arg1 uuid.UUID) (db.Space, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpaceByID", arg0, arg1)
+	ret0, _ := ret[0].(db.Space)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpaceByID indicates an expected call of GetSpaceByID.
+func (mr *MockStoreMockRecorder) GetSpaceByID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpaceByID", reflect.TypeOf((*MockStore)(nil).GetSpaceByID), arg0, arg1)
+}
+
+// GetSpaceByName mocks base method.
+func (m *MockStore) GetSpaceByName(arg0 context.Context, arg1 string) (db.Space, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpaceByName", arg0, arg1)
+	ret0, _ := ret[0].(db.Space)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpaceByName indicates an expected call of GetSpaceByName.
+func (mr *MockStoreMockRecorder) GetSpaceByName(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpaceByName", reflect.TypeOf((*MockStore)(nil).GetSpaceByName), arg0, arg1)
+}
+
+// GetSpaceSuccessor mocks base method.
+func (m *MockStore) GetSpaceSuccessor(arg0 context.Context, arg1 db.GetSpaceSuccessorParams) (db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpaceSuccessor", arg0, arg1)
+	ret0, _ := ret[0].(db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpaceSuccessor indicates an expected call of GetSpaceSuccessor.
+func (mr *MockStoreMockRecorder) GetSpaceSuccessor(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpaceSuccessor", reflect.TypeOf((*MockStore)(nil).GetSpaceSuccessor), arg0, arg1)
+}
+
+// GetTOTPByUserID mocks base method.
+func (m *MockStore) GetTOTPByUserID(arg0 context.Context, arg1 uuid.UUID) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTPByUserID", arg0, arg1)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTPByUserID indicates an expected call of GetTOTPByUserID.
+func (mr *MockStoreMockRecorder) GetTOTPByUserID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTPByUserID", reflect.TypeOf((*MockStore)(nil).GetTOTPByUserID), arg0, arg1)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+}
+
+// GetUserByID mocks base method.
+func (m *MockStore) GetUserByID(arg0 context.Context, arg1 uuid.UUID) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockStoreMockRecorder) GetUserByID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockStore)(nil).GetUserByID), arg0, arg1)
+}
+
+// GrantAllPermissions mocks base method.
+func (m *MockStore) GrantAllPermissions(arg0 context.Context, arg1 db.GrantAllPermissionsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantAllPermissions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantAllPermissions indicates an expected call of GrantAllPermissions.
+func (mr *MockStoreMockRecorder) GrantAllPermissions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantAllPermissions", reflect.TypeOf((*MockStore)(nil).GrantAllPermissions), arg0, arg1)
+}
+
+// InvalidateEmailVerificationTokens mocks base method.
+func (m *MockStore) InvalidateEmailVerificationTokens(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateEmailVerificationTokens", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateEmailVerificationTokens indicates an expected call of InvalidateEmailVerificationTokens.
+func (mr *MockStoreMockRecorder) InvalidateEmailVerificationTokens(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateEmailVerificationTokens", reflect.TypeOf((*MockStore)(nil).InvalidateEmailVerificationTokens), arg0, arg1)
+}
+
+// InvalidatePasswordResetTokens mocks base method.
+func (m *MockStore) InvalidatePasswordResetTokens(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidatePasswordResetTokens", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidatePasswordResetTokens indicates an expected call of InvalidatePasswordResetTokens.
+func (mr *MockStoreMockRecorder) InvalidatePasswordResetTokens(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidatePasswordResetTokens", reflect.TypeOf((*MockStore)(nil).InvalidatePasswordResetTokens), arg0, arg1)
+}
+
+// ListAPIKeysByUser mocks base method.
+func (m *MockStore) ListAPIKeysByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeysByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeysByUser indicates an expected call of ListAPIKeysByUser.
+func (mr *MockStoreMockRecorder) ListAPIKeysByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeysByUser", reflect.TypeOf((*MockStore)(nil).ListAPIKeysByUser), arg0, arg1)
+}
+
+// ListActiveLoginLockouts mocks base method.
+func (m *MockStore) ListActiveLoginLockouts(arg0 context.Context) ([]db.LoginLockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveLoginLockouts", arg0)
+	ret0, _ := ret[0].([]db.LoginLockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveLoginLockouts indicates an expected call of ListActiveLoginLockouts.
+func (mr *MockStoreMockRecorder) ListActiveLoginLockouts(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveLoginLockouts", reflect.TypeOf((*MockStore)(nil).ListActiveLoginLockouts), arg0)
+}
+
+// ListActiveSessionsByUser mocks base method.
+func (m *MockStore) ListActiveSessionsByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveSessionsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveSessionsByUser indicates an expected call of ListActiveSessionsByUser.
+func (mr *MockStoreMockRecorder) ListActiveSessionsByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveSessionsByUser", reflect.TypeOf((*MockStore)(nil).ListActiveSessionsByUser), arg0, arg1)
+}
+
+// ListAdmins mocks base method.
+func (m *MockStore) ListAdmins(arg0 context.Context) ([]db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdmins", arg0)
+	ret0, _ := ret[0].([]db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdmins indicates an expected call of ListAdmins.
+func (mr *MockStoreMockRecorder) ListAdmins(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdmins", reflect.TypeOf((*MockStore)(nil).ListAdmins), arg0)
+}
+
+// ListAuditArchives mocks base method.
+func (m *MockStore) ListAuditArchives(arg0 context.Context) ([]db.AuditArchive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditArchives", arg0)
+	ret0, _ := ret[0].([]db.AuditArchive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditArchives indicates an expected call of ListAuditArchives.
+func (mr *MockStoreMockRecorder) ListAuditArchives(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditArchives", reflect.TypeOf((*MockStore)(nil).ListAuditArchives), arg0)
+}
+
+// ListAuditChain mocks base method.
+func (m *MockStore) ListAuditChain(arg0 context.Context, arg1 db.ListAuditChainParams) ([]db.ListAuditChainRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditChain", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListAuditChainRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditChain indicates an expected call of ListAuditChain.
+func (mr *MockStoreMockRecorder) ListAuditChain(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditChain", reflect.TypeOf((*MockStore)(nil).ListAuditChain), arg0, arg1)
+}
+
+// ListAuditCheckpoints mocks base method.
+func (m *MockStore) ListAuditCheckpoints(arg0 context.Context) ([]db.AuditCheckpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditCheckpoints", arg0)
+	ret0, _ := ret[0].([]db.AuditCheckpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditCheckpoints indicates an expected call of ListAuditCheckpoints.
+func (mr *MockStoreMockRecorder) ListAuditCheckpoints(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditCheckpoints", reflect.TypeOf((*MockStore)(nil).ListAuditCheckpoints), arg0)
+}
+
+// ListAuditLogPartitions mocks base method.
+func (m *MockStore) ListAuditLogPartitions(arg0 context.Context) ([]pgtype.Timestamp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLogPartitions", arg0)
+	ret0, _ := ret[0].([]pgtype.Timestamp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditLogPartitions indicates an expected call of ListAuditLogPartitions.
+func (mr *MockStoreMockRecorder) ListAuditLogPartitions(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLogPartitions", reflect.TypeOf((*MockStore)(nil).ListAuditLogPartitions), arg0)
+}
+
+// ListAuditLogs mocks base method.
+func (m *MockStore) ListAuditLogs(arg0 context.Context, arg1 db.ListAuditLogsParams) ([]db.ListAuditLogsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLogs", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListAuditLogsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditLogs indicates an expected call of ListAuditLogs.
+func (mr *MockStoreMockRecorder) ListAuditLogs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLogs", reflect.TypeOf((*MockStore)(nil).ListAuditLogs), arg0, arg1)
+}
+
+// ListAuditLogsForArchive mocks base method.
+func (m *MockStore) ListAuditLogsForArchive(arg0 context.Context, arg1 db.ListAuditLogsForArchiveParams) ([]db.ListAuditLogsForArchiveRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLogsForArchive", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListAuditLogsForArchiveRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditLogsForArchive indicates an expected call of ListAuditLogsForArchive.
+func (mr *MockStoreMockRecorder) ListAuditLogsForArchive(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLogsForArchive", reflect.TypeOf((*MockStore)(nil).ListAuditLogsForArchive), arg0, arg1)
+}
+
+// ListLoginThrottles mocks base method.
+func (m *MockStore) ListLoginThrottles(arg0 context.Context, arg1 db.ListLoginThrottlesParams) ([]db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginThrottles", arg0, arg1)
+	ret0, _ := ret[0].([]db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginThrottles indicates an expected call of ListLoginThrottles.
+func (mr *MockStoreMockRecorder) ListLoginThrottles(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginThrottles", reflect.TypeOf((*MockStore)(nil).ListLoginThrottles), arg0, arg1)
+}
+
+// ListMessagesByAuthor mocks base method.
+func (m *MockStore) ListMessagesByAuthor(arg0 context.Context, arg1 uuid.UUID) ([]db.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessagesByAuthor", arg0, arg1)
+	ret0, _ := ret[0].([]db.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMessagesByAuthor indicates an expected call of ListMessagesByAuthor.
+func (mr *MockStoreMockRecorder) ListMessagesByAuthor(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessagesByAuthor", reflect.TypeOf((*MockStore)(nil).ListMessagesByAuthor), arg0, arg1)
+}
+
+// ListOAuthClientsByOwner mocks base method.
+func (m *MockStore) ListOAuthClientsByOwner(arg0 context.Context, arg1 uuid.UUID) ([]db.OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOAuthClientsByOwner", arg0, arg1)
+	ret0, _ := ret[0].([]db.OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOAuthClientsByOwner indicates an expected call of ListOAuthClientsByOwner.
+func (mr *MockStoreMockRecorder) ListOAuthClientsByOwner(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOAuthClientsByOwner", reflect.TypeOf((*MockStore)(nil).ListOAuthClientsByOwner), arg0, arg1)
+}
+
+// ListPasskeysByUser mocks base method.
+func (m *MockStore) ListPasskeysByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPasskeysByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPasskeysByUser indicates an expected call of ListPasskeysByUser.
+func (mr *MockStoreMockRecorder) ListPasskeysByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPasskeysByUser", reflect.TypeOf((*MockStore)(nil).ListPasskeysByUser), arg0, arg1)
+}
+
+// ListPermissionsByUser mocks base method.
+func (m *MockStore) ListPermissionsByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPermissionsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPermissionsByUser indicates an expected call of ListPermissionsByUser.
+func (mr *MockStoreMockRecorder) ListPermissionsByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPermissionsByUser", reflect.TypeOf((*MockStore)(nil).ListPermissionsByUser), arg0, arg1)
+}
+
+// ListRequestLogsByUser mocks base method.
+func (m *MockStore) ListRequestLogsByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.RequestLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRequestLogsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.RequestLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRequestLogsByUser indicates an expected call of ListRequestLogsByUser.
+func (mr *MockStoreMockRecorder) ListRequestLogsByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRequestLogsByUser", reflect.TypeOf((*MockStore)(nil).ListRequestLogsByUser), arg0, arg1)
+}
+
+// ListResponseLogsByUser mocks base method.
+func (m *MockStore) ListResponseLogsByUser(arg0 context.Context, arg1 uuid.UUID) ([]db.ResponseLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResponseLogsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.ResponseLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResponseLogsByUser indicates an expected call of ListResponseLogsByUser.
+func (mr *MockStoreMockRecorder) ListResponseLogsByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResponseLogsByUser", reflect.TypeOf((*MockStore)(nil).ListResponseLogsByUser), arg0, arg1)
+}
+
+// ListSpaceEvents mocks base method.
+func (m *MockStore) ListSpaceEvents(arg0 context.Context, arg1 db.ListSpaceEventsParams) ([]db.SpaceEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpaceEvents", arg0, arg1)
+	ret0, _ := ret[0].([]db.SpaceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSpaceEvents indicates an expected call of ListSpaceEvents.
+func (mr *MockStoreMockRecorder) ListSpaceEvents(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpaceEvents", reflect.TypeOf((*MockStore)(nil).ListSpaceEvents), arg0, arg1)
+}
+
+// ListSpaces mocks base method.
+func (m *MockStore) ListSpaces(arg0 context.Context, arg1 db.ListSpacesParams) ([]db.Space, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpaces", arg0, arg1)
+	ret0, _ := ret[0].([]db.Space)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAllPermission indicates an expected call of CreateAllPermission.
-func (mr *MockStoreMockRecorder) CreateAllPermission(arg0, arg1 any) *gomock.Call {
+// ListSpaces indicates an expected call of ListSpaces.
+func (mr *MockStoreMockRecorder) ListSpaces(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAllPermission", reflect.TypeOf((*MockStore)(nil).CreateAllPermission), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpaces", reflect.TypeOf((*MockStore)(nil).ListSpaces), arg0, arg1)
 }
 
-// CreateAuthenticatedRequestLog mocks base method.
-func (m *MockStore) CreateAuthenticatedRequestLog(arg0 context.Context, arg1 db.CreateAuthenticatedRequestLogParams) (db.RequestLog, error) {
+// ListSpacesByOwner mocks base method.
+func (m *MockStore) ListSpacesByOwner(arg0 context.Context, arg1 uuid.UUID) ([]db.Space, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAuthenticatedRequestLog", arg0, arg1)
-	ret0, _ := ret[0].(db.RequestLog)
+	ret := m.ctrl.Call(m, "ListSpacesByOwner", arg0, arg1)
+	ret0, _ := ret[0].([]db.Space)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAuthenticatedRequestLog indicates an expected call of CreateAuthenticatedRequestLog.
-func (mr *MockStoreMockRecorder) CreateAuthenticatedRequestLog(arg0, arg1 any) *gomock.Call {
+// ListSpacesByOwner indicates an expected call of ListSpacesByOwner.
+func (mr *MockStoreMockRecorder) ListSpacesByOwner(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthenticatedRequestLog", reflect.TypeOf((*MockStore)(nil).CreateAuthenticatedRequestLog), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpacesByOwner", reflect.TypeOf((*MockStore)(nil).ListSpacesByOwner), arg0, arg1)
 }
 
-// CreateDeletePermission mocks base method.
-func (m *MockStore) CreateDeletePermission(arg0 context.Context, arg1 db.CreateDeletePermissionParams) (db.Permission, error) {
+// LockAuditChain mocks base method.
+func (m *MockStore) LockAuditChain(arg0 context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateDeletePermission", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "LockAuditChain", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockAuditChain indicates an expected call of LockAuditChain.
+func (mr *MockStoreMockRecorder) LockAuditChain(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockAuditChain", reflect.TypeOf((*MockStore)(nil).LockAuditChain), arg0)
+}
+
+// LockSecondFactor mocks base method.
+func (m *MockStore) LockSecondFactor(arg0 context.Context, arg1 db.LockSecondFactorParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockSecondFactor", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockSecondFactor indicates an expected call of LockSecondFactor.
+func (mr *MockStoreMockRecorder) LockSecondFactor(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockSecondFactor", reflect.TypeOf((*MockStore)(nil).LockSecondFactor), arg0, arg1)
+}
+
+// RecordLoginFailure mocks base method.
+func (m *MockStore) RecordLoginFailure(arg0 context.Context, arg1 db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLoginFailure", arg0, arg1)
+	ret0, _ := ret[0].(db.LoginThrottle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateDeletePermission indicates an expected call of CreateDeletePermission.
-func (mr *MockStoreMockRecorder) CreateDeletePermission(arg0, arg1 any) *gomock.Call {
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockStoreMockRecorder) RecordLoginFailure(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeletePermission", reflect.TypeOf((*MockStore)(nil).CreateDeletePermission), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockStore)(nil).RecordLoginFailure), arg0, arg1)
 }
 
-// CreatePermission mocks base method.
-func (m *MockStore) CreatePermission(arg0 context.Context, arg1 db.CreatePermissionParams) (db.Permission, error) {
+// RecordSecondFactorFailure mocks base method.
+func (m *MockStore) RecordSecondFactorFailure(arg0 context.Context, arg1 db.RecordSecondFactorFailureParams) (db.SecondFactorThrottle, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePermission", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "RecordSecondFactorFailure", arg0, arg1)
+	ret0, _ := ret[0].(db.SecondFactorThrottle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreatePermission indicates an expected call of CreatePermission.
-func (mr *MockStoreMockRecorder) CreatePermission(arg0, arg1 any) *gomock.Call {
+// RecordSecondFactorFailure indicates an expected call of RecordSecondFactorFailure.
+func (mr *MockStoreMockRecorder) RecordSecondFactorFailure(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePermission", reflect.TypeOf((*MockStore)(nil).CreatePermission), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSecondFactorFailure", reflect.TypeOf((*MockStore)(nil).RecordSecondFactorFailure), arg0, arg1)
 }
 
-// CreateReadPermission mocks base method.
-func (m *MockStore) CreateReadPermission(arg0 context.Context, arg1 db.CreateReadPermissionParams) (db.Permission, error) {
+// RegisterUser mocks base method.
+func (m *MockStore) RegisterUser(arg0 context.Context, arg1 db.RegisterUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateReadPermission", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateReadPermission indicates an expected call of CreateReadPermission.
-func (mr *MockStoreMockRecorder) CreateReadPermission(arg0, arg1 any) *gomock.Call {
+// RegisterUser indicates an expected call of RegisterUser.
+func (mr *MockStoreMockRecorder) RegisterUser(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReadPermission", reflect.TypeOf((*MockStore)(nil).CreateReadPermission), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockStore)(nil).RegisterUser), arg0, arg1)
 }
 
-// CreateResponseLog mocks base method.
-func (m *MockStore) CreateResponseLog(arg0 context.Context, arg1 db.CreateResponseLogParams) (db.ResponseLog, error) {
+// RehashUserPassword mocks base method.
+func (m *MockStore) RehashUserPassword(arg0 context.Context, arg1 db.RehashUserPasswordParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateResponseLog", arg0, arg1)
-	ret0, _ := ret[0].(db.ResponseLog)
+	ret := m.ctrl.Call(m, "RehashUserPassword", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateResponseLog indicates an expected call of CreateResponseLog.
-func (mr *MockStoreMockRecorder) CreateResponseLog(arg0, arg1 any) *gomock.Call {
+// RehashUserPassword indicates an expected call of RehashUserPassword.
+func (mr *MockStoreMockRecorder) RehashUserPassword(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResponseLog", reflect.TypeOf((*MockStore)(nil).CreateResponseLog), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashUserPassword", reflect.TypeOf((*MockStore)(nil).RehashUserPassword), arg0, arg1)
 }
 
-// CreateSpace mocks base method.
-func (m *MockStore) CreateSpace(arg0 context.Context, arg1 db.CreateSpaceParams) (db.Space, error) {
+// ResetPasswordTx mocks base method.
+func (m *MockStore) ResetPasswordTx(arg0 context.Context, arg1 db.ResetPasswordTxParams) (db.PasswordResetToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSpace", arg0, arg1)
-	ret0, _ := ret[0].(db.Space)
+	ret := m.ctrl.Call(m, "ResetPasswordTx", arg0, arg1)
+	ret0, _ := ret[0].(db.PasswordResetToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateSpace indicates an expected call of CreateSpace.
-func (mr *MockStoreMockRecorder) CreateSpace(arg0, arg1 any) *gomock.Call {
+// ResetPasswordTx indicates an expected call of ResetPasswordTx.
+func (mr *MockStoreMockRecorder) ResetPasswordTx(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSpace", reflect.TypeOf((*MockStore)(nil).CreateSpace), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPasswordTx", reflect.TypeOf((*MockStore)(nil).ResetPasswordTx), arg0, arg1)
 }
 
-// CreateSpaceTx mocks base method.
-func (m *MockStore) CreateSpaceTx(arg0 context.Context, arg1 db.CreateSpaceTxParams) (db.CreateSpaceTxResult, error) {
+// RevokeAPIKey mocks base method.
+func (m *MockStore) RevokeAPIKey(arg0 context.Context, arg1 db.RevokeAPIKeyParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSpaceTx", arg0, arg1)
-	ret0, _ := ret[0].(db.CreateSpaceTxResult)
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateSpaceTx indicates an expected call of CreateSpaceTx.
-func (mr *MockStoreMockRecorder) CreateSpaceTx(arg0, arg1 any) *gomock.Call {
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockStoreMockRecorder) RevokeAPIKey(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSpaceTx", reflect.TypeOf((*MockStore)(nil).CreateSpaceTx), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockStore)(nil).RevokeAPIKey), arg0, arg1)
+}
+
+// RevokeAPIKeysByUser mocks base method.
+func (m *MockStore) RevokeAPIKeysByUser(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKeysByUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKeysByUser indicates an expected call of RevokeAPIKeysByUser.
+func (mr *MockStoreMockRecorder) RevokeAPIKeysByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKeysByUser", reflect.TypeOf((*MockStore)(nil).RevokeAPIKeysByUser), arg0, arg1)
 }
 
-// CreateUnauthenticatedRequestLog mocks base method.
-func (m *MockStore) CreateUnauthenticatedRequestLog(arg0 context.Context, arg1 db.CreateUnauthenticatedRequestLogParams) (db.RequestLog, error) {
+// RevokeOAuthAccessToken mocks base method.
+func (m *MockStore) RevokeOAuthAccessToken(arg0 context.Context, arg1 string) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUnauthenticatedRequestLog", arg0, arg1)
-	ret0, _ := ret[0].(db.RequestLog)
+	ret := m.ctrl.Call(m, "RevokeOAuthAccessToken", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateUnauthenticatedRequestLog indicates an expected call of CreateUnauthenticatedRequestLog.
-func (mr *MockStoreMockRecorder) CreateUnauthenticatedRequestLog(arg0, arg1 any) *gomock.Call {
+// RevokeOAuthAccessToken indicates an expected call of RevokeOAuthAccessToken.
+func (mr *MockStoreMockRecorder) RevokeOAuthAccessToken(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUnauthenticatedRequestLog", reflect.TypeOf((*MockStore)(nil).CreateUnauthenticatedRequestLog), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthAccessToken", reflect.TypeOf((*MockStore)(nil).RevokeOAuthAccessToken), arg0, arg1)
 }
 
-// CreateWritePermission mocks base method.
-func (m *MockStore) CreateWritePermission(arg0 context.Context, arg1 db.CreateWritePermissionParams) (db.Permission, error) {
+// RevokeOAuthClient mocks base method.
+func (m *MockStore) RevokeOAuthClient(arg0 context.Context, arg1 db.RevokeOAuthClientParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateWritePermission", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "RevokeOAuthClient", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateWritePermission indicates an expected call of CreateWritePermission.
-func (mr *MockStoreMockRecorder) CreateWritePermission(arg0, arg1 any) *gomock.Call {
+// RevokeOAuthClient indicates an expected call of RevokeOAuthClient.
+func (mr *MockStoreMockRecorder) RevokeOAuthClient(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWritePermission", reflect.TypeOf((*MockStore)(nil).CreateWritePermission), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthClient", reflect.TypeOf((*MockStore)(nil).RevokeOAuthClient), arg0, arg1)
 }
 
-// DeleteSpace mocks base method.
-func (m *MockStore) DeleteSpace(arg0 context.Context, arg1 uuid.UUID) error {
+// RevokeOAuthClientsByOwner mocks base method.
+func (m *MockStore) RevokeOAuthClientsByOwner(arg0 context.Context, arg1 uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSpace", arg0, arg1)
+	ret := m.ctrl.Call(m, "RevokeOAuthClientsByOwner", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteSpace indicates an expected call of DeleteSpace.
-func (mr *MockStoreMockRecorder) DeleteSpace(arg0, arg1 any) *gomock.Call {
+// RevokeOAuthClientsByOwner indicates an expected call of RevokeOAuthClientsByOwner.
+func (mr *MockStoreMockRecorder) RevokeOAuthClientsByOwner(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSpace", reflect.TypeOf((*MockStore)(nil).DeleteSpace), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthClientsByOwner", reflect.TypeOf((*MockStore)(nil).RevokeOAuthClientsByOwner), arg0, arg1)
 }
 
-// GetPermissionsByUserAndSpaceID mocks base method.
-func (m *MockStore) GetPermissionsByUserAndSpaceID(arg0 context.Context, arg1 db.GetPermissionsByUserAndSpaceIDParams) (db.Permission, error) {
+// RevokeOAuthGrant mocks base method.
+func (m *MockStore) RevokeOAuthGrant(arg0 context.Context, arg1 uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPermissionsByUserAndSpaceID", arg0, arg1)
-	ret0, _ := ret[0].(db.Permission)
+	ret := m.ctrl.Call(m, "RevokeOAuthGrant", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOAuthGrant indicates an expected call of RevokeOAuthGrant.
+func (mr *MockStoreMockRecorder) RevokeOAuthGrant(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthGrant", reflect.TypeOf((*MockStore)(nil).RevokeOAuthGrant), arg0, arg1)
+}
+
+// RevokeOAuthTokensByUser mocks base method.
+func (m *MockStore) RevokeOAuthTokensByUser(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOAuthTokensByUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOAuthTokensByUser indicates an expected call of RevokeOAuthTokensByUser.
+func (mr *MockStoreMockRecorder) RevokeOAuthTokensByUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthTokensByUser", reflect.TypeOf((*MockStore)(nil).RevokeOAuthTokensByUser), arg0, arg1)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockStore) RevokeOtherSessions(arg0 context.Context, arg1 db.RevokeOtherSessionsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPermissionsByUserAndSpaceID indicates an expected call of GetPermissionsByUserAndSpaceID.
-func (mr *MockStoreMockRecorder) GetPermissionsByUserAndSpaceID(arg0, arg1 any) *gomock.Call {
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockStoreMockRecorder) RevokeOtherSessions(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissionsByUserAndSpaceID", reflect.TypeOf((*MockStore)(nil).GetPermissionsByUserAndSpaceID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockStore)(nil).RevokeOtherSessions), arg0, arg1)
 }
 
-// GetSpaceByID mocks base method.
-func (m *MockStore) GetSpaceByID(arg0 context.Context, arg1 uuid.UUID) (db.Space, error) {
+// RevokeSession mocks base method.
+func (m *MockStore) RevokeSession(arg0 context.Context, arg1 db.RevokeSessionParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSpaceByID", arg0, arg1)
-	ret0, _ := ret[0].(db.Space)
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSpaceByID indicates an expected call of GetSpaceByID.
-func (mr *MockStoreMockRecorder) GetSpaceByID(arg0, arg1 any) *gomock.Call {
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockStoreMockRecorder) RevokeSession(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpaceByID", reflect.TypeOf((*MockStore)(nil).GetSpaceByID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockStore)(nil).RevokeSession), arg0, arg1)
 }
 
-// GetSpaceByName mocks base method.
-func (m *MockStore) GetSpaceByName(arg0 context.Context, arg1 string) (db.Space, error) {
+// RotateOAuthRefreshToken mocks base method.
+func (m *MockStore) RotateOAuthRefreshToken(arg0 context.Context, arg1 string) (db.OauthRefreshToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSpaceByName", arg0, arg1)
-	ret0, _ := ret[0].(db.Space)
+	ret := m.ctrl.Call(m, "RotateOAuthRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(db.OauthRefreshToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSpaceByName indicates an expected call of GetSpaceByName.
-func (mr *MockStoreMockRecorder) GetSpaceByName(arg0, arg1 any) *gomock.Call {
+// RotateOAuthRefreshToken indicates an expected call of RotateOAuthRefreshToken.
+func (mr *MockStoreMockRecorder) RotateOAuthRefreshToken(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpaceByName", reflect.TypeOf((*MockStore)(nil).GetSpaceByName), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateOAuthRefreshToken", reflect.TypeOf((*MockStore)(nil).RotateOAuthRefreshToken), arg0, arg1)
 }
 
-// GetUserByEmail mocks base method.
-func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
+// SetUserAdmin mocks base method.
+func (m *MockStore) SetUserAdmin(arg0 context.Context, arg1 db.SetUserAdminParams) (db.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret := m.ctrl.Call(m, "SetUserAdmin", arg0, arg1)
 	ret0, _ := ret[0].(db.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByEmail indicates an expected call of GetUserByEmail.
-func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 any) *gomock.Call {
+// SetUserAdmin indicates an expected call of SetUserAdmin.
+func (mr *MockStoreMockRecorder) SetUserAdmin(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserAdmin", reflect.TypeOf((*MockStore)(nil).SetUserAdmin), arg0, arg1)
 }
 
-// ListSpaces mocks base method.
-func (m *MockStore) ListSpaces(arg0 context.Context, arg1 db.ListSpacesParams) ([]db.Space, error) {
+// TakeRateLimit mocks base method.
+func (m *MockStore) TakeRateLimit(arg0 context.Context, arg1 db.TakeRateLimitParams) (db.TakeRateLimitRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListSpaces", arg0, arg1)
-	ret0, _ := ret[0].([]db.Space)
+	ret := m.ctrl.Call(m, "TakeRateLimit", arg0, arg1)
+	ret0, _ := ret[0].(db.TakeRateLimitRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListSpaces indicates an expected call of ListSpaces.
-func (mr *MockStoreMockRecorder) ListSpaces(arg0, arg1 any) *gomock.Call {
+// TakeRateLimit indicates an expected call of TakeRateLimit.
+func (mr *MockStoreMockRecorder) TakeRateLimit(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpaces", reflect.TypeOf((*MockStore)(nil).ListSpaces), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeRateLimit", reflect.TypeOf((*MockStore)(nil).TakeRateLimit), arg0, arg1)
 }
 
-// RegisterUser mocks base method.
-func (m *MockStore) RegisterUser(arg0 context.Context, arg1 db.RegisterUserParams) (db.User, error) {
+// TouchAPIKey mocks base method.
+func (m *MockStore) TouchAPIKey(arg0 context.Context, arg1 uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "TouchAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockStoreMockRecorder) TouchAPIKey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockStore)(nil).TouchAPIKey), arg0, arg1)
+}
+
+// TouchSessions mocks base method.
+func (m *MockStore) TouchSessions(arg0 context.Context, arg1 db.TouchSessionsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSessions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchSessions indicates an expected call of TouchSessions.
+func (mr *MockStoreMockRecorder) TouchSessions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSessions", reflect.TypeOf((*MockStore)(nil).TouchSessions), arg0, arg1)
+}
+
+// TransferSpace mocks base method.
+func (m *MockStore) TransferSpace(arg0 context.Context, arg1 db.TransferSpaceParams) (db.Space, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferSpace", arg0, arg1)
+	ret0, _ := ret[0].(db.Space)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RegisterUser indicates an expected call of RegisterUser.
-func (mr *MockStoreMockRecorder) RegisterUser(arg0, arg1 any) *gomock.Call {
+// TransferSpace indicates an expected call of TransferSpace.
+func (mr *MockStoreMockRecorder) TransferSpace(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockStore)(nil).RegisterUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferSpace", reflect.TypeOf((*MockStore)(nil).TransferSpace), arg0, arg1)
+}
+
+// UnlockLoginLockouts mocks base method.
+func (m *MockStore) UnlockLoginLockouts(arg0 context.Context, arg1 db.UnlockLoginLockoutsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockLoginLockouts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockLoginLockouts indicates an expected call of UnlockLoginLockouts.
+func (mr *MockStoreMockRecorder) UnlockLoginLockouts(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockLoginLockouts", reflect.TypeOf((*MockStore)(nil).UnlockLoginLockouts), arg0, arg1)
+}
+
+// UnlockLoginTx mocks base method.
+func (m *MockStore) UnlockLoginTx(arg0 context.Context, arg1 db.UnlockLoginTxParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockLoginTx", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockLoginTx indicates an expected call of UnlockLoginTx.
+func (mr *MockStoreMockRecorder) UnlockLoginTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockLoginTx", reflect.TypeOf((*MockStore)(nil).UnlockLoginTx), arg0, arg1)
 }
 
 // UpdateSpace mocks base method.
@@ -309,3 +1883,137 @@ func (mr *MockStoreMockRecorder) UpdateSpace(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSpace", reflect.TypeOf((*MockStore)(nil).UpdateSpace), arg0, arg1)
 }
+
+// UpdateUserPassword mocks base method.
+func (m *MockStore) UpdateUserPassword(arg0 context.Context, arg1 db.UpdateUserPasswordParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockStoreMockRecorder) UpdateUserPassword(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockStore)(nil).UpdateUserPassword), arg0, arg1)
+}
+
+// UpdateUserProfile mocks base method.
+func (m *MockStore) UpdateUserProfile(arg0 context.Context, arg1 db.UpdateUserProfileParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserProfile", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserProfile indicates an expected call of UpdateUserProfile.
+func (mr *MockStoreMockRecorder) UpdateUserProfile(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserProfile", reflect.TypeOf((*MockStore)(nil).UpdateUserProfile), arg0, arg1)
+}
+
+// UpsertTOTP mocks base method.
+func (m *MockStore) UpsertTOTP(arg0 context.Context, arg1 db.UpsertTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTOTP", arg0, arg1)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTOTP indicates an expected call of UpsertTOTP.
+func (mr *MockStoreMockRecorder) UpsertTOTP(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTOTP", reflect.TypeOf((*MockStore)(nil).UpsertTOTP), arg0, arg1)
+}
+
+// UsePasskey mocks base method.
+func (m *MockStore) UsePasskey(arg0 context.Context, arg1 db.UsePasskeyParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsePasskey", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsePasskey indicates an expected call of UsePasskey.
+func (mr *MockStoreMockRecorder) UsePasskey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePasskey", reflect.TypeOf((*MockStore)(nil).UsePasskey), arg0, arg1)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockStore) UseRecoveryCode(arg0 context.Context, arg1 db.UseRecoveryCodeParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockStoreMockRecorder) UseRecoveryCode(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockStore)(nil).UseRecoveryCode), arg0, arg1)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockStore) UseTOTPStep(arg0 context.Context, arg1 db.UseTOTPStepParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockStoreMockRecorder) UseTOTPStep(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockStore)(nil).UseTOTPStep), arg0, arg1)
+}
+
+// UsersShareSpace mocks base method.
+func (m *MockStore) UsersShareSpace(arg0 context.Context, arg1 db.UsersShareSpaceParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsersShareSpace", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsersShareSpace indicates an expected call of UsersShareSpace.
+func (mr *MockStoreMockRecorder) UsersShareSpace(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsersShareSpace", reflect.TypeOf((*MockStore)(nil).UsersShareSpace), arg0, arg1)
+}
+
+// VerifyEmailTx mocks base method.
+func (m *MockStore) VerifyEmailTx(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmailTx", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmailTx indicates an expected call of VerifyEmailTx.
+func (mr *MockStoreMockRecorder) VerifyEmailTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmailTx", reflect.TypeOf((*MockStore)(nil).VerifyEmailTx), arg0, arg1)
+}
+
+// VerifyUserEmail mocks base method.
+func (m *MockStore) VerifyUserEmail(arg0 context.Context, arg1 uuid.UUID) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserEmail", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyUserEmail indicates an expected call of VerifyUserEmail.
+func (mr *MockStoreMockRecorder) VerifyUserEmail(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserEmail", reflect.TypeOf((*MockStore)(nil).VerifyUserEmail), arg0, arg1)
+}
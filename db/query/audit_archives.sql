@@ -0,0 +1,75 @@
+-- name: CreateAuditLogPartitions :exec
+-- creates the partitions of the month, unless they exist
+SELECT create_audit_log_partitions(sqlc.arg(month)::timestamp);
+
+-- name: ListAuditLogPartitions :many
+-- the months audit logs are partitioned by, oldest first
+SELECT to_timestamp(substring(c.relname FROM '\d{4}m\d{2}$'), 'YYYY"m"MM')::timestamp AS month
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'request_log'::regclass
+AND c.relname ~ '_y\d{4}m\d{2}$'
+ORDER BY month;
+
+-- name: GetAuditLogsSummary :one
+-- the number of entries requested during the month, and the range of the
+-- chain they are linked in
+SELECT
+  count(*) AS entries,
+  count(c.seq) AS chained,
+  COALESCE(min(c.seq), 0)::bigint AS first_seq,
+  COALESCE(max(c.seq), 0)::bigint AS last_seq
+FROM request_log r
+JOIN response_log s ON s.id = r.id AND s.requested_at = r.created_at
+LEFT JOIN audit_chain c ON c.id = r.id
+WHERE r.created_at >= sqlc.arg(month)::timestamp
+AND r.created_at < sqlc.arg(month)::timestamp + interval '1 month';
+
+-- name: ListAuditLogsForArchive :many
+-- the entries requested during the month, oldest first, after the entry of
+-- the cursor when given. Entries that aren't chained have no seq.
+SELECT
+  COALESCE(c.seq, 0)::bigint AS seq,
+  c.hash,
+  r.id,
+  r.method,
+  r.path,
+  r.user_id,
+  r.created_at AS requested_at,
+  r.client_ip,
+  r.user_agent,
+  r.query,
+  r.size AS request_size,
+  r.auth_method,
+  r.space_id,
+  r.request_id,
+  s.status,
+  s.created_at AS responded_at,
+  s.size AS response_size,
+  s.latency_us,
+  s.outcome
+FROM request_log r
+JOIN response_log s ON s.id = r.id AND s.requested_at = r.created_at
+LEFT JOIN audit_chain c ON c.id = r.id
+WHERE r.created_at >= sqlc.arg(month)::timestamp
+AND r.created_at < sqlc.arg(month)::timestamp + interval '1 month'
+AND (
+  sqlc.narg(cursor_at)::timestamp IS NULL
+  OR (r.created_at, r.id) > (sqlc.narg(cursor_at), sqlc.arg(cursor_id)::uuid)
+)
+ORDER BY r.created_at, r.id
+LIMIT sqlc.arg(page_size);
+
+-- name: ArchiveAuditLogs :one
+-- records the archive of an old month and drops it, returning the number of
+-- entries archived. The database counts them, it fails with a unique
+-- violation when the month is already archived.
+SELECT archive_audit_logs(
+  sqlc.arg(month)::timestamp,
+  sqlc.arg(path)::text,
+  sqlc.arg(checksum)::bytea
+)::bigint AS entries;
+
+-- name: ListAuditArchives :many
+SELECT * FROM audit_archives
+ORDER BY month;
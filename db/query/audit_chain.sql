@@ -0,0 +1,64 @@
+-- name: LockAuditChain :exec
+-- keeps other transactions from extending the chain until this one ends
+SELECT pg_advisory_xact_lock(hashtext('audit_chain'));
+
+-- name: GetAuditChainHead :one
+SELECT * FROM audit_chain
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: CreateAuditChain :exec
+INSERT INTO audit_chain (seq, id, hash)
+SELECT
+  unnest(@seqs::bigint[]),
+  unnest(@ids::uuid[]),
+  unnest(@hashes::bytea[]);
+
+-- name: ListAuditChain :many
+-- the chained entries after seq, an entry whose request or response is
+-- missing is left out
+SELECT
+  c.seq,
+  c.hash,
+  r.id,
+  r.method,
+  r.path,
+  r.user_id,
+  r.created_at AS requested_at,
+  r.client_ip,
+  r.user_agent,
+  r.query,
+  r.size AS request_size,
+  r.auth_method,
+  r.space_id,
+  r.request_id,
+  s.status,
+  s.created_at AS responded_at,
+  s.size AS response_size,
+  s.latency_us,
+  s.outcome
+FROM audit_chain c
+JOIN request_log r ON r.id = c.id
+JOIN response_log s ON s.id = c.id
+WHERE c.seq > sqlc.arg(after)
+ORDER BY c.seq
+LIMIT sqlc.arg(page_size);
+
+-- name: CreateAuditCheckpoint :exec
+-- server instances checkpointing the same head at once write it once
+INSERT INTO audit_checkpoints (seq, hash, signature)
+VALUES ($1, $2, $3)
+ON CONFLICT (seq) DO NOTHING;
+
+-- name: GetLatestAuditCheckpoint :one
+SELECT * FROM audit_checkpoints
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: ListAuditCheckpoints :many
+SELECT * FROM audit_checkpoints
+ORDER BY seq;
+
+-- name: GetAuditChain :one
+SELECT * FROM audit_chain
+WHERE seq = $1;
@@ -1,15 +1,93 @@
--- name: CreateAuthenticatedRequestLog :one
-INSERT INTO request_log (method, path, user_id)
-VALUES ($1, $2, $3)
-RETURNING *;
+-- name: ListRequestLogsByUser :many
+SELECT * FROM request_log
+WHERE user_id = $1
+ORDER BY created_at;
 
--- name: CreateUnauthenticatedRequestLog :one
-INSERT INTO request_log (method, path)
-VALUES ($1, $2)
-RETURNING *;
+-- name: ListResponseLogsByUser :many
+SELECT response_log.* FROM response_log
+JOIN request_log ON request_log.id = response_log.id
+WHERE request_log.user_id = $1
+ORDER BY response_log.created_at;
 
--- name: CreateResponseLog :one
-INSERT INTO response_log (id, status)
-VALUES ($1, $2)
-RETURNING *;
+-- name: CreateRequestLogs :exec
+-- a nil user or space id is a request without one
+INSERT INTO request_log (
+  id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id
+)
+SELECT
+  id,
+  method,
+  path,
+  NULLIF(user_id, '00000000-0000-0000-0000-000000000000'),
+  created_at,
+  client_ip,
+  user_agent,
+  query,
+  size,
+  auth_method,
+  NULLIF(space_id, '00000000-0000-0000-0000-000000000000'),
+  request_id
+FROM unnest(
+  @ids::uuid[],
+  @methods::varchar[],
+  @paths::varchar[],
+  @user_ids::uuid[],
+  @created_at::timestamp[],
+  @client_ips::varchar[],
+  @user_agents::varchar[],
+  @queries::varchar[],
+  @sizes::bigint[],
+  @auth_methods::varchar[],
+  @space_ids::uuid[],
+  @request_ids::varchar[]
+) AS l(id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id);
 
+-- name: CreateResponseLogs :exec
+-- responses are partitioned by the time of their request
+INSERT INTO response_log (id, requested_at, status, created_at, size, latency_us, outcome)
+SELECT
+  unnest(@ids::uuid[]),
+  unnest(@requested_at::timestamp[]),
+  unnest(@statuses::int[]),
+  unnest(@created_at::timestamp[]),
+  unnest(@sizes::bigint[]),
+  unnest(@latencies_us::bigint[]),
+  unnest(@outcomes::varchar[]);
+
+-- name: ListAuditLogs :many
+-- the entries matching the given filters, newest first, older than the
+-- entry of the cursor when given. A nil user id matches any user, paths are
+-- matched with LIKE.
+SELECT
+  r.id,
+  r.method,
+  r.path,
+  r.query,
+  r.user_id,
+  r.auth_method,
+  r.space_id,
+  r.request_id,
+  r.client_ip,
+  r.user_agent,
+  r.size AS request_size,
+  r.created_at AS requested_at,
+  s.status,
+  s.size AS response_size,
+  s.latency_us,
+  s.outcome,
+  s.created_at AS responded_at
+FROM request_log r
+JOIN response_log s ON s.id = r.id
+WHERE (sqlc.arg(user_id)::uuid = '00000000-0000-0000-0000-000000000000' OR r.user_id = sqlc.arg(user_id))
+AND (sqlc.narg(path)::varchar IS NULL OR r.path LIKE sqlc.narg(path))
+AND (sqlc.narg(method)::varchar IS NULL OR r.method = sqlc.narg(method))
+AND (sqlc.narg(min_status)::int IS NULL OR s.status >= sqlc.narg(min_status))
+AND (sqlc.narg(max_status)::int IS NULL OR s.status <= sqlc.narg(max_status))
+AND (sqlc.narg(since)::timestamp IS NULL OR r.created_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR r.created_at < sqlc.narg(until))
+AND (
+  sqlc.narg(cursor_at)::timestamp IS NULL
+  OR (r.created_at, r.id) < (sqlc.narg(cursor_at), sqlc.arg(cursor_id)::uuid)
+)
+ORDER BY r.created_at DESC, r.id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,24 @@
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CountEmailVerificationTokensSince :one
+SELECT count(*) FROM email_verification_tokens
+WHERE user_id = $1
+AND created_at > $2;
+
+-- name: ConsumeEmailVerificationToken :one
+-- verification tokens are single use, a token is only returned once and before it expires
+UPDATE email_verification_tokens
+SET used_at = now()
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > now()
+RETURNING *;
+
+-- name: InvalidateEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = now()
+WHERE user_id = $1
+AND used_at IS NULL;
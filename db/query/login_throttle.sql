@@ -0,0 +1,55 @@
+-- name: ListLoginThrottles :many
+-- the throttle of an account for a client IP and the one of the client IP
+-- across accounts
+SELECT * FROM login_throttles
+WHERE client_ip = $1
+AND email IN ('', $2);
+
+-- name: RecordLoginFailure :one
+-- the count starts over when the previous failure is older than window_start
+INSERT INTO login_throttles (email, client_ip, failures)
+VALUES ($1, $2, 1)
+ON CONFLICT (email, client_ip) DO UPDATE
+SET failures = CASE
+    WHEN login_throttles.updated_at < sqlc.arg(window_start)::timestamp THEN 1
+    ELSE login_throttles.failures + 1
+  END,
+  updated_at = now()
+RETURNING *;
+
+-- name: BlockLogin :exec
+UPDATE login_throttles
+SET blocked_until = $3
+WHERE email = $1
+AND client_ip = $2;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE email = $1
+AND client_ip = $2;
+
+-- name: DeleteLoginThrottles :execrows
+-- a NULL filter matches every row
+DELETE FROM login_throttles
+WHERE (sqlc.narg(email)::varchar IS NULL OR email = sqlc.narg(email))
+AND (sqlc.narg(client_ip)::varchar IS NULL OR client_ip = sqlc.narg(client_ip));
+
+-- name: CreateLoginLockout :one
+INSERT INTO login_lockouts (email, client_ip, failures, locked_until)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListActiveLoginLockouts :many
+SELECT * FROM login_lockouts
+WHERE unlocked_at IS NULL
+AND locked_until > now()
+ORDER BY created_at DESC;
+
+-- name: UnlockLoginLockouts :execrows
+-- a NULL filter matches every active lockout
+UPDATE login_lockouts
+SET unlocked_at = now()
+WHERE unlocked_at IS NULL
+AND locked_until > now()
+AND (sqlc.narg(email)::varchar IS NULL OR email = sqlc.narg(email))
+AND (sqlc.narg(client_ip)::varchar IS NULL OR client_ip = sqlc.narg(client_ip));
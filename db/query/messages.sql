@@ -0,0 +1,8 @@
+-- name: ListMessagesByAuthor :many
+SELECT * FROM messages
+WHERE author = $1
+ORDER BY created_at;
+
+-- name: DeleteSpaceMessages :exec
+DELETE FROM messages
+WHERE space_id = $1;
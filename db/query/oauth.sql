@@ -0,0 +1,122 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (owner_id, name, secret_hash, redirect_uris, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1
+AND revoked_at IS NULL
+LIMIT 1;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+AND revoked_at IS NULL
+ORDER BY created_at;
+
+-- name: RevokeOAuthClient :execrows
+UPDATE oauth_clients
+SET revoked_at = now()
+WHERE id = $1
+AND owner_id = $2
+AND revoked_at IS NULL;
+
+-- name: RevokeOAuthClientsByOwner :exec
+UPDATE oauth_clients
+SET revoked_at = now()
+WHERE owner_id = $1
+AND revoked_at IS NULL;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+  code_hash, client_id, user_id, redirect_uri, redirect_uri_sent, scopes, space_ids, code_challenge, expires_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: ConsumeOAuthAuthorizationCode :one
+DELETE FROM oauth_authorization_codes
+WHERE code_hash = $1
+AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at <= now();
+
+-- name: DeleteOAuthAuthorizationCodesByUser :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1;
+
+-- name: CreateOAuthAccessToken :one
+INSERT INTO oauth_access_tokens (
+  id, grant_id, token_hash, client_id, user_id, scopes, space_ids, expires_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetActiveOAuthAccessTokenByHash :one
+SELECT * FROM oauth_access_tokens
+WHERE token_hash = $1
+AND revoked_at IS NULL
+AND expires_at > now()
+AND EXISTS (
+  SELECT 1 FROM oauth_clients
+  WHERE oauth_clients.id = oauth_access_tokens.client_id
+  AND oauth_clients.revoked_at IS NULL
+)
+LIMIT 1;
+
+-- name: RevokeOAuthAccessToken :execrows
+UPDATE oauth_access_tokens
+SET revoked_at = now()
+WHERE token_hash = $1
+AND revoked_at IS NULL;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO oauth_refresh_tokens (
+  grant_id, token_hash, client_id, user_id, scopes, space_ids, expires_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetOAuthRefreshTokenByHash :one
+SELECT * FROM oauth_refresh_tokens
+WHERE token_hash = $1
+LIMIT 1;
+
+-- name: RotateOAuthRefreshToken :one
+-- refresh tokens are single use, a new one is issued with every refresh
+UPDATE oauth_refresh_tokens
+SET revoked_at = now()
+WHERE token_hash = $1
+AND revoked_at IS NULL
+AND expires_at > now()
+AND EXISTS (
+  SELECT 1 FROM oauth_clients
+  WHERE oauth_clients.id = oauth_refresh_tokens.client_id
+  AND oauth_clients.revoked_at IS NULL
+)
+RETURNING *;
+
+-- name: RevokeOAuthGrant :exec
+WITH revoked_refresh_tokens AS (
+  UPDATE oauth_refresh_tokens
+  SET revoked_at = now()
+  WHERE oauth_refresh_tokens.grant_id = $1
+  AND oauth_refresh_tokens.revoked_at IS NULL
+)
+UPDATE oauth_access_tokens
+SET revoked_at = now()
+WHERE oauth_access_tokens.grant_id = $1
+AND oauth_access_tokens.revoked_at IS NULL;
+
+-- name: RevokeOAuthTokensByUser :exec
+WITH revoked_refresh_tokens AS (
+  UPDATE oauth_refresh_tokens
+  SET revoked_at = now()
+  WHERE oauth_refresh_tokens.user_id = $1
+  AND oauth_refresh_tokens.revoked_at IS NULL
+)
+UPDATE oauth_access_tokens
+SET revoked_at = now()
+WHERE oauth_access_tokens.user_id = $1
+AND oauth_access_tokens.revoked_at IS NULL;
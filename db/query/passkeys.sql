@@ -0,0 +1,56 @@
+-- name: CreatePasskey :one
+INSERT INTO passkeys (user_id, credential_id, public_key, sign_count, name)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPasskeyByCredentialID :one
+SELECT * FROM passkeys
+WHERE credential_id = $1 LIMIT 1;
+
+-- name: ListPasskeysByUser :many
+SELECT * FROM passkeys
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UsePasskey :execrows
+UPDATE passkeys
+SET sign_count = $2, last_used_at = now()
+WHERE id = $1
+AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0));
+
+-- name: DeletePasskey :execrows
+DELETE FROM passkeys
+WHERE id = $1
+AND user_id = $2;
+
+-- name: DeletePasskeysByUser :exec
+DELETE FROM passkeys
+WHERE user_id = $1;
+
+-- name: CreateRegistrationChallenge :one
+INSERT INTO webauthn_challenges (user_id, challenge, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateLoginChallenge :one
+INSERT INTO webauthn_challenges (challenge, expires_at)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: ConsumeRegistrationChallenge :one
+DELETE FROM webauthn_challenges
+WHERE id = $1
+AND user_id = $2
+AND expires_at > now()
+RETURNING *;
+
+-- name: ConsumeLoginChallenge :one
+DELETE FROM webauthn_challenges
+WHERE id = $1
+AND user_id IS NULL
+AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredWebauthnChallenges :exec
+DELETE FROM webauthn_challenges
+WHERE expires_at <= now();
@@ -0,0 +1,24 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CountPasswordResetTokensSince :one
+SELECT count(*) FROM password_reset_tokens
+WHERE user_id = $1
+AND created_at > $2;
+
+-- name: ConsumePasswordResetToken :one
+-- reset tokens are single use, a token is only returned once and before it expires
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > now()
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE user_id = $1
+AND used_at IS NULL;
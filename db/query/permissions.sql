@@ -29,3 +29,31 @@ WHERE user_id = $1
 AND space_id = $2
 LIMIT 1;
 
+
+-- name: ListPermissionsByUser :many
+SELECT * FROM permissions
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetSpaceSuccessor :one
+-- the member with the most access to a space, the longest standing first,
+-- other than its current owner
+SELECT * FROM permissions
+WHERE space_id = $1
+AND user_id <> $2
+ORDER BY delete_permission DESC, write_permission DESC, read_permission DESC, created_at
+LIMIT 1;
+
+-- name: GrantAllPermissions :exec
+UPDATE permissions
+SET read_permission = true, write_permission = true, delete_permission = true
+WHERE user_id = $1
+AND space_id = $2;
+
+-- name: DeletePermissionsByUser :exec
+DELETE FROM permissions
+WHERE user_id = $1;
+
+-- name: DeleteSpacePermissions :exec
+DELETE FROM permissions
+WHERE space_id = $1;
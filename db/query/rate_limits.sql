@@ -0,0 +1,28 @@
+-- name: TakeRateLimit :one
+-- takes a request from the bucket of key, atomically. A request is allowed
+-- when it doesn't push the tat, as seen at now, further than tolerance
+-- seconds ahead, each request pushing it emission_interval seconds. ahead
+-- is how many seconds the tat is ahead after the request. The row is always
+-- updated so that it is returned, even when inserted concurrently.
+INSERT INTO rate_limits (bucket, key, tat, allowed)
+VALUES (
+  sqlc.arg(bucket),
+  sqlc.arg(key),
+  now() + make_interval(secs => sqlc.arg(emission_interval)::float8),
+  true
+)
+ON CONFLICT (bucket, key) DO UPDATE
+SET allowed = GREATEST(rate_limits.tat, now()) + make_interval(secs => sqlc.arg(emission_interval)::float8)
+    <= now() + make_interval(secs => sqlc.arg(tolerance)::float8),
+  tat = CASE
+    WHEN GREATEST(rate_limits.tat, now()) + make_interval(secs => sqlc.arg(emission_interval)::float8)
+      <= now() + make_interval(secs => sqlc.arg(tolerance)::float8)
+    THEN GREATEST(rate_limits.tat, now()) + make_interval(secs => sqlc.arg(emission_interval)::float8)
+    ELSE rate_limits.tat
+  END
+RETURNING allowed, extract(epoch FROM GREATEST(tat, now()) - now())::float8 AS ahead;
+
+-- name: DeleteIdleRateLimits :execrows
+-- the buckets of clients idle long enough to be full again
+DELETE FROM rate_limits
+WHERE tat < now();
@@ -0,0 +1,45 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, client_ip, expires_at, token_hash)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetActiveSessionByTokenHash :one
+SELECT * FROM sessions
+WHERE token_hash = $1
+AND revoked_at IS NULL
+AND expires_at > now()
+LIMIT 1;
+
+-- name: GetSessionByID :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListActiveSessionsByUser :many
+SELECT * FROM sessions
+WHERE user_id = $1
+AND revoked_at IS NULL
+AND expires_at > now()
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE user_id = $1
+AND id <> $2
+AND revoked_at IS NULL;
+
+-- name: TouchSessions :exec
+UPDATE sessions
+SET last_seen_at = seen.last_seen_at
+FROM (
+  SELECT unnest(@ids::uuid[]) AS id, unnest(@last_seen::timestamp[]) AS last_seen_at
+) AS seen
+WHERE sessions.id = seen.id
+AND sessions.last_seen_at < seen.last_seen_at;
@@ -26,3 +26,38 @@ RETURNING *;
 -- name: DeleteSpace :exec
 DELETE FROM spaces
 WHERE id = $1;
+
+-- name: ListSpacesByOwner :many
+SELECT * FROM spaces
+WHERE owner = $1
+ORDER BY created_at;
+
+-- name: TransferSpace :one
+UPDATE spaces
+SET owner = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateSpaceEvent :exec
+-- a nil subject id is an event about no member in particular
+INSERT INTO space_events (space_id, type, actor_id, subject_id, details, request_id)
+VALUES (
+  sqlc.arg(space_id),
+  sqlc.arg(type),
+  sqlc.arg(actor_id),
+  NULLIF(sqlc.arg(subject_id)::uuid, '00000000-0000-0000-0000-000000000000'),
+  sqlc.arg(details),
+  sqlc.arg(request_id)
+);
+
+-- name: ListSpaceEvents :many
+-- the events of a space, newest first, older than the event of the cursor
+-- when given
+SELECT * FROM space_events
+WHERE space_id = sqlc.arg(space_id)
+AND (
+  sqlc.narg(cursor_at)::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg(cursor_at), sqlc.arg(cursor_id)::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,68 @@
+-- name: UpsertTOTP :one
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+WHERE user_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPByUserID :one
+SELECT * FROM user_totp
+WHERE user_id = $1 LIMIT 1;
+
+-- name: ConfirmTOTP :one
+UPDATE user_totp
+SET confirmed_at = now(), last_used_step = $2
+WHERE user_id = $1
+AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1
+AND last_used_step < $2;
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT $1, unnest(@code_hashes::varchar[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: GetSecondFactorThrottle :one
+SELECT * FROM second_factor_throttles
+WHERE user_id = $1 LIMIT 1;
+
+-- name: RecordSecondFactorFailure :one
+-- the count starts over when the previous failure is older than window_start
+INSERT INTO second_factor_throttles (user_id, failures)
+VALUES ($1, 1)
+ON CONFLICT (user_id) DO UPDATE
+SET failures = CASE
+    WHEN second_factor_throttles.updated_at < sqlc.arg(window_start)::timestamp THEN 1
+    ELSE second_factor_throttles.failures + 1
+  END,
+  updated_at = now()
+RETURNING *;
+
+-- name: LockSecondFactor :exec
+UPDATE second_factor_throttles
+SET failures = 0, locked_until = $2
+WHERE user_id = $1;
+
+-- name: DeleteSecondFactorThrottle :exec
+DELETE FROM second_factor_throttles
+WHERE user_id = $1;
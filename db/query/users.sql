@@ -3,6 +3,78 @@ INSERT INTO users (email, password)
 VALUES ( $1, $2)
 RETURNING *;
 
+-- name: AnonymizeUser :one
+-- deleted users are kept for the records referencing them, with a random
+-- email and no password nobody can log in with
+UPDATE users
+SET email = substr(md5(random()::text), 1, 14) || '@deleted.invalid',
+    password = '',
+    email_verified_at = NULL,
+    deleted_at = now(),
+    display_name = '',
+    avatar = '',
+    timezone = DEFAULT,
+    locale = DEFAULT
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING *;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1 LIMIT 1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1 LIMIT 1;
+
+-- name: RehashUserPassword :execrows
+-- the hash is only replaced if the password has not changed in the meantime
+UPDATE users
+SET password = sqlc.arg(new_password)
+WHERE id = $1
+AND password = sqlc.arg(old_password);
+
+-- name: UpdateUserProfile :one
+-- only the given fields are changed
+UPDATE users
+SET display_name = COALESCE(sqlc.narg(display_name), display_name),
+    avatar = COALESCE(sqlc.narg(avatar), avatar),
+    timezone = COALESCE(sqlc.narg(timezone), timezone),
+    locale = COALESCE(sqlc.narg(locale), locale)
+WHERE id = sqlc.arg(id)
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UsersShareSpace :one
+SELECT EXISTS (
+  SELECT 1 FROM permissions
+  JOIN permissions AS other_permissions
+  ON other_permissions.space_id = permissions.space_id
+  WHERE permissions.user_id = sqlc.arg(user_id)
+  AND other_permissions.user_id = sqlc.arg(other_user_id)
+);
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1;
+
+-- name: VerifyUserEmail :one
+-- the first verification date is kept
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now())
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserAdmin :one
+UPDATE users
+SET is_admin = $2
+WHERE email = $1
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ListAdmins :many
+SELECT * FROM users
+WHERE is_admin
+AND deleted_at IS NULL
+ORDER BY email;
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Luckny/space-it/pkg/apikey"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User, expiresAt pgtype.Timestamp) ApiKey {
+	generated, err := apikey.Generate()
+	require.NoError(t, err)
+
+	arg := CreateAPIKeyParams{
+		UserID:    user.ID,
+		Name:      "ci",
+		Prefix:    generated.DisplayPrefix,
+		KeyHash:   generated.Hash,
+		Scopes:    []string{"spaces:read", "messages:write"},
+		ExpiresAt: expiresAt,
+	}
+
+	key, err := testStore.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserID, key.UserID)
+	require.Equal(t, arg.KeyHash, key.KeyHash)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.False(t, key.LastUsedAt.Valid)
+	require.False(t, key.RevokedAt.Valid)
+
+	return key
+}
+
+func TestGetActiveAPIKeyByHash(t *testing.T) {
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, user, pgtype.Timestamp{})
+
+	got, err := testStore.GetActiveAPIKeyByHash(context.Background(), key.KeyHash)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+
+	expired := createRandomAPIKey(t, user, pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Minute), Valid: true})
+	_, err = testStore.GetActiveAPIKeyByHash(context.Background(), expired.KeyHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, user, pgtype.Timestamp{})
+
+	err := testStore.TouchAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+
+	got, err := testStore.GetActiveAPIKeyByHash(context.Background(), key.KeyHash)
+	require.NoError(t, err)
+	require.True(t, got.LastUsedAt.Valid)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	key := createRandomAPIKey(t, user, pgtype.Timestamp{})
+
+	revoked, err := testStore.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{ID: key.ID, UserID: other.ID})
+	require.NoError(t, err)
+	require.Zero(t, revoked)
+
+	revoked, err = testStore.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{ID: key.ID, UserID: user.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+
+	_, err = testStore.GetActiveAPIKeyByHash(context.Background(), key.KeyHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	keys, err := testStore.ListAPIKeysByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
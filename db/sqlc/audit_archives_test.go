@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveAuditLogs(t *testing.T) {
+	// a month no other test logs in, archived once
+	month := pgtype.Timestamp{
+		Time:  time.Date(1000+rand.Intn(900), time.Month(1+rand.Intn(12)), 1, 0, 0, 0, 0, time.UTC),
+		Valid: true,
+	}
+	require.NoError(t, testStore.CreateAuditLogPartitions(context.Background(), month))
+	require.NoError(t, testStore.CreateAuditLogPartitions(context.Background(), month))
+
+	months, err := testStore.ListAuditLogPartitions(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, months, month)
+
+	arg := auditLogsParams(3)
+	for i := range arg.Requests.CreatedAt {
+		at := pgtype.Timestamp{Time: month.Time.Add(time.Duration(i) * time.Hour), Valid: true}
+		arg.Requests.CreatedAt[i], arg.Responses.RequestedAt[i], arg.Responses.CreatedAt[i] = at, at, at
+	}
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), arg))
+
+	summary, err := testStore.GetAuditLogsSummary(context.Background(), month)
+	require.NoError(t, err)
+	require.Equal(t, GetAuditLogsSummaryRow{Entries: 3}, summary)
+
+	// entries are read a page after another, oldest first
+	page, err := testStore.ListAuditLogsForArchive(context.Background(), ListAuditLogsForArchiveParams{
+		Month:    month,
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, arg.Requests.Ids[0], page[0].ID)
+	require.Zero(t, page[0].Seq)
+
+	page, err = testStore.ListAuditLogsForArchive(context.Background(), ListAuditLogsForArchiveParams{
+		Month:    month,
+		CursorAt: page[1].RequestedAt,
+		CursorID: page[1].ID,
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, arg.Requests.Ids[2], page[0].ID)
+
+	// the entries are counted by the database
+	archive := ArchiveAuditLogsParams{
+		Month:    month,
+		Path:     "/archives/audit.ndjson.gz",
+		Checksum: []byte("checksum"),
+	}
+	entries, err := testStore.ArchiveAuditLogs(context.Background(), archive)
+	require.NoError(t, err)
+	require.Equal(t, summary.Entries, entries)
+
+	months, err = testStore.ListAuditLogPartitions(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, months, month)
+
+	archives, err := testStore.ListAuditArchives(context.Background())
+	require.NoError(t, err)
+	index := slices.IndexFunc(archives, func(a AuditArchive) bool { return a.Month == month })
+	require.GreaterOrEqual(t, index, 0)
+	require.Equal(t, archive.Path, archives[index].Path)
+	require.Equal(t, archive.Checksum, archives[index].Checksum)
+	require.Equal(t, summary.Entries, archives[index].Entries)
+	require.Equal(t, summary.Chained, archives[index].Chained)
+
+	// a month is archived once
+	_, err = testStore.ArchiveAuditLogs(context.Background(), archive)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, UniqueViolation, pgErr.Code)
+}
+
+func TestArchiveAuditLogsAcrossMonths(t *testing.T) {
+	// a request at the very end of a month no other test logs in, responded
+	// to in the next one
+	month := pgtype.Timestamp{
+		Time:  time.Date(1000+rand.Intn(900), time.Month(1+rand.Intn(12)), 1, 0, 0, 0, 0, time.UTC),
+		Valid: true,
+	}
+	next := pgtype.Timestamp{Time: month.Time.AddDate(0, 1, 0), Valid: true}
+	require.NoError(t, testStore.CreateAuditLogPartitions(context.Background(), month))
+	require.NoError(t, testStore.CreateAuditLogPartitions(context.Background(), next))
+
+	arg := auditLogsParams(1)
+	requestedAt := pgtype.Timestamp{Time: next.Time.Add(-time.Microsecond), Valid: true}
+	arg.Requests.CreatedAt[0], arg.Responses.RequestedAt[0] = requestedAt, requestedAt
+	arg.Responses.CreatedAt[0] = pgtype.Timestamp{Time: next.Time.Add(time.Second), Valid: true}
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), arg))
+
+	// the entry is archived with the month of its request
+	summary, err := testStore.GetAuditLogsSummary(context.Background(), month)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), summary.Entries)
+
+	page, err := testStore.ListAuditLogsForArchive(context.Background(), ListAuditLogsForArchiveParams{
+		Month:    month,
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, arg.Responses.CreatedAt[0], page[0].RespondedAt)
+
+	summary, err = testStore.GetAuditLogsSummary(context.Background(), next)
+	require.NoError(t, err)
+	require.Zero(t, summary.Entries)
+
+	// and its response is dropped with it, nothing is left in the next month
+	entries, err := testStore.ArchiveAuditLogs(context.Background(), ArchiveAuditLogsParams{Month: month})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), entries)
+
+	var responses int
+	err = testStore.(*SQLStore).pool.QueryRow(context.Background(),
+		"SELECT count(*) FROM response_log WHERE id = $1", arg.Responses.Ids[0],
+	).Scan(&responses)
+	require.NoError(t, err)
+	require.Zero(t, responses)
+}
+
+func TestArchiveAuditLogsCurrentMonth(t *testing.T) {
+	now := time.Now().UTC()
+	current := pgtype.Timestamp{Time: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	require.NoError(t, testStore.CreateAuditLogPartitions(context.Background(), current))
+
+	// the current month is neither archived nor dropped
+	_, err := testStore.ArchiveAuditLogs(context.Background(), ArchiveAuditLogsParams{Month: current})
+	require.Error(t, err)
+
+	archives, err := testStore.ListAuditArchives(context.Background())
+	require.NoError(t, err)
+	require.False(t, slices.ContainsFunc(archives, func(a AuditArchive) bool { return a.Month == current }))
+
+	months, err := testStore.ListAuditLogPartitions(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, months, current)
+}
@@ -9,71 +9,303 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createAuthenticatedRequestLog = `-- name: CreateAuthenticatedRequestLog :one
-INSERT INTO request_log (method, path, user_id)
-VALUES ($1, $2, $3)
-RETURNING id, method, path, user_id, created_at
+const createRequestLogs = `-- name: CreateRequestLogs :exec
+INSERT INTO request_log (
+  id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id
+)
+SELECT
+  id,
+  method,
+  path,
+  NULLIF(user_id, '00000000-0000-0000-0000-000000000000'),
+  created_at,
+  client_ip,
+  user_agent,
+  query,
+  size,
+  auth_method,
+  NULLIF(space_id, '00000000-0000-0000-0000-000000000000'),
+  request_id
+FROM unnest(
+  $1::uuid[],
+  $2::varchar[],
+  $3::varchar[],
+  $4::uuid[],
+  $5::timestamp[],
+  $6::varchar[],
+  $7::varchar[],
+  $8::varchar[],
+  $9::bigint[],
+  $10::varchar[],
+  $11::uuid[],
+  $12::varchar[]
+) AS l(id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id)
 `
 
-type CreateAuthenticatedRequestLogParams struct {
-	Method string    `json:"method"`
-	Path   string    `json:"path"`
-	UserID uuid.UUID `json:"user_id"`
+type CreateRequestLogsParams struct {
+	Ids         []uuid.UUID        `json:"ids"`
+	Methods     []string           `json:"methods"`
+	Paths       []string           `json:"paths"`
+	UserIds     []uuid.UUID        `json:"user_ids"`
+	CreatedAt   []pgtype.Timestamp `json:"created_at"`
+	ClientIps   []string           `json:"client_ips"`
+	UserAgents  []string           `json:"user_agents"`
+	Queries     []string           `json:"queries"`
+	Sizes       []int64            `json:"sizes"`
+	AuthMethods []string           `json:"auth_methods"`
+	SpaceIds    []uuid.UUID        `json:"space_ids"`
+	RequestIds  []string           `json:"request_ids"`
 }
 
-func (q *Queries) CreateAuthenticatedRequestLog(ctx context.Context, arg CreateAuthenticatedRequestLogParams) (RequestLog, error) {
-	row := q.db.QueryRow(ctx, createAuthenticatedRequestLog, arg.Method, arg.Path, arg.UserID)
-	var i RequestLog
-	err := row.Scan(
-		&i.ID,
-		&i.Method,
-		&i.Path,
-		&i.UserID,
-		&i.CreatedAt,
+// a nil user or space id is a request without one
+func (q *Queries) CreateRequestLogs(ctx context.Context, arg CreateRequestLogsParams) error {
+	_, err := q.db.Exec(ctx, createRequestLogs,
+		arg.Ids,
+		arg.Methods,
+		arg.Paths,
+		arg.UserIds,
+		arg.CreatedAt,
+		arg.ClientIps,
+		arg.UserAgents,
+		arg.Queries,
+		arg.Sizes,
+		arg.AuthMethods,
+		arg.SpaceIds,
+		arg.RequestIds,
 	)
-	return i, err
+	return err
 }
 
-const createResponseLog = `-- name: CreateResponseLog :one
-INSERT INTO response_log (id, status)
-VALUES ($1, $2)
-RETURNING id, status, created_at
+const createResponseLogs = `-- name: CreateResponseLogs :exec
+INSERT INTO response_log (id, requested_at, status, created_at, size, latency_us, outcome)
+SELECT
+  unnest($1::uuid[]),
+  unnest($2::timestamp[]),
+  unnest($3::int[]),
+  unnest($4::timestamp[]),
+  unnest($5::bigint[]),
+  unnest($6::bigint[]),
+  unnest($7::varchar[])
 `
 
-type CreateResponseLogParams struct {
-	ID     uuid.UUID `json:"id"`
-	Status int32     `json:"status"`
+type CreateResponseLogsParams struct {
+	Ids         []uuid.UUID        `json:"ids"`
+	RequestedAt []pgtype.Timestamp `json:"requested_at"`
+	Statuses    []int32            `json:"statuses"`
+	CreatedAt   []pgtype.Timestamp `json:"created_at"`
+	Sizes       []int64            `json:"sizes"`
+	LatenciesUs []int64            `json:"latencies_us"`
+	Outcomes    []string           `json:"outcomes"`
 }
 
-func (q *Queries) CreateResponseLog(ctx context.Context, arg CreateResponseLogParams) (ResponseLog, error) {
-	row := q.db.QueryRow(ctx, createResponseLog, arg.ID, arg.Status)
-	var i ResponseLog
-	err := row.Scan(&i.ID, &i.Status, &i.CreatedAt)
-	return i, err
+// responses are partitioned by the time of their request
+func (q *Queries) CreateResponseLogs(ctx context.Context, arg CreateResponseLogsParams) error {
+	_, err := q.db.Exec(ctx, createResponseLogs,
+		arg.Ids,
+		arg.RequestedAt,
+		arg.Statuses,
+		arg.CreatedAt,
+		arg.Sizes,
+		arg.LatenciesUs,
+		arg.Outcomes,
+	)
+	return err
 }
 
-const createUnauthenticatedRequestLog = `-- name: CreateUnauthenticatedRequestLog :one
-INSERT INTO request_log (method, path)
-VALUES ($1, $2)
-RETURNING id, method, path, user_id, created_at
+const listAuditLogs = `-- name: ListAuditLogs :many
+SELECT
+  r.id,
+  r.method,
+  r.path,
+  r.query,
+  r.user_id,
+  r.auth_method,
+  r.space_id,
+  r.request_id,
+  r.client_ip,
+  r.user_agent,
+  r.size AS request_size,
+  r.created_at AS requested_at,
+  s.status,
+  s.size AS response_size,
+  s.latency_us,
+  s.outcome,
+  s.created_at AS responded_at
+FROM request_log r
+JOIN response_log s ON s.id = r.id
+WHERE ($1::uuid = '00000000-0000-0000-0000-000000000000' OR r.user_id = $1)
+AND ($2::varchar IS NULL OR r.path LIKE $2)
+AND ($3::varchar IS NULL OR r.method = $3)
+AND ($4::int IS NULL OR s.status >= $4)
+AND ($5::int IS NULL OR s.status <= $5)
+AND ($6::timestamp IS NULL OR r.created_at >= $6)
+AND ($7::timestamp IS NULL OR r.created_at < $7)
+AND (
+  $8::timestamp IS NULL
+  OR (r.created_at, r.id) < ($8, $9::uuid)
+)
+ORDER BY r.created_at DESC, r.id DESC
+LIMIT $10
 `
 
-type CreateUnauthenticatedRequestLogParams struct {
-	Method string `json:"method"`
-	Path   string `json:"path"`
+type ListAuditLogsParams struct {
+	UserID    uuid.UUID        `json:"user_id"`
+	Path      pgtype.Text      `json:"path"`
+	Method    pgtype.Text      `json:"method"`
+	MinStatus pgtype.Int4      `json:"min_status"`
+	MaxStatus pgtype.Int4      `json:"max_status"`
+	Since     pgtype.Timestamp `json:"since"`
+	Until     pgtype.Timestamp `json:"until"`
+	CursorAt  pgtype.Timestamp `json:"cursor_at"`
+	CursorID  uuid.UUID        `json:"cursor_id"`
+	PageSize  int32            `json:"page_size"`
 }
 
-func (q *Queries) CreateUnauthenticatedRequestLog(ctx context.Context, arg CreateUnauthenticatedRequestLogParams) (RequestLog, error) {
-	row := q.db.QueryRow(ctx, createUnauthenticatedRequestLog, arg.Method, arg.Path)
-	var i RequestLog
-	err := row.Scan(
-		&i.ID,
-		&i.Method,
-		&i.Path,
-		&i.UserID,
-		&i.CreatedAt,
+type ListAuditLogsRow struct {
+	ID           uuid.UUID        `json:"id"`
+	Method       string           `json:"method"`
+	Path         string           `json:"path"`
+	Query        string           `json:"query"`
+	UserID       uuid.UUID        `json:"user_id"`
+	AuthMethod   string           `json:"auth_method"`
+	SpaceID      uuid.UUID        `json:"space_id"`
+	RequestID    string           `json:"request_id"`
+	ClientIp     string           `json:"client_ip"`
+	UserAgent    string           `json:"user_agent"`
+	RequestSize  int64            `json:"request_size"`
+	RequestedAt  pgtype.Timestamp `json:"requested_at"`
+	Status       int32            `json:"status"`
+	ResponseSize int64            `json:"response_size"`
+	LatencyUs    int64            `json:"latency_us"`
+	Outcome      string           `json:"outcome"`
+	RespondedAt  pgtype.Timestamp `json:"responded_at"`
+}
+
+// the entries matching the given filters, newest first, older than the
+// entry of the cursor when given. A nil user id matches any user, paths are
+// matched with LIKE.
+func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]ListAuditLogsRow, error) {
+	rows, err := q.db.Query(ctx, listAuditLogs,
+		arg.UserID,
+		arg.Path,
+		arg.Method,
+		arg.MinStatus,
+		arg.MaxStatus,
+		arg.Since,
+		arg.Until,
+		arg.CursorAt,
+		arg.CursorID,
+		arg.PageSize,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAuditLogsRow{}
+	for rows.Next() {
+		var i ListAuditLogsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Method,
+			&i.Path,
+			&i.Query,
+			&i.UserID,
+			&i.AuthMethod,
+			&i.SpaceID,
+			&i.RequestID,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.RequestSize,
+			&i.RequestedAt,
+			&i.Status,
+			&i.ResponseSize,
+			&i.LatencyUs,
+			&i.Outcome,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRequestLogsByUser = `-- name: ListRequestLogsByUser :many
+SELECT id, method, path, user_id, created_at, client_ip, user_agent, query, size, auth_method, space_id, request_id FROM request_log
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListRequestLogsByUser(ctx context.Context, userID uuid.UUID) ([]RequestLog, error) {
+	rows, err := q.db.Query(ctx, listRequestLogsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RequestLog{}
+	for rows.Next() {
+		var i RequestLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Method,
+			&i.Path,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.Query,
+			&i.Size,
+			&i.AuthMethod,
+			&i.SpaceID,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listResponseLogsByUser = `-- name: ListResponseLogsByUser :many
+SELECT response_log.id, response_log.requested_at, response_log.status, response_log.created_at, response_log.size, response_log.latency_us, response_log.outcome FROM response_log
+JOIN request_log ON request_log.id = response_log.id
+WHERE request_log.user_id = $1
+ORDER BY response_log.created_at
+`
+
+func (q *Queries) ListResponseLogsByUser(ctx context.Context, userID uuid.UUID) ([]ResponseLog, error) {
+	rows, err := q.db.Query(ctx, listResponseLogsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ResponseLog{}
+	for rows.Next() {
+		var i ResponseLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.RequestedAt,
+			&i.Status,
+			&i.CreatedAt,
+			&i.Size,
+			&i.LatencyUs,
+			&i.Outcome,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
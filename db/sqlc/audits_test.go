@@ -4,52 +4,291 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
-func createTestUnAuthenticatedRequestLog(t *testing.T, userID uuid.UUID) RequestLog {
+// createTestAuthenticatedRequestLog writes an entry of the user and returns
+// its request
+func createTestAuthenticatedRequestLog(t *testing.T, user User) RequestLog {
+	arg := auditLogsParams(1)
+	arg.Requests.UserIds[0] = user.ID
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), arg))
 
-	arg := CreateUnauthenticatedRequestLogParams{
-		Method: http.MethodGet,
-		Path:   "/somepath",
+	requests, err := testStore.ListRequestLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	for _, request := range requests {
+		if request.ID == arg.Requests.Ids[0] {
+			return request
+		}
 	}
 
-	reqLog, err := testStore.CreateUnauthenticatedRequestLog(context.Background(), arg)
-	require.NoError(t, err)
-	require.NotEmpty(t, reqLog)
+	require.FailNow(t, "request log not found")
+	return RequestLog{}
+}
 
-	require.Equal(t, arg.Method, reqLog.Method)
-	require.Equal(t, arg.Path, reqLog.Path)
+func TestListLogsByUser(t *testing.T) {
+	user := createRandomUser(t)
+	reqLog := createTestAuthenticatedRequestLog(t, user)
+	createTestAuthenticatedRequestLog(t, createRandomUser(t))
 
-	require.NotZero(t, reqLog.ID)
-	require.NotZero(t, reqLog.CreatedAt)
+	requests, err := testStore.ListRequestLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Equal(t, reqLog.ID, requests[0].ID)
 
-	return reqLog
+	responses, err := testStore.ListResponseLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.Equal(t, reqLog.ID, responses[0].ID)
+	require.Equal(t, int32(http.StatusOK), responses[0].Status)
 }
 
-func TestCreateRequestLog(t *testing.T) {
+func TestCreateAuditLogsTx(t *testing.T) {
 	user := createRandomUser(t)
-	createTestUnAuthenticatedRequestLog(t, user.ID)
+	now := time.Now().UTC()
+	at := pgtype.Timestamp{Time: now, Valid: true}
+
+	authenticated, anonymous := uuid.New(), uuid.New()
+	spaceID := uuid.New()
+	err := testStore.CreateAuditLogsTx(context.Background(), CreateAuditLogsTxParams{
+		Requests: CreateRequestLogsParams{
+			Ids:         []uuid.UUID{authenticated, anonymous},
+			Methods:     []string{http.MethodGet, http.MethodPost},
+			Paths:       []string{"/somepath", "/otherpath"},
+			UserIds:     []uuid.UUID{user.ID, uuid.Nil},
+			CreatedAt:   []pgtype.Timestamp{at, at},
+			ClientIps:   []string{"192.0.2.1", "2001:db8::1"},
+			UserAgents:  []string{"test", ""},
+			Queries:     []string{"code=REDACTED", ""},
+			Sizes:       []int64{0, 42},
+			AuthMethods: []string{"password", ""},
+			SpaceIds:    []uuid.UUID{spaceID, uuid.Nil},
+			RequestIds:  []string{authenticated.String(), "client-request"},
+		},
+		Responses: CreateResponseLogsParams{
+			Ids:         []uuid.UUID{authenticated, anonymous},
+			RequestedAt: []pgtype.Timestamp{at, at},
+			Statuses:    []int32{http.StatusOK, http.StatusUnauthorized},
+			CreatedAt:   []pgtype.Timestamp{at, at},
+			Sizes:       []int64{128, 30},
+			LatenciesUs: []int64{1500, 200},
+			Outcomes:    []string{"success", "unauthenticated"},
+		},
+	})
+	require.NoError(t, err)
+
+	requests, err := testStore.ListRequestLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Equal(t, authenticated, requests[0].ID)
+	require.WithinDuration(t, now, requests[0].CreatedAt.Time, time.Millisecond)
+	require.Equal(t, "192.0.2.1", requests[0].ClientIp)
+	require.Equal(t, "code=REDACTED", requests[0].Query)
+	require.Equal(t, "password", requests[0].AuthMethod)
+	require.Equal(t, spaceID, requests[0].SpaceID)
+	require.Equal(t, authenticated.String(), requests[0].RequestID)
+
+	responses, err := testStore.ListResponseLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.Equal(t, int32(http.StatusOK), responses[0].Status)
+	require.Equal(t, int64(128), responses[0].Size)
+	require.Equal(t, int64(1500), responses[0].LatencyUs)
+	require.Equal(t, "success", responses[0].Outcome)
+
+	// a response without its request fails the whole batch
+	err = testStore.CreateAuditLogsTx(context.Background(), CreateAuditLogsTxParams{
+		Requests: CreateRequestLogsParams{
+			Ids:         []uuid.UUID{uuid.New()},
+			Methods:     []string{http.MethodGet},
+			Paths:       []string{"/somepath"},
+			UserIds:     []uuid.UUID{user.ID},
+			CreatedAt:   []pgtype.Timestamp{at},
+			ClientIps:   []string{"192.0.2.1"},
+			UserAgents:  []string{""},
+			Queries:     []string{""},
+			Sizes:       []int64{0},
+			AuthMethods: []string{"password"},
+			SpaceIds:    []uuid.UUID{uuid.Nil},
+			RequestIds:  []string{"client-request"},
+		},
+		Responses: CreateResponseLogsParams{
+			Ids:         []uuid.UUID{uuid.New()},
+			RequestedAt: []pgtype.Timestamp{at},
+			Statuses:    []int32{http.StatusOK},
+			CreatedAt:   []pgtype.Timestamp{at},
+			Sizes:       []int64{0},
+			LatenciesUs: []int64{0},
+			Outcomes:    []string{"success"},
+		},
+	})
+	require.Error(t, err)
+
+	requests, err = testStore.ListRequestLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
 }
 
-func TestCreateResponseLog(t *testing.T) {
-	user := createRandomUser(t)
-	reqLog := createTestUnAuthenticatedRequestLog(t, user.ID)
+// auditLogsParams returns the params of a batch of n successful requests
+func auditLogsParams(n int) CreateAuditLogsTxParams {
+	at := pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+
+	var arg CreateAuditLogsTxParams
+	for i := 0; i < n; i++ {
+		id := uuid.New()
+
+		req := &arg.Requests
+		req.Ids = append(req.Ids, id)
+		req.Methods = append(req.Methods, http.MethodGet)
+		req.Paths = append(req.Paths, "/somepath")
+		req.UserIds = append(req.UserIds, uuid.Nil)
+		req.CreatedAt = append(req.CreatedAt, at)
+		req.ClientIps = append(req.ClientIps, "192.0.2.1")
+		req.UserAgents = append(req.UserAgents, "")
+		req.Queries = append(req.Queries, "")
+		req.Sizes = append(req.Sizes, 0)
+		req.AuthMethods = append(req.AuthMethods, "")
+		req.SpaceIds = append(req.SpaceIds, uuid.Nil)
+		req.RequestIds = append(req.RequestIds, id.String())
+
+		res := &arg.Responses
+		res.Ids = append(res.Ids, id)
+		res.RequestedAt = append(res.RequestedAt, at)
+		res.Statuses = append(res.Statuses, http.StatusOK)
+		res.CreatedAt = append(res.CreatedAt, at)
+		res.Sizes = append(res.Sizes, 0)
+		res.LatenciesUs = append(res.LatenciesUs, 0)
+		res.Outcomes = append(res.Outcomes, "success")
+	}
+
+	return arg
+}
 
-	arg := CreateResponseLogParams{
-		ID:     reqLog.ID,
-		Status: http.StatusOK,
+func TestCreateAuditLogsTxChain(t *testing.T) {
+	first := auditLogsParams(2)
+	var prev []byte
+	first.Chain = func(head []byte) [][]byte {
+		prev = head
+		return [][]byte{[]byte("first"), []byte("second")}
 	}
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), first))
 
-	resLog, err := testStore.CreateResponseLog(context.Background(), arg)
+	head, err := testStore.GetAuditChainHead(context.Background())
 	require.NoError(t, err)
+	require.Equal(t, first.Requests.Ids[1], head.ID)
+	require.Equal(t, []byte("second"), head.Hash)
+
+	// the next batch goes on from the last hash
+	next := auditLogsParams(1)
+	next.Chain = func(head []byte) [][]byte {
+		prev = head
+		return [][]byte{[]byte("third")}
+	}
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), next))
+	require.Equal(t, []byte("second"), prev)
+
+	rows, err := testStore.ListAuditChain(context.Background(), ListAuditChainParams{
+		After:    head.Seq - 2,
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	for i, id := range append(first.Requests.Ids, next.Requests.Ids...) {
+		require.Equal(t, head.Seq-1+int64(i), rows[i].Seq)
+		require.Equal(t, id, rows[i].ID)
+	}
+
+	// a batch chained with a hash missing isn't written
+	broken := auditLogsParams(2)
+	broken.Chain = func(head []byte) [][]byte {
+		return [][]byte{[]byte("fourth")}
+	}
+	require.Error(t, testStore.CreateAuditLogsTx(context.Background(), broken))
+}
+
+func TestCreateAuditCheckpoint(t *testing.T) {
+	arg := CreateAuditCheckpointParams{
+		Seq:       time.Now().UnixNano(),
+		Hash:      []byte("hash"),
+		Signature: []byte("signature"),
+	}
+	require.NoError(t, testStore.CreateAuditCheckpoint(context.Background(), arg))
+
+	// another instance checkpointing the same entry changes nothing
+	again := arg
+	again.Signature = []byte("other")
+	require.NoError(t, testStore.CreateAuditCheckpoint(context.Background(), again))
+
+	latest, err := testStore.GetLatestAuditCheckpoint(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, arg.Seq, latest.Seq)
+	require.Equal(t, arg.Signature, latest.Signature)
+}
+
+func TestListAuditLogs(t *testing.T) {
+	user := createRandomUser(t)
+
+	// three requests of the user, a second apart, the last one failing
+	arg := auditLogsParams(3)
+	start := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range arg.Requests.Ids {
+		at := pgtype.Timestamp{Time: start.Add(time.Duration(i) * time.Second), Valid: true}
+		arg.Requests.UserIds[i] = user.ID
+		arg.Requests.CreatedAt[i] = at
+		arg.Responses.RequestedAt[i] = at
+		arg.Responses.CreatedAt[i] = at
+	}
+	arg.Requests.Paths[2] = "/spaces/100%"
+	arg.Responses.Statuses[2] = http.StatusNotFound
+	require.NoError(t, testStore.CreateAuditLogsTx(context.Background(), arg))
+
+	list := func(params ListAuditLogsParams) []uuid.UUID {
+		params.UserID = user.ID
+		if params.PageSize == 0 {
+			params.PageSize = 10
+		}
+
+		rows, err := testStore.ListAuditLogs(context.Background(), params)
+		require.NoError(t, err)
+
+		ids := []uuid.UUID{}
+		for _, row := range rows {
+			require.Equal(t, user.ID, row.UserID)
+			ids = append(ids, row.ID)
+		}
+		return ids
+	}
+	ids := arg.Requests.Ids
 
-	require.NotEmpty(t, resLog)
+	// newest first
+	require.Equal(t, []uuid.UUID{ids[2], ids[1], ids[0]}, list(ListAuditLogsParams{}))
 
-	require.Equal(t, arg.ID, resLog.ID, reqLog.ID)
-	require.Equal(t, arg.Status, resLog.Status)
+	// a page after another
+	require.Equal(t, []uuid.UUID{ids[2], ids[1]}, list(ListAuditLogsParams{PageSize: 2}))
+	require.Equal(t, []uuid.UUID{ids[0]}, list(ListAuditLogsParams{
+		CursorAt: arg.Requests.CreatedAt[1],
+		CursorID: ids[1],
+		PageSize: 2,
+	}))
 
-	require.NotZero(t, resLog.CreatedAt)
+	require.Equal(t, []uuid.UUID{ids[2]}, list(ListAuditLogsParams{
+		MinStatus: pgtype.Int4{Int32: 400, Valid: true},
+	}))
+	require.Equal(t, []uuid.UUID{ids[1], ids[0]}, list(ListAuditLogsParams{
+		MaxStatus: pgtype.Int4{Int32: 399, Valid: true},
+	}))
+	require.Equal(t, []uuid.UUID{ids[2]}, list(ListAuditLogsParams{
+		Path: pgtype.Text{String: `/spaces/%`, Valid: true},
+	}))
+	require.Empty(t, list(ListAuditLogsParams{
+		Method: pgtype.Text{String: http.MethodPost, Valid: true},
+	}))
+	require.Equal(t, []uuid.UUID{ids[1]}, list(ListAuditLogsParams{
+		Since: arg.Requests.CreatedAt[1],
+		Until: arg.Requests.CreatedAt[2],
+	}))
 }
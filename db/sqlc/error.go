@@ -1,6 +1,9 @@
 package db
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -24,3 +27,13 @@ var ErrConnectionFailure = &pgconn.PgError{
 var ErrForeignKeyConstraint = &pgconn.PgError{
 	Code: ForeignKeyViolation,
 }
+
+// IsDataError tells whether err is the database refusing the data written, a
+// data exception or an integrity constraint violation, rather than failing
+func IsDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
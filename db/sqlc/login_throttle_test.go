@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Luckny/space-it/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func recordRandomLoginFailure(t *testing.T, email, clientIP string) LoginThrottle {
+	throttle, err := testStore.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+		Email:       email,
+		ClientIp:    clientIP,
+		WindowStart: pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Hour), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, email, throttle.Email)
+	require.Equal(t, clientIP, throttle.ClientIp)
+
+	return throttle
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	email := util.RandomEmail()
+	clientIP := "198.51.100.1"
+
+	require.Equal(t, int32(1), recordRandomLoginFailure(t, email, clientIP).Failures)
+	require.Equal(t, int32(2), recordRandomLoginFailure(t, email, clientIP).Failures)
+
+	// failures older than the window are forgotten
+	throttle, err := testStore.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+		Email:       email,
+		ClientIp:    clientIP,
+		WindowStart: pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.Failures)
+}
+
+func TestListLoginThrottles(t *testing.T) {
+	email := util.RandomEmail()
+	clientIP := "198.51.100.2"
+
+	recordRandomLoginFailure(t, email, clientIP)
+	recordRandomLoginFailure(t, "", clientIP)
+	recordRandomLoginFailure(t, util.RandomEmail(), clientIP)
+
+	blockedUntil := pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+	err := testStore.BlockLogin(context.Background(), BlockLoginParams{
+		Email:        email,
+		ClientIp:     clientIP,
+		BlockedUntil: blockedUntil,
+	})
+	require.NoError(t, err)
+
+	throttles, err := testStore.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{
+		ClientIp: clientIP,
+		Email:    email,
+	})
+	require.NoError(t, err)
+	require.Len(t, throttles, 2)
+
+	for _, throttle := range throttles {
+		require.Contains(t, []string{email, ""}, throttle.Email)
+		if throttle.Email == email {
+			require.WithinDuration(t, blockedUntil.Time, throttle.BlockedUntil.Time, time.Second)
+		}
+	}
+
+	err = testStore.DeleteLoginThrottle(context.Background(), DeleteLoginThrottleParams{
+		Email:    email,
+		ClientIp: clientIP,
+	})
+	require.NoError(t, err)
+
+	throttles, err = testStore.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{
+		ClientIp: clientIP,
+		Email:    email,
+	})
+	require.NoError(t, err)
+	require.Len(t, throttles, 1)
+}
+
+func TestUnlockLoginTx(t *testing.T) {
+	email := util.RandomEmail()
+	clientIPs := []string{"198.51.100.3", "198.51.100.4"}
+
+	for _, clientIP := range clientIPs {
+		recordRandomLoginFailure(t, email, clientIP)
+
+		lockout, err := testStore.CreateLoginLockout(context.Background(), CreateLoginLockoutParams{
+			Email:       email,
+			ClientIp:    clientIP,
+			Failures:    5,
+			LockedUntil: pgtype.Timestamp{Time: time.Now().UTC().Add(time.Hour), Valid: true},
+		})
+		require.NoError(t, err)
+		require.False(t, lockout.UnlockedAt.Valid)
+	}
+
+	unlocked, err := testStore.UnlockLoginTx(context.Background(), UnlockLoginTxParams{
+		Email: pgtype.Text{String: email, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), unlocked)
+
+	for _, clientIP := range clientIPs {
+		throttles, err := testStore.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{
+			ClientIp: clientIP,
+			Email:    email,
+		})
+		require.NoError(t, err)
+		require.Empty(t, throttles)
+	}
+
+	active, err := testStore.ListActiveLoginLockouts(context.Background())
+	require.NoError(t, err)
+	for _, lockout := range active {
+		require.NotEqual(t, email, lockout.Email)
+	}
+}
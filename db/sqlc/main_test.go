@@ -2,11 +2,11 @@ package db
 
 import (
 	"context"
+	"log"
 	"os"
 	"testing"
 
 	"github.com/Luckny/space-it/pkg/config"
-	"github.com/Luckny/space-it/util"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,7 +16,7 @@ func TestMain(m *testing.M) {
 	config := config.Load("../../")
 	connPool, err := pgxpool.New(context.Background(), config.DBSource)
 	if err != nil {
-		util.ErrorLog.Fatal("cannot connect to db", err)
+		log.Fatal("cannot connect to db: ", err)
 	}
 
 	defer connPool.Close()
@@ -9,6 +9,70 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID        `json:"id"`
+	UserID     uuid.UUID        `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	KeyHash    string           `json:"key_hash"`
+	Scopes     []string         `json:"scopes"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+}
+
+type AuditArchive struct {
+	Month     pgtype.Timestamp `json:"month"`
+	Path      string           `json:"path"`
+	Checksum  []byte           `json:"checksum"`
+	Entries   int64            `json:"entries"`
+	Chained   int64            `json:"chained"`
+	FirstSeq  int64            `json:"first_seq"`
+	LastSeq   int64            `json:"last_seq"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type AuditChain struct {
+	Seq  int64     `json:"seq"`
+	ID   uuid.UUID `json:"id"`
+	Hash []byte    `json:"hash"`
+}
+
+type AuditCheckpoint struct {
+	Seq       int64            `json:"seq"`
+	Hash      []byte           `json:"hash"`
+	Signature []byte           `json:"signature"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID        `json:"id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+}
+
+type LoginLockout struct {
+	ID          uuid.UUID        `json:"id"`
+	Email       string           `json:"email"`
+	ClientIp    string           `json:"client_ip"`
+	Failures    int32            `json:"failures"`
+	LockedUntil pgtype.Timestamp `json:"locked_until"`
+	UnlockedAt  pgtype.Timestamp `json:"unlocked_at"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Email        string           `json:"email"`
+	ClientIp     string           `json:"client_ip"`
+	Failures     int32            `json:"failures"`
+	BlockedUntil pgtype.Timestamp `json:"blocked_until"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
 type Message struct {
 	ID        uuid.UUID        `json:"id"`
 	SpaceID   uuid.UUID        `json:"space_id"`
@@ -16,6 +80,77 @@ type Message struct {
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
 
+type OauthAccessToken struct {
+	ID        uuid.UUID        `json:"id"`
+	GrantID   uuid.UUID        `json:"grant_id"`
+	TokenHash string           `json:"token_hash"`
+	ClientID  uuid.UUID        `json:"client_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Scopes    []string         `json:"scopes"`
+	SpaceIds  []uuid.UUID      `json:"space_ids"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	RevokedAt pgtype.Timestamp `json:"revoked_at"`
+}
+
+type OauthAuthorizationCode struct {
+	ID              uuid.UUID        `json:"id"`
+	CodeHash        string           `json:"code_hash"`
+	ClientID        uuid.UUID        `json:"client_id"`
+	UserID          uuid.UUID        `json:"user_id"`
+	RedirectUri     string           `json:"redirect_uri"`
+	Scopes          []string         `json:"scopes"`
+	SpaceIds        []uuid.UUID      `json:"space_ids"`
+	CodeChallenge   string           `json:"code_challenge"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	ExpiresAt       pgtype.Timestamp `json:"expires_at"`
+	RedirectUriSent bool             `json:"redirect_uri_sent"`
+}
+
+type OauthClient struct {
+	ID           uuid.UUID        `json:"id"`
+	OwnerID      uuid.UUID        `json:"owner_id"`
+	Name         string           `json:"name"`
+	SecretHash   pgtype.Text      `json:"secret_hash"`
+	RedirectUris []string         `json:"redirect_uris"`
+	Scopes       []string         `json:"scopes"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	RevokedAt    pgtype.Timestamp `json:"revoked_at"`
+}
+
+type OauthRefreshToken struct {
+	ID        uuid.UUID        `json:"id"`
+	GrantID   uuid.UUID        `json:"grant_id"`
+	TokenHash string           `json:"token_hash"`
+	ClientID  uuid.UUID        `json:"client_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Scopes    []string         `json:"scopes"`
+	SpaceIds  []uuid.UUID      `json:"space_ids"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	RevokedAt pgtype.Timestamp `json:"revoked_at"`
+}
+
+type Passkey struct {
+	ID           uuid.UUID        `json:"id"`
+	UserID       uuid.UUID        `json:"user_id"`
+	CredentialID []byte           `json:"credential_id"`
+	PublicKey    []byte           `json:"public_key"`
+	SignCount    int64            `json:"sign_count"`
+	Name         string           `json:"name"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	LastUsedAt   pgtype.Timestamp `json:"last_used_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID        `json:"id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+}
+
 type Permission struct {
 	SpaceID          uuid.UUID        `json:"space_id"`
 	UserID           uuid.UUID        `json:"user_id"`
@@ -26,18 +161,63 @@ type Permission struct {
 	UpdatedAt        pgtype.Timestamp `json:"updated_at"`
 }
 
-type RequestLog struct {
+type RateLimit struct {
+	Bucket  string           `json:"bucket"`
+	Key     string           `json:"key"`
+	Tat     pgtype.Timestamp `json:"tat"`
+	Allowed bool             `json:"allowed"`
+}
+
+type RecoveryCode struct {
 	ID        uuid.UUID        `json:"id"`
-	Method    string           `json:"method"`
-	Path      string           `json:"path"`
 	UserID    uuid.UUID        `json:"user_id"`
+	CodeHash  string           `json:"code_hash"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
 
+type RequestLog struct {
+	ID         uuid.UUID        `json:"id"`
+	Method     string           `json:"method"`
+	Path       string           `json:"path"`
+	UserID     uuid.UUID        `json:"user_id"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	ClientIp   string           `json:"client_ip"`
+	UserAgent  string           `json:"user_agent"`
+	Query      string           `json:"query"`
+	Size       int64            `json:"size"`
+	AuthMethod string           `json:"auth_method"`
+	SpaceID    uuid.UUID        `json:"space_id"`
+	RequestID  string           `json:"request_id"`
+}
+
 type ResponseLog struct {
-	ID        uuid.UUID        `json:"id"`
-	Status    int32            `json:"status"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ID          uuid.UUID        `json:"id"`
+	RequestedAt pgtype.Timestamp `json:"requested_at"`
+	Status      int32            `json:"status"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	Size        int64            `json:"size"`
+	LatencyUs   int64            `json:"latency_us"`
+	Outcome     string           `json:"outcome"`
+}
+
+type SecondFactorThrottle struct {
+	UserID      uuid.UUID        `json:"user_id"`
+	Failures    int32            `json:"failures"`
+	LockedUntil pgtype.Timestamp `json:"locked_until"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+}
+
+type Session struct {
+	ID         uuid.UUID        `json:"id"`
+	UserID     uuid.UUID        `json:"user_id"`
+	UserAgent  string           `json:"user_agent"`
+	ClientIp   string           `json:"client_ip"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastSeenAt pgtype.Timestamp `json:"last_seen_at"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+	TokenHash  pgtype.Text      `json:"token_hash"`
 }
 
 type Space struct {
@@ -47,9 +227,43 @@ type Space struct {
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
 
+type SpaceEvent struct {
+	ID        uuid.UUID        `json:"id"`
+	SpaceID   uuid.UUID        `json:"space_id"`
+	Type      string           `json:"type"`
+	ActorID   uuid.UUID        `json:"actor_id"`
+	SubjectID uuid.UUID        `json:"subject_id"`
+	Details   []byte           `json:"details"`
+	RequestID string           `json:"request_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
 type User struct {
+	ID              uuid.UUID        `json:"id"`
+	Email           string           `json:"email"`
+	Password        string           `json:"password"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	EmailVerifiedAt pgtype.Timestamp `json:"email_verified_at"`
+	DeletedAt       pgtype.Timestamp `json:"deleted_at"`
+	DisplayName     string           `json:"display_name"`
+	Avatar          string           `json:"avatar"`
+	Timezone        string           `json:"timezone"`
+	Locale          string           `json:"locale"`
+	IsAdmin         bool             `json:"is_admin"`
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID        `json:"user_id"`
+	Secret       string           `json:"secret"`
+	LastUsedStep int64            `json:"last_used_step"`
+	ConfirmedAt  pgtype.Timestamp `json:"confirmed_at"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+}
+
+type WebauthnChallenge struct {
 	ID        uuid.UUID        `json:"id"`
-	Email     string           `json:"email"`
-	Password  string           `json:"password"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Challenge []byte           `json:"challenge"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
 }
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, owner User) OauthClient {
+	arg := CreateOAuthClientParams{
+		OwnerID:      owner.ID,
+		Name:         "deploy bot",
+		RedirectUris: []string{"https://tool.example.com/callback"},
+		Scopes:       []string{"spaces:read"},
+	}
+
+	client, err := testStore.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.OwnerID, client.OwnerID)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.False(t, client.SecretHash.Valid)
+
+	return client
+}
+
+func randomHash(t *testing.T) string {
+	token, err := oauth.GenerateToken("")
+	require.NoError(t, err)
+	return oauth.Hash(token)
+}
+
+func TestRevokeOAuthClient(t *testing.T) {
+	owner := createRandomUser(t)
+	other := createRandomUser(t)
+	client := createRandomOAuthClient(t, owner)
+
+	revoked, err := testStore.RevokeOAuthClient(context.Background(), RevokeOAuthClientParams{ID: client.ID, OwnerID: other.ID})
+	require.NoError(t, err)
+	require.Zero(t, revoked)
+
+	revoked, err = testStore.RevokeOAuthClient(context.Background(), RevokeOAuthClientParams{ID: client.ID, OwnerID: owner.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+
+	_, err = testStore.GetOAuthClient(context.Background(), client.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestConsumeOAuthAuthorizationCode(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, user)
+
+	code, err := testStore.CreateOAuthAuthorizationCode(context.Background(), CreateOAuthAuthorizationCodeParams{
+		CodeHash:        randomHash(t),
+		ClientID:        client.ID,
+		UserID:          user.ID,
+		RedirectUri:     client.RedirectUris[0],
+		RedirectUriSent: true,
+		Scopes:          client.Scopes,
+		SpaceIds:        []uuid.UUID{},
+		CodeChallenge:   "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		ExpiresAt:       pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	consumed, err := testStore.ConsumeOAuthAuthorizationCode(context.Background(), code.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, code.ID, consumed.ID)
+	require.True(t, consumed.RedirectUriSent)
+
+	// codes are single use
+	_, err = testStore.ConsumeOAuthAuthorizationCode(context.Background(), code.CodeHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestOAuthTokens(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, user)
+	grantID := uuid.New()
+	expiresAt := pgtype.Timestamp{Time: time.Now().UTC().Add(time.Hour), Valid: true}
+
+	accessToken, err := testStore.CreateOAuthAccessToken(context.Background(), CreateOAuthAccessTokenParams{
+		ID:        uuid.New(),
+		GrantID:   grantID,
+		TokenHash: randomHash(t),
+		ClientID:  client.ID,
+		UserID:    user.ID,
+		Scopes:    client.Scopes,
+		SpaceIds:  []uuid.UUID{uuid.New()},
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	got, err := testStore.GetActiveOAuthAccessTokenByHash(context.Background(), accessToken.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, accessToken.SpaceIds, got.SpaceIds)
+
+	refreshToken, err := testStore.CreateOAuthRefreshToken(context.Background(), CreateOAuthRefreshTokenParams{
+		GrantID:   grantID,
+		TokenHash: randomHash(t),
+		ClientID:  client.ID,
+		UserID:    user.ID,
+		Scopes:    client.Scopes,
+		SpaceIds:  []uuid.UUID{},
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	// refresh tokens can only be rotated once
+	_, err = testStore.RotateOAuthRefreshToken(context.Background(), refreshToken.TokenHash)
+	require.NoError(t, err)
+	_, err = testStore.RotateOAuthRefreshToken(context.Background(), refreshToken.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testStore.RevokeOAuthGrant(context.Background(), grantID)
+	require.NoError(t, err)
+
+	_, err = testStore.GetActiveOAuthAccessTokenByHash(context.Background(), accessToken.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
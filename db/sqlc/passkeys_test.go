@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Luckny/space-it/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasskey(t *testing.T, user User) Passkey {
+	arg := CreatePasskeyParams{
+		UserID:       user.ID,
+		CredentialID: []byte(util.RandomEmail()),
+		PublicKey:    []byte("cose key"),
+		Name:         "security key",
+	}
+
+	passkey, err := testStore.CreatePasskey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserID, passkey.UserID)
+	require.Equal(t, arg.CredentialID, passkey.CredentialID)
+	require.Equal(t, arg.PublicKey, passkey.PublicKey)
+	require.Zero(t, passkey.SignCount)
+	require.False(t, passkey.LastUsedAt.Valid)
+
+	return passkey
+}
+
+func TestGetPasskeyByCredentialID(t *testing.T) {
+	user := createRandomUser(t)
+	passkey := createRandomPasskey(t, user)
+
+	got, err := testStore.GetPasskeyByCredentialID(context.Background(), passkey.CredentialID)
+	require.NoError(t, err)
+	require.Equal(t, passkey.ID, got.ID)
+}
+
+func TestUsePasskey(t *testing.T) {
+	user := createRandomUser(t)
+	passkey := createRandomPasskey(t, user)
+
+	used, err := testStore.UsePasskey(context.Background(), UsePasskeyParams{ID: passkey.ID, SignCount: 5})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), used)
+
+	// the counter must increase
+	for _, count := range []int64{5, 4, 0} {
+		used, err = testStore.UsePasskey(context.Background(), UsePasskeyParams{ID: passkey.ID, SignCount: count})
+		require.NoError(t, err)
+		require.Zero(t, used)
+	}
+}
+
+func TestDeletePasskey(t *testing.T) {
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	passkey := createRandomPasskey(t, user)
+
+	deleted, err := testStore.DeletePasskey(context.Background(), DeletePasskeyParams{ID: passkey.ID, UserID: other.ID})
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	deleted, err = testStore.DeletePasskey(context.Background(), DeletePasskeyParams{ID: passkey.ID, UserID: user.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+}
+
+func TestConsumeChallenges(t *testing.T) {
+	user := createRandomUser(t)
+	expiresAt := pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+
+	login, err := testStore.CreateLoginChallenge(context.Background(), CreateLoginChallengeParams{
+		Challenge: []byte("login challenge"),
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	registration, err := testStore.CreateRegistrationChallenge(context.Background(), CreateRegistrationChallengeParams{
+		UserID:    user.ID,
+		Challenge: []byte("registration challenge"),
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	// a registration challenge can't be used to log in
+	_, err = testStore.ConsumeLoginChallenge(context.Background(), registration.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	consumed, err := testStore.ConsumeLoginChallenge(context.Background(), login.ID)
+	require.NoError(t, err)
+	require.Equal(t, login.Challenge, consumed.Challenge)
+
+	// challenges are single use
+	_, err = testStore.ConsumeLoginChallenge(context.Background(), login.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testStore.ConsumeRegistrationChallenge(context.Background(), ConsumeRegistrationChallengeParams{
+		ID:     registration.ID,
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+}
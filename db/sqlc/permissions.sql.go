@@ -150,6 +150,26 @@ func (q *Queries) CreateWritePermission(ctx context.Context, arg CreateWritePerm
 	return i, err
 }
 
+const deletePermissionsByUser = `-- name: DeletePermissionsByUser :exec
+DELETE FROM permissions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeletePermissionsByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deletePermissionsByUser, userID)
+	return err
+}
+
+const deleteSpacePermissions = `-- name: DeleteSpacePermissions :exec
+DELETE FROM permissions
+WHERE space_id = $1
+`
+
+func (q *Queries) DeleteSpacePermissions(ctx context.Context, spaceID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteSpacePermissions, spaceID)
+	return err
+}
+
 const getPermissionsByUserAndSpaceID = `-- name: GetPermissionsByUserAndSpaceID :one
 SELECT space_id, user_id, read_permission, write_permission, delete_permission, created_at, updated_at FROM permissions
 WHERE user_id = $1
@@ -176,3 +196,84 @@ func (q *Queries) GetPermissionsByUserAndSpaceID(ctx context.Context, arg GetPer
 	)
 	return i, err
 }
+
+const getSpaceSuccessor = `-- name: GetSpaceSuccessor :one
+SELECT space_id, user_id, read_permission, write_permission, delete_permission, created_at, updated_at FROM permissions
+WHERE space_id = $1
+AND user_id <> $2
+ORDER BY delete_permission DESC, write_permission DESC, read_permission DESC, created_at
+LIMIT 1
+`
+
+type GetSpaceSuccessorParams struct {
+	SpaceID uuid.UUID `json:"space_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+// the member with the most access to a space, the longest standing first,
+// other than its current owner
+func (q *Queries) GetSpaceSuccessor(ctx context.Context, arg GetSpaceSuccessorParams) (Permission, error) {
+	row := q.db.QueryRow(ctx, getSpaceSuccessor, arg.SpaceID, arg.UserID)
+	var i Permission
+	err := row.Scan(
+		&i.SpaceID,
+		&i.UserID,
+		&i.ReadPermission,
+		&i.WritePermission,
+		&i.DeletePermission,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const grantAllPermissions = `-- name: GrantAllPermissions :exec
+UPDATE permissions
+SET read_permission = true, write_permission = true, delete_permission = true
+WHERE user_id = $1
+AND space_id = $2
+`
+
+type GrantAllPermissionsParams struct {
+	UserID  uuid.UUID `json:"user_id"`
+	SpaceID uuid.UUID `json:"space_id"`
+}
+
+func (q *Queries) GrantAllPermissions(ctx context.Context, arg GrantAllPermissionsParams) error {
+	_, err := q.db.Exec(ctx, grantAllPermissions, arg.UserID, arg.SpaceID)
+	return err
+}
+
+const listPermissionsByUser = `-- name: ListPermissionsByUser :many
+SELECT space_id, user_id, read_permission, write_permission, delete_permission, created_at, updated_at FROM permissions
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListPermissionsByUser(ctx context.Context, userID uuid.UUID) ([]Permission, error) {
+	rows, err := q.db.Query(ctx, listPermissionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Permission{}
+	for rows.Next() {
+		var i Permission
+		if err := rows.Scan(
+			&i.SpaceID,
+			&i.UserID,
+			&i.ReadPermission,
+			&i.WritePermission,
+			&i.DeletePermission,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
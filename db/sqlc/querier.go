@@ -8,26 +8,134 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AnonymizeUser(ctx context.Context, id uuid.UUID) (User, error)
+	ArchiveAuditLogs(ctx context.Context, arg ArchiveAuditLogsParams) (int64, error)
+	BlockLogin(ctx context.Context, arg BlockLoginParams) error
+	ConfirmTOTP(ctx context.Context, arg ConfirmTOTPParams) (UserTotp, error)
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (EmailVerificationToken, error)
+	ConsumeLoginChallenge(ctx context.Context, id uuid.UUID) (WebauthnChallenge, error)
+	ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	ConsumeRegistrationChallenge(ctx context.Context, arg ConsumeRegistrationChallengeParams) (WebauthnChallenge, error)
+	CountEmailVerificationTokensSince(ctx context.Context, arg CountEmailVerificationTokensSinceParams) (int64, error)
+	CountPasswordResetTokensSince(ctx context.Context, arg CountPasswordResetTokensSinceParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAllPermission(ctx context.Context, arg CreateAllPermissionParams) (Permission, error)
-	CreateAuthenticatedRequestLog(ctx context.Context, arg CreateAuthenticatedRequestLogParams) (RequestLog, error)
+	CreateAuditChain(ctx context.Context, arg CreateAuditChainParams) error
+	CreateAuditCheckpoint(ctx context.Context, arg CreateAuditCheckpointParams) error
+	CreateAuditLogPartitions(ctx context.Context, month pgtype.Timestamp) error
 	CreateDeletePermission(ctx context.Context, arg CreateDeletePermissionParams) (Permission, error)
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (WebauthnChallenge, error)
+	CreateLoginLockout(ctx context.Context, arg CreateLoginLockoutParams) (LoginLockout, error)
+	CreateOAuthAccessToken(ctx context.Context, arg CreateOAuthAccessTokenParams) (OauthAccessToken, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (OauthRefreshToken, error)
+	CreatePasskey(ctx context.Context, arg CreatePasskeyParams) (Passkey, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
 	CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error)
 	CreateReadPermission(ctx context.Context, arg CreateReadPermissionParams) (Permission, error)
-	CreateResponseLog(ctx context.Context, arg CreateResponseLogParams) (ResponseLog, error)
+	CreateRecoveryCodes(ctx context.Context, arg CreateRecoveryCodesParams) error
+	CreateRegistrationChallenge(ctx context.Context, arg CreateRegistrationChallengeParams) (WebauthnChallenge, error)
+	CreateRequestLogs(ctx context.Context, arg CreateRequestLogsParams) error
+	CreateResponseLogs(ctx context.Context, arg CreateResponseLogsParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateSpace(ctx context.Context, arg CreateSpaceParams) (Space, error)
-	CreateUnauthenticatedRequestLog(ctx context.Context, arg CreateUnauthenticatedRequestLogParams) (RequestLog, error)
+	CreateSpaceEvent(ctx context.Context, arg CreateSpaceEventParams) error
 	CreateWritePermission(ctx context.Context, arg CreateWritePermissionParams) (Permission, error)
+	DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) error
+	DeleteExpiredWebauthnChallenges(ctx context.Context) error
+	DeleteIdleRateLimits(ctx context.Context) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) error
+	DeleteLoginThrottles(ctx context.Context, arg DeleteLoginThrottlesParams) (int64, error)
+	DeleteOAuthAuthorizationCodesByUser(ctx context.Context, userID uuid.UUID) error
+	DeletePasskey(ctx context.Context, arg DeletePasskeyParams) (int64, error)
+	DeletePasskeysByUser(ctx context.Context, userID uuid.UUID) error
+	DeletePermissionsByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	DeleteSecondFactorThrottle(ctx context.Context, userID uuid.UUID) error
 	DeleteSpace(ctx context.Context, id uuid.UUID) error
+	DeleteSpaceMessages(ctx context.Context, spaceID uuid.UUID) error
+	DeleteSpacePermissions(ctx context.Context, spaceID uuid.UUID) error
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+	GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
+	GetActiveOAuthAccessTokenByHash(ctx context.Context, tokenHash string) (OauthAccessToken, error)
+	GetActiveSessionByTokenHash(ctx context.Context, tokenHash pgtype.Text) (Session, error)
+	GetAuditChain(ctx context.Context, seq int64) (AuditChain, error)
+	GetAuditChainHead(ctx context.Context) (AuditChain, error)
+	GetAuditLogsSummary(ctx context.Context, month pgtype.Timestamp) (GetAuditLogsSummaryRow, error)
+	GetLatestAuditCheckpoint(ctx context.Context) (AuditCheckpoint, error)
+	GetOAuthClient(ctx context.Context, id uuid.UUID) (OauthClient, error)
+	GetOAuthRefreshTokenByHash(ctx context.Context, tokenHash string) (OauthRefreshToken, error)
+	GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (Passkey, error)
 	GetPermissionsByUserAndSpaceID(ctx context.Context, arg GetPermissionsByUserAndSpaceIDParams) (Permission, error)
+	GetSecondFactorThrottle(ctx context.Context, userID uuid.UUID) (SecondFactorThrottle, error)
+	GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error)
 	GetSpaceByID(ctx context.Context, id uuid.UUID) (Space, error)
 	GetSpaceByName(ctx context.Context, name string) (Space, error)
+	GetSpaceSuccessor(ctx context.Context, arg GetSpaceSuccessorParams) (Permission, error)
+	GetTOTPByUserID(ctx context.Context, userID uuid.UUID) (UserTotp, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GrantAllPermissions(ctx context.Context, arg GrantAllPermissionsParams) error
+	InvalidateEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error
+	InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]ApiKey, error)
+	ListActiveLoginLockouts(ctx context.Context) ([]LoginLockout, error)
+	ListActiveSessionsByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListAdmins(ctx context.Context) ([]User, error)
+	ListAuditArchives(ctx context.Context) ([]AuditArchive, error)
+	ListAuditChain(ctx context.Context, arg ListAuditChainParams) ([]ListAuditChainRow, error)
+	ListAuditCheckpoints(ctx context.Context) ([]AuditCheckpoint, error)
+	ListAuditLogPartitions(ctx context.Context) ([]pgtype.Timestamp, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]ListAuditLogsRow, error)
+	ListAuditLogsForArchive(ctx context.Context, arg ListAuditLogsForArchiveParams) ([]ListAuditLogsForArchiveRow, error)
+	ListLoginThrottles(ctx context.Context, arg ListLoginThrottlesParams) ([]LoginThrottle, error)
+	ListMessagesByAuthor(ctx context.Context, author uuid.UUID) ([]Message, error)
+	ListOAuthClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]OauthClient, error)
+	ListPasskeysByUser(ctx context.Context, userID uuid.UUID) ([]Passkey, error)
+	ListPermissionsByUser(ctx context.Context, userID uuid.UUID) ([]Permission, error)
+	ListRequestLogsByUser(ctx context.Context, userID uuid.UUID) ([]RequestLog, error)
+	ListResponseLogsByUser(ctx context.Context, userID uuid.UUID) ([]ResponseLog, error)
+	ListSpaceEvents(ctx context.Context, arg ListSpaceEventsParams) ([]SpaceEvent, error)
 	ListSpaces(ctx context.Context, arg ListSpacesParams) ([]Space, error)
+	ListSpacesByOwner(ctx context.Context, owner uuid.UUID) ([]Space, error)
+	LockAuditChain(ctx context.Context) error
+	LockSecondFactor(ctx context.Context, arg LockSecondFactorParams) error
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RecordSecondFactorFailure(ctx context.Context, arg RecordSecondFactorFailureParams) (SecondFactorThrottle, error)
 	RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error)
+	RevokeAPIKeysByUser(ctx context.Context, userID uuid.UUID) error
+	RevokeOAuthAccessToken(ctx context.Context, tokenHash string) (int64, error)
+	RevokeOAuthClient(ctx context.Context, arg RevokeOAuthClientParams) (int64, error)
+	RevokeOAuthClientsByOwner(ctx context.Context, ownerID uuid.UUID) error
+	RevokeOAuthGrant(ctx context.Context, grantID uuid.UUID) error
+	RevokeOAuthTokensByUser(ctx context.Context, userID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) (int64, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	RotateOAuthRefreshToken(ctx context.Context, tokenHash string) (OauthRefreshToken, error)
+	SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error)
+	TakeRateLimit(ctx context.Context, arg TakeRateLimitParams) (TakeRateLimitRow, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+	TouchSessions(ctx context.Context, arg TouchSessionsParams) error
+	TransferSpace(ctx context.Context, arg TransferSpaceParams) (Space, error)
+	UnlockLoginLockouts(ctx context.Context, arg UnlockLoginLockoutsParams) (int64, error)
 	UpdateSpace(ctx context.Context, arg UpdateSpaceParams) (Space, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpsertTOTP(ctx context.Context, arg UpsertTOTPParams) (UserTotp, error)
+	UsePasskey(ctx context.Context, arg UsePasskeyParams) (int64, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+	UsersShareSpace(ctx context.Context, arg UsersShareSpaceParams) (bool, error)
+	VerifyUserEmail(ctx context.Context, id uuid.UUID) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimit(t *testing.T) {
+	// 1 request per minute, bursts of 2
+	arg := TakeRateLimitParams{
+		Bucket:           "test",
+		Key:              "user:" + uuid.NewString(),
+		EmissionInterval: 60,
+		Tolerance:        120,
+	}
+
+	taken, err := testStore.TakeRateLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, taken.Allowed)
+	require.InDelta(t, 60, taken.Ahead, 1)
+
+	taken, err = testStore.TakeRateLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, taken.Allowed)
+	require.InDelta(t, 120, taken.Ahead, 1)
+
+	// the bucket is empty, throttled requests don't push the tat
+	for i := 0; i < 2; i++ {
+		taken, err = testStore.TakeRateLimit(context.Background(), arg)
+		require.NoError(t, err)
+		require.False(t, taken.Allowed)
+		require.InDelta(t, 120, taken.Ahead, 1)
+	}
+
+	// other keys have their own bucket
+	other := arg
+	other.Key = "user:" + uuid.NewString()
+	taken, err = testStore.TakeRateLimit(context.Background(), other)
+	require.NoError(t, err)
+	require.True(t, taken.Allowed)
+}
+
+func TestTakeRateLimitConcurrently(t *testing.T) {
+	// concurrent first requests on a new key, bursts of 2
+	arg := TakeRateLimitParams{
+		Bucket:           "test",
+		Key:              "user:" + uuid.NewString(),
+		EmissionInterval: 60,
+		Tolerance:        120,
+	}
+
+	n := 20
+	results := make(chan TakeRateLimitRow, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			taken, err := testStore.TakeRateLimit(context.Background(), arg)
+			errs <- err
+			results <- taken
+		}()
+	}
+
+	// every request gets an answer, none is let through for a missing row
+	allowed := 0
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		if taken := <-results; taken.Allowed {
+			allowed++
+		}
+	}
+	require.Equal(t, 2, allowed)
+}
+
+func TestDeleteIdleRateLimits(t *testing.T) {
+	arg := TakeRateLimitParams{
+		Bucket:           "test",
+		Key:              "user:" + uuid.NewString(),
+		EmissionInterval: 60,
+		Tolerance:        60,
+	}
+
+	_, err := testStore.TakeRateLimit(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the bucket is not full again yet
+	_, err = testStore.DeleteIdleRateLimits(context.Background())
+	require.NoError(t, err)
+
+	taken, err := testStore.TakeRateLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, taken.Allowed)
+}
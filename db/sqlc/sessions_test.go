@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User) Session {
+	arg := CreateSessionParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserAgent: "Mozilla/5.0",
+		ClientIp:  "127.0.0.1",
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(time.Hour), Valid: true},
+		TokenHash: pgtype.Text{
+			String: fmt.Sprintf("%x", sha256.Sum256([]byte(uuid.NewString()))),
+			Valid:  true,
+		},
+	}
+
+	session, err := testStore.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.UserID, session.UserID)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.Equal(t, arg.TokenHash, session.TokenHash)
+	require.False(t, session.RevokedAt.Valid)
+
+	require.NotZero(t, session.CreatedAt)
+	require.NotZero(t, session.LastSeenAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user)
+}
+
+func TestListActiveSessionsByUser(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomSession(t, user)
+	}
+
+	sessions, err := testStore.ListActiveSessionsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+
+	for _, session := range sessions {
+		require.Equal(t, user.ID, session.UserID)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	// another user cannot revoke the session
+	other := createRandomUser(t)
+	revoked, err := testStore.RevokeSession(context.Background(), RevokeSessionParams{
+		ID:     session.ID,
+		UserID: other.ID,
+	})
+	require.NoError(t, err)
+	require.Zero(t, revoked)
+
+	revoked, err = testStore.RevokeSession(context.Background(), RevokeSessionParams{
+		ID:     session.ID,
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+
+	session, err = testStore.GetSessionByID(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+}
+
+func TestGetActiveSessionByTokenHash(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	found, err := testStore.GetActiveSessionByTokenHash(context.Background(), session.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, found.ID)
+
+	_, err = testStore.RevokeSession(context.Background(), RevokeSessionParams{
+		ID:     session.ID,
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+
+	// revoked sessions can't be introspected
+	_, err = testStore.GetActiveSessionByTokenHash(context.Background(), session.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	user := createRandomUser(t)
+	current := createRandomSession(t, user)
+	createRandomSession(t, user)
+	createRandomSession(t, user)
+
+	revoked, err := testStore.RevokeOtherSessions(context.Background(), RevokeOtherSessionsParams{
+		UserID: user.ID,
+		ID:     current.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), revoked)
+
+	sessions, err := testStore.ListActiveSessionsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, current.ID, sessions[0].ID)
+}
+
+func TestTouchSessions(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+
+	lastSeen := time.Now().UTC().Add(time.Minute)
+	err := testStore.TouchSessions(context.Background(), TouchSessionsParams{
+		Ids: []uuid.UUID{session1.ID, session2.ID},
+		LastSeen: []pgtype.Timestamp{
+			{Time: lastSeen, Valid: true},
+			{Time: lastSeen, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, id := range []uuid.UUID{session1.ID, session2.ID} {
+		session, err := testStore.GetSessionByID(context.Background(), id)
+		require.NoError(t, err)
+		require.WithinDuration(t, lastSeen, session.LastSeenAt.Time, time.Second)
+	}
+}
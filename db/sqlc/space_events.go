@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// types of the events of the activity feed of a space
+const (
+	SpaceEventCreated     = "space.created"
+	SpaceEventTransferred = "space.transferred"
+	SpaceEventDeleted     = "space.deleted"
+	SpaceEventMemberAdded = "member.added"
+	SpaceEventMemberLeft  = "member.left"
+)
+
+// SpaceEventPermissions are the details of the events granting permissions
+type SpaceEventPermissions struct {
+	Read   bool `json:"read"`
+	Write  bool `json:"write"`
+	Delete bool `json:"delete"`
+}
+
+// SpaceEventName is the detail of the events naming the space, kept for the
+// events of deleted spaces
+type SpaceEventName struct {
+	Name string `json:"name"`
+}
+
+// recordSpaceEvent records an event of a space with its details encoded as
+// JSON, in the transaction of the change it is about
+func recordSpaceEvent(ctx context.Context, q *Queries, arg CreateSpaceEventParams, details any) error {
+	var err error
+	arg.Details, err = json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateSpaceEvent(ctx, arg)
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createSpace = `-- name: CreateSpace :one
@@ -34,6 +35,40 @@ func (q *Queries) CreateSpace(ctx context.Context, arg CreateSpaceParams) (Space
 	return i, err
 }
 
+const createSpaceEvent = `-- name: CreateSpaceEvent :exec
+INSERT INTO space_events (space_id, type, actor_id, subject_id, details, request_id)
+VALUES (
+  $1,
+  $2,
+  $3,
+  NULLIF($4::uuid, '00000000-0000-0000-0000-000000000000'),
+  $5,
+  $6
+)
+`
+
+type CreateSpaceEventParams struct {
+	SpaceID   uuid.UUID `json:"space_id"`
+	Type      string    `json:"type"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	SubjectID uuid.UUID `json:"subject_id"`
+	Details   []byte    `json:"details"`
+	RequestID string    `json:"request_id"`
+}
+
+// a nil subject id is an event about no member in particular
+func (q *Queries) CreateSpaceEvent(ctx context.Context, arg CreateSpaceEventParams) error {
+	_, err := q.db.Exec(ctx, createSpaceEvent,
+		arg.SpaceID,
+		arg.Type,
+		arg.ActorID,
+		arg.SubjectID,
+		arg.Details,
+		arg.RequestID,
+	)
+	return err
+}
+
 const deleteSpace = `-- name: DeleteSpace :exec
 DELETE FROM spaces
 WHERE id = $1
@@ -78,6 +113,60 @@ func (q *Queries) GetSpaceByName(ctx context.Context, name string) (Space, error
 	return i, err
 }
 
+const listSpaceEvents = `-- name: ListSpaceEvents :many
+SELECT id, space_id, type, actor_id, subject_id, details, request_id, created_at FROM space_events
+WHERE space_id = $1
+AND (
+  $2::timestamp IS NULL
+  OR (created_at, id) < ($2, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListSpaceEventsParams struct {
+	SpaceID  uuid.UUID        `json:"space_id"`
+	CursorAt pgtype.Timestamp `json:"cursor_at"`
+	CursorID uuid.UUID        `json:"cursor_id"`
+	PageSize int32            `json:"page_size"`
+}
+
+// the events of a space, newest first, older than the event of the cursor
+// when given
+func (q *Queries) ListSpaceEvents(ctx context.Context, arg ListSpaceEventsParams) ([]SpaceEvent, error) {
+	rows, err := q.db.Query(ctx, listSpaceEvents,
+		arg.SpaceID,
+		arg.CursorAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SpaceEvent{}
+	for rows.Next() {
+		var i SpaceEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.SpaceID,
+			&i.Type,
+			&i.ActorID,
+			&i.SubjectID,
+			&i.Details,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSpaces = `-- name: ListSpaces :many
 SELECT id, name, owner, created_at FROM spaces
 ORDER BY name
@@ -115,6 +204,61 @@ func (q *Queries) ListSpaces(ctx context.Context, arg ListSpacesParams) ([]Space
 	return items, nil
 }
 
+const listSpacesByOwner = `-- name: ListSpacesByOwner :many
+SELECT id, name, owner, created_at FROM spaces
+WHERE owner = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListSpacesByOwner(ctx context.Context, owner uuid.UUID) ([]Space, error) {
+	rows, err := q.db.Query(ctx, listSpacesByOwner, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Space{}
+	for rows.Next() {
+		var i Space
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Owner,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transferSpace = `-- name: TransferSpace :one
+UPDATE spaces
+SET owner = $2
+WHERE id = $1
+RETURNING id, name, owner, created_at
+`
+
+type TransferSpaceParams struct {
+	ID    uuid.UUID `json:"id"`
+	Owner uuid.UUID `json:"owner"`
+}
+
+func (q *Queries) TransferSpace(ctx context.Context, arg TransferSpaceParams) (Space, error) {
+	row := q.db.QueryRow(ctx, transferSpace, arg.ID, arg.Owner)
+	var i Space
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Owner,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const updateSpace = `-- name: UpdateSpace :one
 UPDATE spaces
 SET name = $2
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -12,6 +13,15 @@ import (
 type Store interface {
 	Querier
 	CreateSpaceTx(ctx context.Context, arg CreateSpaceTxParams) (CreateSpaceTxResult, error)
+	AddSpaceMemberTx(ctx context.Context, arg AddSpaceMemberTxParams) (Permission, error)
+	EnableTwoFactorTx(ctx context.Context, arg EnableTwoFactorTxParams) (UserTotp, error)
+	DisableTwoFactorTx(ctx context.Context, userID uuid.UUID) error
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (int64, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordResetToken, error)
+	VerifyEmailTx(ctx context.Context, tokenHash string) (User, error)
+	UnlockLoginTx(ctx context.Context, arg UnlockLoginTxParams) (int64, error)
+	DeleteUserTx(ctx context.Context, arg DeleteUserTxParams) (DeleteUserTxResult, error)
+	CreateAuditLogsTx(ctx context.Context, arg CreateAuditLogsTxParams) error
 }
 
 type SQLStore struct {
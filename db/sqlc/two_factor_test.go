@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func enableRandomTwoFactor(t *testing.T, user User) UserTotp {
+	_, err := testStore.UpsertTOTP(context.Background(), UpsertTOTPParams{
+		UserID: user.ID,
+		Secret: "JBSWY3DPEHPK3PXP",
+	})
+	require.NoError(t, err)
+
+	totp, err := testStore.EnableTwoFactorTx(context.Background(), EnableTwoFactorTxParams{
+		UserID:             user.ID,
+		Step:               100,
+		RecoveryCodeHashes: []string{"hash-1", "hash-2"},
+	})
+	require.NoError(t, err)
+	require.True(t, totp.ConfirmedAt.Valid)
+	require.Equal(t, int64(100), totp.LastUsedStep)
+
+	return totp
+}
+
+func TestUpsertTOTPKeepsConfirmedSecret(t *testing.T) {
+	user := createRandomUser(t)
+	enableRandomTwoFactor(t, user)
+
+	_, err := testStore.UpsertTOTP(context.Background(), UpsertTOTPParams{
+		UserID: user.ID,
+		Secret: "KRSXG5CTMVRXEZLU",
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	user := createRandomUser(t)
+	enableRandomTwoFactor(t, user)
+
+	arg := UseTOTPStepParams{UserID: user.ID, LastUsedStep: 101}
+
+	used, err := testStore.UseTOTPStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), used)
+
+	// the same step cannot be used twice
+	used, err = testStore.UseTOTPStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, used)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	enableRandomTwoFactor(t, user)
+
+	arg := UseRecoveryCodeParams{UserID: user.ID, CodeHash: "hash-1"}
+
+	used, err := testStore.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), used)
+
+	used, err = testStore.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, used)
+}
+
+func TestDisableTwoFactorTx(t *testing.T) {
+	user := createRandomUser(t)
+	enableRandomTwoFactor(t, user)
+
+	err := testStore.DisableTwoFactorTx(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	_, err = testStore.GetTOTPByUserID(context.Background(), user.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	used, err := testStore.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{UserID: user.ID, CodeHash: "hash-2"})
+	require.NoError(t, err)
+	require.Zero(t, used)
+}
+
+func TestSecondFactorThrottle(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testStore.GetSecondFactorThrottle(context.Background(), user.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg := RecordSecondFactorFailureParams{
+		UserID:      user.ID,
+		WindowStart: pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Hour), Valid: true},
+	}
+	for i := int32(1); i <= 2; i++ {
+		throttle, err := testStore.RecordSecondFactorFailure(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, throttle.Failures)
+	}
+
+	// failures older than the window are forgotten
+	arg.WindowStart = pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+	throttle, err := testStore.RecordSecondFactorFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.Failures)
+
+	lockedUntil := pgtype.Timestamp{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+	err = testStore.LockSecondFactor(context.Background(), LockSecondFactorParams{
+		UserID:      user.ID,
+		LockedUntil: lockedUntil,
+	})
+	require.NoError(t, err)
+
+	throttle, err = testStore.GetSecondFactorThrottle(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Zero(t, throttle.Failures)
+	require.WithinDuration(t, lockedUntil.Time, throttle.LockedUntil.Time, time.Second)
+
+	require.NoError(t, testStore.DeleteSecondFactorThrottle(context.Background(), user.ID))
+	_, err = testStore.GetSecondFactorThrottle(context.Background(), user.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
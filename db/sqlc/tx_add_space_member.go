@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type AddSpaceMemberTxParams struct {
+	Permission CreatePermissionParams `json:"permission"`
+	// admin adding the member
+	ActorID   uuid.UUID `json:"actor_id"`
+	RequestID string    `json:"request_id"`
+}
+
+// AddSpaceMemberTx gives a user permissions to a space and records it in the
+// activity of the space
+func (store *SQLStore) AddSpaceMemberTx(ctx context.Context, arg AddSpaceMemberTxParams) (Permission, error) {
+	var permission Permission
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		permission, err = q.CreatePermission(ctx, arg.Permission)
+		if err != nil {
+			return err
+		}
+
+		return recordSpaceEvent(ctx, q, CreateSpaceEventParams{
+			SpaceID:   permission.SpaceID,
+			Type:      SpaceEventMemberAdded,
+			ActorID:   arg.ActorID,
+			SubjectID: permission.UserID,
+			RequestID: arg.RequestID,
+		}, SpaceEventPermissions{
+			Read:   permission.ReadPermission,
+			Write:  permission.WritePermission,
+			Delete: permission.DeletePermission,
+		})
+	})
+
+	if err != nil {
+		return Permission{}, err
+	}
+
+	return permission, nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddSpaceMemberTx(t *testing.T) {
+	owner := createRandomUser(t)
+	member := createRandomUser(t)
+	space := createRandomSpace(t, owner)
+
+	arg := AddSpaceMemberTxParams{
+		Permission: CreatePermissionParams{
+			UserID:         member.ID,
+			SpaceID:        space.ID,
+			ReadPermission: true,
+		},
+		ActorID:   owner.ID,
+		RequestID: "add-member",
+	}
+
+	permission, err := testStore.AddSpaceMemberTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, member.ID, permission.UserID)
+	require.True(t, permission.ReadPermission)
+	require.False(t, permission.WritePermission)
+
+	events := requireSpaceEvents(t, space, SpaceEventMemberAdded)
+	require.Equal(t, owner.ID, events[0].ActorID)
+	require.Equal(t, member.ID, events[0].SubjectID)
+	require.Equal(t, arg.RequestID, events[0].RequestID)
+
+	var details SpaceEventPermissions
+	require.NoError(t, json.Unmarshal(events[0].Details, &details))
+	require.Equal(t, SpaceEventPermissions{Read: true}, details)
+
+	// a member is added once, and nothing is recorded when it fails
+	_, err = testStore.AddSpaceMemberTx(context.Background(), arg)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, UniqueViolation, pgErr.Code)
+	requireSpaceEvents(t, space, SpaceEventMemberAdded)
+}
+
+func TestListSpaceEvents(t *testing.T) {
+	owner := createRandomUser(t)
+	result, err := testStore.CreateSpaceTx(context.Background(), CreateSpaceTxParams{
+		Name:  "events " + owner.ID.String(),
+		Owner: owner.ID,
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		member := createRandomUser(t)
+		_, err := testStore.AddSpaceMemberTx(context.Background(), AddSpaceMemberTxParams{
+			Permission: CreatePermissionParams{UserID: member.ID, SpaceID: result.Space.ID},
+			ActorID:    owner.ID,
+		})
+		require.NoError(t, err)
+	}
+
+	events := requireSpaceEvents(t, result.Space, SpaceEventMemberAdded, SpaceEventMemberAdded, SpaceEventCreated)
+	require.JSONEq(t, `{"name": "`+result.Space.Name+`"}`, string(events[2].Details))
+
+	// the page after the first event holds the others
+	page, err := testStore.ListSpaceEvents(context.Background(), ListSpaceEventsParams{
+		SpaceID:  result.Space.ID,
+		CursorAt: events[0].CreatedAt,
+		CursorID: events[0].ID,
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, events[1:], page)
+}
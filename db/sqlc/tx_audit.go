@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type CreateAuditLogsTxParams struct {
+	Requests  CreateRequestLogsParams  `json:"requests"`
+	Responses CreateResponseLogsParams `json:"responses"`
+
+	// Chain returns the hashes linking the entries, in order, to the chain
+	// whose last hash is prev, nil when the chain is empty. The entries are
+	// left out of the chain when it is nil.
+	Chain func(prev []byte) [][]byte `json:"-"`
+}
+
+// CreateAuditLogsTx writes a batch of requests and of their responses, all
+// or none of them, and appends them to the audit chain. Batches are
+// appended one at a time across server instances.
+func (store *SQLStore) CreateAuditLogsTx(ctx context.Context, arg CreateAuditLogsTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.CreateRequestLogs(ctx, arg.Requests); err != nil {
+			return err
+		}
+
+		if err := q.CreateResponseLogs(ctx, arg.Responses); err != nil {
+			return err
+		}
+
+		if arg.Chain == nil {
+			return nil
+		}
+
+		if err := q.LockAuditChain(ctx); err != nil {
+			return err
+		}
+
+		head, err := q.GetAuditChainHead(ctx)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		hashes := arg.Chain(head.Hash)
+		if len(hashes) != len(arg.Requests.Ids) {
+			return fmt.Errorf("%d hashes for %d entries", len(hashes), len(arg.Requests.Ids))
+		}
+
+		seqs := make([]int64, len(hashes))
+		for i := range seqs {
+			seqs[i] = head.Seq + int64(i) + 1
+		}
+
+		return q.CreateAuditChain(ctx, CreateAuditChainParams{
+			Seqs:   seqs,
+			Ids:    arg.Requests.Ids,
+			Hashes: hashes,
+		})
+	})
+}
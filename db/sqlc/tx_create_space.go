@@ -9,6 +9,8 @@ import (
 type CreateSpaceTxParams struct {
 	Name  string    `json:"name"`
 	Owner uuid.UUID `json:"owner"`
+	// correlates the creation event with the request
+	RequestID string `json:"request_id"`
 }
 
 type CreateSpaceTxResult struct {
@@ -16,7 +18,8 @@ type CreateSpaceTxResult struct {
 	Permission Permission `json:"permission"`
 }
 
-// CreateSpaceTx creates a new space and gives it owner permissions
+// CreateSpaceTx creates a new space and gives it owner permissions, the
+// creation starts the activity of the space
 // returns created space and owner and permission object
 func (store *SQLStore) CreateSpaceTx(
 	ctx context.Context,
@@ -39,7 +42,16 @@ func (store *SQLStore) CreateSpaceTx(
 			UserID:  arg.Owner,
 			SpaceID: result.Space.ID,
 		})
-		return err
+		if err != nil {
+			return err
+		}
+
+		return recordSpaceEvent(ctx, q, CreateSpaceEventParams{
+			SpaceID:   result.Space.ID,
+			Type:      SpaceEventCreated,
+			ActorID:   arg.Owner,
+			RequestID: arg.RequestID,
+		}, SpaceEventName{Name: result.Space.Name})
 	})
 
 	if txErr != nil {
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type DeleteUserTxParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	// when true the spaces of the user go to their member with the most
+	// access, spaces without other members are deleted anyway
+	TransferSpaces bool `json:"transfer_spaces"`
+	// correlates the events of the spaces with the request
+	RequestID string `json:"request_id"`
+}
+
+type DeleteUserTxResult struct {
+	User User `json:"user"`
+	// spaces of the user, with their new owner
+	TransferredSpaces []Space `json:"transferred_spaces"`
+	DeletedSpaces     []Space `json:"deleted_spaces"`
+}
+
+// DeleteUserTx deletes the account of a user. The spaces of the user are
+// transferred or deleted, the user leaves the other spaces, which is recorded
+// in the activity of each space, and every
+// credential of the user is revoked. The user itself is anonymized rather
+// than deleted, the request logs and messages referencing it are kept. It
+// returns ErrRecordNotFound when the user is already deleted.
+func (store *SQLStore) DeleteUserTx(ctx context.Context, arg DeleteUserTxParams) (DeleteUserTxResult, error) {
+	result := DeleteUserTxResult{
+		TransferredSpaces: []Space{},
+		DeletedSpaces:     []Space{},
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		spaces, err := q.ListSpacesByOwner(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, space := range spaces {
+			if arg.TransferSpaces {
+				transferred, err := transferOwnedSpace(ctx, q, space, arg.UserID)
+				if err == nil {
+					err = recordSpaceEvent(ctx, q, CreateSpaceEventParams{
+						SpaceID:   space.ID,
+						Type:      SpaceEventTransferred,
+						ActorID:   arg.UserID,
+						SubjectID: transferred.Owner,
+						RequestID: arg.RequestID,
+					}, SpaceEventName{Name: space.Name})
+					if err != nil {
+						return err
+					}
+
+					result.TransferredSpaces = append(result.TransferredSpaces, transferred)
+					continue
+				}
+				if err != ErrRecordNotFound {
+					return err
+				}
+			}
+
+			if err := deleteSpaceWithContent(ctx, q, space.ID); err != nil {
+				return err
+			}
+
+			err := recordSpaceEvent(ctx, q, CreateSpaceEventParams{
+				SpaceID:   space.ID,
+				Type:      SpaceEventDeleted,
+				ActorID:   arg.UserID,
+				RequestID: arg.RequestID,
+			}, SpaceEventName{Name: space.Name})
+			if err != nil {
+				return err
+			}
+			result.DeletedSpaces = append(result.DeletedSpaces, space)
+		}
+
+		// permissions of the deleted spaces are gone already
+		memberships, err := q.ListPermissionsByUser(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, membership := range memberships {
+			err := recordSpaceEvent(ctx, q, CreateSpaceEventParams{
+				SpaceID:   membership.SpaceID,
+				Type:      SpaceEventMemberLeft,
+				ActorID:   arg.UserID,
+				RequestID: arg.RequestID,
+			}, struct{}{})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := q.DeletePermissionsByUser(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		if err := revokeCredentials(ctx, q, arg.UserID); err != nil {
+			return err
+		}
+
+		result.User, err = q.AnonymizeUser(ctx, arg.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DeleteUserTxResult{}, err
+	}
+
+	return result, nil
+}
+
+// transferOwnedSpace gives the space to its member with the most access, with
+// every permission. It returns ErrRecordNotFound when the owner is the only
+// member.
+func transferOwnedSpace(ctx context.Context, q *Queries, space Space, owner uuid.UUID) (Space, error) {
+	successor, err := q.GetSpaceSuccessor(ctx, GetSpaceSuccessorParams{
+		SpaceID: space.ID,
+		UserID:  owner,
+	})
+	if err != nil {
+		return Space{}, err
+	}
+
+	err = q.GrantAllPermissions(ctx, GrantAllPermissionsParams{
+		UserID:  successor.UserID,
+		SpaceID: space.ID,
+	})
+	if err != nil {
+		return Space{}, err
+	}
+
+	return q.TransferSpace(ctx, TransferSpaceParams{
+		ID:    space.ID,
+		Owner: successor.UserID,
+	})
+}
+
+// deleteSpaceWithContent deletes a space with its messages and memberships
+func deleteSpaceWithContent(ctx context.Context, q *Queries, spaceID uuid.UUID) error {
+	if err := q.DeleteSpaceMessages(ctx, spaceID); err != nil {
+		return err
+	}
+
+	if err := q.DeleteSpacePermissions(ctx, spaceID); err != nil {
+		return err
+	}
+
+	return q.DeleteSpace(ctx, spaceID)
+}
+
+// revokeCredentials signs the user out everywhere and makes every way to
+// authenticate as the user unusable
+func revokeCredentials(ctx context.Context, q *Queries, userID uuid.UUID) error {
+	_, err := q.RevokeOtherSessions(ctx, RevokeOtherSessionsParams{
+		UserID: userID,
+		ID:     uuid.Nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, revoke := range []func(context.Context, uuid.UUID) error{
+		q.RevokeAPIKeysByUser,
+		q.RevokeOAuthClientsByOwner,
+		q.RevokeOAuthTokensByUser,
+		q.DeleteOAuthAuthorizationCodesByUser,
+		q.DeleteTOTP,
+		q.DeleteRecoveryCodes,
+		q.DeletePasskeysByUser,
+		q.InvalidatePasswordResetTokens,
+		q.InvalidateEmailVerificationTokens,
+	} {
+		if err := revoke(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
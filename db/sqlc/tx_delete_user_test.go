@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUserTxTransfersSpaces(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	key := createRandomAPIKey(t, user, pgtype.Timestamp{})
+	request := createTestAuthenticatedRequestLog(t, user)
+
+	// shared space goes to the member with the most access
+	shared := createRandomSpace(t, user)
+	createTestAdminPermission(t, user, shared)
+	reader := createRandomUser(t)
+	createTestReadPermission(t, reader, shared)
+	writer := createRandomUser(t)
+	createTestWritePermission(t, writer, shared)
+
+	// a space without other members is deleted
+	alone := createRandomSpace(t, user)
+	createTestAdminPermission(t, user, alone)
+
+	// the user leaves the spaces of others
+	other := createRandomSpace(t, reader)
+	createTestReadPermission(t, user, other)
+
+	result, err := testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{
+		UserID:         user.ID,
+		TransferSpaces: true,
+		RequestID:      "delete-account",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.TransferredSpaces, 1)
+	require.Equal(t, shared.ID, result.TransferredSpaces[0].ID)
+	require.Equal(t, writer.ID, result.TransferredSpaces[0].Owner)
+	require.Len(t, result.DeletedSpaces, 1)
+	require.Equal(t, alone.ID, result.DeletedSpaces[0].ID)
+
+	successor, err := testStore.GetPermissionsByUserAndSpaceID(context.Background(), GetPermissionsByUserAndSpaceIDParams{
+		UserID:  writer.ID,
+		SpaceID: shared.ID,
+	})
+	require.NoError(t, err)
+	require.True(t, successor.ReadPermission && successor.WritePermission && successor.DeletePermission)
+
+	_, err = testStore.GetSpaceByID(context.Background(), alone.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	memberships, err := testStore.ListPermissionsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, memberships)
+
+	// each space records what happened to it, newest first
+	requireSpaceEvents(t, shared, SpaceEventMemberLeft, SpaceEventTransferred)
+	requireSpaceEvents(t, alone, SpaceEventDeleted)
+	requireSpaceEvents(t, other, SpaceEventMemberLeft)
+
+	// the user is anonymized and can't authenticate anymore
+	require.True(t, result.User.DeletedAt.Valid)
+	require.NotEqual(t, user.Email, result.User.Email)
+	require.Empty(t, result.User.Password)
+
+	_, err = testStore.GetUserByEmail(context.Background(), user.Email)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	session, err = testStore.GetSessionByID(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+
+	_, err = testStore.GetActiveAPIKeyByHash(context.Background(), key.KeyHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// request logs still reference the user
+	requests, err := testStore.ListRequestLogsByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Equal(t, request.ID, requests[0].ID)
+
+	// an account is only deleted once
+	_, err = testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{UserID: user.ID})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestDeleteUserTxDeletesSpaces(t *testing.T) {
+	user := createRandomUser(t)
+	member := createRandomUser(t)
+
+	space := createRandomSpace(t, user)
+	createTestAdminPermission(t, user, space)
+	createTestAdminPermission(t, member, space)
+
+	result, err := testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{
+		UserID:         user.ID,
+		TransferSpaces: false,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.TransferredSpaces)
+	require.Len(t, result.DeletedSpaces, 1)
+
+	_, err = testStore.GetSpaceByID(context.Background(), space.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	memberships, err := testStore.ListPermissionsByUser(context.Background(), member.ID)
+	require.NoError(t, err)
+	require.Empty(t, memberships)
+}
+
+// requireSpaceEvents checks the types of the events of a space, newest first
+func requireSpaceEvents(t *testing.T, space Space, types ...string) []SpaceEvent {
+	events, err := testStore.ListSpaceEvents(context.Background(), ListSpaceEventsParams{
+		SpaceID:  space.ID,
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+
+	got := make([]string, len(events))
+	for i, event := range events {
+		got[i] = event.Type
+	}
+	require.Equal(t, types, got)
+
+	return events
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type UnlockLoginTxParams struct {
+	// account to unlock, every account when NULL
+	Email pgtype.Text `json:"email"`
+	// client IP to unlock, every client IP when NULL
+	ClientIp pgtype.Text `json:"client_ip"`
+}
+
+// UnlockLoginTx clears the failed login attempts matching the filters and
+// records the end of their active lockouts. It returns the number of
+// lockouts ended.
+func (store *SQLStore) UnlockLoginTx(ctx context.Context, arg UnlockLoginTxParams) (int64, error) {
+	var unlocked int64
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.DeleteLoginThrottles(ctx, DeleteLoginThrottlesParams{
+			Email:    arg.Email,
+			ClientIp: arg.ClientIp,
+		})
+		if err != nil {
+			return err
+		}
+
+		unlocked, err = q.UnlockLoginLockouts(ctx, UnlockLoginLockoutsParams{
+			Email:    arg.Email,
+			ClientIp: arg.ClientIp,
+		})
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return unlocked, nil
+}
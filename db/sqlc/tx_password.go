@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ChangePasswordTxParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	// session kept signed in, uuid.Nil signs every session out
+	SessionID uuid.UUID `json:"session_id"`
+	// hash of the new password
+	Password string `json:"password"`
+}
+
+// ChangePasswordTx replaces the password of a user, signs the user's other
+// sessions out and invalidates pending reset tokens. It returns the number of
+// revoked sessions.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (int64, error) {
+	var revoked int64
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		revoked, err = changePassword(ctx, q, arg)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+type ResetPasswordTxParams struct {
+	// hash of the reset token sent to the user
+	TokenHash string `json:"token_hash"`
+	// hash of the new password
+	Password string `json:"password"`
+}
+
+// ResetPasswordTx consumes a reset token and replaces the password of its
+// user, every session of the user is signed out. It returns
+// ErrRecordNotFound when the token is unknown, used or expired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordResetToken, error) {
+	var result PasswordResetToken
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.ConsumePasswordResetToken(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		_, err = changePassword(ctx, q, ChangePasswordTxParams{
+			UserID:    result.UserID,
+			SessionID: uuid.Nil,
+			Password:  arg.Password,
+		})
+		return err
+	})
+
+	if err != nil {
+		return PasswordResetToken{}, err
+	}
+
+	return result, nil
+}
+
+func changePassword(ctx context.Context, q *Queries, arg ChangePasswordTxParams) (int64, error) {
+	err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		ID:       arg.UserID,
+		Password: arg.Password,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := q.InvalidatePasswordResetTokens(ctx, arg.UserID); err != nil {
+		return 0, err
+	}
+
+	return q.RevokeOtherSessions(ctx, RevokeOtherSessionsParams{
+		UserID: arg.UserID,
+		ID:     arg.SessionID,
+	})
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Luckny/space-it/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordResetToken(t *testing.T, user User, expiresIn time.Duration) PasswordResetToken {
+	arg := CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: fmt.Sprintf("%x", sha256.Sum256([]byte(uuid.NewString()))),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(expiresIn), Valid: true},
+	}
+
+	resetToken, err := testStore.CreatePasswordResetToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserID, resetToken.UserID)
+	require.Equal(t, arg.TokenHash, resetToken.TokenHash)
+	require.False(t, resetToken.UsedAt.Valid)
+
+	return resetToken
+}
+
+func TestCountPasswordResetTokensSince(t *testing.T) {
+	user := createRandomUser(t)
+	since := pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Minute), Valid: true}
+
+	for i := 0; i < 2; i++ {
+		createRandomPasswordResetToken(t, user, time.Hour)
+	}
+
+	count, err := testStore.CountPasswordResetTokensSince(context.Background(), CountPasswordResetTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	current := createRandomSession(t, user)
+	other := createRandomSession(t, user)
+	resetToken := createRandomPasswordResetToken(t, user, time.Hour)
+
+	revoked, err := testStore.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UserID:    user.ID,
+		SessionID: current.ID,
+		Password:  util.RandomPassword(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+
+	updated, err := testStore.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, user.Password, updated.Password)
+
+	other, err = testStore.GetSessionByID(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.True(t, other.RevokedAt.Valid)
+
+	current, err = testStore.GetSessionByID(context.Background(), current.ID)
+	require.NoError(t, err)
+	require.False(t, current.RevokedAt.Valid)
+
+	// pending reset links stop working
+	_, err = testStore.ConsumePasswordResetToken(context.Background(), resetToken.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	resetToken := createRandomPasswordResetToken(t, user, time.Hour)
+	password := util.RandomPassword()
+
+	result, err := testStore.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash: resetToken.TokenHash,
+		Password:  password,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, result.UserID)
+	require.True(t, result.UsedAt.Valid)
+
+	updated, err := testStore.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, password, updated.Password)
+
+	session, err = testStore.GetSessionByID(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+
+	// tokens are single use
+	_, err = testStore.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash: resetToken.TokenHash,
+		Password:  util.RandomPassword(),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTxExpiredToken(t *testing.T) {
+	user := createRandomUser(t)
+	resetToken := createRandomPasswordResetToken(t, user, -time.Minute)
+
+	_, err := testStore.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash: resetToken.TokenHash,
+		Password:  util.RandomPassword(),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	unchanged, err := testStore.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, user.Password, unchanged.Password)
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EnableTwoFactorTxParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	// time step of the code used to confirm the enrollment
+	Step int64 `json:"step"`
+	// hashes of the recovery codes given to the user
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnableTwoFactorTx confirms a pending TOTP enrollment and replaces the
+// user's recovery codes
+func (store *SQLStore) EnableTwoFactorTx(ctx context.Context, arg EnableTwoFactorTxParams) (UserTotp, error) {
+	var result UserTotp
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.ConfirmTOTP(ctx, ConfirmTOTPParams{
+			UserID:       arg.UserID,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = q.DeleteRecoveryCodes(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		return q.CreateRecoveryCodes(ctx, CreateRecoveryCodesParams{
+			UserID:     arg.UserID,
+			CodeHashes: arg.RecoveryCodeHashes,
+		})
+	})
+
+	if err != nil {
+		return UserTotp{}, err
+	}
+
+	return result, nil
+}
+
+// DisableTwoFactorTx removes the user's TOTP secret and recovery codes
+func (store *SQLStore) DisableTwoFactorTx(ctx context.Context, userID uuid.UUID) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+
+		return q.DeleteTOTP(ctx, userID)
+	})
+}
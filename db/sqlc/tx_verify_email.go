@@ -0,0 +1,26 @@
+package db
+
+import "context"
+
+// VerifyEmailTx consumes a verification token and marks the email of its user
+// as verified. It returns ErrRecordNotFound when the token is unknown, used or
+// expired.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, tokenHash string) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		verificationToken, err := q.ConsumeEmailVerificationToken(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.VerifyUserEmail(ctx, verificationToken.UserID)
+		return err
+	})
+
+	if err != nil {
+		return User{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomEmailVerificationToken(t *testing.T, user User, expiresIn time.Duration) EmailVerificationToken {
+	arg := CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: fmt.Sprintf("%x", sha256.Sum256([]byte(uuid.NewString()))),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(expiresIn), Valid: true},
+	}
+
+	verificationToken, err := testStore.CreateEmailVerificationToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserID, verificationToken.UserID)
+	require.Equal(t, arg.TokenHash, verificationToken.TokenHash)
+	require.False(t, verificationToken.UsedAt.Valid)
+
+	return verificationToken
+}
+
+func TestCountEmailVerificationTokensSince(t *testing.T) {
+	user := createRandomUser(t)
+	since := pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Minute), Valid: true}
+
+	for i := 0; i < 2; i++ {
+		createRandomEmailVerificationToken(t, user, time.Hour)
+	}
+
+	count, err := testStore.CountEmailVerificationTokensSince(context.Background(), CountEmailVerificationTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	user := createRandomUser(t)
+	require.False(t, user.EmailVerifiedAt.Valid)
+	verificationToken := createRandomEmailVerificationToken(t, user, time.Hour)
+
+	verified, err := testStore.VerifyEmailTx(context.Background(), verificationToken.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, verified.ID)
+	require.True(t, verified.EmailVerifiedAt.Valid)
+
+	// a token is used once
+	_, err = testStore.VerifyEmailTx(context.Background(), verificationToken.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestVerifyEmailTxExpiredToken(t *testing.T) {
+	user := createRandomUser(t)
+	verificationToken := createRandomEmailVerificationToken(t, user, -time.Minute)
+
+	_, err := testStore.VerifyEmailTx(context.Background(), verificationToken.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	updated, err := testStore.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.False(t, updated.EmailVerifiedAt.Valid)
+}
@@ -7,10 +7,49 @@ package db
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :one
+UPDATE users
+SET email = substr(md5(random()::text), 1, 14) || '@deleted.invalid',
+    password = '',
+    email_verified_at = NULL,
+    deleted_at = now(),
+    display_name = '',
+    avatar = '',
+    timezone = DEFAULT,
+    locale = DEFAULT
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin
+`
+
+// deleted users are kept for the records referencing them, with a random
+// email and no password nobody can log in with
+func (q *Queries) AnonymizeUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, anonymizeUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password, created_at FROM users
+SELECT id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -22,14 +61,84 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin FROM users
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const listAdmins = `-- name: ListAdmins :many
+SELECT id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin FROM users
+WHERE is_admin
+AND deleted_at IS NULL
+ORDER BY email
+`
+
+func (q *Queries) ListAdmins(ctx context.Context) ([]User, error) {
+	rows, err := q.db.Query(ctx, listAdmins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Password,
+			&i.CreatedAt,
+			&i.EmailVerifiedAt,
+			&i.DeletedAt,
+			&i.DisplayName,
+			&i.Avatar,
+			&i.Timezone,
+			&i.Locale,
+			&i.IsAdmin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const registerUser = `-- name: RegisterUser :one
 INSERT INTO users (email, password)
 VALUES ( $1, $2)
-RETURNING id, email, password, created_at
+RETURNING id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin
 `
 
 type RegisterUserParams struct {
@@ -45,6 +154,177 @@ func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (Use
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET password = $2
+WHERE id = $1
+AND password = $3
+`
+
+type RehashUserPasswordParams struct {
+	ID          uuid.UUID `json:"id"`
+	NewPassword string    `json:"new_password"`
+	OldPassword string    `json:"old_password"`
+}
+
+// the hash is only replaced if the password has not changed in the meantime
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rehashUserPassword, arg.ID, arg.NewPassword, arg.OldPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :one
+UPDATE users
+SET is_admin = $2
+WHERE email = $1
+AND deleted_at IS NULL
+RETURNING id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin
+`
+
+type SetUserAdminParams struct {
+	Email   string `json:"email"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserAdmin, arg.Email, arg.IsAdmin)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       uuid.UUID `json:"id"`
+	Password string    `json:"password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.Password)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = COALESCE($1, display_name),
+    avatar = COALESCE($2, avatar),
+    timezone = COALESCE($3, timezone),
+    locale = COALESCE($4, locale)
+WHERE id = $5
+AND deleted_at IS NULL
+RETURNING id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin
+`
+
+type UpdateUserProfileParams struct {
+	DisplayName pgtype.Text `json:"display_name"`
+	Avatar      pgtype.Text `json:"avatar"`
+	Timezone    pgtype.Text `json:"timezone"`
+	Locale      pgtype.Text `json:"locale"`
+	ID          uuid.UUID   `json:"id"`
+}
+
+// only the given fields are changed
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile,
+		arg.DisplayName,
+		arg.Avatar,
+		arg.Timezone,
+		arg.Locale,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const usersShareSpace = `-- name: UsersShareSpace :one
+SELECT EXISTS (
+  SELECT 1 FROM permissions
+  JOIN permissions AS other_permissions
+  ON other_permissions.space_id = permissions.space_id
+  WHERE permissions.user_id = $1
+  AND other_permissions.user_id = $2
+)
+`
+
+type UsersShareSpaceParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	OtherUserID uuid.UUID `json:"other_user_id"`
+}
+
+func (q *Queries) UsersShareSpace(ctx context.Context, arg UsersShareSpaceParams) (bool, error) {
+	row := q.db.QueryRow(ctx, usersShareSpace, arg.UserID, arg.OtherUserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now())
+WHERE id = $1
+RETURNING id, email, password, created_at, email_verified_at, deleted_at, display_name, avatar, timezone, locale, is_admin
+`
+
+// the first verification date is kept
+func (q *Queries) VerifyUserEmail(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, verifyUserEmail, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.Avatar,
+		&i.Timezone,
+		&i.Locale,
+		&i.IsAdmin,
 	)
 	return i, err
 }
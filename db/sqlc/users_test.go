@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Luckny/space-it/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -46,3 +48,126 @@ func TestGetUserByEmail(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt.Time, user2.CreatedAt.Time, time.Second)
 
 }
+
+func TestRehashUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+	rehashed := util.RandomPassword()
+
+	n, err := testStore.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		ID:          user.ID,
+		NewPassword: rehashed,
+		OldPassword: user.Password,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// a password changed in the meantime is not overwritten
+	n, err = testStore.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		ID:          user.ID,
+		NewPassword: util.RandomPassword(),
+		OldPassword: user.Password,
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	updated, err := testStore.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, rehashed, updated.Password)
+}
+
+func TestRegisterUserWithLongEmail(t *testing.T) {
+	arg := RegisterUserParams{
+		Email:    strings.Repeat("a", 64) + "." + util.RandomEmail(),
+		Password: util.RandomPassword(),
+	}
+
+	user, err := testStore.RegisterUser(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Email, user.Email)
+
+	// profile defaults
+	require.Empty(t, user.DisplayName)
+	require.Empty(t, user.Avatar)
+	require.Equal(t, "UTC", user.Timezone)
+	require.Equal(t, "en", user.Locale)
+}
+
+func TestUpdateUserProfile(t *testing.T) {
+	user := createRandomUser(t)
+
+	updated, err := testStore.UpdateUserProfile(context.Background(), UpdateUserProfileParams{
+		ID:          user.ID,
+		DisplayName: pgtype.Text{String: "Ada", Valid: true},
+		Avatar:      pgtype.Text{String: "https://cdn.example.com/ada.png", Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Ada", updated.DisplayName)
+	require.Equal(t, "https://cdn.example.com/ada.png", updated.Avatar)
+	require.Equal(t, user.Timezone, updated.Timezone)
+	require.Equal(t, user.Locale, updated.Locale)
+
+	// fields not given are kept, empty ones are cleared
+	updated, err = testStore.UpdateUserProfile(context.Background(), UpdateUserProfileParams{
+		ID:       user.ID,
+		Avatar:   pgtype.Text{String: "", Valid: true},
+		Timezone: pgtype.Text{String: "Europe/Paris", Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Ada", updated.DisplayName)
+	require.Empty(t, updated.Avatar)
+	require.Equal(t, "Europe/Paris", updated.Timezone)
+}
+
+func TestUsersShareSpace(t *testing.T) {
+	owner := createRandomUser(t)
+	member := createRandomUser(t)
+	stranger := createRandomUser(t)
+
+	space := createRandomSpace(t, owner)
+	createTestAdminPermission(t, owner, space)
+	createTestReadPermission(t, member, space)
+	createRandomSpace(t, stranger)
+
+	shared, err := testStore.UsersShareSpace(context.Background(), UsersShareSpaceParams{
+		UserID:      member.ID,
+		OtherUserID: owner.ID,
+	})
+	require.NoError(t, err)
+	require.True(t, shared)
+
+	shared, err = testStore.UsersShareSpace(context.Background(), UsersShareSpaceParams{
+		UserID:      member.ID,
+		OtherUserID: stranger.ID,
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+}
+
+func TestSetUserAdmin(t *testing.T) {
+	user := createRandomUser(t)
+	require.False(t, user.IsAdmin)
+
+	admin, err := testStore.SetUserAdmin(context.Background(), SetUserAdminParams{
+		Email:   user.Email,
+		IsAdmin: true,
+	})
+	require.NoError(t, err)
+	require.True(t, admin.IsAdmin)
+
+	admins, err := testStore.ListAdmins(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, admins, admin)
+
+	revoked, err := testStore.SetUserAdmin(context.Background(), SetUserAdminParams{
+		Email:   user.Email,
+		IsAdmin: false,
+	})
+	require.NoError(t, err)
+	require.False(t, revoked.IsAdmin)
+
+	_, err = testStore.SetUserAdmin(context.Background(), SetUserAdminParams{
+		Email:   util.RandomEmail(),
+		IsAdmin: true,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,54 @@
+// Package apikey generates the personal API keys users authenticate scripts with
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix starts every key so they are easy to recognize, e.g. by secret scanners
+const Prefix = "sit_"
+
+// number of random bytes in a key
+const secretLength = 32
+
+// length of the part of the key kept in clear to identify it
+const displayLength = len(Prefix) + 8
+
+// Key is a newly generated API key. Only Hash and DisplayPrefix are stored,
+// the key is shown once to the user.
+type Key struct {
+	Key           string
+	Hash          string
+	DisplayPrefix string
+}
+
+// Generate returns a new random API key
+func Generate() (Key, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+
+	key := Prefix + base64.RawURLEncoding.EncodeToString(secret)
+	return Key{
+		Key:           key,
+		Hash:          Hash(key),
+		DisplayPrefix: key[:displayLength],
+	}, nil
+}
+
+// Hash returns the value stored for a key.
+// Keys are random enough that a fast hash is sufficient.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether token looks like an API key rather than another bearer token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
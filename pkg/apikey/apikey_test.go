@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, err := Generate()
+	require.NoError(t, err)
+
+	require.True(t, IsAPIKey(key.Key))
+	require.Equal(t, Hash(key.Key), key.Hash)
+	require.Len(t, key.Hash, 64)
+	require.Equal(t, key.Key[:len(key.DisplayPrefix)], key.DisplayPrefix)
+	require.NotContains(t, key.Hash, key.Key)
+
+	other, err := Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key.Key, other.Key)
+	require.NotEqual(t, key.Hash, other.Hash)
+}
+
+func TestIsAPIKey(t *testing.T) {
+	require.True(t, IsAPIKey("sit_abc"))
+	require.False(t, IsAPIKey("eyJhbGciOi"))
+	require.False(t, IsAPIKey(""))
+}
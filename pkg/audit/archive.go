@@ -0,0 +1,242 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// entries read at once when archiving a month
+const archivePageSize = 1000
+
+// ArchivedEntry is a line of an archive, an entry as it was stored with its
+// link in the chain. Entries that weren't chained have no seq nor hash.
+type ArchivedEntry struct {
+	Seq  int64  `json:"seq,omitempty"`
+	Hash []byte `json:"hash,omitempty"`
+
+	ID           uuid.UUID `json:"id"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Query        string    `json:"query"`
+	UserID       uuid.UUID `json:"user_id"`
+	AuthMethod   string    `json:"auth_method"`
+	SpaceID      uuid.UUID `json:"space_id"`
+	RequestID    string    `json:"request_id"`
+	ClientIP     string    `json:"client_ip"`
+	UserAgent    string    `json:"user_agent"`
+	RequestSize  int64     `json:"request_size"`
+	RequestedAt  time.Time `json:"requested_at"`
+	Status       int32     `json:"status"`
+	ResponseSize int64     `json:"response_size"`
+	LatencyUs    int64     `json:"latency_us"`
+	Outcome      string    `json:"outcome"`
+	RespondedAt  time.Time `json:"responded_at"`
+}
+
+func newArchivedEntry(row db.ListAuditLogsForArchiveRow) ArchivedEntry {
+	return ArchivedEntry{
+		Seq:          row.Seq,
+		Hash:         row.Hash,
+		ID:           row.ID,
+		Method:       row.Method,
+		Path:         row.Path,
+		Query:        row.Query,
+		UserID:       row.UserID,
+		AuthMethod:   row.AuthMethod,
+		SpaceID:      row.SpaceID,
+		RequestID:    row.RequestID,
+		ClientIP:     row.ClientIp,
+		UserAgent:    row.UserAgent,
+		RequestSize:  row.RequestSize,
+		RequestedAt:  row.RequestedAt.Time,
+		Status:       row.Status,
+		ResponseSize: row.ResponseSize,
+		LatencyUs:    row.LatencyUs,
+		Outcome:      row.Outcome,
+		RespondedAt:  row.RespondedAt.Time,
+	}
+}
+
+// link returns the values of the archived entry its hash covers
+func (e ArchivedEntry) link() link {
+	return link{
+		ID:           e.ID,
+		Method:       e.Method,
+		Path:         e.Path,
+		UserID:       e.UserID,
+		RequestedAt:  e.RequestedAt.UnixMicro(),
+		ClientIP:     e.ClientIP,
+		UserAgent:    e.UserAgent,
+		Query:        e.Query,
+		RequestSize:  e.RequestSize,
+		AuthMethod:   e.AuthMethod,
+		SpaceID:      e.SpaceID,
+		RequestID:    e.RequestID,
+		Status:       e.Status,
+		RespondedAt:  e.RespondedAt.UnixMicro(),
+		ResponseSize: e.ResponseSize,
+		LatencyUs:    e.LatencyUs,
+		Outcome:      e.Outcome,
+	}
+}
+
+// ArchiveName is the name of the archive file of a month
+func ArchiveName(month time.Time) string {
+	return fmt.Sprintf("space-it-audit-%s.ndjson.gz", month.Format("2006-01"))
+}
+
+// writeArchive writes the entries of the month to a gzipped NDJSON file in
+// dir, oldest first, and returns its path and checksum. The file only
+// appears once complete.
+func writeArchive(ctx context.Context, store db.Store, dir string, month time.Time) (string, []byte, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", nil, err
+	}
+
+	path := filepath.Join(dir, ArchiveName(month))
+	file, err := os.CreateTemp(dir, ArchiveName(month)+".*.tmp")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	checksum := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(file, checksum))
+	encoder := json.NewEncoder(zw)
+
+	arg := db.ListAuditLogsForArchiveParams{
+		Month:    pgtype.Timestamp{Time: month, Valid: true},
+		PageSize: archivePageSize,
+	}
+	for {
+		rows, err := store.ListAuditLogsForArchive(ctx, arg)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, row := range rows {
+			if err := encoder.Encode(newArchivedEntry(row)); err != nil {
+				return "", nil, err
+			}
+		}
+
+		if len(rows) < archivePageSize {
+			break
+		}
+		last := rows[len(rows)-1]
+		arg.CursorAt, arg.CursorID = last.RequestedAt, last.ID
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return "", nil, err
+	}
+	if err := file.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", nil, err
+	}
+
+	return path, checksum.Sum(nil), nil
+}
+
+// VerifyArchive checks that the archive file read from r is the one recorded
+// and that its entries are those the chain links. The hashes of the chain
+// stay in the database once their entries are archived.
+func VerifyArchive(ctx context.Context, store db.Store, archive db.AuditArchive, r io.Reader) (Report, error) {
+	var report Report
+
+	checksum := sha256.New()
+	zr, err := gzip.NewReader(io.TeeReader(r, checksum))
+	if err != nil {
+		return report, err
+	}
+
+	var entries int64
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var entry ArchivedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return report, err
+		}
+		entries++
+
+		if entry.Seq == 0 {
+			continue
+		}
+
+		if b, err := verifyArchivedEntry(ctx, store, entry); err != nil || b != nil {
+			report.Break = b
+			return report, err
+		}
+		report.Entries++
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	// the rest of the file is read for its checksum
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return report, err
+	}
+
+	switch {
+	case !bytes.Equal(checksum.Sum(nil), archive.Checksum):
+		report.Break = &Break{Reason: "archive doesn't match its checksum"}
+	case entries != archive.Entries:
+		report.Break = &Break{Reason: fmt.Sprintf("archive holds %d entries, %d were archived", entries, archive.Entries)}
+	case report.Entries != archive.Chained:
+		report.Break = &Break{Reason: fmt.Sprintf("archive holds %d chained entries, %d were archived", report.Entries, archive.Chained)}
+	}
+
+	return report, nil
+}
+
+// verifyArchivedEntry checks the entry against its hash in the chain, and
+// the hash against the one of the entry before it
+func verifyArchivedEntry(ctx context.Context, store db.Store, entry ArchivedEntry) (*Break, error) {
+	chained, err := store.GetAuditChain(ctx, entry.Seq)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return &Break{Seq: entry.Seq, Reason: "entry missing from the chain"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var prev []byte
+	if entry.Seq > 1 {
+		before, err := store.GetAuditChain(ctx, entry.Seq-1)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return &Break{Seq: entry.Seq - 1, Reason: "entry missing from the chain"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		prev = before.Hash
+	}
+
+	if chained.ID != entry.ID || !bytes.Equal(entry.link().hash(prev), chained.Hash) {
+		return &Break{Seq: entry.Seq, Reason: "entry doesn't match its hash"}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// archiveStore serves the stored chain as the entries of a month to archive,
+// and their hashes as the chain keeps them
+func archiveStore(t *testing.T, rows []db.ListAuditChainRow) *mockdb.MockStore {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ListAuditLogsForArchive(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.ListAuditLogsForArchiveParams) ([]db.ListAuditLogsForArchiveRow, error) {
+			page := []db.ListAuditLogsForArchiveRow{}
+			if !arg.CursorAt.Valid {
+				for _, row := range rows {
+					page = append(page, db.ListAuditLogsForArchiveRow(row))
+				}
+			}
+			return page, nil
+		})
+	store.EXPECT().
+		GetAuditChain(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, seq int64) (db.AuditChain, error) {
+			if seq < 1 || seq > int64(len(rows)) {
+				return db.AuditChain{}, db.ErrRecordNotFound
+			}
+			row := rows[seq-1]
+			return db.AuditChain{Seq: row.Seq, ID: row.ID, Hash: row.Hash}, nil
+		})
+
+	return store
+}
+
+// rewriteArchive replaces the entries of the archive at path, as someone
+// with access to the disk could
+func rewriteArchive(t *testing.T, path string, change func(entries []ArchivedEntry)) []byte {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	entries := []ArchivedEntry{}
+	decoder := json.NewDecoder(zr)
+	for decoder.More() {
+		var entry ArchivedEntry
+		require.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	change(entries)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(zw)
+	for _, entry := range entries {
+		require.NoError(t, encoder.Encode(entry))
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	checksum := sha256.Sum256(buf.Bytes())
+	return checksum[:]
+}
+
+func TestArchive(t *testing.T) {
+	rows := storedChain(t, 3)
+	month := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	store := archiveStore(t, rows)
+
+	path, checksum, err := writeArchive(context.Background(), store, t.TempDir(), month)
+	require.NoError(t, err)
+	require.Equal(t, "space-it-audit-2026-01.ndjson.gz", filepath.Base(path))
+
+	archive := db.AuditArchive{Path: path, Checksum: checksum, Entries: 3, Chained: 3, FirstSeq: 1, LastSeq: 3}
+	verify := func(archive db.AuditArchive) Report {
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		report, err := VerifyArchive(context.Background(), store, archive, file)
+		require.NoError(t, err)
+		return report
+	}
+
+	report := verify(archive)
+	require.Nil(t, report.Break)
+	require.EqualValues(t, 3, report.Entries)
+
+	// the record of the archive tells how many entries it holds
+	incomplete := archive
+	incomplete.Entries, incomplete.Chained = 4, 4
+	require.Equal(t, &Break{Reason: "archive holds 3 entries, 4 were archived"}, verify(incomplete).Break)
+
+	// a rewritten file no longer matches its checksum
+	rewriteArchive(t, path, func(entries []ArchivedEntry) {
+		entries[0].Hash = nil
+	})
+	require.Equal(t, &Break{Reason: "archive doesn't match its checksum"}, verify(archive).Break)
+
+	// nor its entries their hashes, whatever the checksum
+	archive.Checksum = rewriteArchive(t, path, func(entries []ArchivedEntry) {
+		entries[1].Status = 204
+	})
+	require.Equal(t, &Break{Seq: 2, Reason: "entry doesn't match its hash"}, verify(archive).Break)
+}
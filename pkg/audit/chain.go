@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+)
+
+// link holds the values of an entry its hash covers, as they are stored.
+// Timestamps are in microseconds, the precision of the database.
+type link struct {
+	ID          uuid.UUID
+	Method      string
+	Path        string
+	UserID      uuid.UUID
+	RequestedAt int64
+	ClientIP    string
+	UserAgent   string
+	Query       string
+	RequestSize int64
+	AuthMethod  string
+	SpaceID     uuid.UUID
+	RequestID   string
+
+	Status       int32
+	RespondedAt  int64
+	ResponseSize int64
+	LatencyUs    int64
+	Outcome      string
+}
+
+// hash chains the entry to the one whose hash is prev, empty for the first
+// entry of the chain. Every value is written with its length so that no two
+// entries are written the same.
+func (l link) hash(prev []byte) []byte {
+	h := sha256.New()
+	writeBytes(h, prev)
+
+	writeBytes(h, l.ID[:])
+	writeBytes(h, []byte(l.Method))
+	writeBytes(h, []byte(l.Path))
+	writeBytes(h, l.UserID[:])
+	writeInt(h, l.RequestedAt)
+	writeBytes(h, []byte(l.ClientIP))
+	writeBytes(h, []byte(l.UserAgent))
+	writeBytes(h, []byte(l.Query))
+	writeInt(h, l.RequestSize)
+	writeBytes(h, []byte(l.AuthMethod))
+	writeBytes(h, l.SpaceID[:])
+	writeBytes(h, []byte(l.RequestID))
+
+	writeInt(h, int64(l.Status))
+	writeInt(h, l.RespondedAt)
+	writeInt(h, l.ResponseSize)
+	writeInt(h, l.LatencyUs)
+	writeBytes(h, []byte(l.Outcome))
+
+	return h.Sum(nil)
+}
+
+func writeBytes(h hash.Hash, b []byte) {
+	writeInt(h, int64(len(b)))
+	h.Write(b)
+}
+
+func writeInt(h hash.Hash, n int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(n))
+	h.Write(b[:])
+}
+
+// chain returns the function chaining the entries of arg, in order
+func chain(arg db.CreateAuditLogsTxParams) func(prev []byte) [][]byte {
+	req, res := arg.Requests, arg.Responses
+
+	links := make([]link, len(req.Ids))
+	for i := range links {
+		links[i] = link{
+			ID:           req.Ids[i],
+			Method:       req.Methods[i],
+			Path:         req.Paths[i],
+			UserID:       req.UserIds[i],
+			RequestedAt:  req.CreatedAt[i].Time.UnixMicro(),
+			ClientIP:     req.ClientIps[i],
+			UserAgent:    req.UserAgents[i],
+			Query:        req.Queries[i],
+			RequestSize:  req.Sizes[i],
+			AuthMethod:   req.AuthMethods[i],
+			SpaceID:      req.SpaceIds[i],
+			RequestID:    req.RequestIds[i],
+			Status:       res.Statuses[i],
+			RespondedAt:  res.CreatedAt[i].Time.UnixMicro(),
+			ResponseSize: res.Sizes[i],
+			LatencyUs:    res.LatenciesUs[i],
+			Outcome:      res.Outcomes[i],
+		}
+	}
+
+	return func(prev []byte) [][]byte {
+		hashes := make([][]byte, len(links))
+		for i, l := range links {
+			hashes[i] = l.hash(prev)
+			prev = hashes[i]
+		}
+		return hashes
+	}
+}
+
+// linkOf returns the values of a stored entry its hash covers
+func linkOf(row db.ListAuditChainRow) link {
+	return link{
+		ID:           row.ID,
+		Method:       row.Method,
+		Path:         row.Path,
+		UserID:       row.UserID,
+		RequestedAt:  row.RequestedAt.Time.UnixMicro(),
+		ClientIP:     row.ClientIp,
+		UserAgent:    row.UserAgent,
+		Query:        row.Query,
+		RequestSize:  row.RequestSize,
+		AuthMethod:   row.AuthMethod,
+		SpaceID:      row.SpaceID,
+		RequestID:    row.RequestID,
+		Status:       row.Status,
+		RespondedAt:  row.RespondedAt.Time.UnixMicro(),
+		ResponseSize: row.ResponseSize,
+		LatencyUs:    row.LatencyUs,
+		Outcome:      row.Outcome,
+	}
+}
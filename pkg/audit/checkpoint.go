@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+)
+
+// how often the chain is checkpointed when no interval is given
+const DefaultCheckpointInterval = time.Hour
+
+// prefixes the message signed by checkpoints so that their signatures can't
+// be taken for anything else
+const checkpointContext = "space-it audit checkpoint\x00"
+
+// GenerateCheckpointKey returns a new checkpoint signing key, encoded as
+// ParseCheckpointKey expects
+func GenerateCheckpointKey() (string, error) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key.Seed()), nil
+}
+
+// ParseCheckpointKey decodes a base64 ed25519 seed
+func ParseCheckpointKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("checkpoint key must be a base64 %d bytes ed25519 seed", ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParseCheckpointPublicKey decodes a base64 ed25519 public key
+func ParseCheckpointPublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("checkpoint public key must be a base64 %d bytes ed25519 key", ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(key), nil
+}
+
+// checkpointMessage is what the checkpoint of the chain at seq signs
+func checkpointMessage(seq int64, hash []byte) []byte {
+	msg := []byte(checkpointContext)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(seq))
+	return append(msg, hash...)
+}
+
+// Checkpoint signs the last entry of the chain, unless it is already
+// checkpointed or the chain is empty
+func Checkpoint(ctx context.Context, store db.Store, key ed25519.PrivateKey) error {
+	head, err := store.GetAuditChainHead(ctx)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	latest, err := store.GetLatestAuditCheckpoint(ctx)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return err
+	}
+	if latest.Seq >= head.Seq {
+		return nil
+	}
+
+	return store.CreateAuditCheckpoint(ctx, db.CreateAuditCheckpointParams{
+		Seq:       head.Seq,
+		Hash:      head.Hash,
+		Signature: ed25519.Sign(key, checkpointMessage(head.Seq, head.Hash)),
+	})
+}
+
+// RunCheckpoints checkpoints the chain every interval until the context is
+// done
+func RunCheckpoints(ctx context.Context, store db.Store, key ed25519.PrivateKey, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := Checkpoint(ctx, store, key); err != nil {
+				slog.ErrorContext(ctx, "audit: checkpoint failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry is a request and its response
+type Entry struct {
+	ID     uuid.UUID
+	Method string
+	Path   string
+	// redacted with RedactQuery
+	Query string
+	// uuid.Nil when the request is not authenticated
+	UserID uuid.UUID
+	// one of the httpx.AuthMethod values, empty when not authenticated
+	AuthMethod string
+	// uuid.Nil when the request is not about a space
+	SpaceID uuid.UUID
+	// correlates the entry with the logs of the request
+	RequestID   string
+	ClientIP    string
+	UserAgent   string
+	RequestSize int64
+	RequestedAt time.Time
+
+	Status       int
+	ResponseSize int64
+	RespondedAt  time.Time
+}
+
+// Outcomes of a request, by response status
+const (
+	OutcomeSuccess         = "success"
+	OutcomeUnauthenticated = "unauthenticated"
+	OutcomeDenied          = "denied"
+	OutcomeThrottled       = "throttled"
+	OutcomeClientError     = "client_error"
+	OutcomeServerError     = "server_error"
+)
+
+// Outcome sums up how a request went from its response status
+func Outcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return OutcomeUnauthenticated
+	case status == http.StatusForbidden:
+		return OutcomeDenied
+	case status == http.StatusTooManyRequests:
+		return OutcomeThrottled
+	case status >= 500:
+		return OutcomeServerError
+	case status >= 400:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutcome(t *testing.T) {
+	for status, outcome := range map[int]string{
+		http.StatusOK:                  OutcomeSuccess,
+		http.StatusCreated:             OutcomeSuccess,
+		http.StatusFound:               OutcomeSuccess,
+		http.StatusBadRequest:          OutcomeClientError,
+		http.StatusNotFound:            OutcomeClientError,
+		http.StatusUnauthorized:        OutcomeUnauthenticated,
+		http.StatusForbidden:           OutcomeDenied,
+		http.StatusTooManyRequests:     OutcomeThrottled,
+		http.StatusInternalServerError: OutcomeServerError,
+		http.StatusServiceUnavailable:  OutcomeServerError,
+	} {
+		require.Equal(t, outcome, Outcome(status), status)
+	}
+}
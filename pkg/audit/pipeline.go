@@ -0,0 +1,310 @@
+// Package audit writes the audit trail of the API requests in the
+// background, so requests don't wait for it
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// settings of New used when zero
+const (
+	DefaultBufferSize    = 10000
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = time.Second
+	DefaultWriteTimeout  = 10 * time.Second
+)
+
+// how long writing waits after a failed batch, doubled with each failure in
+// a row, so an unavailable database isn't retried with every new entry
+const (
+	minRetryBackoff = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// Options of a Pipeline, the defaults are used for zero values
+type Options struct {
+	// entries waiting to be written
+	BufferSize int
+	// entries written at once
+	BatchSize int
+	// longest time an entry waits for its batch to fill up
+	FlushInterval time.Duration
+	// longest time a batch is written for, the database may hang
+	WriteTimeout time.Duration
+	// when true, entries recorded while the buffer is full are dropped and
+	// counted. Recording waits for room otherwise.
+	DropWhenFull bool
+}
+
+// Pipeline buffers audit entries and writes them in batches.
+//
+// Entries are written once BatchSize of them are waiting, or FlushInterval
+// after the oldest was recorded. A batch that fails to be written is tried
+// again with the next one once the retry backoff is over, at most BufferSize
+// entries are kept meanwhile and the oldest are dropped beyond. A batch the
+// database refuses for its data is split to write the valid entries, the
+// invalid ones are dropped.
+type Pipeline struct {
+	store   db.Store
+	options Options
+
+	entries chan Entry
+	// closed once the last entries are written
+	done chan struct{}
+	// closed when closing starts, recording stops waiting for room
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// guards entries against being recorded once closed
+	mu     sync.RWMutex
+	closed bool
+
+	dropped atomic.Uint64
+}
+
+// New returns a pipeline writing to store. It writes nothing until it runs.
+func New(store db.Store, options Options) *Pipeline {
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = DefaultFlushInterval
+	}
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = DefaultWriteTimeout
+	}
+
+	return &Pipeline{
+		store:   store,
+		options: options,
+		entries: make(chan Entry, options.BufferSize),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+}
+
+// Record queues an entry to be written. It only waits when the buffer is
+// full and entries are not dropped, until the pipeline is closing. Entries
+// recorded once the pipeline is closing are dropped.
+func (p *Pipeline) Record(entry Entry) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		return
+	}
+
+	if p.options.DropWhenFull {
+		select {
+		case p.entries <- entry:
+		default:
+			p.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case p.entries <- entry:
+	case <-p.closing:
+		p.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries dropped so far
+func (p *Pipeline) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Run writes the recorded entries until the pipeline is closed, then writes
+// those left
+func (p *Pipeline) Run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.options.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Entry
+	var reported uint64
+	var backoff time.Duration
+	var retryAt time.Time
+	for {
+		select {
+		case entry, ok := <-p.entries:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+
+			batch = append(batch, entry)
+			if len(batch) < p.options.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		switch {
+		case time.Now().Before(retryAt):
+			batch = p.trim(batch)
+		default:
+			batch = p.flush(batch)
+			if len(batch) == 0 {
+				backoff = 0
+				break
+			}
+			backoff = min(max(2*backoff, minRetryBackoff), maxRetryBackoff)
+			retryAt = time.Now().Add(backoff)
+		}
+
+		if dropped := p.Dropped(); dropped > reported {
+			slog.Error("audit: entries dropped", "count", dropped-reported)
+			reported = dropped
+		}
+	}
+}
+
+// Close stops recording entries and waits for those recorded to be written,
+// or for the context to be done
+func (p *Pipeline) Close(ctx context.Context) error {
+	// recordings waiting for room give up, so that they release the lock
+	p.closeOnce.Do(func() { close(p.closing) })
+
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.entries)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush writes batch and returns the entries that could not be written
+func (p *Pipeline) flush(batch []Entry) []Entry {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	left, err := p.write(batch)
+	if err != nil {
+		slog.Error("audit: cannot write entries", "count", len(left), "error", err)
+		return p.trim(left)
+	}
+
+	return batch[:0]
+}
+
+// write writes batch, halving it down to the entries the database refuses
+// for their data, which are dropped. It returns the entries left to write
+// when the database fails.
+func (p *Pipeline) write(batch []Entry) ([]Entry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.options.WriteTimeout)
+	defer cancel()
+
+	err := p.store.CreateAuditLogsTx(ctx, newAuditLogsParams(batch))
+	switch {
+	case err == nil:
+		return nil, nil
+	case !db.IsDataError(err):
+		return batch, err
+	case len(batch) == 1:
+		slog.Error("audit: invalid entry dropped", "id", batch[0].ID, "error", err)
+		p.dropped.Add(1)
+		return nil, nil
+	}
+
+	half := len(batch) / 2
+	if left, err := p.write(batch[:half]); err != nil {
+		return slices.Concat(left, batch[half:]), err
+	}
+	return p.write(batch[half:])
+}
+
+// trim drops the oldest entries of batch beyond the buffer size
+func (p *Pipeline) trim(batch []Entry) []Entry {
+	if excess := len(batch) - p.options.BufferSize; excess > 0 {
+		p.dropped.Add(uint64(excess))
+		batch = batch[excess:]
+	}
+	return batch
+}
+
+func newAuditLogsParams(batch []Entry) db.CreateAuditLogsTxParams {
+	n := len(batch)
+	arg := db.CreateAuditLogsTxParams{
+		Requests: db.CreateRequestLogsParams{
+			Ids:         make([]uuid.UUID, 0, n),
+			Methods:     make([]string, 0, n),
+			Paths:       make([]string, 0, n),
+			UserIds:     make([]uuid.UUID, 0, n),
+			CreatedAt:   make([]pgtype.Timestamp, 0, n),
+			ClientIps:   make([]string, 0, n),
+			UserAgents:  make([]string, 0, n),
+			Queries:     make([]string, 0, n),
+			Sizes:       make([]int64, 0, n),
+			AuthMethods: make([]string, 0, n),
+			SpaceIds:    make([]uuid.UUID, 0, n),
+			RequestIds:  make([]string, 0, n),
+		},
+		Responses: db.CreateResponseLogsParams{
+			Ids:         make([]uuid.UUID, 0, n),
+			RequestedAt: make([]pgtype.Timestamp, 0, n),
+			Statuses:    make([]int32, 0, n),
+			CreatedAt:   make([]pgtype.Timestamp, 0, n),
+			Sizes:       make([]int64, 0, n),
+			LatenciesUs: make([]int64, 0, n),
+			Outcomes:    make([]string, 0, n),
+		},
+	}
+
+	for _, e := range batch {
+		req := &arg.Requests
+		req.Ids = append(req.Ids, e.ID)
+		req.Methods = append(req.Methods, e.Method)
+		req.Paths = append(req.Paths, e.Path)
+		req.UserIds = append(req.UserIds, e.UserID)
+		req.CreatedAt = append(req.CreatedAt, timestamp(e.RequestedAt))
+		req.ClientIps = append(req.ClientIps, e.ClientIP)
+		req.UserAgents = append(req.UserAgents, e.UserAgent)
+		req.Queries = append(req.Queries, e.Query)
+		req.Sizes = append(req.Sizes, e.RequestSize)
+		req.AuthMethods = append(req.AuthMethods, e.AuthMethod)
+		req.SpaceIds = append(req.SpaceIds, e.SpaceID)
+		req.RequestIds = append(req.RequestIds, e.RequestID)
+
+		res := &arg.Responses
+		res.Ids = append(res.Ids, e.ID)
+		res.RequestedAt = append(res.RequestedAt, timestamp(e.RequestedAt))
+		res.Statuses = append(res.Statuses, int32(e.Status))
+		res.CreatedAt = append(res.CreatedAt, timestamp(e.RespondedAt))
+		res.Sizes = append(res.Sizes, e.ResponseSize)
+		res.LatenciesUs = append(res.LatenciesUs, e.RespondedAt.Sub(e.RequestedAt).Microseconds())
+		res.Outcomes = append(res.Outcomes, Outcome(e.Status))
+	}
+
+	arg.Chain = chain(arg)
+	return arg
+}
+
+// timestamp returns t as stored, the hash of an entry covers the stored
+// microseconds only
+func timestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t.UTC().Truncate(time.Microsecond), Valid: true}
+}
@@ -0,0 +1,354 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomEntry() Entry {
+	now := time.Now()
+	return Entry{
+		ID:          uuid.New(),
+		Method:      http.MethodGet,
+		Path:        "/api/v1/test",
+		UserID:      uuid.New(),
+		RequestedAt: now,
+		Status:      http.StatusOK,
+		RespondedAt: now.Add(time.Millisecond),
+	}
+}
+
+// batchRecorder records the ids of the entries of each batch written
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]uuid.UUID
+	written chan struct{}
+}
+
+func newBatchRecorder(store *mockdb.MockStore) *batchRecorder {
+	r := &batchRecorder{written: make(chan struct{}, 100)}
+	store.EXPECT().
+		CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+			r.mu.Lock()
+			r.batches = append(r.batches, arg.Requests.Ids)
+			r.mu.Unlock()
+			r.written <- struct{}{}
+			return nil
+		})
+
+	return r
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sizes := []int{}
+	for _, b := range r.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestPipelineFlushesFullBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	batches := newBatchRecorder(store)
+
+	pipeline := New(store, Options{BatchSize: 3, FlushInterval: time.Hour})
+	go pipeline.Run()
+
+	entries := []Entry{}
+	for i := 0; i < 7; i++ {
+		entries = append(entries, randomEntry())
+		pipeline.Record(entries[i])
+	}
+
+	// two full batches are written right away
+	for i := 0; i < 2; i++ {
+		select {
+		case <-batches.written:
+		case <-time.After(time.Second):
+			t.Fatal("batch not written")
+		}
+	}
+	require.Equal(t, []int{3, 3}, batches.sizes())
+
+	// the last entry waits for close
+	require.NoError(t, pipeline.Close(context.Background()))
+	require.Equal(t, []int{3, 3, 1}, batches.sizes())
+	require.Equal(t, entries[6].ID, batches.batches[2][0])
+	require.Zero(t, pipeline.Dropped())
+}
+
+func TestPipelineFlushesOnInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	batches := newBatchRecorder(store)
+
+	pipeline := New(store, Options{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	go pipeline.Run()
+	defer pipeline.Close(context.Background())
+
+	pipeline.Record(randomEntry())
+
+	select {
+	case <-batches.written:
+	case <-time.After(time.Second):
+		t.Fatal("batch not written")
+	}
+	require.Equal(t, []int{1}, batches.sizes())
+}
+
+func TestPipelineDropsWhenFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	batches := newBatchRecorder(store)
+
+	// nothing is written until it runs, the buffer fills up
+	pipeline := New(store, Options{BufferSize: 2, DropWhenFull: true})
+	for i := 0; i < 5; i++ {
+		pipeline.Record(randomEntry())
+	}
+	require.Equal(t, uint64(3), pipeline.Dropped())
+
+	go pipeline.Run()
+	require.NoError(t, pipeline.Close(context.Background()))
+	require.Equal(t, []int{2}, batches.sizes())
+
+	// entries recorded once closed are dropped too
+	pipeline.Record(randomEntry())
+	require.Equal(t, uint64(4), pipeline.Dropped())
+}
+
+func TestPipelineBlocksWhenFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	batches := newBatchRecorder(store)
+
+	pipeline := New(store, Options{BufferSize: 1})
+	pipeline.Record(randomEntry())
+
+	recorded := make(chan struct{})
+	go func() {
+		pipeline.Record(randomEntry())
+		close(recorded)
+	}()
+
+	select {
+	case <-recorded:
+		t.Fatal("recorded while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	go pipeline.Run()
+	<-recorded
+
+	require.NoError(t, pipeline.Close(context.Background()))
+	written := 0
+	for _, size := range batches.sizes() {
+		written += size
+	}
+	require.Equal(t, 2, written)
+	require.Zero(t, pipeline.Dropped())
+}
+
+func TestPipelineRetriesFailedBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	first, second := randomEntry(), randomEntry()
+	gomock.InOrder(
+		store.EXPECT().
+			CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(sql.ErrConnDone),
+		store.EXPECT().
+			CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+				require.Equal(t, []uuid.UUID{first.ID, second.ID}, arg.Requests.Ids)
+				return nil
+			}),
+	)
+
+	pipeline := New(store, Options{BatchSize: 1, FlushInterval: time.Hour})
+	go pipeline.Run()
+
+	pipeline.Record(first)
+	pipeline.Record(second)
+	require.NoError(t, pipeline.Close(context.Background()))
+	require.Zero(t, pipeline.Dropped())
+}
+
+func TestPipelineDropsInvalidEntries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	entries := []Entry{}
+	for i := 0; i < 5; i++ {
+		entries = append(entries, randomEntry())
+	}
+	invalid := entries[2].ID
+
+	// batches holding the invalid entry are refused whole
+	written := []uuid.UUID{}
+	store.EXPECT().
+		CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+			if slices.Contains(arg.Requests.Ids, invalid) {
+				return &pgconn.PgError{Code: "22021"}
+			}
+			written = append(written, arg.Requests.Ids...)
+			return nil
+		})
+
+	pipeline := New(store, Options{BatchSize: len(entries)})
+	go pipeline.Run()
+
+	for _, entry := range entries {
+		pipeline.Record(entry)
+	}
+	require.NoError(t, pipeline.Close(context.Background()))
+
+	require.ElementsMatch(t, []uuid.UUID{entries[0].ID, entries[1].ID, entries[3].ID, entries[4].ID}, written)
+	require.Equal(t, uint64(1), pipeline.Dropped())
+}
+
+func TestPipelineBacksOffAfterFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	entries := []Entry{}
+	for i := 0; i < 10; i++ {
+		entries = append(entries, randomEntry())
+	}
+
+	// the database is down, new entries don't retry it until the backoff is
+	// over, they wait and the oldest are dropped beyond the buffer size
+	gomock.InOrder(
+		store.EXPECT().
+			CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+				require.Equal(t, []uuid.UUID{entries[0].ID}, arg.Requests.Ids)
+				return sql.ErrConnDone
+			}),
+		store.EXPECT().
+			CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateAuditLogsTxParams) error {
+				require.Equal(t, []uuid.UUID{entries[7].ID, entries[8].ID, entries[9].ID}, arg.Requests.Ids)
+				return nil
+			}),
+	)
+
+	pipeline := New(store, Options{BufferSize: 3, BatchSize: 1, FlushInterval: time.Millisecond})
+	go pipeline.Run()
+
+	for _, entry := range entries {
+		pipeline.Record(entry)
+	}
+	// ticks go by without retrying
+	time.Sleep(50 * time.Millisecond)
+
+	// the entries left are tried once more when closing
+	require.NoError(t, pipeline.Close(context.Background()))
+	require.Equal(t, uint64(7), pipeline.Dropped())
+}
+
+func TestPipelineCloseTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	// never runs, the entries are never written
+	pipeline := New(store, Options{})
+	pipeline.Record(randomEntry())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, pipeline.Close(ctx), context.DeadlineExceeded)
+}
+
+func TestPipelineCloseWhileDatabaseHangs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	// writes hang until they time out
+	store.EXPECT().
+		CreateAuditLogsTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(ctx context.Context, _ db.CreateAuditLogsTxParams) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	pipeline := New(store, Options{BufferSize: 1, BatchSize: 1, WriteTimeout: 200 * time.Millisecond})
+	go pipeline.Run()
+
+	// the first entry is being written, the second fills the buffer and the
+	// third waits for room
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		for i := 0; i < 3; i++ {
+			pipeline.Record(randomEntry())
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.ErrorIs(t, pipeline.Close(ctx), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// the waiting entry is dropped, the others are given up on once their
+	// writes time out
+	<-recorded
+	require.NoError(t, pipeline.Close(context.Background()))
+	require.GreaterOrEqual(t, pipeline.Dropped(), uint64(1))
+}
+
+func TestNewAuditLogsParams(t *testing.T) {
+	entry := randomEntry()
+	anonymous := randomEntry()
+	anonymous.UserID = uuid.Nil
+	anonymous.Status = http.StatusUnauthorized
+	entry.SpaceID = uuid.New()
+	entry.AuthMethod = "password"
+	entry.RequestSize = 42
+	entry.ResponseSize = 7
+
+	arg := newAuditLogsParams([]Entry{entry, anonymous})
+
+	require.Equal(t, []uuid.UUID{entry.ID, anonymous.ID}, arg.Requests.Ids)
+	require.Equal(t, []uuid.UUID{entry.UserID, uuid.Nil}, arg.Requests.UserIds)
+	require.Equal(t, arg.Requests.Ids, arg.Responses.Ids)
+	require.Equal(t, []int32{http.StatusOK, http.StatusUnauthorized}, arg.Responses.Statuses)
+	require.Equal(t, entry.RequestedAt.UTC().Truncate(time.Microsecond), arg.Requests.CreatedAt[0].Time)
+	require.Equal(t, arg.Requests.CreatedAt, arg.Responses.RequestedAt)
+	require.Equal(t, entry.RespondedAt.UTC().Truncate(time.Microsecond), arg.Responses.CreatedAt[0].Time)
+
+	require.Equal(t, []uuid.UUID{entry.SpaceID, uuid.Nil}, arg.Requests.SpaceIds)
+	require.Equal(t, []string{"password", ""}, arg.Requests.AuthMethods)
+	require.Equal(t, []int64{42, 0}, arg.Requests.Sizes)
+	require.Equal(t, []int64{7, 0}, arg.Responses.Sizes)
+	require.Equal(t, []int64{1000, 1000}, arg.Responses.LatenciesUs)
+	require.Equal(t, []string{OutcomeSuccess, OutcomeUnauthenticated}, arg.Responses.Outcomes)
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"net/url"
+	"strings"
+)
+
+// replaces the values of secret query parameters
+const redacted = "REDACTED"
+
+// query parameters whose name contains one of these are secret
+var secretParams = []string{
+	"token",
+	"secret",
+	"password",
+	"key",
+	"code",
+	"signature",
+	"credential",
+	"auth",
+}
+
+// RedactQuery returns the raw query with the values of the parameters that
+// may carry secrets, e.g. OAuth2 codes or reset tokens, replaced. A query
+// that can't be parsed is redacted as a whole.
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, value, _ := strings.Cut(param, "=")
+		unescaped, err := url.QueryUnescape(name)
+		if err != nil {
+			return redacted
+		}
+
+		if value != "" && isSecretParam(unescaped) {
+			params[i] = name + "=" + redacted
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+func isSecretParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretParams {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactQuery(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		redacted string
+	}{
+		{query: "", redacted: ""},
+		{query: "page=2&limit=10", redacted: "page=2&limit=10"},
+		{query: "code=abc&state=xyz", redacted: "code=REDACTED&state=xyz"},
+		{query: "reset_token=abc", redacted: "reset_token=REDACTED"},
+		{query: "API_KEY=abc&q=x", redacted: "API_KEY=REDACTED&q=x"},
+		{query: "client_secret=abc&password=", redacted: "client_secret=REDACTED&password="},
+		{query: "flag&token", redacted: "flag&token"},
+		{query: "%zz=abc", redacted: "REDACTED"},
+	} {
+		require.Equal(t, tc.redacted, RedactQuery(tc.query), tc.query)
+	}
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// how often partitions are created and old months archived when no interval
+// is given
+const DefaultRetentionInterval = 24 * time.Hour
+
+// RetentionPolicy tells which months of audit logs are kept in the database
+type RetentionPolicy struct {
+	// months kept before the current one, every month is kept when zero
+	Months int
+	// directory the older months are archived to, they are dropped without
+	// an archive when empty
+	ArchiveDir string
+}
+
+// Retain creates the partitions of the current and next month, then archives
+// and drops the months older than the policy keeps. A month archived by
+// another server instance meanwhile is skipped.
+func Retain(ctx context.Context, store db.Store, policy RetentionPolicy, now time.Time) error {
+	current := startOfMonth(now)
+	for _, month := range []time.Time{current, current.AddDate(0, 1, 0)} {
+		if err := store.CreateAuditLogPartitions(ctx, pgtype.Timestamp{Time: month, Valid: true}); err != nil {
+			return err
+		}
+	}
+
+	if policy.Months <= 0 {
+		return nil
+	}
+
+	months, err := store.ListAuditLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	oldest := current.AddDate(0, -policy.Months, 0)
+	for _, month := range months {
+		if !month.Time.Before(oldest) {
+			break
+		}
+
+		err := archiveMonth(ctx, store, policy.ArchiveDir, month.Time)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == db.UniqueViolation {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveMonth writes the entries of the month to its archive, unless there
+// is no archive directory, and drops them from the database. The database
+// records the archive, counting the entries itself.
+func archiveMonth(ctx context.Context, store db.Store, dir string, month time.Time) error {
+	arg := db.ArchiveAuditLogsParams{
+		Month: pgtype.Timestamp{Time: month, Valid: true},
+	}
+
+	if dir != "" {
+		var err error
+		arg.Path, arg.Checksum, err = writeArchive(ctx, store, dir, month)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := store.ArchiveAuditLogs(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "audit: month archived", "month", month.Format("2006-01"), "entries", entries, "path", arg.Path)
+	return nil
+}
+
+// RunRetention applies the policy every interval until the context is done,
+// starting right away
+func RunRetention(ctx context.Context, store db.Store, policy RetentionPolicy, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRetentionInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := Retain(ctx, store, policy, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "audit: retention failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func month(year int, m time.Month) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Date(year, m, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func TestRetain(t *testing.T) {
+	now := time.Date(2026, time.May, 15, 12, 0, 0, 0, time.UTC)
+	partitions := []pgtype.Timestamp{
+		month(2026, time.January),
+		month(2026, time.February),
+		month(2026, time.March),
+		month(2026, time.April),
+		month(2026, time.May),
+		month(2026, time.June),
+	}
+
+	t.Run("archived", func(t *testing.T) {
+		store := archiveStore(t, storedChain(t, 3))
+		dir := t.TempDir()
+
+		store.EXPECT().CreateAuditLogPartitions(gomock.Any(), month(2026, time.May)).Times(1)
+		store.EXPECT().CreateAuditLogPartitions(gomock.Any(), month(2026, time.June)).Times(1)
+		store.EXPECT().ListAuditLogPartitions(gomock.Any()).Times(1).Return(partitions, nil)
+
+		archives := []db.ArchiveAuditLogsParams{}
+		store.EXPECT().
+			ArchiveAuditLogs(gomock.Any(), gomock.Any()).
+			Times(2).
+			DoAndReturn(func(_ context.Context, arg db.ArchiveAuditLogsParams) (int64, error) {
+				archives = append(archives, arg)
+				return 3, nil
+			})
+
+		err := Retain(context.Background(), store, RetentionPolicy{Months: 2, ArchiveDir: dir}, now)
+		require.NoError(t, err)
+
+		// months before March are archived, oldest first
+		require.Len(t, archives, 2)
+		require.Equal(t, month(2026, time.January), archives[0].Month)
+		require.Equal(t, month(2026, time.February), archives[1].Month)
+		require.Equal(t, filepath.Join(dir, ArchiveName(archives[0].Month.Time)), archives[0].Path)
+		require.Len(t, archives[0].Checksum, 32)
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		store := archiveStore(t, nil)
+
+		store.EXPECT().CreateAuditLogPartitions(gomock.Any(), gomock.Any()).Times(2)
+		store.EXPECT().ListAuditLogPartitions(gomock.Any()).Times(1).Return(partitions, nil)
+		store.EXPECT().ListAuditLogsForArchive(gomock.Any(), gomock.Any()).Times(0)
+		store.EXPECT().
+			ArchiveAuditLogs(gomock.Any(), gomock.Eq(db.ArchiveAuditLogsParams{
+				Month: month(2026, time.January),
+			})).
+			Times(1).
+			Return(int64(3), nil)
+
+		err := Retain(context.Background(), store, RetentionPolicy{Months: 3}, now)
+		require.NoError(t, err)
+	})
+
+	t.Run("archived by another instance", func(t *testing.T) {
+		store := archiveStore(t, nil)
+
+		store.EXPECT().CreateAuditLogPartitions(gomock.Any(), gomock.Any()).Times(2)
+		store.EXPECT().ListAuditLogPartitions(gomock.Any()).Times(1).Return(partitions, nil)
+		store.EXPECT().
+			ArchiveAuditLogs(gomock.Any(), gomock.Any()).
+			Times(2).
+			Return(int64(0), db.ErrUniqueViolation)
+
+		err := Retain(context.Background(), store, RetentionPolicy{Months: 2}, now)
+		require.NoError(t, err)
+	})
+
+	t.Run("kept forever", func(t *testing.T) {
+		store := archiveStore(t, nil)
+
+		store.EXPECT().CreateAuditLogPartitions(gomock.Any(), gomock.Any()).Times(2)
+		store.EXPECT().ListAuditLogPartitions(gomock.Any()).Times(0)
+		store.EXPECT().ArchiveAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+
+		err := Retain(context.Background(), store, RetentionPolicy{}, now)
+		require.NoError(t, err)
+	})
+}
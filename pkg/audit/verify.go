@@ -0,0 +1,237 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+)
+
+// entries read at once when verifying the chain
+const verifyPageSize = 1000
+
+// Break is the first entry of the chain that doesn't match what was written
+type Break struct {
+	Seq    int64
+	Reason string
+}
+
+// Report of a verification of the chain
+type Report struct {
+	// entries and checkpoints verified before the break, if any
+	Entries     int64
+	Checkpoints int
+	// entries of archived months, their hashes are verified but not the
+	// entries themselves
+	Archived int64
+	// nil when the chain is intact
+	Break *Break
+}
+
+// Verify walks the chain from its first entry and reports the first one
+// that was changed or removed. Checkpoints are matched against the entries
+// they sign, an entry missing after the last one is reported too. Their
+// signatures are checked with publicKey, unless it is nil. Entries of
+// archived months are skipped, the chain goes on from the hash of the last
+// one.
+func Verify(ctx context.Context, store db.Store, publicKey ed25519.PublicKey) (Report, error) {
+	var report Report
+
+	checkpoints, err := store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	archives, err := store.ListAuditArchives(ctx)
+	if err != nil {
+		return report, err
+	}
+	if b := checkArchives(archives); b != nil {
+		report.Break = b
+		return report, nil
+	}
+
+	var prev []byte
+	seq := int64(1)
+
+	// skip goes over the archived entries before next, the chain goes on
+	// from the hash of the last one
+	skip := func(next int64) error {
+		left := len(checkpoints)
+		var err error
+		prev, checkpoints, report.Break, err = skipArchived(ctx, store, next, checkpoints, publicKey)
+		if err == nil && report.Break == nil {
+			report.Checkpoints += left - len(checkpoints)
+			report.Archived += next - seq
+			seq = next
+		}
+		return err
+	}
+
+	for {
+		rows, err := store.ListAuditChain(ctx, db.ListAuditChainParams{
+			After:    seq - 1,
+			PageSize: verifyPageSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, row := range rows {
+			if row.Seq != seq {
+				if next := archivedUntil(archives, seq, row.Seq); next > seq {
+					if err := skip(next); err != nil || report.Break != nil {
+						return report, err
+					}
+				}
+
+				if row.Seq != seq {
+					report.Break = &Break{Seq: seq, Reason: "entry missing"}
+					return report, nil
+				}
+			}
+
+			if !bytes.Equal(linkOf(row).hash(prev), row.Hash) {
+				report.Break = &Break{Seq: seq, Reason: "entry doesn't match its hash"}
+				return report, nil
+			}
+
+			if len(checkpoints) > 0 && checkpoints[0].Seq == seq {
+				if b := verifyCheckpoint(checkpoints[0], row.Hash, publicKey); b != nil {
+					report.Break = b
+					return report, nil
+				}
+				report.Checkpoints++
+				checkpoints = checkpoints[1:]
+			}
+
+			prev = row.Hash
+			report.Entries++
+			seq++
+		}
+
+		if len(rows) < verifyPageSize {
+			break
+		}
+	}
+
+	// checkpoints past the end of the chain sign entries since archived, or
+	// removed
+	if len(checkpoints) > 0 {
+		last := checkpoints[len(checkpoints)-1].Seq
+		if next := archivedUntil(archives, seq, last+1); next > seq {
+			if err := skip(next); err != nil || report.Break != nil {
+				return report, err
+			}
+		}
+	}
+	if len(checkpoints) > 0 {
+		report.Break = &Break{
+			Seq:    seq,
+			Reason: fmt.Sprintf("entry missing, entry %d is checkpointed", checkpoints[len(checkpoints)-1].Seq),
+		}
+	}
+
+	return report, nil
+}
+
+// checkArchives reports an archive whose range can't hold its chained
+// entries, or that overlaps another month. Entries are chained when their
+// response is written, so the ranges of consecutive months can interleave
+// but the later one still starts and ends after the earlier one.
+// archives are sorted by month.
+func checkArchives(archives []db.AuditArchive) *Break {
+	var prev *db.AuditArchive
+	for i := range archives {
+		archive := &archives[i]
+		if archive.Chained == 0 {
+			continue
+		}
+
+		if archive.FirstSeq < 1 || archive.LastSeq < archive.FirstSeq ||
+			archive.Chained > archive.LastSeq-archive.FirstSeq+1 {
+			return &Break{Seq: archive.FirstSeq, Reason: "archive doesn't match the chain"}
+		}
+
+		if prev != nil {
+			adjacent := prev.Month.Time.AddDate(0, 1, 0).Equal(archive.Month.Time)
+			if adjacent && (archive.FirstSeq <= prev.FirstSeq || archive.LastSeq <= prev.LastSeq) ||
+				!adjacent && archive.FirstSeq <= prev.LastSeq {
+				return &Break{Seq: archive.FirstSeq, Reason: "archives overlap"}
+			}
+		}
+		prev = archive
+	}
+
+	return nil
+}
+
+// archivedUntil returns the first entry from seq on, before end, that isn't
+// in an archived month, end when there is none
+func archivedUntil(archives []db.AuditArchive, seq, end int64) int64 {
+	for seq < end {
+		covered := false
+		for _, archive := range archives {
+			if archive.Chained > 0 && archive.FirstSeq <= seq && seq <= archive.LastSeq {
+				seq = archive.LastSeq + 1
+				covered = true
+			}
+		}
+		if !covered {
+			return seq
+		}
+	}
+
+	return end
+}
+
+// skipArchived goes over archived entries up to seq next, excluded. Their
+// checkpoints are matched against the hashes of the chain and the hash the
+// chain goes on from is returned with the checkpoints left.
+func skipArchived(
+	ctx context.Context,
+	store db.Store,
+	next int64,
+	checkpoints []db.AuditCheckpoint,
+	publicKey ed25519.PublicKey,
+) ([]byte, []db.AuditCheckpoint, *Break, error) {
+	for len(checkpoints) > 0 && checkpoints[0].Seq < next {
+		chained, err := store.GetAuditChain(ctx, checkpoints[0].Seq)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, nil, &Break{Seq: checkpoints[0].Seq, Reason: "entry missing from the chain"}, nil
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if b := verifyCheckpoint(checkpoints[0], chained.Hash, publicKey); b != nil {
+			return nil, nil, b, nil
+		}
+		checkpoints = checkpoints[1:]
+	}
+
+	last, err := store.GetAuditChain(ctx, next-1)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return nil, nil, &Break{Seq: next - 1, Reason: "entry missing from the chain"}, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return last.Hash, checkpoints, nil, nil
+}
+
+func verifyCheckpoint(checkpoint db.AuditCheckpoint, hash []byte, publicKey ed25519.PublicKey) *Break {
+	if publicKey != nil && !ed25519.Verify(publicKey, checkpointMessage(checkpoint.Seq, checkpoint.Hash), checkpoint.Signature) {
+		return &Break{Seq: checkpoint.Seq, Reason: "checkpoint signature invalid"}
+	}
+
+	if !bytes.Equal(checkpoint.Hash, hash) {
+		return &Break{Seq: checkpoint.Seq, Reason: "entry doesn't match its checkpoint"}
+	}
+
+	return nil
+}
@@ -0,0 +1,411 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net/http"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// storedChain returns n entries as the chain stores them
+func storedChain(t *testing.T, n int) []db.ListAuditChainRow {
+	entries := make([]Entry, n)
+	for i := range entries {
+		entries[i] = randomEntry()
+	}
+
+	arg := newAuditLogsParams(entries)
+	hashes := arg.Chain(nil)
+	require.Len(t, hashes, n)
+
+	req, res := arg.Requests, arg.Responses
+	rows := make([]db.ListAuditChainRow, n)
+	for i := range rows {
+		rows[i] = db.ListAuditChainRow{
+			Seq:          int64(i) + 1,
+			Hash:         hashes[i],
+			ID:           req.Ids[i],
+			Method:       req.Methods[i],
+			Path:         req.Paths[i],
+			UserID:       req.UserIds[i],
+			RequestedAt:  req.CreatedAt[i],
+			ClientIp:     req.ClientIps[i],
+			UserAgent:    req.UserAgents[i],
+			Query:        req.Queries[i],
+			RequestSize:  req.Sizes[i],
+			AuthMethod:   req.AuthMethods[i],
+			SpaceID:      req.SpaceIds[i],
+			RequestID:    req.RequestIds[i],
+			Status:       res.Statuses[i],
+			RespondedAt:  res.CreatedAt[i],
+			ResponseSize: res.Sizes[i],
+			LatencyUs:    res.LatenciesUs[i],
+			Outcome:      res.Outcomes[i],
+		}
+	}
+
+	return rows
+}
+
+func signedCheckpoint(key ed25519.PrivateKey, row db.ListAuditChainRow) db.AuditCheckpoint {
+	return db.AuditCheckpoint{
+		Seq:       row.Seq,
+		Hash:      row.Hash,
+		Signature: ed25519.Sign(key, checkpointMessage(row.Seq, row.Hash)),
+	}
+}
+
+func TestChainLinksEntries(t *testing.T) {
+	rows := storedChain(t, 3)
+
+	// the chain goes on from the previous batch
+	arg := newAuditLogsParams([]Entry{randomEntry()})
+	next := arg.Chain(rows[2].Hash)
+	require.Len(t, next, 1)
+	require.NotEqual(t, arg.Chain(nil)[0], next[0])
+
+	// each hash covers the previous one
+	require.Equal(t, rows[1].Hash, linkOf(rows[1]).hash(rows[0].Hash))
+	require.NotEqual(t, rows[1].Hash, linkOf(rows[1]).hash(rows[2].Hash))
+}
+
+func TestVerify(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		// changes the stored chain and returns its checkpoints
+		tamper func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint)
+		// seqs of the first and last entries archived, after tampering
+		archive     [2]int64
+		entries     int64
+		archived    int64
+		checkpoints int
+		brokenAt    int64
+		reason      string
+	}{
+		{
+			name: "intact",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows, []db.AuditCheckpoint{signedCheckpoint(key, rows[1]), signedCheckpoint(key, rows[4])}
+			},
+			entries:     5,
+			checkpoints: 2,
+		},
+		{
+			name: "entry changed",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				rows[2].Status = http.StatusOK + 1
+				return rows, nil
+			},
+			entries:  2,
+			brokenAt: 3,
+			reason:   "entry doesn't match its hash",
+		},
+		{
+			name: "entry removed",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return append(rows[:1], rows[2:]...), nil
+			},
+			entries:  1,
+			brokenAt: 2,
+			reason:   "entry missing",
+		},
+		{
+			name: "entry and its hash changed",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				checkpoints := []db.AuditCheckpoint{signedCheckpoint(key, rows[4])}
+				rows[4].Path = "/rewritten"
+				rows[4].Hash = linkOf(rows[4]).hash(rows[3].Hash)
+				return rows, checkpoints
+			},
+			entries:  4,
+			brokenAt: 5,
+			reason:   "entry doesn't match its checkpoint",
+		},
+		{
+			name: "last entries removed",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows[:3], []db.AuditCheckpoint{signedCheckpoint(key, rows[1]), signedCheckpoint(key, rows[4])}
+			},
+			entries:     3,
+			checkpoints: 1,
+			brokenAt:    4,
+			reason:      "entry missing, entry 5 is checkpointed",
+		},
+		{
+			name: "forged checkpoint",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows, []db.AuditCheckpoint{signedCheckpoint(otherKey, rows[3])}
+			},
+			entries:  3,
+			brokenAt: 4,
+			reason:   "checkpoint signature invalid",
+		},
+		{
+			name: "entries archived",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows, []db.AuditCheckpoint{signedCheckpoint(key, rows[1]), signedCheckpoint(key, rows[3])}
+			},
+			archive:     [2]int64{2, 3},
+			entries:     3,
+			archived:    2,
+			checkpoints: 2,
+		},
+		{
+			name: "last entries archived",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows, []db.AuditCheckpoint{signedCheckpoint(key, rows[2]), signedCheckpoint(key, rows[4])}
+			},
+			archive:     [2]int64{4, 5},
+			entries:     3,
+			archived:    2,
+			checkpoints: 2,
+		},
+		{
+			name: "entry removed after archived ones",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return append(rows[:3], rows[4:]...), nil
+			},
+			archive:  [2]int64{2, 3},
+			entries:  1,
+			archived: 2,
+			brokenAt: 4,
+			reason:   "entry missing",
+		},
+		{
+			name: "archived checkpoint forged",
+			tamper: func(rows []db.ListAuditChainRow) ([]db.ListAuditChainRow, []db.AuditCheckpoint) {
+				return rows, []db.AuditCheckpoint{signedCheckpoint(otherKey, rows[1])}
+			},
+			archive:  [2]int64{2, 3},
+			entries:  1,
+			brokenAt: 2,
+			reason:   "checkpoint signature invalid",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+
+			stored := storedChain(t, 5)
+			tampered, checkpoints := tc.tamper(append([]db.ListAuditChainRow{}, stored...))
+
+			// archived entries are no longer listed
+			archives := []db.AuditArchive{}
+			rows := tampered
+			if first, last := tc.archive[0], tc.archive[1]; first != 0 {
+				archives = append(archives, db.AuditArchive{Chained: last - first + 1, FirstSeq: first, LastSeq: last})
+				rows = []db.ListAuditChainRow{}
+				for _, row := range tampered {
+					if row.Seq < first || row.Seq > last {
+						rows = append(rows, row)
+					}
+				}
+			}
+
+			store.EXPECT().
+				ListAuditCheckpoints(gomock.Any()).
+				Times(1).
+				Return(checkpoints, nil)
+			store.EXPECT().
+				ListAuditArchives(gomock.Any()).
+				Times(1).
+				Return(archives, nil)
+			// the chain keeps the hashes of archived entries
+			store.EXPECT().
+				GetAuditChain(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, seq int64) (db.AuditChain, error) {
+					row := stored[seq-1]
+					return db.AuditChain{Seq: row.Seq, ID: row.ID, Hash: row.Hash}, nil
+				})
+			store.EXPECT().
+				ListAuditChain(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, arg db.ListAuditChainParams) ([]db.ListAuditChainRow, error) {
+					page := []db.ListAuditChainRow{}
+					for _, row := range rows {
+						if row.Seq > arg.After && len(page) < int(arg.PageSize) {
+							page = append(page, row)
+						}
+					}
+					return page, nil
+				})
+
+			report, err := Verify(context.Background(), store, public)
+			require.NoError(t, err)
+			require.Equal(t, tc.entries, report.Entries)
+			require.Equal(t, tc.archived, report.Archived)
+			require.Equal(t, tc.checkpoints, report.Checkpoints)
+
+			if tc.brokenAt == 0 {
+				require.Nil(t, report.Break)
+				return
+			}
+			require.Equal(t, &Break{Seq: tc.brokenAt, Reason: tc.reason}, report.Break)
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	head := db.AuditChain{Seq: 42, Hash: []byte("head")}
+
+	t.Run("new entries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+
+		store.EXPECT().GetAuditChainHead(gomock.Any()).Times(1).Return(head, nil)
+		store.EXPECT().GetLatestAuditCheckpoint(gomock.Any()).Times(1).Return(db.AuditCheckpoint{Seq: 41}, nil)
+		store.EXPECT().
+			CreateAuditCheckpoint(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateAuditCheckpointParams) error {
+				require.Equal(t, head.Seq, arg.Seq)
+				require.Equal(t, head.Hash, arg.Hash)
+				require.True(t, ed25519.Verify(public, checkpointMessage(arg.Seq, arg.Hash), arg.Signature))
+				return nil
+			})
+
+		require.NoError(t, Checkpoint(context.Background(), store, key))
+	})
+
+	t.Run("already checkpointed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+
+		store.EXPECT().GetAuditChainHead(gomock.Any()).Times(1).Return(head, nil)
+		store.EXPECT().GetLatestAuditCheckpoint(gomock.Any()).Times(1).Return(db.AuditCheckpoint{Seq: 42}, nil)
+		store.EXPECT().CreateAuditCheckpoint(gomock.Any(), gomock.Any()).Times(0)
+
+		require.NoError(t, Checkpoint(context.Background(), store, key))
+	})
+
+	t.Run("empty chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+
+		store.EXPECT().GetAuditChainHead(gomock.Any()).Times(1).Return(db.AuditChain{}, db.ErrRecordNotFound)
+		store.EXPECT().CreateAuditCheckpoint(gomock.Any(), gomock.Any()).Times(0)
+
+		require.NoError(t, Checkpoint(context.Background(), store, key))
+	})
+}
+
+func TestParseCheckpointKey(t *testing.T) {
+	encoded, err := GenerateCheckpointKey()
+	require.NoError(t, err)
+
+	key, err := ParseCheckpointKey(encoded)
+	require.NoError(t, err)
+	require.Len(t, key, ed25519.PrivateKeySize)
+
+	_, err = ParseCheckpointKey("c2hvcnQ=")
+	require.Error(t, err)
+	_, err = ParseCheckpointPublicKey(encoded[:10])
+	require.Error(t, err)
+}
+
+func TestVerifyChecksArchives(t *testing.T) {
+	month := func(m time.Month) pgtype.Timestamp {
+		return pgtype.Timestamp{Time: time.Date(2024, m, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+
+	testCases := []struct {
+		name     string
+		archives []db.AuditArchive
+		brokenAt int64
+		reason   string
+	}{
+		{
+			name: "consecutive months interleave",
+			archives: []db.AuditArchive{
+				{Month: month(time.January), Chained: 10, FirstSeq: 1, LastSeq: 11},
+				{Month: month(time.February), Chained: 10, FirstSeq: 10, LastSeq: 20},
+				{Month: month(time.March)},
+			},
+		},
+		{
+			name: "more entries than the range holds",
+			archives: []db.AuditArchive{
+				{Month: month(time.January), Chained: 10, FirstSeq: 1, LastSeq: 5},
+			},
+			brokenAt: 1,
+			reason:   "archive doesn't match the chain",
+		},
+		{
+			name: "range reversed",
+			archives: []db.AuditArchive{
+				{Month: month(time.January), Chained: 1, FirstSeq: 5, LastSeq: 4},
+			},
+			brokenAt: 5,
+			reason:   "archive doesn't match the chain",
+		},
+		{
+			name: "consecutive month inside the previous one",
+			archives: []db.AuditArchive{
+				{Month: month(time.January), Chained: 10, FirstSeq: 1, LastSeq: 10},
+				{Month: month(time.February), Chained: 5, FirstSeq: 3, LastSeq: 7},
+			},
+			brokenAt: 3,
+			reason:   "archives overlap",
+		},
+		{
+			name: "months apart overlap",
+			archives: []db.AuditArchive{
+				{Month: month(time.January), Chained: 10, FirstSeq: 1, LastSeq: 10},
+				{Month: month(time.March), Chained: 10, FirstSeq: 10, LastSeq: 19},
+			},
+			brokenAt: 10,
+			reason:   "archives overlap",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+
+			store.EXPECT().
+				ListAuditCheckpoints(gomock.Any()).
+				Times(1).
+				Return([]db.AuditCheckpoint{}, nil)
+			store.EXPECT().
+				ListAuditArchives(gomock.Any()).
+				Times(1).
+				Return(tc.archives, nil)
+			store.EXPECT().
+				GetAuditChain(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(db.AuditChain{}, nil)
+			store.EXPECT().
+				ListAuditChain(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return([]db.ListAuditChainRow{}, nil)
+
+			report, err := Verify(context.Background(), store, nil)
+			require.NoError(t, err)
+
+			if tc.brokenAt == 0 {
+				require.Nil(t, report.Break)
+				return
+			}
+			require.Equal(t, &Break{Seq: tc.brokenAt, Reason: tc.reason}, report.Break)
+			require.Zero(t, report.Entries)
+		})
+	}
+}
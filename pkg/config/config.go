@@ -15,8 +15,154 @@ type Config struct {
 	CookieAge        time.Duration `mapstructure:"COOKIE_AGE"`
 	CookieIsSecure   bool          `mapstructure:"COOKIE_IS_SECURE"`
 	CookieIsHttpOnly bool          `mapstructure:"COOKIE_IS_HTTP_ONLY"`
+
+	// comma separated "<hash key>:<block key>" pairs, the first one signs and
+	// encrypts new cookies and all of them are accepted when verifying
+	CookieKeys []string `mapstructure:"COOKIE_KEYS"`
+
+	// issuer shown in authenticator apps, defaults to space-it
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+
+	// passkey login is disabled when the relying party id is empty. The id is
+	// the domain passkeys are bound to and origins the comma separated list of
+	// origins allowed to use them, e.g. https://app.example.com
+	WebAuthnRPID    string   `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPName  string   `mapstructure:"WEBAUTHN_RP_NAME"`
+	WebAuthnOrigins []string `mapstructure:"WEBAUTHN_ORIGINS"`
+
+	// comma separated ids of the confidential OAuth2 clients an operator
+	// trusts to introspect every token, like sessions and the access tokens
+	// of other clients. Other clients only learn about the access tokens
+	// issued to them.
+	OAuthIntrospectionClients []string `mapstructure:"OAUTH_INTROSPECTION_CLIENTS"`
+
+	// how often buffered session last-seen timestamps are written
+	SessionFlushInterval time.Duration `mapstructure:"SESSION_FLUSH_INTERVAL"`
+
+	// sender of the emails sent to users. Until a mail server is configured,
+	// emails are appended to the mailer file, or written to stdout when empty
+	MailFrom   string `mapstructure:"MAIL_FROM"`
+	MailerFile string `mapstructure:"MAILER_FILE"`
+
+	// page where users choose a new password, the reset token is added to its
+	// query. Only the token is sent when empty.
+	PasswordResetURL string `mapstructure:"PASSWORD_RESET_URL"`
+
+	// page where users confirm their email, the verification token is added
+	// to its query. Only the token is sent when empty.
+	EmailVerificationURL string `mapstructure:"EMAIL_VERIFICATION_URL"`
+
+	// what users can't do until their email is verified, one of the
+	// EmailVerificationRequired values. Nothing is blocked when empty.
+	EmailVerificationRequired string `mapstructure:"EMAIL_VERIFICATION_REQUIRED"`
+
+	// argon2id parameters of new password hashes, memory in KiB. Defaults are
+	// used when zero. Hashes made with other parameters, or with bcrypt, are
+	// replaced when their user logs in.
+	PasswordArgon2Memory  uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Time    uint32 `mapstructure:"PASSWORD_ARGON2_TIME"`
+	PasswordArgon2Threads uint8  `mapstructure:"PASSWORD_ARGON2_THREADS"`
+
+	// zxcvbn score, from 0 to 4, new passwords must be estimated at. Defaults
+	// to 3 when zero.
+	PasswordMinStrength int `mapstructure:"PASSWORD_MIN_STRENGTH"`
+
+	// file of SHA-1 hashes of breached passwords, one per line optionally
+	// followed by ":<count>" as downloaded from Have I Been Pwned. New
+	// passwords in it are refused. Not checked when empty.
+	BreachedPasswordsFile string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+
+	// when true, registering responds the same whether the email is taken or
+	// not, the owner of a taken email is notified by email instead
+	RegistrationConcealsAccounts bool `mapstructure:"REGISTRATION_CONCEALS_ACCOUNTS"`
+
+	// failed password attempts before an account is locked out for a client
+	// IP, and before a client IP is locked out across accounts. Lockouts
+	// double with each further failure. Defaults are used when zero.
+	LoginMaxFailures      int           `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxFailuresPerIP int           `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LoginLockout          time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+
+	// comma separated addresses or CIDRs of the proxies whose X-Forwarded-For
+	// header gives the client IP. The header is ignored when empty.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// requests per second and bursts allowed to each API key, user or client
+	// IP. The auth limit applies, on top of the API one, to the routes
+	// accepting credentials or sending emails. The client IP limit applies to
+	// every request before its credentials are checked, it should be higher
+	// than the API one. Defaults are used when zero.
+	RateLimitAPI           float64 `mapstructure:"RATE_LIMIT_API"`
+	RateLimitAPIBurst      int     `mapstructure:"RATE_LIMIT_API_BURST"`
+	RateLimitAuth          float64 `mapstructure:"RATE_LIMIT_AUTH"`
+	RateLimitAuthBurst     int     `mapstructure:"RATE_LIMIT_AUTH_BURST"`
+	RateLimitClientIP      float64 `mapstructure:"RATE_LIMIT_CLIENT_IP"`
+	RateLimitClientIPBurst int     `mapstructure:"RATE_LIMIT_CLIENT_IP_BURST"`
+
+	// clients whose rate limits are remembered, those idle for the longest
+	// time are forgotten first. Defaults to 10000 when zero.
+	RateLimitMaxKeys int `mapstructure:"RATE_LIMIT_MAX_KEYS"`
+
+	// where rate limits are kept, one of the RateLimitBackend values. Each
+	// server instance keeps its own in memory when empty.
+	RateLimitBackend string `mapstructure:"RATE_LIMIT_BACKEND"`
+
+	// when true, requests are refused while the rate limit backend is
+	// unavailable instead of going through unlimited
+	RateLimitFailClosed bool `mapstructure:"RATE_LIMIT_FAIL_CLOSED"`
+
+	// audit entries are written in the background, in batches of up to
+	// AUDIT_BATCH_SIZE entries at least every AUDIT_FLUSH_INTERVAL. Up to
+	// AUDIT_BUFFER_SIZE entries wait to be written, then requests wait for
+	// room, or their entries are dropped and counted when AUDIT_DROP_WHEN_FULL.
+	// Defaults are used when zero.
+	AuditBufferSize    int           `mapstructure:"AUDIT_BUFFER_SIZE"`
+	AuditBatchSize     int           `mapstructure:"AUDIT_BATCH_SIZE"`
+	AuditFlushInterval time.Duration `mapstructure:"AUDIT_FLUSH_INTERVAL"`
+	AuditDropWhenFull  bool          `mapstructure:"AUDIT_DROP_WHEN_FULL"`
+
+	// base64 ed25519 seed signing checkpoints of the audit chain every
+	// AUDIT_CHECKPOINT_INTERVAL, defaults to an hour when zero. The chain is
+	// not checkpointed when the key is empty. Generate one with
+	// "admin audit generate-key".
+	AuditCheckpointKey      string        `mapstructure:"AUDIT_CHECKPOINT_KEY"`
+	AuditCheckpointInterval time.Duration `mapstructure:"AUDIT_CHECKPOINT_INTERVAL"`
+
+	// months of audit logs kept in the database besides the current one,
+	// every month is kept when zero. Older months are archived to gzipped
+	// NDJSON files in AUDIT_ARCHIVE_DIR, on the disk of the server instance
+	// archiving them, or dropped when it is empty. Partitions are created and
+	// old months archived every AUDIT_RETENTION_INTERVAL, defaults to a day
+	// when zero.
+	AuditRetentionMonths   int           `mapstructure:"AUDIT_RETENTION_MONTHS"`
+	AuditArchiveDir        string        `mapstructure:"AUDIT_ARCHIVE_DIR"`
+	AuditRetentionInterval time.Duration `mapstructure:"AUDIT_RETENTION_INTERVAL"`
+
+	// how long a shutdown waits for the requests in progress and the audit
+	// entries to be written, defaults to 30s when zero
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// lowest level of the records logged, one of debug, info, warn or error.
+	// Defaults to info when empty. At debug level every query is logged with
+	// the ID of the request it is run for.
+	LogLevel string `mapstructure:"LOG_LEVEL"`
 }
 
+// Values of EmailVerificationRequired
+const (
+	// users can't log in or authenticate in any other way
+	EmailVerificationRequiredForLogin = "login"
+	// users can't create spaces
+	EmailVerificationRequiredForSpaces = "spaces"
+)
+
+// Values of RateLimitBackend
+const (
+	RateLimitBackendMemory = "memory"
+	// shared by the server instances using the database
+	RateLimitBackendPostgres = "postgres"
+)
+
 // LoadConfig reads configuration from file or environment variables.
 func Load(path string) (config Config) {
 	viper.AddConfigPath(path)
@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+)
+
+// User is the public representation of a db.User.
+// It is what handlers respond with, what sessions store and what the auth
+// middlewares put in the request context, it never carries the password hash.
+type User struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// NewUser creates the public representation of a user
+func NewUser(user db.User) User {
+	return User{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		CreatedAt:     user.CreatedAt.Time,
+	}
+}
+
+// Profile is the account of a user with what they tell about themselves, as
+// shown to the user
+type Profile struct {
+	User
+	DisplayName string `json:"display_name"`
+	// URL of an image, empty when none
+	Avatar   string `json:"avatar"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+}
+
+// NewProfile creates the profile of a user as shown to the user
+func NewProfile(user db.User) Profile {
+	return Profile{
+		User:        NewUser(user),
+		DisplayName: user.DisplayName,
+		Avatar:      user.Avatar,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+	}
+}
+
+// PublicProfile is the profile of a user as shown to the users sharing a
+// space with them, it doesn't carry the email
+type PublicProfile struct {
+	ID          uuid.UUID `json:"id"`
+	DisplayName string    `json:"display_name"`
+	Avatar      string    `json:"avatar"`
+	Timezone    string    `json:"timezone"`
+}
+
+// NewPublicProfile creates the profile of a user as shown to other users
+func NewPublicProfile(user db.User) PublicProfile {
+	return PublicProfile{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		Avatar:      user.Avatar,
+		Timezone:    user.Timezone,
+	}
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// publicTypes lists every representation returned to clients or stored in tokens
+var publicTypes = []interface{}{
+	User{},
+	Profile{},
+	PublicProfile{},
+}
+
+func TestPublicTypesHaveNoPassword(t *testing.T) {
+	for _, value := range publicTypes {
+		typ := reflect.TypeOf(value)
+		t.Run(typ.Name(), func(t *testing.T) {
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				require.NotContains(t, strings.ToLower(field.Name), "password")
+				require.NotContains(t, strings.ToLower(field.Tag.Get("json")), "password")
+			}
+		})
+	}
+}
+
+func TestPublicProfileHasNoEmail(t *testing.T) {
+	typ := reflect.TypeOf(PublicProfile{})
+	for i := 0; i < typ.NumField(); i++ {
+		require.NotContains(t, strings.ToLower(typ.Field(i).Name), "email")
+	}
+}
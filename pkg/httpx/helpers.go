@@ -2,21 +2,136 @@ package httpx
 
 import (
 	"fmt"
+	"log/slog"
 
 	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/logx"
+	"github.com/Luckny/space-it/pkg/token"
 	"github.com/gin-gonic/gin"
 )
 
-func GetUserFromContext(c *gin.Context) (*db.User, error) {
+// SetUserInContext marks the request as authenticated by user.
+// Only the public representation of the user is stored in the context.
+// The records logged for the request from now on carry the user ID.
+func SetUserInContext(c *gin.Context, user db.User) {
+	u := dto.NewUser(user)
+	c.Set("user", &u)
+	if c.Request != nil {
+		logx.Enrich(c.Request.Context(), slog.String(logx.KeyUserID, user.ID.String()))
+	}
+}
+
+func GetUserFromContext(c *gin.Context) (*dto.User, error) {
 	u, ok := c.Get("user")
 	if !ok {
 		return nil, fmt.Errorf("error getting user from context")
 	}
 
-	user, ok := u.(*db.User)
+	user, ok := u.(*dto.User)
 	if !ok {
 		return nil, fmt.Errorf("error getting user from context")
 	}
 
 	return user, nil
 }
+
+// SetPendingUserInContext marks the request as authenticated by its first factor
+// only, the user still has to provide a second factor to be authenticated
+func SetPendingUserInContext(c *gin.Context, user dto.User) {
+	c.Set("pendingUser", &user)
+}
+
+func GetPendingUserFromContext(c *gin.Context) (*dto.User, error) {
+	u, ok := c.Get("pendingUser")
+	if !ok {
+		return nil, fmt.Errorf("error getting pending user from context")
+	}
+
+	user, ok := u.(*dto.User)
+	if !ok {
+		return nil, fmt.Errorf("error getting pending user from context")
+	}
+
+	return user, nil
+}
+
+// GetTokenFromContext returns the token payload set by the VerifyToken middleware
+func GetTokenFromContext(c *gin.Context) (*token.Payload, bool) {
+	t, ok := c.Get("token")
+	if !ok {
+		return nil, false
+	}
+
+	payload, ok := t.(*token.Payload)
+	return payload, ok
+}
+
+// SetAPIKeyInContext marks the request as authenticated by key, on behalf of its user
+func SetAPIKeyInContext(c *gin.Context, key db.ApiKey) {
+	c.Set("apiKey", &key)
+}
+
+// GetAPIKeyFromContext returns the API key set by the AuthenticateAPIKey middleware
+func GetAPIKeyFromContext(c *gin.Context) (*db.ApiKey, bool) {
+	k, ok := c.Get("apiKey")
+	if !ok {
+		return nil, false
+	}
+
+	key, ok := k.(*db.ApiKey)
+	return key, ok
+}
+
+// SetOAuthTokenInContext marks the request as authenticated by an OAuth2
+// access token, on behalf of the token's user
+func SetOAuthTokenInContext(c *gin.Context, payload *token.Payload) {
+	c.Set("oauthToken", payload)
+}
+
+// GetOAuthTokenFromContext returns the access token set by the
+// AuthenticateOAuthToken middleware
+func GetOAuthTokenFromContext(c *gin.Context) (*token.Payload, bool) {
+	t, ok := c.Get("oauthToken")
+	if !ok {
+		return nil, false
+	}
+
+	payload, ok := t.(*token.Payload)
+	return payload, ok
+}
+
+// How a request is authenticated, or was attempted to be when a second factor
+// is pending
+const (
+	AuthMethodPassword = "password"
+	AuthMethodPasskey  = "passkey"
+	AuthMethodSession  = "session"
+	AuthMethodAPIKey   = "api_key"
+	AuthMethodOAuth    = "oauth"
+)
+
+// SetAuthMethodInContext records how the request is authenticated
+func SetAuthMethodInContext(c *gin.Context, method string) {
+	c.Set("authMethod", method)
+}
+
+// GetAuthMethodFromContext returns how the request is authenticated, empty
+// when it is not
+func GetAuthMethodFromContext(c *gin.Context) string {
+	return c.GetString("authMethod")
+}
+
+// SetRequestIDInContext records the id correlating the request with its
+// audit entry, the events it causes and its logs. The context of the request
+// carries it too, down to the store.
+func SetRequestIDInContext(c *gin.Context, id string) {
+	c.Set("requestID", id)
+	c.Request = c.Request.WithContext(logx.WithRequestID(c.Request.Context(), id))
+}
+
+// GetRequestIDFromContext returns the id of the request, empty when it has
+// none
+func GetRequestIDFromContext(c *gin.Context) string {
+	return c.GetString("requestID")
+}
@@ -1,29 +1,11 @@
 package httpx
 
 import (
-	"bytes"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-type ResponseWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
-}
-
-func (r ResponseWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
-	return r.ResponseWriter.Write(b)
-}
-
-func NewResponseWriter(writer gin.ResponseWriter) *ResponseWriter {
-	return &ResponseWriter{
-		body:           &bytes.Buffer{},
-		ResponseWriter: writer,
-	}
-}
-
 // WriteResponse writes a json response with api required headers
 func WriteResponse(c *gin.Context, status int, value interface{}) {
 	c.Header("Content-Type", "application/json")
@@ -43,3 +25,15 @@ func WriteError(c *gin.Context, status int, err error) {
 	}
 	WriteResponse(c, status, map[string]string{"error": err.Error()})
 }
+
+// FieldErrors are the problems of a request by field name
+type FieldErrors map[string][]string
+
+// WriteFieldErrors writes a bad request response telling clients which
+// fields are invalid and why
+func WriteFieldErrors(c *gin.Context, fields FieldErrors) {
+	WriteResponse(c, http.StatusBadRequest, map[string]interface{}{
+		"error":  "invalid request",
+		"fields": fields,
+	})
+}
@@ -0,0 +1,131 @@
+// Package logx sets up the structured logs of the project. Records are JSON
+// objects, those logged with the context of a request carry its request ID
+// and whatever the request was enriched with, like its user and space.
+package logx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Keys of the attributes requests are enriched with
+const (
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+	KeySpaceID   = "space_id"
+)
+
+// New returns a logger writing JSON records of level or above to w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(NewHandler(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+// ParseLevel parses one of debug, info, warn or error, info when empty
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return level, nil
+	}
+
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+type requestKey struct{}
+
+// request holds the attributes of a request. Attributes are added as the
+// request goes through the middlewares, records logged before they are
+// added don't have them.
+type request struct {
+	id string
+
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID, records
+// logged with it or the contexts derived from it carry the ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{
+		id:    id,
+		attrs: []slog.Attr{slog.String(KeyRequestID, id)},
+	})
+}
+
+// RequestID returns the ID of the request ctx belongs to, empty when it
+// doesn't belong to one
+func RequestID(ctx context.Context) string {
+	req, ok := ctx.Value(requestKey{}).(*request)
+	if !ok {
+		return ""
+	}
+
+	return req.id
+}
+
+// Enrich adds attrs to the records logged for the request ctx belongs to,
+// from now on. It does nothing when ctx doesn't belong to a request.
+func Enrich(ctx context.Context, attrs ...slog.Attr) {
+	req, ok := ctx.Value(requestKey{}).(*request)
+	if !ok {
+		return
+	}
+
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	for _, attr := range attrs {
+		req.set(attr)
+	}
+}
+
+// set replaces the attribute with the same key, a request authenticated
+// twice is logged as its last user
+func (req *request) set(attr slog.Attr) {
+	for i := range req.attrs {
+		if req.attrs[i].Key == attr.Key {
+			req.attrs[i] = attr
+			return
+		}
+	}
+	req.attrs = append(req.attrs, attr)
+}
+
+func (req *request) snapshot() []slog.Attr {
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	return append([]slog.Attr(nil), req.attrs...)
+}
+
+// Handler adds the attributes of the request a record is logged for to the
+// records it passes on
+type Handler struct {
+	next slog.Handler
+}
+
+// NewHandler returns a handler passing records on to next
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		record = record.Clone()
+		record.AddAttrs(req.snapshot()...)
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,105 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/tracelog"
+	"github.com/stretchr/testify/require"
+)
+
+// decode returns the records written to buf
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var record map[string]any
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := WithRequestID(context.Background(), "request")
+	require.Equal(t, "request", RequestID(ctx))
+	require.Empty(t, RequestID(context.Background()))
+
+	logger.InfoContext(ctx, "before")
+	Enrich(ctx, slog.String(KeyUserID, "first"), slog.String(KeySpaceID, "space"))
+	Enrich(ctx, slog.String(KeyUserID, "user"))
+	logger.With("component", "test").InfoContext(ctx, "after")
+	logger.InfoContext(context.Background(), "outside")
+	logger.DebugContext(ctx, "filtered")
+
+	// enriching a context without a request does nothing
+	Enrich(context.Background(), slog.String(KeyUserID, "user"))
+
+	records := decode(t, &buf)
+	require.Len(t, records, 3)
+
+	require.Equal(t, "before", records[0]["msg"])
+	require.Equal(t, "request", records[0][KeyRequestID])
+	require.NotContains(t, records[0], KeyUserID)
+
+	require.Equal(t, "after", records[1]["msg"])
+	require.Equal(t, "INFO", records[1]["level"])
+	require.Equal(t, "test", records[1]["component"])
+	require.Equal(t, "request", records[1][KeyRequestID])
+	require.Equal(t, "user", records[1][KeyUserID])
+	require.Equal(t, "space", records[1][KeySpaceID])
+
+	require.Equal(t, "outside", records[2]["msg"])
+	require.NotContains(t, records[2], KeyRequestID)
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, tc := range []struct {
+		s     string
+		level slog.Level
+		ok    bool
+	}{
+		{s: "", level: slog.LevelInfo, ok: true},
+		{s: "debug", level: slog.LevelDebug, ok: true},
+		{s: "WARN", level: slog.LevelWarn, ok: true},
+		{s: "error", level: slog.LevelError, ok: true},
+		{s: "verbose"},
+	} {
+		level, err := ParseLevel(tc.s)
+		if !tc.ok {
+			require.Error(t, err, tc.s)
+			continue
+		}
+		require.NoError(t, err, tc.s)
+		require.Equal(t, tc.level, level, tc.s)
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := QueryTracer(New(&buf, slog.LevelDebug))
+	ctx := WithRequestID(context.Background(), "request")
+
+	tracer.Logger.Log(ctx, tracelog.LogLevelInfo, "Query", map[string]any{
+		"sql":  "SELECT 1",
+		"args": []any{"secret"},
+	})
+	tracer.Logger.Log(ctx, tracelog.LogLevelError, "Query", map[string]any{"err": "failed"})
+
+	records := decode(t, &buf)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "DEBUG", records[0]["level"])
+	require.Equal(t, "db: Query", records[0]["msg"])
+	require.Equal(t, "SELECT 1", records[0]["sql"])
+	require.Equal(t, "request", records[0][KeyRequestID])
+	require.NotContains(t, records[0], "args")
+
+	require.Equal(t, "ERROR", records[1]["level"])
+}
@@ -0,0 +1,42 @@
+package logx
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+// QueryTracer returns a pgx tracer logging the queries run with the context
+// of a request under its request ID. Queries are logged at debug level and
+// failed ones at error level. Their arguments are never logged, they hold
+// password hashes and tokens.
+func QueryTracer(logger *slog.Logger) *tracelog.TraceLog {
+	return &tracelog.TraceLog{
+		Logger: tracelog.LoggerFunc(func(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
+			attrs := make([]slog.Attr, 0, len(data))
+			for key, value := range data {
+				if key == "args" {
+					continue
+				}
+				attrs = append(attrs, slog.Any(key, value))
+			}
+
+			logger.LogAttrs(ctx, queryLevel(level), "db: "+msg, attrs...)
+		}),
+		LogLevel: tracelog.LogLevelInfo,
+	}
+}
+
+// queryLevel maps pgx levels on slog ones, what pgx logs as info is logged
+// for every query
+func queryLevel(level tracelog.LogLevel) slog.Level {
+	switch {
+	case level <= tracelog.LogLevelError:
+		return slog.LevelError
+	case level == tracelog.LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
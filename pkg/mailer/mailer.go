@@ -0,0 +1,68 @@
+// Package mailer sends the emails of the application, e.g. password reset links
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	// Send delivers msg or returns why it could not
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes emails to a writer instead of delivering them, so flows
+// that send emails can be used locally
+type LogMailer struct {
+	from string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewLogMailer(from string, w io.Writer) *LogMailer {
+	return &LogMailer{from: from, w: w}
+}
+
+// NewFileMailer creates a LogMailer appending emails to the file at path, or
+// writing them to stdout when path is empty
+func NewFileMailer(from, path string) (*LogMailer, error) {
+	if path == "" {
+		return NewLogMailer(from, os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open mailer file: %w", err)
+	}
+
+	return NewLogMailer(from, file), nil
+}
+
+// Send writes msg with its headers, followed by a separator line
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(
+		m.w,
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n.\r\n",
+		m.from,
+		msg.To,
+		msg.Subject,
+		time.Now().Format(time.RFC1123Z),
+		msg.Body,
+	)
+	return err
+}
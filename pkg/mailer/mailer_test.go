@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer("space-it <no-reply@example.com>", &buf)
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@email.com",
+		Subject: "Reset your password",
+		Body:    "https://app.example.com/reset?token=abc",
+	})
+	require.NoError(t, err)
+
+	sent := buf.String()
+	require.Contains(t, sent, "From: space-it <no-reply@example.com>\r\n")
+	require.Contains(t, sent, "To: user@email.com\r\n")
+	require.Contains(t, sent, "Subject: Reset your password\r\n")
+	require.Contains(t, sent, "\r\n\r\nhttps://app.example.com/reset?token=abc\r\n.\r\n")
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+
+	mailer, err := NewFileMailer("no-reply@example.com", path)
+	require.NoError(t, err)
+
+	for _, to := range []string{"first@email.com", "second@email.com"} {
+		require.NoError(t, mailer.Send(context.Background(), Message{To: to, Subject: "hi"}))
+	}
+
+	// emails are appended
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "To: first@email.com")
+	require.Contains(t, string(content), "To: second@email.com")
+
+	_, err = NewFileMailer("no-reply@example.com", filepath.Join(t.TempDir(), "missing", "mail.log"))
+	require.Error(t, err)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	require.Empty(t, mailer.Sent())
+
+	first := Message{To: "first@email.com", Subject: "one"}
+	second := Message{To: "second@email.com", Subject: "two"}
+	require.NoError(t, mailer.Send(context.Background(), first))
+	require.NoError(t, mailer.Send(context.Background(), second))
+
+	require.Equal(t, []Message{first, second}, mailer.Sent())
+	require.Equal(t, []Message{second}, mailer.SentTo("second@email.com"))
+	require.Empty(t, mailer.SentTo("unknown@email.com"))
+}
@@ -0,0 +1,158 @@
+// Package oauth holds the parts of the OAuth2 authorization server that don't
+// depend on HTTP handlers: tokens, PKCE and redirect URI rules.
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Prefixes of the secrets issued by the authorization server, so they are
+// easy to tell apart from each other and from API keys
+const (
+	AccessTokenPrefix  = "sat_"
+	RefreshTokenPrefix = "srt_"
+	ClientSecretPrefix = "scs_"
+)
+
+// Grant types accepted by the token endpoint
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// ResponseTypeCode is the only response type supported by the authorization endpoint
+const ResponseTypeCode = "code"
+
+// CodeChallengeS256 is the only PKCE method supported, plain challenges
+// would leak the verifier with the authorization request
+const CodeChallengeS256 = "S256"
+
+const (
+	AuthorizationCodeDuration = time.Minute
+	AccessTokenDuration       = time.Hour
+	RefreshTokenDuration      = 30 * 24 * time.Hour
+)
+
+// number of random bytes in tokens and codes
+const secretLength = 32
+
+var (
+	ErrUnsupportedChallengeMethod = errors.New("code_challenge_method must be S256")
+	ErrInvalidCodeChallenge       = errors.New("invalid code_challenge")
+	ErrInvalidCodeVerifier        = errors.New("invalid code_verifier")
+	ErrInvalidRedirectURI         = errors.New("redirect uri must be an absolute https url without fragment, or http on a loopback address")
+)
+
+// GenerateToken returns a new random secret starting with prefix
+func GenerateToken(prefix string) (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Hash returns the value stored for a token, code or client secret.
+// They are random enough that a fast hash is sufficient.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAccessToken reports whether token looks like an access token issued by
+// the authorization server rather than another bearer token
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// IsRefreshToken reports whether token looks like a refresh token issued by
+// the authorization server
+func IsRefreshToken(token string) bool {
+	return strings.HasPrefix(token, RefreshTokenPrefix)
+}
+
+// ValidCodeChallenge checks a challenge sent with an authorization request
+func ValidCodeChallenge(challenge, method string) error {
+	if method != CodeChallengeS256 {
+		return ErrUnsupportedChallengeMethod
+	}
+
+	// base64url encoded sha256 sum
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(decoded) != sha256.Size {
+		return ErrInvalidCodeChallenge
+	}
+
+	return nil
+}
+
+// VerifyCodeChallenge checks the verifier sent to the token endpoint against
+// the challenge of the authorization request (RFC 7636)
+func VerifyCodeChallenge(verifier, challenge string) error {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return ErrInvalidCodeVerifier
+	}
+
+	for _, c := range verifier {
+		if !isUnreserved(c) {
+			return ErrInvalidCodeVerifier
+		}
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) != 1 {
+		return ErrInvalidCodeVerifier
+	}
+
+	return nil
+}
+
+func isUnreserved(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// ValidRedirectURI checks a redirect URI when a client is registered. Plain
+// http is only allowed for native apps listening on a loopback address.
+func ValidRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return ErrInvalidRedirectURI
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if u.Hostname() == "localhost" {
+			return nil
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+
+	return ErrInvalidRedirectURI
+}
+
+// ParseScope splits a space separated scope parameter
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a scope parameter
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,69 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateToken(t *testing.T) {
+	token, err := GenerateToken(AccessTokenPrefix)
+	require.NoError(t, err)
+	require.True(t, IsAccessToken(token))
+	require.Len(t, Hash(token), 64)
+
+	other, err := GenerateToken(RefreshTokenPrefix)
+	require.NoError(t, err)
+	require.False(t, IsAccessToken(other))
+	require.True(t, IsRefreshToken(other))
+	require.False(t, IsRefreshToken(token))
+	require.NotEqual(t, Hash(token), Hash(other))
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// example of RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	require.NoError(t, ValidCodeChallenge(challenge, CodeChallengeS256))
+	require.ErrorIs(t, ValidCodeChallenge(challenge, "plain"), ErrUnsupportedChallengeMethod)
+	require.ErrorIs(t, ValidCodeChallenge("short", CodeChallengeS256), ErrInvalidCodeChallenge)
+
+	require.NoError(t, VerifyCodeChallenge(verifier, challenge))
+	require.ErrorIs(t, VerifyCodeChallenge(strings.ToUpper(verifier), challenge), ErrInvalidCodeVerifier)
+	// too short and invalid characters
+	require.ErrorIs(t, VerifyCodeChallenge("abc", challenge), ErrInvalidCodeVerifier)
+	require.ErrorIs(t, VerifyCodeChallenge(strings.Repeat("+", 43), challenge), ErrInvalidCodeVerifier)
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	valid := []string{
+		"https://tool.example.com/callback",
+		"https://tool.example.com/callback?team=1",
+		"http://localhost:8080/callback",
+		"http://127.0.0.1:53682/",
+		"http://[::1]/callback",
+	}
+	for _, uri := range valid {
+		require.NoError(t, ValidRedirectURI(uri), uri)
+	}
+
+	invalid := []string{
+		"",
+		"/callback",
+		"http://tool.example.com/callback",
+		"https://tool.example.com/callback#token",
+		"javascript:alert(1)",
+	}
+	for _, uri := range invalid {
+		require.ErrorIs(t, ValidRedirectURI(uri), ErrInvalidRedirectURI, uri)
+	}
+}
+
+func TestScope(t *testing.T) {
+	scopes := ParseScope(" spaces:read  messages:write ")
+	require.Equal(t, []string{"spaces:read", "messages:write"}, scopes)
+	require.Equal(t, "spaces:read messages:write", FormatScope(scopes))
+	require.Empty(t, ParseScope(""))
+}
@@ -0,0 +1,56 @@
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued at once
+const RecoveryCodeCount = 10
+
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes returns n single-use codes formatted as xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b, err := randomChars(10)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+
+	return codes, nil
+}
+
+// randomChars returns n characters of the recovery alphabet without modulo bias
+func randomChars(n int) ([]byte, error) {
+	max := 256 - 256%len(recoveryAlphabet)
+	chars := make([]byte, 0, n)
+	buf := make([]byte, n)
+
+	for len(chars) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		for _, b := range buf {
+			if int(b) >= max || len(chars) == n {
+				continue
+			}
+			chars = append(chars, recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+	}
+
+	return chars, nil
+}
+
+// HashRecoveryCode returns the value stored for a recovery code.
+// Codes are random enough that a fast hash is sufficient.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
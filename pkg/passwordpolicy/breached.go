@@ -0,0 +1,85 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// length of the hash prefixes breached hashes are grouped by
+const hashPrefixLength = 5
+
+// BreachedList holds the SHA-1 hashes of passwords known from data breaches.
+// Like the k-anonymity range API of Have I Been Pwned, hashes are grouped by
+// their first 5 hex characters and a password is looked up in the range of
+// its prefix. The list is read from disk, passwords never leave the server.
+type BreachedList struct {
+	// sorted hash suffixes by prefix
+	ranges map[string][]string
+	size   int
+}
+
+// LoadBreachedList reads a file with one uppercase or lowercase SHA-1 hash
+// per line, optionally followed by ":<count>" as in the files downloaded from
+// Have I Been Pwned. Blank lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{ranges: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-1 hash", path, line)
+		}
+
+		prefix := hash[:hashPrefixLength]
+		list.ranges[prefix] = append(list.ranges[prefix], hash[hashPrefixLength:])
+		list.size++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, suffixes := range list.ranges {
+		slices.Sort(suffixes)
+	}
+
+	return list, nil
+}
+
+// Len returns the number of hashes in the list
+func (list *BreachedList) Len() int {
+	return list.size
+}
+
+// Range returns the sorted suffixes of the hashes starting with prefix, the
+// first 5 uppercase hex characters of a SHA-1 hash
+func (list *BreachedList) Range(prefix string) []string {
+	return list.ranges[prefix]
+}
+
+// Contains reports whether the password is in the list
+func (list *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := slices.BinarySearch(list.Range(hash[:hashPrefixLength]), hash[hashPrefixLength:])
+	return found
+}
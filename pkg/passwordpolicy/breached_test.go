@@ -0,0 +1,31 @@
+package passwordpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBreachedList(t *testing.T) {
+	list, err := LoadBreachedList("testdata/breached.txt")
+	require.NoError(t, err)
+	require.Equal(t, 4, list.Len())
+
+	require.True(t, list.Contains("password"))
+	// lowercase hashes and lines without a count
+	require.True(t, list.Contains("Xk7#qPz!r2Lw"))
+	require.True(t, list.Contains("5up3r-Str0ng-Pa55"))
+	require.False(t, list.Contains("Password"))
+	require.False(t, list.Contains(""))
+
+	require.Equal(t, []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD8"}, list.Range("5BAA6"))
+	require.Empty(t, list.Range("00000"))
+}
+
+func TestLoadInvalidBreachedList(t *testing.T) {
+	_, err := LoadBreachedList("testdata/invalid.txt")
+	require.EqualError(t, err, "testdata/invalid.txt:2: invalid SHA-1 hash")
+
+	_, err = LoadBreachedList("testdata/missing.txt")
+	require.Error(t, err)
+}
@@ -0,0 +1,72 @@
+// Package passwordpolicy decides which passwords users may choose: long
+// enough, not too long for the hash, hard enough to guess and not known from
+// a data breach.
+package passwordpolicy
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	// MinLength is the minimum number of characters of a password
+	MinLength = 8
+	// MaxBytes is the maximum length of a password in bytes. Bcrypt ignores
+	// the bytes after 72, passwords still hashed with it would be truncated.
+	MaxBytes = 72
+
+	// score required by default
+	defaultMinScore = ScoreSafelyUnguessable
+)
+
+// Policy checks new passwords
+type Policy struct {
+	minScore int
+	// nil when breached passwords are not checked
+	breached *BreachedList
+}
+
+// New returns a policy requiring passwords estimated at least minScore, or
+// ScoreSafelyUnguessable when zero. Passwords in breached are refused, it can
+// be nil.
+func New(minScore int, breached *BreachedList) (*Policy, error) {
+	if minScore == 0 {
+		minScore = defaultMinScore
+	}
+	if minScore < ScoreTooGuessable || minScore > ScoreVeryUnguessable {
+		return nil, fmt.Errorf("invalid minimum password strength %d", minScore)
+	}
+
+	return &Policy{minScore: minScore, breached: breached}, nil
+}
+
+// Check returns why the password is refused, nothing when it is accepted.
+// User inputs are words of the account an attacker would try first, like its
+// email.
+func (policy *Policy) Check(password string, userInputs ...string) []string {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", MinLength))
+	}
+	if len(password) > MaxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", MaxBytes))
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	if policy.breached != nil && policy.breached.Contains(password) {
+		problems = append(problems, "appears in a known data breach")
+	}
+
+	if estimate := EstimateStrength(password, userInputs...); estimate.Score < policy.minScore {
+		problem := "is too easy to guess"
+		if estimate.Warning != "" {
+			problem += ", it " + estimate.Warning
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems
+}
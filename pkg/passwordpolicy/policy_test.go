@@ -0,0 +1,84 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Luckny/space-it/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	breached, err := LoadBreachedList("testdata/breached.txt")
+	require.NoError(t, err)
+
+	policy, err := New(0, breached)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		password   string
+		userInputs []string
+		problems   []string
+	}{
+		{
+			name:     "accepted",
+			password: util.RandomPassword(),
+		},
+		{
+			name:     "too short",
+			password: "xK9#mQ2",
+			problems: []string{"must be at least 8 characters"},
+		},
+		{
+			// 8 characters but more than 8 bytes
+			name:     "multibyte",
+			password: "élan-vïtal-Ünïque",
+		},
+		{
+			name:     "too long",
+			password: strings.Repeat("é", MaxBytes/2+1),
+			problems: []string{"must be at most 72 bytes"},
+		},
+		{
+			name:     "breached and guessable",
+			password: "password",
+			problems: []string{
+				"appears in a known data breach",
+				"is too easy to guess, it is a commonly used password",
+			},
+		},
+		{
+			name:     "breached",
+			password: "Xk7#qPz!r2Lw",
+			problems: []string{"appears in a known data breach"},
+		},
+		{
+			name:       "email",
+			password:   "marie.curie1",
+			userInputs: []string{"marie.curie@example.com"},
+			problems:   []string{"is too easy to guess, it contains your email"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.problems, policy.Check(tc.password, tc.userInputs...))
+		})
+	}
+}
+
+func TestPolicyWithoutBreachedList(t *testing.T) {
+	policy, err := New(ScoreVeryGuessable, nil)
+	require.NoError(t, err)
+	require.Empty(t, policy.Check("Xk7#qPz!r2Lw"))
+	require.Empty(t, policy.Check("john1990"))
+	require.Equal(t, []string{"is too easy to guess, it is a commonly used password"}, policy.Check("password"))
+}
+
+func TestNewPolicyInvalidScore(t *testing.T) {
+	for _, score := range []int{-1, ScoreVeryUnguessable + 1} {
+		_, err := New(score, nil)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,438 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// common passwords and words, most common first
+//
+//go:embed common.txt
+var commonList string
+
+// rank of each common password, 1 for the most common
+var commonRanks = rankWords(strings.Fields(commonList))
+
+// keyboard rows, a run along one is a spatial pattern
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "azertyuiop", "qsdfghjklm", "wxcvbn"}
+
+// common substitutions of letters
+var l33t = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+	'0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+const (
+	// guesses for each character not part of a pattern
+	bruteforceCardinality = 10
+	// minimum guesses of a pattern of one and more characters
+	minSingleGuesses = 10
+	minMultiGuesses  = 50
+)
+
+// Scores of an estimate, from the zxcvbn thresholds
+const (
+	ScoreTooGuessable = iota
+	ScoreVeryGuessable
+	ScoreSomewhatGuessable
+	ScoreSafelyUnguessable
+	ScoreVeryUnguessable
+)
+
+// Estimate is a zxcvbn-style estimate of how hard a password is to guess
+type Estimate struct {
+	// log10 of the number of guesses needed to find the password
+	Guesses float64 `json:"guesses"`
+	// from ScoreTooGuessable to ScoreVeryUnguessable
+	Score int `json:"score"`
+	// the most guessable part of the password, empty when there is none
+	Warning string `json:"warning,omitempty"`
+}
+
+// match is a pattern found in a password, from rune i to rune j excluded
+type match struct {
+	i, j int
+	// log10 of the guesses of the pattern
+	guesses float64
+	warning string
+}
+
+// EstimateStrength estimates the guesses an attacker needs to find the
+// password. Like zxcvbn it looks for the cheapest way to build the password
+// from common passwords, user inputs, keyboard runs, sequences, repeats and
+// years, the rest being brute forced. User inputs are words guessable from
+// the account, like its email.
+func EstimateStrength(password string, userInputs ...string) Estimate {
+	return estimate([]rune(password), rankWords(splitInputs(userInputs)))
+}
+
+func estimate(runes []rune, inputRanks map[string]int) Estimate {
+	n := len(runes)
+	if n == 0 {
+		return Estimate{Warning: "is empty"}
+	}
+
+	matches := findMatches(runes, inputRanks)
+
+	// best[k][l] is the cheapest way to build the first k runes with l
+	// patterns, a sequence of guesses is multiplied by l! for their order
+	type step struct {
+		guesses float64
+		prev    *match
+	}
+	best := make([][]step, n+1)
+	for k := range best {
+		best[k] = make([]step, n+1)
+		for l := range best[k] {
+			best[k][l].guesses = math.Inf(1)
+		}
+	}
+	best[0][0].guesses = 0
+
+	byEnd := make([][]*match, n+1)
+	for k := range matches {
+		m := &matches[k]
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	for j := 1; j <= n; j++ {
+		candidates := byEnd[j]
+		// characters brute forced since any i
+		for i := 0; i < j; i++ {
+			candidates = append(candidates, &match{i: i, j: j, guesses: bruteforceGuesses(j - i)})
+		}
+
+		for _, m := range candidates {
+			for l := 0; l < j; l++ {
+				if g := best[m.i][l].guesses + m.guesses; g < best[j][l+1].guesses {
+					best[j][l+1] = step{guesses: g, prev: m}
+				}
+			}
+		}
+	}
+
+	guesses, length := math.Inf(1), 0
+	for l := 1; l <= n; l++ {
+		if g := best[n][l].guesses + logFactorial(l); g < guesses {
+			guesses, length = g, l
+		}
+	}
+
+	// warn about the pattern of the cheapest way saving the most guesses
+	// over brute force
+	result := Estimate{Guesses: guesses, Score: score(guesses)}
+	savings := 0.0
+	for k := n; k > 0; length-- {
+		m := best[k][length].prev
+		if saved := bruteforceGuesses(m.j-m.i) - m.guesses; m.warning != "" && saved > savings {
+			result.Warning = m.warning
+			savings = saved
+		}
+		k = m.i
+	}
+
+	return result
+}
+
+// score returns the zxcvbn score of log10 guesses
+func score(guesses float64) int {
+	switch {
+	case guesses < 3:
+		return ScoreTooGuessable
+	case guesses < 6:
+		return ScoreVeryGuessable
+	case guesses < 8:
+		return ScoreSomewhatGuessable
+	case guesses < 10:
+		return ScoreSafelyUnguessable
+	default:
+		return ScoreVeryUnguessable
+	}
+}
+
+func findMatches(runes []rune, inputRanks map[string]int) []match {
+	var matches []match
+
+	lower := []rune(strings.ToLower(string(runes)))
+	unleeted := make([]rune, len(lower))
+	for k, r := range lower {
+		if sub, ok := l33t[r]; ok {
+			unleeted[k] = sub
+		} else {
+			unleeted[k] = r
+		}
+	}
+
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		for j := i + 3; j <= n; j++ {
+			matches = append(matches, dictionaryMatches(runes, lower, unleeted, i, j, inputRanks)...)
+
+			if m, ok := keyboardMatch(lower, i, j); ok {
+				matches = append(matches, m)
+			}
+		}
+
+		if j := i + 4; j <= n {
+			if m, ok := yearMatch(runes, i, j); ok {
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	matches = append(matches, sequenceMatches(lower)...)
+	matches = append(matches, repeatMatches(runes, inputRanks)...)
+
+	return matches
+}
+
+// dictionaryMatches finds common passwords and user inputs, also reversed or
+// with l33t substitutions, in runes i to j
+func dictionaryMatches(runes, lower, unleeted []rune, i, j int, inputRanks map[string]int) []match {
+	var matches []match
+
+	variations := math.Log10(uppercaseVariations(runes[i:j]))
+	word := string(lower[i:j])
+	leet := string(unleeted[i:j])
+	reversed := reverse(word)
+
+	candidates := []struct {
+		word    string
+		factor  float64
+		warning string
+	}{
+		{word: word},
+		{word: reversed, factor: math.Log10(2), warning: "is a reversed word"},
+	}
+	if leet != word {
+		candidates = append(candidates, struct {
+			word    string
+			factor  float64
+			warning string
+		}{word: leet, factor: math.Log10(2), warning: "uses predictable substitutions"})
+	}
+
+	for _, c := range candidates {
+		if rank, ok := inputRanks[c.word]; ok {
+			matches = append(matches, match{
+				i:       i,
+				j:       j,
+				guesses: math.Log10(float64(rank)) + variations + c.factor,
+				warning: "contains your email",
+			})
+		}
+
+		if rank, ok := commonRanks[c.word]; ok {
+			warning := c.warning
+			if warning == "" {
+				warning = "is a commonly used password"
+			}
+			matches = append(matches, match{
+				i:       i,
+				j:       j,
+				guesses: math.Log10(max(float64(rank), minMultiGuesses)) + variations + c.factor,
+				warning: warning,
+			})
+		}
+	}
+
+	return matches
+}
+
+// keyboardMatch matches a run along a keyboard row, both ways
+func keyboardMatch(lower []rune, i, j int) (match, bool) {
+	run := string(lower[i:j])
+	for _, row := range keyboardRows {
+		turns := 1.0
+		if !strings.Contains(row, run) {
+			if !strings.Contains(reverse(row), run) {
+				continue
+			}
+			turns = 2
+		}
+
+		return match{
+			i:       i,
+			j:       j,
+			guesses: math.Log10(float64(len(row)*(j-i)) * turns),
+			warning: "is a keyboard pattern",
+		}, true
+	}
+
+	return match{}, false
+}
+
+// yearMatch matches a recent year in the 4 runes from i
+func yearMatch(runes []rune, i, j int) (match, bool) {
+	year, err := strconv.Atoi(string(runes[i:j]))
+	if err != nil || year < 1900 || year > 2099 {
+		return match{}, false
+	}
+
+	distance := math.Abs(float64(time.Now().Year() - year))
+	return match{
+		i:       i,
+		j:       j,
+		guesses: math.Log10(max(distance, 20)),
+		warning: "contains a year",
+	}, true
+}
+
+// sequenceMatches matches runs of at least 3 runes with a constant step of 1
+// or 2, like abc, 2468 or 9876
+func sequenceMatches(lower []rune) []match {
+	var matches []match
+
+	n := len(lower)
+	for i := 0; i+2 < n; {
+		delta := lower[i+1] - lower[i]
+		j := i + 2
+		for j < n && lower[j]-lower[j-1] == delta {
+			j++
+		}
+
+		if j-i >= 3 && delta != 0 && delta >= -2 && delta <= 2 {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("az019", lower[i]):
+				base = 4
+			case unicode.IsDigit(lower[i]):
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+
+			matches = append(matches, match{
+				i:       i,
+				j:       j,
+				guesses: math.Log10(base * float64(j-i)),
+				warning: "is an easy to guess sequence",
+			})
+			i = j - 1
+			continue
+		}
+		i++
+	}
+
+	return matches
+}
+
+// repeatMatches matches blocks repeated at least twice, like aaa or abcabc
+func repeatMatches(runes []rune, inputRanks map[string]int) []match {
+	var matches []match
+
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		for period := 1; i+2*period <= n; period++ {
+			j := i + period
+			for j < n && runes[j] == runes[j-period] {
+				j++
+			}
+
+			count := (j - i) / period
+			if count < 2 || count*period < 3 {
+				continue
+			}
+
+			blockGuesses := math.Log10(bruteforceCardinality)
+			if period > 1 {
+				blockGuesses = estimate(runes[i:i+period], inputRanks).Guesses
+			}
+
+			matches = append(matches, match{
+				i:       i,
+				j:       i + count*period,
+				guesses: blockGuesses + math.Log10(float64(count)),
+				warning: "is a repeated pattern",
+			})
+		}
+	}
+
+	return matches
+}
+
+// bruteforceGuesses returns the log10 guesses of n brute forced runes
+func bruteforceGuesses(n int) float64 {
+	guesses := float64(n) * math.Log10(bruteforceCardinality)
+	if n == 1 {
+		return max(guesses, math.Log10(minSingleGuesses))
+	}
+	return max(guesses, math.Log10(minMultiGuesses))
+}
+
+// uppercaseVariations returns how many ways the word could be capitalized
+// given its uppercase letters, capitalizing the first letter or all of them
+// barely counts
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && unicode.IsUpper(word[0]):
+		return 2
+	}
+
+	variations := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for d := 1; d <= k; d++ {
+		result = result * float64(n-k+d) / float64(d)
+	}
+	return result
+}
+
+func logFactorial(n int) float64 {
+	lgamma, _ := math.Lgamma(float64(n + 1))
+	return lgamma / math.Ln10
+}
+
+// splitInputs splits user inputs like emails into their lowercase words
+func splitInputs(inputs []string) []string {
+	var words []string
+	for _, input := range inputs {
+		input = strings.ToLower(input)
+		words = append(words, input)
+		words = append(words, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return words
+}
+
+func rankWords(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for k, word := range words {
+		if _, ok := ranks[word]; !ok && len(word) >= 3 {
+			ranks[word] = k + 1
+		}
+	}
+	return ranks
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
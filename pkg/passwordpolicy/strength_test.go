@@ -0,0 +1,53 @@
+package passwordpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	testCases := []struct {
+		password   string
+		userInputs []string
+		score      int
+		warning    string
+	}{
+		{password: "password", score: ScoreTooGuessable, warning: "is a commonly used password"},
+		{password: "P4ssw0rd", score: ScoreTooGuessable, warning: "uses predictable substitutions"},
+		{password: "drowssap", score: ScoreTooGuessable, warning: "is a reversed word"},
+		{password: "lkjhgfdsa", score: ScoreTooGuessable, warning: "is a keyboard pattern"},
+		{password: "abcdefgh", score: ScoreTooGuessable, warning: "is an easy to guess sequence"},
+		{password: "aaaaaaaaaaaa", score: ScoreTooGuessable, warning: "is a repeated pattern"},
+		{password: "john1990", score: ScoreVeryGuessable},
+		{
+			password:   "marie.curie",
+			userInputs: []string{"marie.curie@example.com"},
+			score:      ScoreTooGuessable,
+			warning:    "contains your email",
+		},
+		{password: "xK9#mQ2$vL", score: ScoreVeryUnguessable},
+		{password: "Tr0ub4dor&3", score: ScoreVeryUnguessable},
+	}
+
+	for _, tc := range testCases {
+		estimate := EstimateStrength(tc.password, tc.userInputs...)
+		require.Equal(t, tc.score, estimate.Score, "%s: %+v", tc.password, estimate)
+		if tc.warning != "" {
+			require.Equal(t, tc.warning, estimate.Warning, tc.password)
+		}
+	}
+}
+
+func TestEstimateStrengthGrowsWithLength(t *testing.T) {
+	previous := EstimateStrength("")
+	require.Equal(t, ScoreTooGuessable, previous.Score)
+
+	password := ""
+	for _, r := range "h7Rq2mZx9Lw" {
+		password += string(r)
+		estimate := EstimateStrength(password)
+		require.Greater(t, estimate.Guesses, previous.Guesses, password)
+		previous = estimate
+	}
+}
@@ -0,0 +1,100 @@
+package token
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultActivityFlushInterval is used when no flush interval is configured
+const DefaultActivityFlushInterval = time.Minute
+
+// ActivityTracker buffers session last-seen timestamps in memory and
+// writes them to the database in batches, so verifying a token does not
+// cost a write on every request.
+type ActivityTracker struct {
+	store   db.Store
+	mu      sync.Mutex
+	pending map[uuid.UUID]time.Time
+}
+
+func NewActivityTracker(store db.Store) *ActivityTracker {
+	return &ActivityTracker{
+		store:   store,
+		pending: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Touch records that a session was used at the given time
+func (a *ActivityTracker) Touch(sessionID uuid.UUID, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if last, ok := a.pending[sessionID]; ok && !at.After(last) {
+		return
+	}
+	a.pending[sessionID] = at
+}
+
+// LastSeen returns the buffered last-seen time of a session that has not
+// been flushed yet
+func (a *ActivityTracker) LastSeen(sessionID uuid.UUID) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	at, ok := a.pending[sessionID]
+	return at, ok
+}
+
+// Flush writes all buffered timestamps in a single query
+func (a *ActivityTracker) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[uuid.UUID]time.Time)
+	a.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	arg := db.TouchSessionsParams{
+		Ids:      make([]uuid.UUID, 0, len(pending)),
+		LastSeen: make([]pgtype.Timestamp, 0, len(pending)),
+	}
+	for id, at := range pending {
+		arg.Ids = append(arg.Ids, id)
+		arg.LastSeen = append(arg.LastSeen, pgtype.Timestamp{Time: at.UTC(), Valid: true})
+	}
+
+	return a.store.TouchSessions(ctx, arg)
+}
+
+// Run flushes buffered timestamps every interval until the context is done
+func (a *ActivityTracker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultActivityFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Flush(ctx); err != nil {
+				slog.Error("cannot flush session activity", "error", err)
+			}
+		case <-ctx.Done():
+			// last flush with a fresh context since ctx is already cancelled
+			if err := a.Flush(context.Background()); err != nil {
+				slog.Error("cannot flush session activity", "error", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestActivityTracker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	tracker := NewActivityTracker(store)
+
+	sessionID := uuid.New()
+	first := time.Now()
+	latest := first.Add(time.Second)
+
+	tracker.Touch(sessionID, latest)
+	// an older timestamp does not overwrite a newer one
+	tracker.Touch(sessionID, first)
+
+	lastSeen, ok := tracker.LastSeen(sessionID)
+	require.True(t, ok)
+	require.Equal(t, latest, lastSeen)
+
+	// all touches are written in a single query
+	store.EXPECT().
+		TouchSessions(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.TouchSessionsParams) error {
+			require.Equal(t, []uuid.UUID{sessionID}, arg.Ids)
+			require.Len(t, arg.LastSeen, 1)
+			require.True(t, latest.Equal(arg.LastSeen[0].Time))
+			return nil
+		})
+
+	require.NoError(t, tracker.Flush(context.Background()))
+
+	_, ok = tracker.LastSeen(sessionID)
+	require.False(t, ok)
+
+	// nothing buffered, nothing written
+	require.NoError(t, tracker.Flush(context.Background()))
+}
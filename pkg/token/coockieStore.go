@@ -4,13 +4,18 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/gob"
+	"encoding/hex"
+	"log/slog"
+	"strings"
 	"time"
 
 	db "github.com/Luckny/space-it/db/sqlc"
 	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/dto"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func init() {
@@ -18,13 +23,14 @@ func init() {
 	gob.Register(map[string]string{})
 	gob.Register(time.Time{})
 	gob.Register(uuid.UUID{})
-	gob.Register(db.User{})
+	gob.Register(dto.User{})
 }
 
 type CookieStore struct {
 	Name string
 	Maker
 	store    *sessions.CookieStore
+	db       db.Store
 	secure   bool
 	httpOnly bool
 }
@@ -32,11 +38,26 @@ type CookieStore struct {
 // var Store = newCookieStore()
 var SessionName = "_HOST-session"
 
-func NewCookieStore() Maker {
-	config := config.Load("../../")
+// NewCookieStore creates a cookie backed token maker. Every session is also
+// tracked server side so it can be listed and revoked.
+//
+// Cookies are signed and encrypted with the first of the configured key pairs
+// and verified with all of them, so keys can be rotated without logging
+// everyone out. It panics if a configured key pair is invalid.
+func NewCookieStore(config config.Config, store db.Store) Maker {
+	pairs, err := ParseKeyPairs(config.CookieKeys)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(pairs) == 0 && config.CookieSecret != "" {
+		slog.Warn("COOKIE_KEYS is not set, session cookies are signed but not encrypted")
+	}
+
 	return &CookieStore{
 		Name:     "_HOST-session",
-		store:    sessions.NewCookieStore([]byte(config.CookieSecret)),
+		store:    sessions.NewCookieStore(codecKeys(pairs, config.CookieSecret)...),
+		db:       store,
 		secure:   config.CookieIsSecure,
 		httpOnly: config.CookieIsHttpOnly,
 	}
@@ -51,6 +72,10 @@ func (c *CookieStore) CreateToken(ctx *gin.Context, payload *Payload) (string, e
 
 	if !session.IsNew {
 		// invalidate old session
+		if err := c.revokeServerSession(ctx, session); err != nil {
+			return "", err
+		}
+
 		session.Options.MaxAge = -1
 		err = session.Save(ctx.Request, ctx.Writer)
 		if err != nil {
@@ -67,20 +92,39 @@ func (c *CookieStore) CreateToken(ctx *gin.Context, payload *Payload) (string, e
 	session.Options.Path = "/"
 
 	// set session values
-	session.Values["user"] = payload.User // TODO: dto
+	session.Values["user"] = payload.User
 	session.Values["attributes"] = payload.Attributes
 	session.Values["expiresAt"] = payload.ExpiresAt
 	session.Values["sessionId"] = payload.ID
 	session.Values["issuedAt"] = payload.IssuedAt
 
+	b := sha256.Sum256([]byte(payload.ID.String()))
+	tokenID := encodeToBase64(b[:])
+
+	// track the session server side, sessions waiting for a second factor
+	// can't be introspected so their token is not stored
+	_, err = c.db.CreateSession(ctx, db.CreateSessionParams{
+		ID:        payload.ID,
+		UserID:    payload.User.ID,
+		UserAgent: truncate(ctx.Request.UserAgent(), 255),
+		ClientIp:  ctx.ClientIP(),
+		ExpiresAt: pgtype.Timestamp{Time: payload.ExpiresAt.UTC(), Valid: true},
+		TokenHash: pgtype.Text{
+			String: hashTokenID(tokenID),
+			Valid:  !payload.SecondFactorPending(),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
 	// save session
 	err = session.Save(ctx.Request, ctx.Writer)
 	if err != nil {
 		return "", err
 	}
 
-	b := sha256.Sum256([]byte(payload.ID.String()))
-	return encodeToBase64(b[:]), nil
+	return tokenID, nil
 }
 
 // VerifyToken checks if the token is valid
@@ -96,7 +140,10 @@ func (c *CookieStore) VerifyToken(ctx *gin.Context, tokenID string) (*Payload, e
 	}
 
 	// get the session id
-	sessionId := session.Values["sessionId"].(uuid.UUID)
+	sessionId, ok := session.Values["sessionId"].(uuid.UUID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
 
 	// compare the provided token with the computed token
 	provided, err := decodeBase64String(tokenID)
@@ -110,8 +157,11 @@ func (c *CookieStore) VerifyToken(ctx *gin.Context, tokenID string) (*Payload, e
 		return nil, ErrInvalidToken
 	}
 
-	// TODO: user dto
-	user := session.Values["user"].(db.User)
+	// sessions created before users were stored as dto are not valid anymore
+	user, ok := session.Values["user"].(dto.User)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
 
 	// return the token
 	token := &Payload{
@@ -126,9 +176,56 @@ func (c *CookieStore) VerifyToken(ctx *gin.Context, tokenID string) (*Payload, e
 		return nil, ErrInvalidToken
 	}
 
+	// the session may have been revoked from another device
+	serverSession, err := c.db.GetSessionByID(ctx, token.ID)
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if serverSession.RevokedAt.Valid {
+		return nil, ErrInvalidToken
+	}
+
 	return token, nil
 }
 
+// IntrospectToken looks the session up by its token, without the cookie.
+// Sessions waiting for a second factor are never valid.
+func (c *CookieStore) IntrospectToken(ctx *gin.Context, tokenID string) (*Payload, error) {
+	// session tokens are the base64 encoding of a sha256 sum
+	provided, err := decodeBase64String(tokenID)
+	if err != nil || len(provided) != sha256.Size {
+		return nil, ErrInvalidToken
+	}
+
+	session, err := c.db.GetActiveSessionByTokenHash(ctx, pgtype.Text{
+		String: hashTokenID(tokenID),
+		Valid:  true,
+	})
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := c.db.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payload{
+		ID:         session.ID,
+		User:       dto.NewUser(user),
+		Attributes: make(map[string]string),
+		IssuedAt:   session.CreatedAt.Time,
+		ExpiresAt:  session.ExpiresAt.Time,
+	}, nil
+}
+
 func (c *CookieStore) RevokeToken(ctx *gin.Context, tokenID string) error {
 	session, err := c.store.Get(ctx.Request, c.Name)
 	if err != nil {
@@ -140,7 +237,10 @@ func (c *CookieStore) RevokeToken(ctx *gin.Context, tokenID string) error {
 	}
 
 	// get the session id
-	sessionId := session.Values["sessionId"].(uuid.UUID)
+	sessionId, ok := session.Values["sessionId"].(uuid.UUID)
+	if !ok {
+		return nil
+	}
 
 	// compare the provided token with the computed token
 	provided, err := decodeBase64String(tokenID)
@@ -154,6 +254,10 @@ func (c *CookieStore) RevokeToken(ctx *gin.Context, tokenID string) error {
 		return nil
 	}
 
+	if err := c.revokeServerSession(ctx, session); err != nil {
+		return err
+	}
+
 	session.Options.MaxAge = -1
 	err = session.Save(ctx.Request, ctx.Writer)
 	if err != nil {
@@ -161,3 +265,40 @@ func (c *CookieStore) RevokeToken(ctx *gin.Context, tokenID string) error {
 	}
 	return nil
 }
+
+// revokeServerSession marks the server side record of a cookie session as revoked
+func (c *CookieStore) revokeServerSession(ctx *gin.Context, session *sessions.Session) error {
+	sessionId, ok := session.Values["sessionId"].(uuid.UUID)
+	if !ok {
+		return nil
+	}
+
+	user, ok := session.Values["user"].(dto.User)
+	if !ok {
+		return nil
+	}
+
+	_, err := c.db.RevokeSession(ctx, db.RevokeSessionParams{
+		ID:     sessionId,
+		UserID: user.ID,
+	})
+	return err
+}
+
+// hashTokenID returns the hex encoded sha256 sum stored for a session token
+func hashTokenID(tokenID string) string {
+	sum := sha256.Sum256([]byte(tokenID))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate returns the first n bytes of s at most, without the bytes of a cut
+// character. Invalid UTF-8 and NUL bytes are dropped whatever the length,
+// Postgres refuses them and the session couldn't be created.
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+	if len(s) <= n {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,270 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/config"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user, _ := mockdb.RandomUser(t)
+	oldKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+	newKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.Session{}, nil)
+	store.EXPECT().
+		GetSessionByID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.Session{}, nil)
+
+	// a session created before the rotation
+	oldStore := NewCookieStore(config.Config{CookieKeys: []string{oldKey.String()}}, store)
+	cookie, tokenID := createTestToken(t, oldStore, user)
+
+	testCases := []struct {
+		name    string
+		keys    []string
+		isValid bool
+	}{
+		{
+			name:    "old key still verifies",
+			keys:    []string{newKey.String(), oldKey.String()},
+			isValid: true,
+		},
+		{
+			name:    "retired key no longer verifies",
+			keys:    []string{newKey.String()},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker := NewCookieStore(config.Config{CookieKeys: tc.keys}, store)
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Request.AddCookie(cookie)
+
+			payload, err := maker.VerifyToken(ctx, tokenID)
+			if tc.isValid {
+				require.NoError(t, err)
+				require.Equal(t, user.ID, payload.User.ID)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCookieStoreEncryptsPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user, _ := mockdb.RandomUser(t)
+	key, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(db.Session{}, nil)
+
+	// signed only cookies expose the gob encoded payload
+	legacy := NewCookieStore(config.Config{CookieSecret: "a-32-byte-long-cookie-secret-key"}, store)
+	cookie, _ := createTestToken(t, legacy, user)
+	require.Contains(t, string(decodeCookieValue(t, cookie)), user.Email)
+
+	maker := NewCookieStore(config.Config{CookieKeys: []string{key.String()}}, store)
+	cookie, _ = createTestToken(t, maker, user)
+	require.NotContains(t, string(decodeCookieValue(t, cookie)), user.Email)
+}
+
+func TestCookieStoreIntrospectToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user, _ := mockdb.RandomUser(t)
+	key, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	maker := NewCookieStore(config.Config{CookieKeys: []string{key.String()}}, store)
+
+	// the hash of the token is stored with the session
+	var stored db.Session
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+			stored = db.Session{
+				ID:        arg.ID,
+				UserID:    arg.UserID,
+				CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+				ExpiresAt: arg.ExpiresAt,
+				TokenHash: arg.TokenHash,
+			}
+			return stored, nil
+		})
+	_, tokenID := createTestToken(t, maker, user)
+	require.True(t, stored.TokenHash.Valid)
+
+	store.EXPECT().
+		GetActiveSessionByTokenHash(gomock.Any(), gomock.Eq(stored.TokenHash)).
+		Times(1).
+		Return(stored, nil)
+	store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+
+	// no cookie is needed
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	payload, err := maker.IntrospectToken(ctx, tokenID)
+	require.NoError(t, err)
+	require.Equal(t, stored.ID, payload.ID)
+	require.Equal(t, dto.NewUser(user), payload.User)
+	require.WithinDuration(t, stored.ExpiresAt.Time, payload.ExpiresAt, time.Second)
+
+	// revoked, expired or unknown
+	store.EXPECT().
+		GetActiveSessionByTokenHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.Session{}, db.ErrRecordNotFound)
+	_, err = maker.IntrospectToken(ctx, tokenID)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// not a session token, the store is not queried
+	_, err = maker.IntrospectToken(ctx, "sat_not-a-session-token")
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestCookieStorePendingSessionIsNotIntrospectable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user, _ := mockdb.RandomUser(t)
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	maker := NewCookieStore(config.Config{CookieSecret: "a-32-byte-long-cookie-secret-key"}, store)
+
+	var stored db.CreateSessionParams
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+			stored = arg
+			return db.Session{}, nil
+		})
+
+	payload, err := NewPayload(dto.NewUser(user), time.Minute)
+	require.NoError(t, err)
+	payload.Attributes[SecondFactorAttribute] = SecondFactorPending
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	_, err = maker.CreateToken(ctx, payload)
+	require.NoError(t, err)
+	require.False(t, stored.TokenHash.Valid)
+}
+
+func TestCookieStoreSanitizesUserAgent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		stored    string
+	}{
+		{
+			name:      "invalid UTF-8 and NUL bytes are dropped",
+			userAgent: "agent\xff\x00/1.0",
+			stored:    "agent/1.0",
+		},
+		{
+			name:      "cut on a character boundary",
+			userAgent: strings.Repeat("a", 254) + "é",
+			stored:    strings.Repeat("a", 254),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, _ := mockdb.RandomUser(t)
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			maker := NewCookieStore(config.Config{CookieSecret: "a-32-byte-long-cookie-secret-key"}, store)
+
+			var stored db.CreateSessionParams
+			store.EXPECT().
+				CreateSession(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+					stored = arg
+					return db.Session{}, nil
+				})
+
+			payload, err := NewPayload(dto.NewUser(user), time.Minute)
+			require.NoError(t, err)
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+			ctx.Request.Header.Set("User-Agent", tc.userAgent)
+			_, err = maker.CreateToken(ctx, payload)
+			require.NoError(t, err)
+			require.Equal(t, tc.stored, stored.UserAgent)
+		})
+	}
+}
+
+// decodeCookieValue returns the value part of a securecookie "date|value|mac" cookie
+func decodeCookieValue(t *testing.T, cookie *http.Cookie) []byte {
+	decoded, err := base64.URLEncoding.DecodeString(cookie.Value)
+	require.NoError(t, err)
+
+	parts := bytes.SplitN(decoded, []byte("|"), 3)
+	require.Len(t, parts, 3)
+
+	value, err := base64.URLEncoding.DecodeString(string(parts[1]))
+	require.NoError(t, err)
+
+	return value
+}
+
+// createTestToken logs the user in and returns the session cookie and token
+func createTestToken(t *testing.T, maker Maker, user db.User) (*http.Cookie, string) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+
+	payload, err := NewPayload(dto.NewUser(user), time.Minute)
+	require.NoError(t, err)
+
+	tokenID, err := maker.CreateToken(ctx, payload)
+	require.NoError(t, err)
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	return cookies[0], tokenID
+}
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	hashKeyLength  = 64
+	blockKeyLength = 32
+)
+
+var ErrInvalidKeyPair = errors.New("invalid cookie key pair")
+
+// KeyPair is a cookie signing key and its encryption key.
+// A pair is encoded as "<hash key>:<block key>", both base64 encoded.
+type KeyPair struct {
+	HashKey  []byte
+	BlockKey []byte
+}
+
+// GenerateKeyPair creates a random HMAC-SHA256 signing key and AES-256 encryption key
+func GenerateKeyPair() (KeyPair, error) {
+	pair := KeyPair{
+		HashKey:  make([]byte, hashKeyLength),
+		BlockKey: make([]byte, blockKeyLength),
+	}
+
+	if _, err := rand.Read(pair.HashKey); err != nil {
+		return KeyPair{}, err
+	}
+	if _, err := rand.Read(pair.BlockKey); err != nil {
+		return KeyPair{}, err
+	}
+
+	return pair, nil
+}
+
+// String encodes the key pair in the format read from the config
+func (k KeyPair) String() string {
+	return encodeToBase64(k.HashKey) + ":" + encodeToBase64(k.BlockKey)
+}
+
+// ParseKeyPair decodes a key pair from the config format
+func ParseKeyPair(s string) (KeyPair, error) {
+	hashKey, blockKey, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return KeyPair{}, fmt.Errorf("%w: expected <hash key>:<block key>", ErrInvalidKeyPair)
+	}
+
+	var pair KeyPair
+	var err error
+	if pair.HashKey, err = decodeBase64String(hashKey); err != nil {
+		return KeyPair{}, fmt.Errorf("%w: hash key: %v", ErrInvalidKeyPair, err)
+	}
+	if pair.BlockKey, err = decodeBase64String(blockKey); err != nil {
+		return KeyPair{}, fmt.Errorf("%w: block key: %v", ErrInvalidKeyPair, err)
+	}
+
+	if len(pair.HashKey) < 32 {
+		return KeyPair{}, fmt.Errorf("%w: hash key must be at least 32 bytes", ErrInvalidKeyPair)
+	}
+
+	// AES-128, AES-192 or AES-256
+	switch len(pair.BlockKey) {
+	case 16, 24, 32:
+	default:
+		return KeyPair{}, fmt.Errorf("%w: block key must be 16, 24 or 32 bytes", ErrInvalidKeyPair)
+	}
+
+	return pair, nil
+}
+
+// ParseKeyPairs decodes a list of key pairs, in order
+func ParseKeyPairs(keys []string) ([]KeyPair, error) {
+	pairs := make([]KeyPair, 0, len(keys))
+	for i, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+
+		pair, err := ParseKeyPair(key)
+		if err != nil {
+			return nil, fmt.Errorf("cookie key %d: %w", i, err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// PromoteKey moves key to the front of keys so it becomes the signing key.
+// Other keys keep their order and are still used for verification. At most
+// retain keys are kept when retain is positive.
+func PromoteKey(keys []string, key string, retain int) []string {
+	promoted := []string{key}
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if k == "" || k == key {
+			continue
+		}
+		promoted = append(promoted, k)
+	}
+
+	if retain > 0 && len(promoted) > retain {
+		promoted = promoted[:retain]
+	}
+
+	return promoted
+}
+
+// codecKeys flattens key pairs into the hash/block key list expected by
+// gorilla sessions. The first pair is used to encode new cookies and every
+// pair is tried when decoding. A legacy secret is accepted for verification
+// only, so cookies signed before encryption was enabled remain valid.
+func codecKeys(pairs []KeyPair, legacySecret string) [][]byte {
+	keys := make([][]byte, 0, 2*len(pairs)+2)
+	for _, pair := range pairs {
+		keys = append(keys, pair.HashKey, pair.BlockKey)
+	}
+
+	if legacySecret != "" {
+		keys = append(keys, []byte(legacySecret), nil)
+	}
+
+	return keys
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPair(t *testing.T) {
+	pair, err := GenerateKeyPair()
+	require.NoError(t, err)
+	require.Len(t, pair.HashKey, hashKeyLength)
+	require.Len(t, pair.BlockKey, blockKeyLength)
+
+	parsed, err := ParseKeyPair(pair.String())
+	require.NoError(t, err)
+	require.Equal(t, pair, parsed)
+
+	other, err := GenerateKeyPair()
+	require.NoError(t, err)
+	require.NotEqual(t, pair.String(), other.String())
+}
+
+func TestParseKeyPair(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     string
+		isValid bool
+	}{
+		{
+			name:    "missing block key",
+			key:     encodeToBase64(make([]byte, 64)),
+			isValid: false,
+		},
+		{
+			name:    "short hash key",
+			key:     encodeToBase64(make([]byte, 16)) + ":" + encodeToBase64(make([]byte, 32)),
+			isValid: false,
+		},
+		{
+			name:    "bad block key length",
+			key:     encodeToBase64(make([]byte, 64)) + ":" + encodeToBase64(make([]byte, 20)),
+			isValid: false,
+		},
+		{
+			name:    "not base64",
+			key:     "not base64:not base64",
+			isValid: false,
+		},
+		{
+			name:    "aes-128 block key",
+			key:     encodeToBase64(make([]byte, 32)) + ":" + encodeToBase64(make([]byte, 16)),
+			isValid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseKeyPair(tc.key)
+			if tc.isValid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidKeyPair)
+			}
+		})
+	}
+}
+
+func TestPromoteKey(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+
+	require.Equal(t, []string{"d", "a", "b", "c"}, PromoteKey(keys, "d", 0))
+	require.Equal(t, []string{"d", "a"}, PromoteKey(keys, "d", 2))
+	// an existing key is moved, not duplicated
+	require.Equal(t, []string{"c", "a", "b"}, PromoteKey(keys, "c", 0))
+	require.Equal(t, []string{"a"}, PromoteKey(nil, "a", 3))
+}
@@ -7,6 +7,11 @@ type Maker interface {
 	CreateToken(ctx *gin.Context, payload *Payload) (string, error)
 	// VerifyToken checks if the token is valid
 	VerifyToken(ctx *gin.Context, tokenID string) (*Payload, error)
+	// IntrospectToken checks if the token is valid without relying on the
+	// request it was sent with, so a token can be validated on behalf of
+	// another service. It returns ErrInvalidToken for tokens the maker did
+	// not issue.
+	IntrospectToken(ctx *gin.Context, tokenID string) (*Payload, error)
 	// RevokeToken deletes a specific token from the store
 	RevokeToken(ctx *gin.Context, tokenID string) error
 }
@@ -0,0 +1,167 @@
+package token
+
+import (
+	"strings"
+
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Attributes of OAuth2 access tokens. Scopes and spaces are space separated,
+// a token without spaces is not restricted to some spaces.
+const (
+	ClientIDAttribute = "client_id"
+	GrantIDAttribute  = "grant_id"
+	ScopeAttribute    = "scope"
+	SpacesAttribute   = "spaces"
+)
+
+// OAuthStore is a database backed token maker for the access tokens issued
+// by the OAuth2 authorization server. Tokens are opaque, only their hash is
+// stored.
+type OAuthStore struct {
+	db db.Store
+}
+
+func NewOAuthStore(store db.Store) Maker {
+	return &OAuthStore{db: store}
+}
+
+// CreateToken create a new access token for the client, grant, scopes and
+// spaces set in the payload attributes
+func (o *OAuthStore) CreateToken(ctx *gin.Context, payload *Payload) (string, error) {
+	clientID, err := uuid.Parse(payload.Attributes[ClientIDAttribute])
+	if err != nil {
+		return "", err
+	}
+
+	grantID, err := uuid.Parse(payload.Attributes[GrantIDAttribute])
+	if err != nil {
+		return "", err
+	}
+
+	spaceIDs, err := payload.Spaces()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := oauth.GenerateToken(oauth.AccessTokenPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = o.db.CreateOAuthAccessToken(ctx, db.CreateOAuthAccessTokenParams{
+		ID:        payload.ID,
+		GrantID:   grantID,
+		TokenHash: oauth.Hash(token),
+		ClientID:  clientID,
+		UserID:    payload.User.ID,
+		Scopes:    payload.Scopes(),
+		SpaceIds:  spaceIDs,
+		ExpiresAt: pgtype.Timestamp{Time: payload.ExpiresAt.UTC(), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyToken checks that the access token is neither expired nor revoked,
+// and that its client is still registered
+func (o *OAuthStore) VerifyToken(ctx *gin.Context, tokenID string) (*Payload, error) {
+	if !oauth.IsAccessToken(tokenID) {
+		return nil, ErrInvalidToken
+	}
+
+	accessToken, err := o.db.GetActiveOAuthAccessTokenByHash(ctx, oauth.Hash(tokenID))
+	if err != nil {
+		if err == db.ErrRecordNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := o.db.GetUserByID(ctx, accessToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payload{
+		ID:   accessToken.ID,
+		User: dto.NewUser(user),
+		Attributes: map[string]string{
+			ClientIDAttribute: accessToken.ClientID.String(),
+			GrantIDAttribute:  accessToken.GrantID.String(),
+			ScopeAttribute:    oauth.FormatScope(accessToken.Scopes),
+			SpacesAttribute:   formatSpaces(accessToken.SpaceIds),
+		},
+		IssuedAt:  accessToken.CreatedAt.Time,
+		ExpiresAt: accessToken.ExpiresAt.Time,
+	}, nil
+}
+
+// IntrospectToken checks the access token like VerifyToken, access tokens do
+// not depend on the request they are sent with
+func (o *OAuthStore) IntrospectToken(ctx *gin.Context, tokenID string) (*Payload, error) {
+	return o.VerifyToken(ctx, tokenID)
+}
+
+// RevokeToken revokes an access token, unknown tokens are ignored
+func (o *OAuthStore) RevokeToken(ctx *gin.Context, tokenID string) error {
+	_, err := o.db.RevokeOAuthAccessToken(ctx, oauth.Hash(tokenID))
+	return err
+}
+
+// NewOAuthPayload creates the payload of an access token issued to client on
+// behalf of user
+func NewOAuthPayload(
+	user dto.User,
+	clientID, grantID uuid.UUID,
+	scopes []string,
+	spaceIDs []uuid.UUID,
+) (*Payload, error) {
+	payload, err := NewPayload(user, oauth.AccessTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Attributes[ClientIDAttribute] = clientID.String()
+	payload.Attributes[GrantIDAttribute] = grantID.String()
+	payload.Attributes[ScopeAttribute] = oauth.FormatScope(scopes)
+	payload.Attributes[SpacesAttribute] = formatSpaces(spaceIDs)
+	return payload, nil
+}
+
+// Scopes returns the scopes an OAuth2 access token was granted
+func (payload *Payload) Scopes() []string {
+	return oauth.ParseScope(payload.Attributes[ScopeAttribute])
+}
+
+// Spaces returns the spaces an OAuth2 access token is restricted to, none
+// when it is not restricted
+func (payload *Payload) Spaces() ([]uuid.UUID, error) {
+	fields := strings.Fields(payload.Attributes[SpacesAttribute])
+	spaceIDs := make([]uuid.UUID, len(fields))
+	for i, field := range fields {
+		id, err := uuid.Parse(field)
+		if err != nil {
+			return nil, err
+		}
+		spaceIDs[i] = id
+	}
+
+	return spaceIDs, nil
+}
+
+func formatSpaces(spaceIDs []uuid.UUID) string {
+	spaces := make([]string, len(spaceIDs))
+	for i, id := range spaceIDs {
+		spaces[i] = id.String()
+	}
+	return strings.Join(spaces, " ")
+}
@@ -0,0 +1,112 @@
+package token
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Luckny/space-it/db/mock"
+	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
+	"github.com/Luckny/space-it/pkg/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOAuthStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	maker := NewOAuthStore(store)
+
+	user, _ := mockdb.RandomUser(t)
+	clientID := uuid.New()
+	grantID := uuid.New()
+	spaceIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	scopes := []string{"spaces:read", "messages:write"}
+
+	payload, err := NewOAuthPayload(dto.NewUser(user), clientID, grantID, scopes, spaceIDs)
+	require.NoError(t, err)
+
+	// only the hash of the token is stored
+	var stored db.OauthAccessToken
+	store.EXPECT().
+		CreateOAuthAccessToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateOAuthAccessTokenParams) (db.OauthAccessToken, error) {
+			stored = db.OauthAccessToken{
+				ID:        arg.ID,
+				GrantID:   arg.GrantID,
+				TokenHash: arg.TokenHash,
+				ClientID:  arg.ClientID,
+				UserID:    arg.UserID,
+				Scopes:    arg.Scopes,
+				SpaceIds:  arg.SpaceIds,
+				ExpiresAt: arg.ExpiresAt,
+			}
+			return stored, nil
+		})
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	accessToken, err := maker.CreateToken(ctx, payload)
+	require.NoError(t, err)
+	require.True(t, oauth.IsAccessToken(accessToken))
+	require.Equal(t, oauth.Hash(accessToken), stored.TokenHash)
+	require.Equal(t, payload.ID, stored.ID)
+	require.Equal(t, grantID, stored.GrantID)
+	require.Equal(t, clientID, stored.ClientID)
+	require.Equal(t, scopes, stored.Scopes)
+	require.Equal(t, spaceIDs, stored.SpaceIds)
+
+	store.EXPECT().
+		GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Eq(stored.TokenHash)).
+		Times(1).
+		Return(stored, nil)
+	store.EXPECT().
+		GetUserByID(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(user, nil)
+
+	verified, err := maker.VerifyToken(ctx, accessToken)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+	require.Equal(t, user.ID, verified.User.ID)
+	require.Equal(t, scopes, verified.Scopes())
+	require.Equal(t, clientID.String(), verified.Attributes[ClientIDAttribute])
+	require.WithinDuration(t, payload.ExpiresAt, verified.ExpiresAt, time.Second)
+
+	verifiedSpaces, err := verified.Spaces()
+	require.NoError(t, err)
+	require.Equal(t, spaceIDs, verifiedSpaces)
+
+	// revoked or expired
+	store.EXPECT().
+		GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.OauthAccessToken{}, db.ErrRecordNotFound)
+
+	_, err = maker.VerifyToken(ctx, accessToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// introspection does not need the request either
+	store.EXPECT().
+		GetActiveOAuthAccessTokenByHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.OauthAccessToken{}, db.ErrRecordNotFound)
+
+	_, err = maker.IntrospectToken(ctx, accessToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// not an access token, the store is not queried
+	_, err = maker.VerifyToken(ctx, "sit_key")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	store.EXPECT().
+		RevokeOAuthAccessToken(gomock.Any(), gomock.Eq(stored.TokenHash)).
+		Times(1).
+		Return(int64(1), nil)
+
+	require.NoError(t, maker.RevokeToken(ctx, accessToken))
+}
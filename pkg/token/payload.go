@@ -4,7 +4,7 @@ import (
 	"errors"
 	"time"
 
-	db "github.com/Luckny/space-it/db/sqlc"
+	"github.com/Luckny/space-it/pkg/dto"
 	"github.com/google/uuid"
 )
 
@@ -14,17 +14,24 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Attribute set on tokens issued when only the first factor of a user with
+// two-factor authentication has been verified
+const (
+	SecondFactorAttribute = "second_factor"
+	SecondFactorPending   = "pending"
+)
+
 // Payload contains the payload data of the token
 type Payload struct {
 	ID         uuid.UUID         `json:"id"`
-	User       db.User           `json:"user"`
+	User       dto.User          `json:"user"`
 	Attributes map[string]string `json:"attributes"`
 	IssuedAt   time.Time         `json:"issued_at"`
 	ExpiresAt  time.Time         `json:"expired_at"`
 }
 
 // NewPayload creates a new token payload with a specific user id and duration
-func NewPayload(user db.User, duration time.Duration) (*Payload, error) {
+func NewPayload(user dto.User, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -47,3 +54,8 @@ func (payload *Payload) Valid() bool {
 	}
 	return true
 }
+
+// SecondFactorPending reports whether the token only proves the first factor
+func (payload *Payload) SecondFactorPending() bool {
+	return payload.Attributes[SecondFactorAttribute] == SecondFactorPending
+}
@@ -0,0 +1,99 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent      = 1 << 0
+	flagUserVerified     = 1 << 2
+	flagAttestedCredData = 1 << 6
+	flagExtensionData    = 1 << 7
+)
+
+// rp id hash, flags and sign count
+const authDataMinLength = 32 + 1 + 4
+
+var cborHandle = &codec.CborHandle{}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	// only set during registration
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData decodes the binary authenticator data structure
+// https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthData)
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.flags&flagAttestedCredData == 0 {
+		return data, nil
+	}
+
+	// aaguid followed by the credential id length
+	rest := raw[authDataMinLength:]
+	if len(rest) < 16+2 {
+		return nil, fmt.Errorf("%w: truncated attested credential", ErrInvalidAuthData)
+	}
+	rest = rest[16:]
+
+	idLength := int(binary.BigEndian.Uint16(rest[:2]))
+	rest = rest[2:]
+	if len(rest) < idLength {
+		return nil, fmt.Errorf("%w: truncated credential id", ErrInvalidAuthData)
+	}
+	data.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// the public key is followed by the extensions, if any
+	var key map[int64]interface{}
+	dec := codec.NewDecoderBytes(rest, cborHandle)
+	if err := dec.Decode(&key); err != nil {
+		return nil, fmt.Errorf("%w: public key: %v", ErrInvalidAuthData, err)
+	}
+
+	keyLength := dec.NumBytesRead()
+	if data.flags&flagExtensionData == 0 && keyLength != len(rest) {
+		return nil, fmt.Errorf("%w: unexpected trailing data", ErrInvalidAuthData)
+	}
+	data.publicKey = rest[:keyLength]
+
+	return data, nil
+}
+
+type attestationObject struct {
+	Format   string                 `codec:"fmt"`
+	AttStmt  map[string]interface{} `codec:"attStmt"`
+	AuthData []byte                 `codec:"authData"`
+}
+
+// parseAttestationObject returns the authenticator data of an attestation object
+func parseAttestationObject(raw []byte) ([]byte, error) {
+	var obj attestationObject
+	if err := codec.NewDecoderBytes(raw, cborHandle).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+	}
+
+	if obj.Format == "" || len(obj.AuthData) == 0 {
+		return nil, fmt.Errorf("%w: missing fields", ErrInvalidAttestation)
+	}
+
+	return obj.AuthData, nil
+}
@@ -0,0 +1,151 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithm identifiers
+// https://www.iana.org/assignments/cose/cose.xhtml#algorithms
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms lists the algorithms offered to authenticators, by preference
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+
+	// EC2 and OKP keys
+	coseCurve = -1
+	coseX     = -2
+	coseY     = -3
+
+	// RSA keys
+	coseModulus  = -1
+	coseExponent = -2
+)
+
+const (
+	keyTypeOKP = 1
+	keyTypeEC2 = 2
+	keyTypeRSA = 3
+
+	curveP256    = 1
+	curveEd25519 = 6
+)
+
+// PublicKey verifies assertion signatures
+type PublicKey interface {
+	Verify(data, signature []byte) error
+}
+
+// ParsePublicKey decodes a COSE encoded public key
+func ParsePublicKey(cose []byte) (PublicKey, error) {
+	var params map[int64]interface{}
+	if err := codec.NewDecoderBytes(cose, cborHandle).Decode(&params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, err)
+	}
+
+	kty, _ := coseInt(params[coseKeyType])
+	alg, _ := coseInt(params[coseAlgorithm])
+
+	switch {
+	case kty == keyTypeEC2 && alg == AlgES256:
+		crv, _ := coseInt(params[coseCurve])
+		x, _ := params[coseX].([]byte)
+		y, _ := params[coseY].([]byte)
+		if crv != curveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid ES256 key", ErrUnsupportedAlgorithm)
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point not on curve", ErrUnsupportedAlgorithm)
+		}
+		return es256Key{key}, nil
+
+	case kty == keyTypeOKP && alg == AlgEdDSA:
+		crv, _ := coseInt(params[coseCurve])
+		x, _ := params[coseX].([]byte)
+		if crv != curveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid EdDSA key", ErrUnsupportedAlgorithm)
+		}
+		return ed25519Key(x), nil
+
+	case kty == keyTypeRSA && alg == AlgRS256:
+		n, _ := params[coseModulus].([]byte)
+		e, _ := params[coseExponent].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RS256 key", ErrUnsupportedAlgorithm)
+		}
+		return rs256Key{&rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("%w: key type %d, algorithm %d", ErrUnsupportedAlgorithm, kty, alg)
+}
+
+type es256Key struct {
+	*ecdsa.PublicKey
+}
+
+func (k es256Key) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	// signatures are ASN.1 DER encoded
+	if !ecdsa.VerifyASN1(k.PublicKey, digest[:], signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type ed25519Key ed25519.PublicKey
+
+func (k ed25519Key) Verify(data, signature []byte) error {
+	if !ed25519.Verify(ed25519.PublicKey(k), data, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type rs256Key struct {
+	*rsa.PublicKey
+}
+
+func (k rs256Key) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(k.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// coseInt converts a CBOR integer, decoded as signed or unsigned
+func coseInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= 1<<62
+	}
+	return 0, false
+}
@@ -0,0 +1,196 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies used for passkey login.
+//
+// Attestation statements are not verified, registrations are accepted with
+// any attestation format as if "none" was requested.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// ChallengeLength is the number of random bytes of a challenge
+	ChallengeLength = 32
+
+	// Timeout is how long a ceremony can take
+	Timeout = 5 * time.Minute
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidClientData     = errors.New("invalid client data")
+	ErrInvalidAuthData       = errors.New("invalid authenticator data")
+	ErrInvalidAttestation    = errors.New("invalid attestation object")
+	ErrUnsupportedAlgorithm  = errors.New("unsupported public key algorithm")
+	ErrInvalidSignature      = errors.New("invalid signature")
+	ErrUserNotPresent        = errors.New("user presence not confirmed")
+	ErrUserNotVerified       = errors.New("user verification not performed")
+	ErrInvalidRelyingPartyID = errors.New("invalid relying party id")
+)
+
+// RelyingParty is the website passkeys are scoped to
+type RelyingParty struct {
+	// domain the credentials are bound to, e.g. example.com
+	ID   string
+	Name string
+	// origins the browser is allowed to run the ceremonies from,
+	// e.g. https://app.example.com
+	Origins []string
+}
+
+// NewRelyingParty returns a relying party accepting ceremonies from origins
+func NewRelyingParty(id, name string, origins []string) (*RelyingParty, error) {
+	if id == "" {
+		return nil, ErrInvalidRelyingPartyID
+	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("webauthn: at least one origin is required")
+	}
+	if name == "" {
+		name = id
+	}
+
+	return &RelyingParty{ID: id, Name: name, Origins: origins}, nil
+}
+
+// NewChallenge returns a random challenge to be signed by the authenticator
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// Credential is a public key credential created by an authenticator
+type Credential struct {
+	ID []byte
+	// COSE encoded public key
+	PublicKey []byte
+	SignCount uint32
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create
+// and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.credentialID == nil {
+		return nil, fmt.Errorf("%w: missing attested credential", ErrInvalidAuthData)
+	}
+
+	// refuse keys that could not be used to log in
+	if _, err := ParsePublicKey(data.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        data.credentialID,
+		PublicKey: data.publicKey,
+		SignCount: data.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get against
+// the stored public key and returns the authenticator's signature counter
+func (rp *RelyingParty) VerifyAssertion(challenge, publicKey, clientDataJSON, authData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+
+	data, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	// the authenticator signs its data followed by the hash of the client data
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(slices.Clone(authData), clientDataHash[:]...)
+
+	if err := key.Verify(signed, signature); err != nil {
+		return 0, err
+	}
+
+	return data.signCount, nil
+}
+
+type clientData struct {
+	Type      string    `json:"type"`
+	Challenge Base64URL `json:"challenge"`
+	Origin    string    `json:"origin"`
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidClientData, data.Type)
+	}
+
+	if subtle.ConstantTimeCompare(data.Challenge, challenge) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidClientData)
+	}
+
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return fmt.Errorf("%w: unexpected origin %q", ErrInvalidClientData, data.Origin)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthData(authData []byte) (*authenticatorData, error) {
+	data, err := parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party id mismatch", ErrInvalidAuthData)
+	}
+
+	if data.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+
+	// passkeys replace the password and the second factor, the
+	// authenticator must have verified the user with a PIN or biometrics
+	if data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	return data, nil
+}
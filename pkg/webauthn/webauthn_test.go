@@ -0,0 +1,165 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Luckny/space-it/pkg/webauthn"
+	"github.com/Luckny/space-it/pkg/webauthn/webauthntest"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://app.example.com"
+)
+
+func newTestRelyingParty(t *testing.T) *webauthn.RelyingParty {
+	rp, err := webauthn.NewRelyingParty(testRPID, "Space It", []string{testOrigin})
+	require.NoError(t, err)
+	return rp
+}
+
+func registerCredential(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	reg, err := authenticator.Register(challenge, []byte("user-handle"))
+	require.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(challenge, reg.ClientDataJSON, reg.AttestationObject)
+	require.NoError(t, err)
+	require.Equal(t, reg.CredentialID, credential.ID)
+	require.Zero(t, credential.SignCount)
+
+	return credential
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	credential := registerCredential(t, rp, authenticator)
+
+	for i := 1; i <= 2; i++ {
+		challenge, err := webauthn.NewChallenge()
+		require.NoError(t, err)
+
+		assertion, err := authenticator.Login(challenge)
+		require.NoError(t, err)
+
+		signCount, err := rp.VerifyAssertion(
+			challenge,
+			credential.PublicKey,
+			assertion.ClientDataJSON,
+			assertion.AuthenticatorData,
+			assertion.Signature,
+		)
+		require.NoError(t, err)
+		require.Equal(t, uint32(i), signCount)
+	}
+}
+
+func TestVerifyRegistrationErrors(t *testing.T) {
+	rp := newTestRelyingParty(t)
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+	otherChallenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		authenticator *webauthntest.Authenticator
+		challenge     []byte
+		err           error
+	}{
+		{
+			name:          "challenge mismatch",
+			authenticator: webauthntest.NewAuthenticator(testRPID, testOrigin),
+			challenge:     otherChallenge,
+			err:           webauthn.ErrInvalidClientData,
+		},
+		{
+			name:          "phishing origin",
+			authenticator: webauthntest.NewAuthenticator(testRPID, "https://app.examp1e.com"),
+			challenge:     challenge,
+			err:           webauthn.ErrInvalidClientData,
+		},
+		{
+			name:          "other relying party",
+			authenticator: webauthntest.NewAuthenticator("examp1e.com", testOrigin),
+			challenge:     challenge,
+			err:           webauthn.ErrInvalidAuthData,
+		},
+		{
+			name: "user not verified",
+			authenticator: func() *webauthntest.Authenticator {
+				a := webauthntest.NewAuthenticator(testRPID, testOrigin)
+				a.UserVerified = false
+				return a
+			}(),
+			challenge: challenge,
+			err:       webauthn.ErrUserNotVerified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg, err := tc.authenticator.Register(challenge, []byte("user-handle"))
+			require.NoError(t, err)
+
+			_, err = rp.VerifyRegistration(tc.challenge, reg.ClientDataJSON, reg.AttestationObject)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestVerifyAssertionErrors(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	credential := registerCredential(t, rp, authenticator)
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	t.Run("tampered signature", func(t *testing.T) {
+		assertion, err := authenticator.Login(challenge)
+		require.NoError(t, err)
+
+		assertion.Signature[len(assertion.Signature)-1] ^= 0xff
+		_, err = rp.VerifyAssertion(challenge, credential.PublicKey, assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature)
+		require.ErrorIs(t, err, webauthn.ErrInvalidSignature)
+	})
+
+	t.Run("other credential", func(t *testing.T) {
+		other := webauthntest.NewAuthenticator(testRPID, testOrigin)
+		registerCredential(t, rp, other)
+
+		assertion, err := other.Login(challenge)
+		require.NoError(t, err)
+
+		_, err = rp.VerifyAssertion(challenge, credential.PublicKey, assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature)
+		require.ErrorIs(t, err, webauthn.ErrInvalidSignature)
+	})
+
+	t.Run("registration response replayed", func(t *testing.T) {
+		reg, err := webauthntest.NewAuthenticator(testRPID, testOrigin).Register(challenge, nil)
+		require.NoError(t, err)
+
+		assertion, err := authenticator.Login(challenge)
+		require.NoError(t, err)
+
+		_, err = rp.VerifyAssertion(challenge, credential.PublicKey, reg.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature)
+		require.ErrorIs(t, err, webauthn.ErrInvalidClientData)
+	})
+}
+
+func TestBase64URL(t *testing.T) {
+	data, err := json.Marshal(webauthn.Base64URL{0xfb, 0xff})
+	require.NoError(t, err)
+	require.JSONEq(t, `"-_8"`, string(data))
+
+	var decoded webauthn.Base64URL
+	require.NoError(t, json.Unmarshal([]byte(`"-_8="`), &decoded))
+	require.Equal(t, webauthn.Base64URL{0xfb, 0xff}, decoded)
+}
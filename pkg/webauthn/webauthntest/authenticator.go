@@ -0,0 +1,167 @@
+// Package webauthntest provides a software authenticator to test passkey
+// registration and login without a browser.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Authenticator holds a single ES256 credential
+type Authenticator struct {
+	RelyingPartyID string
+	Origin         string
+	// whether the user is verified with a PIN or biometrics, true by default
+	UserVerified bool
+
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	userHandle   []byte
+	signCount    uint32
+}
+
+type Registration struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type Assertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+func NewAuthenticator(rpID, origin string) *Authenticator {
+	return &Authenticator{
+		RelyingPartyID: rpID,
+		Origin:         origin,
+		UserVerified:   true,
+	}
+}
+
+// Register creates a new credential, replacing the previous one
+func (a *Authenticator) Register(challenge, userHandle []byte) (Registration, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	a.key = key
+	a.userHandle = userHandle
+	a.signCount = 0
+	a.credentialID = make([]byte, 16)
+	if _, err := rand.Read(a.credentialID); err != nil {
+		return Registration{}, err
+	}
+
+	cose, err := a.PublicKey()
+	if err != nil {
+		return Registration{}, err
+	}
+
+	// aaguid, credential id length, credential id and public key
+	attested := make([]byte, 16, 16+2+len(a.credentialID)+len(cose))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, cose...)
+
+	authData := append(a.authData(0x40), attested...)
+
+	var attestation []byte
+	err = codec.NewEncoderBytes(&attestation, &codec.CborHandle{}).Encode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return Registration{}, err
+	}
+
+	return Registration{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    a.clientData("webauthn.create", challenge),
+		AttestationObject: attestation,
+	}, nil
+}
+
+// Login signs challenge with the registered credential
+func (a *Authenticator) Login(challenge []byte) (Assertion, error) {
+	if a.key == nil {
+		return Assertion{}, fmt.Errorf("no credential registered")
+	}
+
+	a.signCount++
+	authData := a.authData(0)
+	clientData := a.clientData("webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return Assertion{}, err
+	}
+
+	return Assertion{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        a.userHandle,
+	}, nil
+}
+
+// PublicKey returns the COSE encoded public key of the credential
+func (a *Authenticator) PublicKey() ([]byte, error) {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+
+	var cose []byte
+	err := codec.NewEncoderBytes(&cose, &codec.CborHandle{}).Encode(map[int]interface{}{
+		1:  2,  // EC2
+		3:  -7, // ES256
+		-1: 1,  // P-256
+		-2: x,
+		-3: y,
+	})
+	return cose, err
+}
+
+// SetSignCount sets the counter used in the next assertion, minus one
+func (a *Authenticator) SetSignCount(count uint32) {
+	a.signCount = count
+}
+
+func (a *Authenticator) authData(extraFlags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RelyingPartyID))
+
+	flags := byte(0x01) | extraFlags
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.Origin,
+	})
+	return data
+}
@@ -3,14 +3,9 @@ package util
 import (
 	"encoding/base64"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 )
 
-var InfoLog = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-var ErrorLog = log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
-
 func ExtractAuthHeader(header string) (string, string, error) {
 	// decode base 64 auth header
 	info, err := base64.StdEncoding.DecodeString(strings.Split(header, " ")[1])
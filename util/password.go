@@ -1,21 +1,193 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
+// ErrPasswordMismatch is returned when a password doesn't match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// Argon2Params are the argon2id parameters of new password hashes
+type Argon2Params struct {
+	// memory in KiB
+	Memory uint32
+	// number of passes over the memory
+	Time    uint32
+	Threads uint8
+}
+
+// DefaultArgon2Params follow the OWASP recommendation
+var DefaultArgon2Params = Argon2Params{Memory: 19 * 1024, Time: 2, Threads: 1}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	// prefix of the hashes of the current scheme, in the PHC string format:
+	// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+	argon2Prefix = "$argon2id$"
+)
+
+// passwordScheme is how new passwords are hashed
+type passwordScheme struct {
+	params Argon2Params
+	// a hash no password matches, computed with the params on first use
+	dummyHash func() string
+}
+
+var currentPasswordScheme atomic.Pointer[passwordScheme]
+
+func init() {
+	SetArgon2Params(DefaultArgon2Params)
+}
+
+// SetArgon2Params sets the parameters of new password hashes, the defaults
+// are used for zero values. Hashes with other parameters are outdated.
+func SetArgon2Params(params Argon2Params) {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2Params.Memory
+	}
+	if params.Time == 0 {
+		params.Time = DefaultArgon2Params.Time
+	}
+	if params.Threads == 0 {
+		params.Threads = DefaultArgon2Params.Threads
+	}
+
+	scheme := &passwordScheme{params: params}
+	scheme.dummyHash = sync.OnceValue(func() string {
+		hashedPassword, err := hashArgon2(RandomPassword(), params)
+		if err != nil {
+			panic(err)
+		}
+		return hashedPassword
+	})
+
+	currentPasswordScheme.Store(scheme)
+}
+
+// HashPassword returns the argon2id hash of the password. The algorithm and
+// its parameters are encoded in the hash.
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return hashArgon2(password, currentPasswordScheme.Load().params)
+}
+
+// CheckPassword checks if the provided password is correct or not. Argon2id
+// and bcrypt hashes are supported.
+func CheckPassword(password string, hashedPassword string) error {
+	if strings.HasPrefix(hashedPassword, argon2Prefix) {
+		return checkArgon2(password, hashedPassword)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// CheckDummyPassword checks the password against a dummy hash and always
+// fails. Checking the password of an unknown user with it takes as long as
+// for an existing user, so response times don't tell which emails exist.
+func CheckDummyPassword(password string) error {
+	if err := CheckPassword(password, currentPasswordScheme.Load().dummyHash()); err != nil {
+		return err
+	}
+	return ErrPasswordMismatch
+}
+
+// NeedsRehash reports whether a hash was not made with the current scheme and
+// should be replaced the next time its password is known
+func NeedsRehash(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2(hashedPassword)
 	if err != nil {
+		return true
+	}
+
+	return params != currentPasswordScheme.Load().params
+}
+
+func hashArgon2(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
-	return string(hashedPassword), nil
+
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, argon2KeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func checkArgon2(password string, hashedPassword string) error {
+	params, salt, key, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// decodeArgon2 returns the parameters, salt and key of an argon2id hash
+func decodeArgon2(hashedPassword string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	rest, ok := strings.CutPrefix(hashedPassword, argon2Prefix)
+	if !ok {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	parts := strings.Split(rest, "$")
+	if len(parts) != 4 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	_, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads)
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if params.Time == 0 || params.Threads == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	return params, salt, key, nil
 }
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -13,16 +14,83 @@ func TestPassword(t *testing.T) {
 	hashedPassword1, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword1)
+	require.True(t, strings.HasPrefix(hashedPassword1, "$argon2id$v=19$m=19456,t=2,p=1$"))
 
 	err = CheckPassword(password, hashedPassword1)
 	require.NoError(t, err)
 
 	wrongPassword := randomString(6)
 	err = CheckPassword(wrongPassword, hashedPassword1)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.ErrorIs(t, err, ErrPasswordMismatch)
 
 	hashedPassword2, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestCheckBcryptPassword(t *testing.T) {
+	password := randomString(6)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPassword(password, string(hashedPassword)))
+	require.ErrorIs(t, CheckPassword(randomString(6), string(hashedPassword)), ErrPasswordMismatch)
+	require.True(t, NeedsRehash(string(hashedPassword)))
+}
+
+func TestInvalidPasswordHash(t *testing.T) {
+	for _, hashedPassword := range []string{
+		"",
+		"plain",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+	} {
+		err := CheckPassword("password", hashedPassword)
+		require.Error(t, err, hashedPassword)
+		require.NotErrorIs(t, err, ErrPasswordMismatch, hashedPassword)
+		require.True(t, NeedsRehash(hashedPassword), hashedPassword)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer SetArgon2Params(DefaultArgon2Params)
+
+	hashedPassword, err := HashPassword(randomString(6))
+	require.NoError(t, err)
+	require.False(t, NeedsRehash(hashedPassword))
+
+	SetArgon2Params(Argon2Params{Memory: 8 * 1024, Time: 1})
+	require.True(t, NeedsRehash(hashedPassword))
+
+	// zero values use the defaults
+	SetArgon2Params(Argon2Params{})
+	require.False(t, NeedsRehash(hashedPassword))
+}
+
+func TestCheckPasswordWithOtherParams(t *testing.T) {
+	defer SetArgon2Params(DefaultArgon2Params)
+
+	password := randomString(6)
+	SetArgon2Params(Argon2Params{Memory: 8 * 1024, Time: 1, Threads: 2})
+	hashedPassword, err := HashPassword(password)
+	require.NoError(t, err)
+	require.Contains(t, hashedPassword, "$m=8192,t=1,p=2$")
+
+	// the parameters of a hash are used to check it
+	SetArgon2Params(DefaultArgon2Params)
+	require.NoError(t, CheckPassword(password, hashedPassword))
+}
+
+func TestCheckDummyPassword(t *testing.T) {
+	err := CheckDummyPassword(randomString(6))
+	require.ErrorIs(t, err, ErrPasswordMismatch)
+
+	// as slow as the check of a real hash
+	dummyHash := currentPasswordScheme.Load().dummyHash()
+	require.True(t, strings.HasPrefix(dummyHash, "$argon2id$"))
+	require.False(t, NeedsRehash(dummyHash))
+}